@@ -14,9 +14,38 @@
  * See the License for the specific language governing permissions and
  * limitations under the License.
  */
-
 package main
 
+import (
+	"flag"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"seata.apache.org/seata-go/pkg/tc"
+	"seata.apache.org/seata-go/pkg/util/log"
+)
+
+// start the standalone tc, e.g.
+//
+//	go run ./cmd -address :8091 -store.mode file -store.file.dir ./sessionStore
 func main() {
-	// start the server
+	var cfg tc.Config
+	cfg.RegisterFlags(flag.CommandLine)
+	flag.Parse()
+	log.Init()
+
+	server, err := tc.NewServer(cfg)
+	if err != nil {
+		log.Fatalf("create tc server failed: %v", err)
+	}
+	if err = server.Start(); err != nil {
+		log.Fatalf("start tc server failed: %v", err)
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	sig := <-signals
+	log.Infof("tc server received signal %v, shutting down", sig)
+	server.Close()
 }
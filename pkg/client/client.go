@@ -18,11 +18,21 @@
 package client
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"sync"
 
+	koanf "github.com/knadh/koanf/v2"
+	"github.com/prometheus/client_golang/prometheus"
+
+	configCenter "seata.apache.org/seata-go/pkg/config"
 	"seata.apache.org/seata-go/pkg/datasource"
 	at "seata.apache.org/seata-go/pkg/datasource/sql"
+	sqlDatasource "seata.apache.org/seata-go/pkg/datasource/sql/datasource"
+	"seata.apache.org/seata-go/pkg/datasource/sql/exec"
 	"seata.apache.org/seata-go/pkg/datasource/sql/exec/config"
+	"seata.apache.org/seata-go/pkg/datasource/sql/types"
 	"seata.apache.org/seata-go/pkg/discovery"
 	"seata.apache.org/seata-go/pkg/integration"
 	remoteConfig "seata.apache.org/seata-go/pkg/remoting/config"
@@ -30,78 +40,324 @@ import (
 	"seata.apache.org/seata-go/pkg/remoting/processor/client"
 	"seata.apache.org/seata-go/pkg/rm"
 	"seata.apache.org/seata-go/pkg/rm/tcc"
+	"seata.apache.org/seata-go/pkg/rm/tcc/fence/handler"
 	"seata.apache.org/seata-go/pkg/tm"
 	"seata.apache.org/seata-go/pkg/util/log"
 )
 
+// defaultClient is the client the package level functions work on, it uses the process wide
+// components, e.g. the registry, the sql drivers and the tm used by tm.WithGlobalTx.
+var defaultClient = &Client{isDefault: true}
+
+// Client is a seata client which owns its registry, sessions to the tc cluster, transaction
+// manager and resource managers, so that several clients run in one process, e.g. to talk to
+// different tx service groups or tc clusters, or to run isolated tests in parallel.
+//
+// The clients created by New support the tcc mode only. The at and xa datasource proxies are
+// registered to database/sql process wide, and their state is process wide as well: the undo
+// config, the lock config, the datasource managers and the at and xa resource managers. They
+// are initialized by the default client from its config, the undo and lock configs of the
+// config given to New are ignored, and the at and xa branches always go through the sessions
+// of the default client.
+type Client struct {
+	cfg *Config
+	o   *options
+	// isDefault the client uses the process wide components
+	isDefault bool
+
+	registry           discovery.RegistryService
+	remotingClient     *getty.GettyRemotingClient
+	tm                 *tm.GlobalTransactionManager
+	rmRemoting         *rm.RMRemoting
+	rmCache            *rm.ResourceManagerCache
+	tccResourceManager *tcc.TCCResourceManager
+	fenceHandler       *handler.TCCFenceWrapperHandler
+	stopProcessors     func(ctx context.Context) error
+	// configCenter the config center connected by InitPath, it follows the hot reloadable keys
+	configCenter *configCenter.ConfigCenter
+
+	// initLock guards the init, a failed init is retried by the next call
+	initLock     sync.Mutex
+	initialized  bool
+	shutdownOnce sync.Once
+	shutdownErr  error
+}
+
 // Init seata client client
 func Init() {
 	InitPath("")
 }
 
-// InitPath init client with config path
+// InitPath init client with config path, it panics if the client fails to init
 func InitPath(configFilePath string) {
-	cfg := LoadPath(configFilePath)
-	initRegistry(cfg)
-	initRmClient(cfg)
-	initTmClient(cfg)
-	initDatasource()
-}
-
-var (
-	onceInitTmClient   sync.Once
-	onceInitRmClient   sync.Once
-	onceInitDatasource sync.Once
-	onceInitRegistry   sync.Once
-)
+	if err := InitPathWithError(configFilePath); err != nil {
+		panic(err)
+	}
+}
 
-// InitTmClient init client tm client
-func initTmClient(cfg *Config) {
-	onceInitTmClient.Do(func() {
-		tm.InitTm(cfg.ClientConfig.TmConfig)
-	})
+// InitPathWithError init client with config path, it returns the error if the config fails to
+// load or the registry fails to init
+func InitPathWithError(configFilePath string) error {
+	cfg, koan, err := loadConfig(configFilePath)
+	if err != nil {
+		return err
+	}
+	return defaultClient.init(cfg, koan, newOptions(nil))
+}
+
+// InitWithConfig init client with the config built by the caller, it neither reads the config
+// file nor connects to the config center
+func InitWithConfig(cfg *Config, opts ...Option) error {
+	if err := validateConfig(cfg); err != nil {
+		return err
+	}
+	return defaultClient.init(cfg, nil, newOptions(opts))
+}
+
+// Default returns the client the package level functions work on
+func Default() *Client {
+	return defaultClient
+}
+
+// New creates a client with the config built by the caller and connects it to the tc cluster
+// of cfg.TxServiceGroup. The client does not share any component with the default client or
+// the other clients except the logger. Its metrics are registered to a registry of its own, so
+// that they do not conflict with the ones of the other clients, pass a registerer wrapped with
+// a label of the client by WithPrometheusRegisterer to expose them.
+func New(cfg *Config, opts ...Option) (*Client, error) {
+	if err := validateConfig(cfg); err != nil {
+		return nil, err
+	}
+	c := &Client{}
+	opts = append([]Option{WithPrometheusRegisterer(prometheus.NewRegistry())}, opts...)
+	if err := c.init(cfg, nil, newOptions(opts)); err != nil {
+		return nil, err
+	}
+	return c, nil
 }
 
-// initRemoting init remoting
-func initRemoting(cfg *Config) {
-	seataConfig := remoteConfig.SeataConfig{
-		ApplicationID:        cfg.ApplicationID,
-		TxServiceGroup:       cfg.TxServiceGroup,
-		ServiceVgroupMapping: cfg.ServiceConfig.VgroupMapping,
-		ServiceGrouplist:     cfg.ServiceConfig.Grouplist,
-		LoadBalanceType:      cfg.GettyConfig.LoadBalanceType,
+func validateConfig(cfg *Config) error {
+	if cfg == nil {
+		return errors.New("config is nil")
+	}
+	if err := cfg.Validate(); err != nil {
+		return fmt.Errorf("invalid config:\n%w", err)
 	}
+	return nil
+}
+
+// Shutdown gracefully stops the seata client. It stops beginning new global transactions,
+// waits up to transport.shutdown.wait for the inflight rpc requests and the pending async
+// branch commits, then closes the sessions, the registry, the config center and all background
+// goroutines.
+func Shutdown(ctx context.Context) error {
+	return defaultClient.Shutdown(ctx)
+}
 
-	getty.InitGetty(&cfg.GettyConfig, &seataConfig)
+// TransactionManager returns the tm which begins and ends the global transactions of the client
+func (c *Client) TransactionManager() *tm.GlobalTransactionManager {
+	return c.tm
 }
 
-// InitRmClient init client rm client
-func initRmClient(cfg *Config) {
-	onceInitRmClient.Do(func() {
+// ResourceManagers returns the resource managers which process the branches of the client
+func (c *Client) ResourceManagers() *rm.ResourceManagerCache {
+	return c.rmCache
+}
+
+// RemotingClient returns the remoting client which talks to the tc cluster of the client
+func (c *Client) RemotingClient() *getty.GettyRemotingClient {
+	return c.remotingClient
+}
+
+// WithGlobalTx runs the business in a global transaction begun and ended by the client
+func (c *Client) WithGlobalTx(ctx context.Context, gc *tm.GtxConfig, business tm.CallbackWithCtx) error {
+	return tm.WithGlobalTx(tm.WithGlobalTransactionManager(ctx, c.tm), gc, business)
+}
+
+// NewTCCServiceProxy creates a tcc service proxy whose resource and branches are registered to
+// the tc cluster of the client
+func (c *Client) NewTCCServiceProxy(service interface{}) (*tcc.TCCServiceProxy, error) {
+	return tcc.NewTCCServiceProxyWithResourceManager(service, c.tccResourceManager)
+}
+
+// init inits the client once, the config center is connected if the local config is given, its
+// properties are merged over the local config. The client is reset if it fails to init, so that
+// the next call retries.
+func (c *Client) init(cfg *Config, local *koanf.Koanf, o *options) error {
+	c.initLock.Lock()
+	defer c.initLock.Unlock()
+	if c.initialized {
+		return nil
+	}
+
+	c.cfg, c.o = cfg, o
+	if local != nil {
+		c.initConfigCenter(local)
+	}
+	if err := c.initRegistry(); err != nil {
+		c.closeConfigCenter()
+		c.cfg, c.o = nil, nil
+		return err
+	}
+	c.initLogger()
+	if c.isDefault {
+		c.initDefault()
+	} else {
+		c.initInstance()
+	}
+	c.initialized = true
+	return nil
+}
+
+func (c *Client) initRegistry() (err error) {
+	if c.o.registry != nil {
+		c.registry = c.o.registry
+	} else if c.registry, err = discovery.NewRegistryService(&c.cfg.ServiceConfig, &c.cfg.RegistryConfig); err != nil {
+		return err
+	}
+	if c.isDefault {
+		discovery.SetRegistry(c.registry)
+	}
+	return nil
+}
+
+// initLogger inits the logger, it is process wide and shared by all clients
+func (c *Client) initLogger() {
+	if c.o.logger != nil {
+		log.SetLogger(c.o.logger)
+	} else if c.isDefault {
 		log.Init()
-		initRemoting(cfg)
-		rm.InitRm(rm.RmConfig{
-			Config:         cfg.ClientConfig.RmConfig,
-			ApplicationID:  cfg.ApplicationID,
-			TxServiceGroup: cfg.TxServiceGroup,
-		})
-		config.Init(cfg.ClientConfig.RmConfig.LockConfig)
-		client.RegisterProcessor()
-		integration.Init()
-		tcc.InitTCC()
-		at.InitAT(cfg.ClientConfig.UndoConfig, cfg.AsyncWorkerConfig)
-		at.InitXA(cfg.ClientConfig.XaConfig)
-	})
+		initLogLevel(c.cfg.LogConfig)
+	}
 }
 
-func initDatasource() {
-	onceInitDatasource.Do(func() {
-		datasource.Init()
-	})
+func (c *Client) seataConfig() *remoteConfig.SeataConfig {
+	return &remoteConfig.SeataConfig{
+		ApplicationID:        c.cfg.ApplicationID,
+		TxServiceGroup:       c.cfg.TxServiceGroup,
+		ServiceVgroupMapping: c.cfg.ServiceConfig.VgroupMapping,
+		ServiceGrouplist:     c.cfg.ServiceConfig.Grouplist,
+		LoadBalanceType:      c.cfg.GettyConfig.LoadBalanceType,
+	}
 }
 
-func initRegistry(cfg *Config) {
-	onceInitRegistry.Do(func() {
-		discovery.InitRegistry(&cfg.ServiceConfig, &cfg.RegistryConfig)
+func (c *Client) gettyOptions() []getty.Option {
+	return []getty.Option{
+		getty.WithRegisterer(c.o.registerer),
+		getty.WithTLSConfig(c.o.tlsConfig),
+		getty.WithRegistry(c.registry),
+	}
+}
+
+func (c *Client) rmConfig() rm.RmConfig {
+	return rm.RmConfig{
+		Config:         c.cfg.ClientConfig.RmConfig,
+		ApplicationID:  c.cfg.ApplicationID,
+		TxServiceGroup: c.cfg.TxServiceGroup,
+	}
+}
+
+// initDefault inits the process wide components used by the package level functions
+func (c *Client) initDefault() {
+	cfg := c.cfg
+	c.remotingClient = getty.GetGettyRemotingClient()
+	c.tm = tm.GetGlobalTransactionManager()
+	c.rmRemoting = rm.GetRMRemotingInstance()
+	c.rmCache = rm.GetRmCacheInstance()
+	c.tccResourceManager = tcc.GetTCCResourceManagerInstance()
+	c.fenceHandler = handler.GetFenceHandler()
+	c.stopProcessors = client.Shutdown
+
+	getty.InitGetty(&cfg.GettyConfig, c.seataConfig(), c.gettyOptions()...)
+	rm.InitRm(c.rmConfig())
+	config.Init(cfg.ClientConfig.RmConfig.LockConfig)
+	client.RegisterProcessor(cfg.ClientConfig.RmConfig)
+	integration.Init()
+	tcc.InitTCC()
+	at.InitATWithRegisterer(c.o.registerer, cfg.ClientConfig.UndoConfig, cfg.AsyncWorkerConfig)
+	at.InitXA(cfg.ClientConfig.XaConfig)
+
+	tm.InitTm(cfg.ClientConfig.TmConfig)
+	tm.SetDisableGlobalTransaction(cfg.ServiceConfig.DisableGlobalTransaction)
+
+	datasource.Init()
+	for _, hook := range c.o.sqlHooks {
+		if hook.Type() == types.SQLTypeUnknown {
+			exec.RegisterCommonHook(hook)
+		} else {
+			exec.RegisterHook(hook)
+		}
+	}
+}
+
+// initInstance inits the components owned by the client, the processors and the resource
+// managers are ready before connecting to the tc, so that no message of the tc is missed.
+func (c *Client) initInstance() {
+	cfg := c.cfg
+	c.remotingClient = getty.NewGettyRemotingClient()
+	c.rmCache = rm.NewResourceManagerCache()
+	c.rmRemoting = rm.NewRMRemoting(c.rmConfig(), c.remotingClient, c.rmCache)
+	c.fenceHandler = handler.NewFenceHandler()
+	c.tccResourceManager = tcc.NewTCCResourceManager(c.rmRemoting, c.fenceHandler)
+	c.rmCache.RegisterResourceManager(c.tccResourceManager)
+	c.stopProcessors = client.RegisterProcessorTo(c.remotingClient, c.rmCache, c.o.registerer, cfg.ClientConfig.RmConfig)
+	c.tm = tm.NewGlobalTransactionManager(cfg.ClientConfig.TmConfig, c.remotingClient)
+
+	c.remotingClient.Init(&cfg.GettyConfig, c.seataConfig(), c.gettyOptions()...)
+}
+
+// Shutdown gracefully stops the client, see the package level Shutdown. It does nothing if the
+// client is not initialized, and returns the error of the first call if it is called again.
+func (c *Client) Shutdown(ctx context.Context) error {
+	if c.cfg == nil {
+		return nil
+	}
+	c.shutdownOnce.Do(func() {
+		c.shutdownErr = c.shutdown(ctx)
 	})
+	return c.shutdownErr
+}
+
+func (c *Client) shutdown(ctx context.Context) error {
+	if wait := c.cfg.TransportConfig.ShutdownConfig.Wait; wait > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, wait)
+		defer cancel()
+	}
+
+	log.Infof("seata client of tx service group %s is shutting down", c.cfg.TxServiceGroup)
+	var errs []error
+	if c.tm != nil {
+		c.tm.Shutdown()
+	}
+	if c.stopProcessors != nil {
+		if err := c.stopProcessors(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if c.rmCache != nil {
+		if err := c.rmCache.Close(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if c.remotingClient != nil {
+		if err := c.remotingClient.Shutdown(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if c.registry != nil {
+		c.registry.Close()
+	}
+	if c.fenceHandler != nil {
+		c.fenceHandler.DestroyLogCleanChannel()
+	}
+	c.closeConfigCenter()
+	if c.isDefault {
+		if err := sqlDatasource.DestroyTableCaches(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	log.Infof("seata client of tx service group %s is shut down", c.cfg.TxServiceGroup)
+	return errors.Join(errs...)
 }
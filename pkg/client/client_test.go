@@ -0,0 +1,137 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client
+
+import (
+	"context"
+	"crypto/tls"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/goleak"
+	"go.uber.org/zap"
+
+	"seata.apache.org/seata-go/pkg/discovery"
+	"seata.apache.org/seata-go/pkg/protocol/branch"
+	"seata.apache.org/seata-go/pkg/tm"
+	"seata.apache.org/seata-go/pkg/util/flagext"
+)
+
+func TestInitPathWithError(t *testing.T) {
+	// the missing config file fails before any component is initialized
+	err := InitPathWithError(filepath.Join(t.TempDir(), "seatago.yml"))
+	assert.Error(t, err)
+	assert.Panics(t, func() {
+		InitPath(filepath.Join(t.TempDir(), "seatago.yml"))
+	})
+}
+
+func TestInitWithConfig(t *testing.T) {
+	err := InitWithConfig(nil)
+	assert.EqualError(t, err, "config is nil")
+
+	// the invalid config fails before any component is initialized
+	cfg := &Config{}
+	flagext.DefaultValues(cfg)
+	cfg.TransportConfig.ShutdownConfig.Wait = -time.Second
+	err = InitWithConfig(cfg)
+	assert.ErrorContains(t, err, "invalid config")
+	assert.ErrorContains(t, err, "transport.shutdown.wait")
+}
+
+func TestNewOptions(t *testing.T) {
+	o := newOptions(nil)
+	assert.Equal(t, prometheus.DefaultRegisterer, o.registerer)
+	assert.Nil(t, o.logger)
+	assert.Nil(t, o.registry)
+	assert.Nil(t, o.tlsConfig)
+
+	registerer := prometheus.NewRegistry()
+	tlsConfig := &tls.Config{ServerName: "seata"}
+	logger := zap.NewNop().Sugar()
+	registry, err := discovery.NewRegistryService(&discovery.ServiceConfig{}, &discovery.RegistryConfig{Type: "file"})
+	assert.NoError(t, err)
+	o = newOptions([]Option{
+		WithPrometheusRegisterer(registerer),
+		WithTLSConfig(tlsConfig),
+		WithRegistry(registry),
+		WithLogger(logger),
+		WithSQLHooks(nil, nil),
+	})
+	assert.Equal(t, registerer, o.registerer)
+	assert.Equal(t, tlsConfig, o.tlsConfig)
+	assert.Equal(t, registry, o.registry)
+	assert.Equal(t, logger, o.logger)
+	assert.Len(t, o.sqlHooks, 2)
+}
+
+func TestNew(t *testing.T) {
+	_, err := New(nil)
+	assert.EqualError(t, err, "config is nil")
+
+	cfg := &Config{}
+	flagext.DefaultValues(cfg)
+	cfg.TransportConfig.ShutdownConfig.Wait = -time.Second
+	_, err = New(cfg)
+	assert.ErrorContains(t, err, "invalid config")
+
+	cfg = &Config{}
+	flagext.DefaultValues(cfg)
+	cfg.TxServiceGroup = "new_tx_group"
+	// every client registers its metrics to a registry of its own by default
+	c1, err := New(cfg)
+	assert.NoError(t, err)
+	c2, err := New(cfg)
+	assert.NoError(t, err)
+
+	assert.NotSame(t, c1.TransactionManager(), c2.TransactionManager())
+	assert.NotSame(t, c1.TransactionManager(), tm.GetGlobalTransactionManager())
+	assert.NotSame(t, c1.RemotingClient(), c2.RemotingClient())
+	assert.NotSame(t, c1.RemotingClient(), Default().RemotingClient())
+	assert.NotNil(t, c1.ResourceManagers().GetResourceManager(branch.BranchTypeTCC))
+	assert.NotSame(t, c1.ResourceManagers(), c2.ResourceManagers())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	assert.NoError(t, c1.Shutdown(ctx))
+	assert.NoError(t, c2.Shutdown(ctx))
+
+	ctx = tm.InitSeataContext(context.Background())
+	assert.ErrorIs(t, c1.TransactionManager().Begin(ctx, time.Second), tm.ErrTmShutdown)
+}
+
+func TestShutdown(t *testing.T) {
+	defer goleak.VerifyNone(t,
+		// the default timer wheel of gost is a process wide singleton
+		goleak.IgnoreTopFunction("github.com/dubbogo/gost/time.NewTimerWheel.func1"),
+		goleak.IgnoreTopFunction("github.com/dubbogo/gost/container/chan.(*UnboundedChan).run"),
+	)
+	InitPath("../../testdata/conf/seatago.yml")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	assert.Nil(t, Shutdown(ctx))
+
+	ctx = tm.InitSeataContext(context.Background())
+	assert.ErrorIs(t, tm.GetGlobalTransactionManager().Begin(ctx, time.Second), tm.ErrTmShutdown)
+	// shutdown twice is allowed
+	assert.Nil(t, Shutdown(context.Background()))
+}
@@ -32,6 +32,7 @@ import (
 	"github.com/knadh/koanf/providers/rawbytes"
 	koanf "github.com/knadh/koanf/v2"
 
+	configCenter "seata.apache.org/seata-go/pkg/config"
 	"seata.apache.org/seata-go/pkg/discovery"
 
 	"seata.apache.org/seata-go/pkg/datasource/sql"
@@ -41,6 +42,7 @@ import (
 	"seata.apache.org/seata-go/pkg/rm/tcc"
 	"seata.apache.org/seata-go/pkg/tm"
 	"seata.apache.org/seata-go/pkg/util/flagext"
+	"seata.apache.org/seata-go/pkg/util/log"
 )
 
 const (
@@ -85,6 +87,8 @@ type Config struct {
 	TransportConfig   remoteConfig.TransportConfig `yaml:"transport" json:"transport" koanf:"transport"`
 	ServiceConfig     discovery.ServiceConfig      `yaml:"service" json:"service" koanf:"service"`
 	RegistryConfig    discovery.RegistryConfig     `yaml:"registry" json:"registry" koanf:"registry"`
+	ConfigCenter      configCenter.Config          `yaml:"config" json:"config" koanf:"config"`
+	LogConfig         log.Config                   `yaml:"log" json:"log" koanf:"log"`
 }
 
 func (c *Config) RegisterFlags(f *flag.FlagSet) {
@@ -103,6 +107,8 @@ func (c *Config) RegisterFlags(f *flag.FlagSet) {
 	c.TransportConfig.RegisterFlagsWithPrefix("transport", f)
 	c.RegistryConfig.RegisterFlagsWithPrefix("registry", f)
 	c.ServiceConfig.RegisterFlagsWithPrefix("service", f)
+	c.ConfigCenter.RegisterFlagsWithPrefix("config", f)
+	c.LogConfig.RegisterFlagsWithPrefix("log", f)
 }
 
 type loaderConf struct {
@@ -113,26 +119,63 @@ type loaderConf struct {
 	name   string // config file name
 }
 
-// Load parse config from user config path
+// Load parse config from user config path, it panics if the config fails to load
 func LoadPath(configFilePath string) *Config {
+	cfg, err := LoadConfig(configFilePath)
+	if err != nil {
+		panic(err)
+	}
+	return cfg
+}
+
+// LoadConfig loads the config from the config file, the path is read from SEATA_GO_CONFIG_PATH
+// if it is empty. The keys of the config file are overridden by the SEATA_ prefixed environment
+// variables, and the config is validated, all the invalid keys are reported in the error.
+func LoadConfig(configFilePath string) (*Config, error) {
+	cfg, _, err := loadConfig(configFilePath)
+	return cfg, err
+}
+
+// loadConfig returns the config along with the resolver of the config file, the resolver is kept
+// to merge the properties of the config center over
+func loadConfig(configFilePath string) (*Config, *koanf.Koanf, error) {
 	if configFilePath == "" {
 		configFilePath = os.Getenv(configFileEnvKey)
 		if configFilePath == "" {
-			panic("system variable SEATA_GO_CONFIG_PATH is empty")
+			return nil, nil, fmt.Errorf("config file path is empty, please set the system variable %s", configFileEnvKey)
 		}
 	}
 
-	var cfg Config
-	// This sets default values from flags to the config.
-	// It needs to be called before parsing the config file!
-	flagext.RegisterFlags(&cfg)
+	conf, err := newLoaderConf(configFilePath)
+	if err != nil {
+		return nil, nil, err
+	}
+	koan, err := getConfigResolver(conf)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err = loadEnv(koan); err != nil {
+		return nil, nil, fmt.Errorf("load the environment variables failed: %w", err)
+	}
+	cfg, err := unmarshalConfig(koan)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err = cfg.Validate(); err != nil {
+		return nil, nil, fmt.Errorf("invalid config %s:\n%w", configFilePath, err)
+	}
+	return cfg, koan, nil
+}
 
-	conf := newLoaderConf(configFilePath)
-	koan := getConfigResolver(conf)
+// unmarshalConfig unmarshals the config under the seata prefix, the absent keys take the
+// default values of the flags
+func unmarshalConfig(koan *koanf.Koanf) (*Config, error) {
+	var cfg Config
+	flagext.DefaultValues(&cfg)
 	if err := koan.UnmarshalWithConf(configPrefix, &cfg, koanf.UnmarshalConf{Tag: yamlSuffix}); err != nil {
-		panic(err)
+		return nil, fmt.Errorf("unmarshal config failed: %w", err)
 	}
-	return &cfg
+	return &cfg, nil
 }
 
 // Load parse config from json bytes
@@ -170,7 +213,7 @@ func resolverFilePath(path string) (name, suffix string) {
 }
 
 // getConfigResolver get config resolver
-func getConfigResolver(conf *loaderConf) *koanf.Koanf {
+func getConfigResolver(conf *loaderConf) (*koanf.Koanf, error) {
 	var (
 		k   *koanf.Koanf
 		err error
@@ -183,7 +226,7 @@ func getConfigResolver(conf *loaderConf) *koanf.Koanf {
 	}
 	bytes := conf.bytes
 	if len(bytes) <= 0 {
-		panic(fmt.Errorf("bytes is nil,please set bytes or file path"))
+		return nil, fmt.Errorf("bytes is nil,please set bytes or file path")
 	}
 	k = koanf.New(conf.delim)
 
@@ -199,12 +242,12 @@ func getConfigResolver(conf *loaderConf) *koanf.Koanf {
 	}
 
 	if err != nil {
-		panic(err)
+		return nil, fmt.Errorf("parse config file %s failed: %w", conf.path, err)
 	}
-	return k
+	return k, nil
 }
 
-func newLoaderConf(configFilePath string) *loaderConf {
+func newLoaderConf(configFilePath string) (*loaderConf, error) {
 	name, suffix := resolverFilePath(configFilePath)
 	conf := &loaderConf{
 		suffix: suffix,
@@ -214,13 +257,13 @@ func newLoaderConf(configFilePath string) *loaderConf {
 	}
 
 	if len(conf.bytes) <= 0 {
-		if bytes, err := ioutil.ReadFile(conf.path); err != nil {
-			panic(err)
-		} else {
-			conf.bytes = bytes
+		bytes, err := ioutil.ReadFile(conf.path)
+		if err != nil {
+			return nil, err
 		}
+		conf.bytes = bytes
 	}
-	return conf
+	return conf, nil
 }
 
 // absolutePath get absolut path
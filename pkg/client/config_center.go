@@ -0,0 +1,147 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/knadh/koanf/providers/confmap"
+	koanf "github.com/knadh/koanf/v2"
+
+	configCenter "seata.apache.org/seata-go/pkg/config"
+	"seata.apache.org/seata-go/pkg/datasource/sql/exec/config"
+	"seata.apache.org/seata-go/pkg/datasource/sql/undo"
+	"seata.apache.org/seata-go/pkg/tm"
+	"seata.apache.org/seata-go/pkg/util/log"
+)
+
+const lockConfigPrefix = "client.rm.lock."
+
+// hotReloadKeys the keys applied at runtime once they are changed in the config center, the
+// others take effect on the next start
+var hotReloadKeys = []string{
+	lockConfigPrefix + "retry-interval",
+	lockConfigPrefix + "retry-times",
+	lockConfigPrefix + "retry-policy-branch-rollback-on-conflict",
+	"service.disable-global-transaction",
+	"client.undo.log-serialization",
+	"log.level",
+}
+
+// initConfigCenter connects the config center of the client config, merges its properties over the
+// local config, and follows the changes of the hot reloadable keys. The local config is used if
+// the config center fails.
+func (c *Client) initConfigCenter(local *koanf.Koanf) {
+	if c.cfg.ConfigCenter.Type == "" {
+		return
+	}
+	center, err := configCenter.NewConfigCenter(&c.cfg.ConfigCenter)
+	if err != nil {
+		log.Errorf("init config center failed, use the local config, err: %v", err)
+		return
+	}
+	c.configCenter = center
+	reloader := &hotReloader{local: local, center: center}
+	for _, key := range hotReloadKeys {
+		center.AddListener(key, reloader)
+	}
+
+	merged, err := mergeConfig(local, center.Properties())
+	if err == nil {
+		err = merged.Validate()
+	}
+	if err != nil {
+		log.Errorf("merge the config center failed, use the local config, err: %v", err)
+		return
+	}
+	c.cfg = merged
+}
+
+// closeConfigCenter closes the config center of the client if it is connected
+func (c *Client) closeConfigCenter() {
+	if c.configCenter != nil {
+		c.configCenter.Close()
+		c.configCenter = nil
+	}
+}
+
+// mergeConfig unmarshals the config from the local config overridden by the properties, the keys of
+// the properties are relative to the seata root. The environment variables still take precedence.
+func mergeConfig(local *koanf.Koanf, properties map[string]string) (*Config, error) {
+	k := koanf.New(".")
+	if err := k.Merge(local); err != nil {
+		return nil, err
+	}
+	remote := make(map[string]interface{}, len(properties))
+	for key, value := range properties {
+		remote[configPrefix+"."+key] = value
+	}
+	if err := k.Load(confmap.Provider(remote, "."), nil); err != nil {
+		return nil, err
+	}
+	if err := loadEnv(k); err != nil {
+		return nil, err
+	}
+	return unmarshalConfig(k)
+}
+
+// hotReloader applies the changed key of the config center
+type hotReloader struct {
+	local  *koanf.Koanf
+	center *configCenter.ConfigCenter
+	// lock orders the applying, so the latest properties are applied at last
+	lock sync.Mutex
+}
+
+func (r *hotReloader) OnChange(event configCenter.ChangeEvent) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	cfg, err := mergeConfig(r.local, r.center.Properties())
+	if err == nil {
+		err = cfg.Validate()
+	}
+	if err != nil {
+		log.Errorf("reload config %s failed, keep the current, err: %v", event.Key, err)
+		return
+	}
+	log.Infof("reload config %s, %s from %q to %q", event.Key, event.Type, event.OldValue, event.NewValue)
+	applyHotReload(event.Key, cfg)
+}
+
+func applyHotReload(key string, cfg *Config) {
+	switch {
+	case strings.HasPrefix(key, lockConfigPrefix):
+		config.Init(cfg.ClientConfig.RmConfig.LockConfig)
+	case key == "service.disable-global-transaction":
+		tm.SetDisableGlobalTransaction(cfg.ServiceConfig.DisableGlobalTransaction)
+	case key == "client.undo.log-serialization":
+		undo.SetLogSerialization(cfg.ClientConfig.UndoConfig.LogSerialization)
+	case key == "log.level":
+		initLogLevel(cfg.LogConfig)
+	}
+}
+
+func initLogLevel(logConfig log.Config) {
+	level, err := log.ParseLevel(logConfig.Level)
+	if err != nil {
+		log.Warnf("invalid log level %s, err: %v", logConfig.Level, err)
+		return
+	}
+	log.SetLevel(level)
+}
@@ -0,0 +1,130 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+
+	configCenter "seata.apache.org/seata-go/pkg/config"
+	"seata.apache.org/seata-go/pkg/datasource/sql/exec/at"
+	"seata.apache.org/seata-go/pkg/datasource/sql/undo"
+	"seata.apache.org/seata-go/pkg/util/flagext"
+)
+
+func TestMergeConfig(t *testing.T) {
+	_, local, err := loadConfig("../../testdata/conf/seatago.yml")
+	assert.NoError(t, err)
+	cfg, err := mergeConfig(local, map[string]string{
+		"client.rm.lock.retry-interval":           "50ms",
+		"client.rm.lock.retry-times":              "30",
+		"service.disable-global-transaction":      "true",
+		"service.vgroup-mapping.default_tx_group": "remote",
+		"client.undo.log-serialization":           "protobuf",
+		"log.level":                               "debug",
+		"registry.kubernetes.sync-timeout":        "1m",
+		"client.rm.report-success-enable":         "true",
+		"not.in.config":                           "ignored",
+	})
+	assert.NoError(t, err)
+
+	assert.Equal(t, 50*time.Millisecond, cfg.ClientConfig.RmConfig.LockConfig.RetryInterval)
+	assert.Equal(t, 30, cfg.ClientConfig.RmConfig.LockConfig.RetryTimes)
+	assert.True(t, cfg.ServiceConfig.DisableGlobalTransaction)
+	assert.Equal(t, "remote", cfg.ServiceConfig.VgroupMapping["default_tx_group"])
+	assert.Equal(t, "protobuf", cfg.ClientConfig.UndoConfig.LogSerialization)
+	assert.Equal(t, "debug", cfg.LogConfig.Level)
+	assert.Equal(t, time.Minute, cfg.RegistryConfig.Kubernetes.SyncTimeout)
+	assert.True(t, cfg.ClientConfig.RmConfig.ReportSuccessEnable)
+	// the keys absent in the config center keep the local values
+	assert.Equal(t, "applicationName", cfg.ApplicationID)
+	assert.Equal(t, "127.0.0.1:6380", cfg.RegistryConfig.Redis.ServerAddr)
+
+	_, err = mergeConfig(local, map[string]string{"client.rm.lock.retry-times": "many"})
+	assert.Error(t, err)
+}
+
+func TestHotReloader(t *testing.T) {
+	lockConfig, logSerialization := at.GetLockConfig(), undo.GetLogSerialization()
+	defer func() {
+		at.SetLockConfig(lockConfig)
+		undo.SetLogSerialization(logSerialization)
+	}()
+
+	name := filepath.Join(t.TempDir(), "seata.properties")
+	assert.NoError(t, os.WriteFile(name, []byte("client.rm.lock.retry-times=20\n"), 0o644))
+	c, err := configCenter.NewConfigCenter(&configCenter.Config{
+		Type: configCenter.FILE,
+		File: configCenter.FileConfig{Name: name, RefreshInterval: 10 * time.Millisecond},
+	})
+	assert.NoError(t, err)
+	defer c.Close()
+
+	_, local, err := loadConfig("../../testdata/conf/seatago.yml")
+	assert.NoError(t, err)
+	reloader := &hotReloader{local: local, center: c}
+	for _, key := range hotReloadKeys {
+		c.AddListener(key, reloader)
+	}
+
+	assert.NoError(t, os.WriteFile(name, []byte("client.rm.lock.retry-times=40\nclient.undo.log-serialization=protobuf\n"), 0o644))
+	assert.Eventually(t, func() bool {
+		return at.GetLockConfig().RetryTimes == 40 && undo.GetLogSerialization() == "protobuf"
+	}, 3*time.Second, 10*time.Millisecond)
+	// the other lock configs follow the local config
+	assert.Equal(t, 30*time.Second, at.GetLockConfig().RetryInterval)
+}
+
+func TestClientConfigCenter(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "seata.properties")
+	assert.NoError(t, os.WriteFile(name, []byte("client.rm.lock.retry-times=many\n"), 0o644))
+	_, local, err := loadConfig("../../testdata/conf/seatago.yml")
+	assert.NoError(t, err)
+
+	cfg := &Config{}
+	flagext.DefaultValues(cfg)
+	cfg.TxServiceGroup = "config_center_tx_group"
+	cfg.ConfigCenter.Type = configCenter.FILE
+	cfg.ConfigCenter.File.Name = name
+	cfg.RegistryConfig.Type = "unknown"
+	c := &Client{}
+	// the config center is closed once the client fails to init
+	err = c.init(cfg, local, newOptions([]Option{WithPrometheusRegisterer(prometheus.NewRegistry())}))
+	assert.Error(t, err)
+	assert.Nil(t, c.configCenter)
+	assert.Nil(t, c.cfg)
+
+	// and connected again by the retry
+	assert.NoError(t, os.WriteFile(name, []byte("client.rm.lock.retry-times=40\n"), 0o644))
+	cfg.RegistryConfig.Type = "file"
+	err = c.init(cfg, local, newOptions([]Option{WithPrometheusRegisterer(prometheus.NewRegistry())}))
+	assert.NoError(t, err)
+	assert.NotNil(t, c.configCenter)
+	assert.Equal(t, 40, c.cfg.ClientConfig.RmConfig.LockConfig.RetryTimes)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	assert.NoError(t, c.Shutdown(ctx))
+	assert.Nil(t, c.configCenter)
+}
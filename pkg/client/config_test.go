@@ -20,6 +20,7 @@ package client
 import (
 	"flag"
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -62,6 +63,8 @@ func TestLoadPath(t *testing.T) {
 	assert.Equal(t, false, cfg.ClientConfig.RmConfig.SagaRetryPersistModeUpdate)
 	assert.Equal(t, -2147482648, cfg.ClientConfig.RmConfig.TccActionInterceptorOrder)
 	assert.Equal(t, "druid", cfg.ClientConfig.RmConfig.SqlParserType)
+	assert.Equal(t, 16, cfg.ClientConfig.RmConfig.PhaseTwoWorkerCount)
+	assert.Equal(t, 1024, cfg.ClientConfig.RmConfig.PhaseTwoQueueSize)
 	assert.Equal(t, 30*time.Second, cfg.ClientConfig.RmConfig.LockConfig.RetryInterval)
 	assert.Equal(t, 10, cfg.ClientConfig.RmConfig.LockConfig.RetryTimes)
 	assert.Equal(t, true, cfg.ClientConfig.RmConfig.LockConfig.RetryPolicyBranchRollbackOnConflict)
@@ -71,6 +74,7 @@ func TestLoadPath(t *testing.T) {
 	assert.Equal(t, "json", cfg.ClientConfig.UndoConfig.LogSerialization)
 	assert.Equal(t, "undo_log", cfg.ClientConfig.UndoConfig.LogTable)
 	assert.Equal(t, true, cfg.ClientConfig.UndoConfig.OnlyCareUpdateColumns)
+	assert.Equal(t, 3000, cfg.ClientConfig.UndoConfig.LogDeleteBatchRows)
 	assert.NotNil(t, cfg.ClientConfig.UndoConfig.CompressConfig)
 	assert.Equal(t, true, cfg.ClientConfig.UndoConfig.CompressConfig.Enable)
 	assert.Equal(t, "zip", cfg.ClientConfig.UndoConfig.CompressConfig.Type)
@@ -115,6 +119,7 @@ func TestLoadPath(t *testing.T) {
 	assert.NotNil(t, cfg.RegistryConfig)
 	assert.Equal(t, "file", cfg.RegistryConfig.Type)
 	assert.Equal(t, "seatago.yml", cfg.RegistryConfig.File.Name)
+	assert.Equal(t, time.Second*2, cfg.RegistryConfig.File.RefreshInterval)
 	assert.Equal(t, "seata-server", cfg.RegistryConfig.Nacos.Application)
 	assert.Equal(t, "127.0.0.1:8848", cfg.RegistryConfig.Nacos.ServerAddr)
 	assert.Equal(t, "SEATA_GROUP", cfg.RegistryConfig.Nacos.Group)
@@ -125,9 +130,157 @@ func TestLoadPath(t *testing.T) {
 	assert.Equal(t, "test-secret-key", cfg.RegistryConfig.Nacos.SecretKey)
 	assert.Equal(t, "default", cfg.RegistryConfig.Etcd3.Cluster)
 	assert.Equal(t, "http://localhost:2379", cfg.RegistryConfig.Etcd3.ServerAddr)
+	assert.Equal(t, "127.0.0.1:7091,127.0.0.1:7092", cfg.RegistryConfig.Raft.ServerAddr)
+	assert.Equal(t, time.Second*20, cfg.RegistryConfig.Raft.MetadataMaxAge)
+	assert.Equal(t, "127.0.0.1:8500", cfg.RegistryConfig.Consul.ServerAddr)
+	assert.Equal(t, "consul-token", cfg.RegistryConfig.Consul.AclToken)
+	assert.Equal(t, time.Second*30, cfg.RegistryConfig.Consul.WaitTime)
+	assert.Equal(t, "127.0.0.1:2181,127.0.0.1:2182", cfg.RegistryConfig.Zk.ServerAddr)
+	assert.Equal(t, time.Second*10, cfg.RegistryConfig.Zk.SessionTimeout)
+	assert.Equal(t, time.Second*3, cfg.RegistryConfig.Zk.ConnectTimeout)
+	assert.Equal(t, "zk-user", cfg.RegistryConfig.Zk.Username)
+	assert.Equal(t, "zk-password", cfg.RegistryConfig.Zk.Password)
+	assert.Equal(t, "127.0.0.1:6380", cfg.RegistryConfig.Redis.ServerAddr)
+	assert.Equal(t, "redis-password", cfg.RegistryConfig.Redis.Password)
+	assert.Equal(t, 2, cfg.RegistryConfig.Redis.DB)
+	assert.Equal(t, time.Second*10, cfg.RegistryConfig.Redis.RefreshInterval)
+	assert.Equal(t, "http://127.0.0.1:8761/eureka", cfg.RegistryConfig.Eureka.ServiceURL)
+	assert.Equal(t, "seata-server", cfg.RegistryConfig.Eureka.Application)
+	assert.Equal(t, "eureka-user", cfg.RegistryConfig.Eureka.Username)
+	assert.Equal(t, "eureka-password", cfg.RegistryConfig.Eureka.Password)
+	assert.Equal(t, time.Second*20, cfg.RegistryConfig.Eureka.RefreshInterval)
+	assert.Equal(t, "_seata._tcp.seata-server.default.svc.cluster.local", cfg.RegistryConfig.Dns.Names["default"])
+	assert.Equal(t, 8092, cfg.RegistryConfig.Dns.Port)
+	assert.Equal(t, "10.96.0.10:53", cfg.RegistryConfig.Dns.Server)
+	assert.Equal(t, time.Second*15, cfg.RegistryConfig.Dns.RefreshInterval)
+	assert.Equal(t, "seata", cfg.RegistryConfig.Kubernetes.Namespace)
+	assert.Equal(t, "seata-server", cfg.RegistryConfig.Kubernetes.Service)
+	assert.Equal(t, "transaction", cfg.RegistryConfig.Kubernetes.PortName)
+	assert.Equal(t, "/etc/seata/kubeconfig", cfg.RegistryConfig.Kubernetes.Kubeconfig)
+	assert.Equal(t, time.Second*5, cfg.RegistryConfig.Kubernetes.SyncTimeout)
 
-	// reset flag.CommandLine
-	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	assert.Equal(t, "file", cfg.ConfigCenter.Type)
+	assert.Equal(t, "config.conf", cfg.ConfigCenter.File.Name)
+	assert.Equal(t, time.Second*2, cfg.ConfigCenter.File.RefreshInterval)
+	assert.Equal(t, "127.0.0.1:8848", cfg.ConfigCenter.Nacos.ServerAddr)
+	assert.Equal(t, "SEATA_GROUP", cfg.ConfigCenter.Nacos.Group)
+	assert.Equal(t, "seata.properties", cfg.ConfigCenter.Nacos.DataId)
+	assert.Equal(t, "http://localhost:2379", cfg.ConfigCenter.Etcd3.ServerAddr)
+	assert.Equal(t, "seata.properties", cfg.ConfigCenter.Etcd3.Key)
+	assert.Equal(t, "info", cfg.LogConfig.Level)
+}
+
+func TestLoadConfig(t *testing.T) {
+	writeConfig := func(name, content string) string {
+		path := filepath.Join(t.TempDir(), name)
+		assert.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+		return path
+	}
+
+	t.Run("path from env", func(t *testing.T) {
+		t.Setenv(configFileEnvKey, "../../testdata/conf/seatago.yml")
+		cfg, err := LoadConfig("")
+		assert.NoError(t, err)
+		assert.Equal(t, "applicationName", cfg.ApplicationID)
+	})
+
+	t.Run("empty path", func(t *testing.T) {
+		t.Setenv(configFileEnvKey, "")
+		_, err := LoadConfig("")
+		assert.EqualError(t, err, "config file path is empty, please set the system variable SEATA_GO_CONFIG_PATH")
+		assert.Panics(t, func() {
+			LoadPath("")
+		})
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		_, err := LoadConfig(filepath.Join(t.TempDir(), "seatago.yml"))
+		assert.True(t, os.IsNotExist(err))
+	})
+
+	t.Run("unsupported suffix", func(t *testing.T) {
+		_, err := LoadConfig(writeConfig("seatago.ini", "enabled=true"))
+		assert.ErrorContains(t, err, "no support ini file suffix")
+	})
+
+	t.Run("parse error", func(t *testing.T) {
+		_, err := LoadConfig(writeConfig("seatago.yml", "seata: ["))
+		assert.ErrorContains(t, err, "parse config file")
+	})
+
+	t.Run("invalid values", func(t *testing.T) {
+		_, err := LoadConfig(writeConfig("seatago.yml", `
+seata:
+  client:
+    tm:
+      default-global-transaction-timeout: -1s
+    undo:
+      log-serialization: kryo
+      compress:
+        type: snappy
+  service:
+    grouplist:
+      default: 127.0.0.1
+  transport:
+    rpc-rm-request-timeout: -30s
+    serialization: hessian
+    compressor: gzip
+  registry:
+    type: apollo
+  config:
+    type: apollo
+`))
+		assert.Error(t, err)
+		for _, msg := range []string{
+			"seata.client.tm.default-global-transaction-timeout: negative duration -1s",
+			"seata.transport.rpc-rm-request-timeout: negative duration -30s",
+			`seata.registry.type: unsupported registry type "apollo"`,
+			`seata.service.grouplist.default: invalid endpoint "127.0.0.1"`,
+			`seata.transport.serialization: unsupported serialization "hessian"`,
+			`seata.transport.compressor: unsupported compressor "gzip"`,
+			`seata.config.type: unsupported config center type "apollo"`,
+			`seata.client.undo.log-serialization: unsupported serialization "kryo"`,
+			`seata.client.undo.compress.type: unsupported compressor type "snappy"`,
+		} {
+			assert.ErrorContains(t, err, msg)
+		}
+	})
+
+	t.Run("env overrides", func(t *testing.T) {
+		t.Setenv("SEATA_APPLICATION_ID", "env-app")
+		t.Setenv("SEATA_CLIENT_RM_LOCK_RETRY_TIMES", "99")
+		t.Setenv("SEATA_CLIENT_RM_LOCK_RETRY_INTERVAL", "15ms")
+		t.Setenv("SEATA_SERVICE_DISABLE_GLOBAL_TRANSACTION", "true")
+		t.Setenv("SEATA_SERVICE_VGROUP_MAPPING_DEFAULT_TX_GROUP", "env-cluster")
+		t.Setenv("SEATA_SERVICE_VGROUP_MAPPING", `{"other_tx_group":"Other"}`)
+		t.Setenv("SEATA_SERVICE_GROUPLIST", `{"env-cluster":"10.0.0.1:8091","Other":"10.0.0.2:8091"}`)
+		t.Setenv("SEATA_UNKNOWN_KEY", "ignored")
+		cfg, err := LoadConfig("../../testdata/conf/seatago.yml")
+		assert.NoError(t, err)
+		assert.Equal(t, "env-app", cfg.ApplicationID)
+		assert.Equal(t, 99, cfg.ClientConfig.RmConfig.LockConfig.RetryTimes)
+		assert.Equal(t, 15*time.Millisecond, cfg.ClientConfig.RmConfig.LockConfig.RetryInterval)
+		assert.True(t, cfg.ServiceConfig.DisableGlobalTransaction)
+		// the mapped cluster resolves to its grouplist even if it has '-' or upper case letters
+		cluster := cfg.ServiceConfig.VgroupMapping["default_tx_group"]
+		assert.Equal(t, "env-cluster", cluster)
+		assert.Equal(t, "10.0.0.1:8091", cfg.ServiceConfig.Grouplist[cluster])
+		cluster = cfg.ServiceConfig.VgroupMapping["other_tx_group"]
+		assert.Equal(t, "Other", cluster)
+		assert.Equal(t, "10.0.0.2:8091", cfg.ServiceConfig.Grouplist[cluster])
+		// the keys absent in the environment keep the values of the config file
+		assert.Equal(t, "127.0.0.1:8091", cfg.ServiceConfig.Grouplist["default"])
+		assert.Equal(t, "default_tx_group", cfg.TxServiceGroup)
+
+		t.Setenv("SEATA_SERVICE_GROUPLIST", "env-cluster=10.0.0.1:8091")
+		_, err = LoadConfig("../../testdata/conf/seatago.yml")
+		assert.ErrorContains(t, err, "SEATA_SERVICE_GROUPLIST: invalid json object of seata.service.grouplist")
+		t.Setenv("SEATA_SERVICE_GROUPLIST", "{}")
+
+		t.Setenv("SEATA_CLIENT_RM_LOCK_RETRY_INTERVAL", "-1s")
+		_, err = LoadConfig("../../testdata/conf/seatago.yml")
+		assert.ErrorContains(t, err, "seata.client.rm.lock.retry-interval: negative duration -1s")
+	})
 }
 
 func TestLoadJson(t *testing.T) {
@@ -0,0 +1,131 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/knadh/koanf/providers/confmap"
+	"github.com/knadh/koanf/providers/env"
+	koanf "github.com/knadh/koanf/v2"
+)
+
+const envPrefix = "SEATA_"
+
+var (
+	onceEnvKeys sync.Once
+	// envKeys the environment variable name -> the key of the config
+	envKeys map[string]string
+	// envMapKeys the environment variable names of the map keys, the longer name goes first
+	envMapKeys []envMapKey
+)
+
+type envMapKey struct {
+	name string
+	key  string
+}
+
+// loadEnv overrides the keys of the config by the SEATA_ prefixed environment variables. The
+// variable name is the key in upper case with '.' and '-' replaced by '_', for example
+// SEATA_CLIENT_RM_LOCK_RETRY_TIMES overrides seata.client.rm.lock.retry-times.
+//
+// The map keys are overridden by the variable of the map key holding the entries as a json object,
+// for example SEATA_SERVICE_GROUPLIST='{"my-cluster":"10.0.0.1:8091"}' sets the grouplist of
+// my-cluster and keeps the other entries. An entry named in lower case with '_' only can also be
+// set by a variable of its own, the rest of the name in lower case is the entry, for example
+// SEATA_SERVICE_VGROUP_MAPPING_DEFAULT_TX_GROUP sets seata.service.vgroup-mapping.default_tx_group.
+func loadEnv(k *koanf.Koanf) error {
+	onceEnvKeys.Do(initEnvKeys)
+	err := k.Load(env.ProviderWithValue(envPrefix, ".", func(name string, value string) (string, interface{}) {
+		if key, ok := envKeys[name]; ok {
+			return key, value
+		}
+		for _, mapKey := range envMapKeys {
+			if entry := strings.TrimPrefix(name, mapKey.name+"_"); entry != name && entry != "" {
+				return mapKey.key + "." + strings.ToLower(entry), value
+			}
+		}
+		// not a key of the config, such as SEATA_GO_CONFIG_PATH
+		return "", nil
+	}), nil)
+	if err != nil {
+		return err
+	}
+	// the json entries are loaded after the single ones, so they win if both set an entry
+	for _, mapKey := range envMapKeys {
+		value, ok := os.LookupEnv(mapKey.name)
+		if !ok {
+			continue
+		}
+		entries := make(map[string]string)
+		if err = json.Unmarshal([]byte(value), &entries); err != nil {
+			return fmt.Errorf("%s: invalid json object of %s: %w", mapKey.name, mapKey.key, err)
+		}
+		values := make(map[string]interface{}, len(entries))
+		for entry, v := range entries {
+			values[entry] = v
+		}
+		if err = k.Load(confmap.Provider(map[string]interface{}{mapKey.key: values}, "."), nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func initEnvKeys() {
+	envKeys = make(map[string]string)
+	walkConfigKeys(reflect.TypeOf(Config{}), configPrefix, func(key string, kind reflect.Kind) {
+		name := envName(key)
+		if kind == reflect.Map {
+			envMapKeys = append(envMapKeys, envMapKey{name: name, key: key})
+		} else {
+			envKeys[name] = key
+		}
+	})
+	sort.Slice(envMapKeys, func(i, j int) bool {
+		return len(envMapKeys[i].name) > len(envMapKeys[j].name)
+	})
+}
+
+func envName(key string) string {
+	return strings.ToUpper(strings.NewReplacer(".", "_", "-", "_").Replace(key))
+}
+
+// walkConfigKeys calls fn with the key of every field in the config struct, the key is joined by
+// the yaml tags of the fields, the nested structs are walked into instead of being called with
+func walkConfigKeys(t reflect.Type, prefix string, fn func(key string, kind reflect.Kind)) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := strings.Split(field.Tag.Get(yamlSuffix), ",")[0]
+		if !field.IsExported() || name == "" || name == "-" {
+			continue
+		}
+		key := prefix + "." + name
+		if field.Type.Kind() == reflect.Struct {
+			walkConfigKeys(field.Type, key, fn)
+			continue
+		}
+		fn(key, field.Type.Kind())
+	}
+}
@@ -0,0 +1,84 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client
+
+import (
+	"crypto/tls"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"seata.apache.org/seata-go/pkg/datasource/sql/exec"
+	"seata.apache.org/seata-go/pkg/discovery"
+	"seata.apache.org/seata-go/pkg/util/log"
+)
+
+type options struct {
+	logger     log.Logger
+	registerer prometheus.Registerer
+	registry   discovery.RegistryService
+	tlsConfig  *tls.Config
+	sqlHooks   []exec.SQLHook
+}
+
+type Option func(*options)
+
+func newOptions(opts []Option) *options {
+	o := &options{registerer: prometheus.DefaultRegisterer}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// WithLogger logs by the logger instead of the default zap logger
+func WithLogger(logger log.Logger) Option {
+	return func(o *options) {
+		o.logger = logger
+	}
+}
+
+// WithPrometheusRegisterer registers the metrics of the async worker and the circuit breakers to
+// the registerer instead of the default registerer of prometheus
+func WithPrometheusRegisterer(registerer prometheus.Registerer) Option {
+	return func(o *options) {
+		o.registerer = registerer
+	}
+}
+
+// WithRegistry looks up the tc instances by the registry instead of the one created by the
+// registry config, the registry is closed on shutdown
+func WithRegistry(registry discovery.RegistryService) Option {
+	return func(o *options) {
+		o.registry = registry
+	}
+}
+
+// WithTLSConfig connects to the tc over tls with the config
+func WithTLSConfig(tlsConfig *tls.Config) Option {
+	return func(o *options) {
+		o.tlsConfig = tlsConfig
+	}
+}
+
+// WithSQLHooks registers the hooks around the sql executed by the datasource proxy, the hook of
+// the unknown sql type is called for all the sql
+func WithSQLHooks(hooks ...exec.SQLHook) Option {
+	return func(o *options) {
+		o.sqlHooks = append(o.sqlHooks, hooks...)
+	}
+}
@@ -0,0 +1,82 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"seata.apache.org/seata-go/pkg/compressor"
+	"seata.apache.org/seata-go/pkg/datasource/sql/undo/parser"
+)
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// Validate checks the config, the errors of all the invalid keys are joined
+func (c *Config) Validate() error {
+	var errs []error
+	errs = append(errs, validateDurations(reflect.ValueOf(c).Elem(), configPrefix)...)
+	if err := c.RegistryConfig.ValidateWithPrefix(configPrefix + ".registry"); err != nil {
+		errs = append(errs, err)
+	}
+	if err := c.ServiceConfig.ValidateWithPrefix(configPrefix + ".service"); err != nil {
+		errs = append(errs, err)
+	}
+	if err := c.TransportConfig.ValidateWithPrefix(configPrefix + ".transport"); err != nil {
+		errs = append(errs, err)
+	}
+	if err := c.ConfigCenter.ValidateWithPrefix(configPrefix + ".config"); err != nil {
+		errs = append(errs, err)
+	}
+
+	// the undo log parsers are registered in the parser package which depends on the undo config
+	undoConfig := c.ClientConfig.UndoConfig
+	if _, err := parser.GetCache().Load(undoConfig.LogSerialization); err != nil {
+		errs = append(errs, fmt.Errorf("%s.client.undo.log-serialization: unsupported serialization %q", configPrefix, undoConfig.LogSerialization))
+	}
+	if _, err := compressor.ParseCompressorType(undoConfig.CompressConfig.Type); err != nil {
+		errs = append(errs, fmt.Errorf("%s.client.undo.compress.type: %v", configPrefix, err))
+	}
+	return errors.Join(errs...)
+}
+
+// validateDurations reports the negative durations in the config struct, a timeout or an
+// interval is never negative
+func validateDurations(v reflect.Value, prefix string) []error {
+	var errs []error
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		name := strings.Split(field.Tag.Get(yamlSuffix), ",")[0]
+		if !field.IsExported() || name == "" || name == "-" {
+			continue
+		}
+		key := prefix + "." + name
+		switch {
+		case field.Type == durationType:
+			if d := time.Duration(v.Field(i).Int()); d < 0 {
+				errs = append(errs, fmt.Errorf("%s: negative duration %s", key, d))
+			}
+		case field.Type.Kind() == reflect.Struct:
+			errs = append(errs, validateDurations(v.Field(i), key)...)
+		}
+	}
+	return errs
+}
@@ -17,6 +17,11 @@
 
 package compressor
 
+import (
+	"fmt"
+	"strings"
+)
+
 type CompressorType string
 
 const (
@@ -31,6 +36,17 @@ const (
 	CompressorZstd    CompressorType = "Zstd"
 )
 
+// ParseCompressorType returns the compressor type of the name ignoring the case, such as gzip
+func ParseCompressorType(name string) (CompressorType, error) {
+	for _, c := range []CompressorType{CompressorNone, CompressorGzip, CompressorZip, CompressorBzip2,
+		CompressorLz4, CompressorZstd, CompressorDeflate} {
+		if strings.EqualFold(string(c), name) {
+			return c, nil
+		}
+	}
+	return "", fmt.Errorf("unsupported compressor type %q", name)
+}
+
 func (c CompressorType) GetCompressor() Compressor {
 	switch c {
 	case CompressorNone:
@@ -0,0 +1,328 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/knadh/koanf/parsers/yaml"
+	"github.com/knadh/koanf/providers/rawbytes"
+	koanf "github.com/knadh/koanf/v2"
+
+	"seata.apache.org/seata-go/pkg/util/log"
+)
+
+// propertyPrefix the root of the seata config, the keys of the properties are relative to it
+const propertyPrefix = "seata."
+
+type ChangeType int
+
+const (
+	// ChangeTypeAdd the key is added to the config
+	ChangeTypeAdd ChangeType = iota
+	// ChangeTypeModify the value of the key is changed
+	ChangeTypeModify
+	// ChangeTypeDelete the key is removed from the config
+	ChangeTypeDelete
+)
+
+func (t ChangeType) String() string {
+	switch t {
+	case ChangeTypeAdd:
+		return "ADD"
+	case ChangeTypeModify:
+		return "MODIFY"
+	case ChangeTypeDelete:
+		return "DELETE"
+	default:
+		return "UNKNOWN"
+	}
+}
+
+// ChangeEvent the change of a key of the config
+type ChangeEvent struct {
+	Key      string
+	Type     ChangeType
+	OldValue string
+	NewValue string
+}
+
+// Listener is notified of the changes of the keys it is added to
+type Listener interface {
+	OnChange(event ChangeEvent)
+}
+
+// provider reads the config content from where the config is kept
+type provider interface {
+	// load returns the current content of the config, nil if the config does not exist
+	load() ([]byte, error)
+	// watch calls onChange with the content once the config may have changed, it blocks until
+	// the provider is closed
+	watch(onChange func(content []byte))
+	close()
+}
+
+// ConfigCenter keeps the properties loaded from the config center and follows the changes of
+// them. The keys of the properties are relative to the seata root, such as
+// client.rm.lock.retry-interval, the listeners of a key are notified when its value changes.
+type ConfigCenter struct {
+	name     string
+	provider provider
+	// content the content loaded last time, nil if the config does not exist
+	content    []byte
+	properties map[string]string
+	rwLock     sync.RWMutex
+
+	listeners    map[string][]Listener
+	listenerLock sync.RWMutex
+
+	closeOnce sync.Once
+	wg        sync.WaitGroup
+}
+
+// NewConfigCenter loads the properties from the config center of the type in config, and starts
+// watching the changes of them.
+func NewConfigCenter(config *Config) (*ConfigCenter, error) {
+	if config == nil {
+		return nil, fmt.Errorf("config center config is nil")
+	}
+	var (
+		name string
+		p    provider
+		err  error
+	)
+	switch config.Type {
+	case FILE:
+		name, p = config.File.Name, newFileProvider(&config.File)
+	case NACOS:
+		name = config.Nacos.DataId
+		p, err = newNacosProvider(&config.Nacos)
+	case ETCD:
+		name = config.Etcd3.Key
+		p, err = newEtcdProvider(&config.Etcd3)
+	default:
+		return nil, fmt.Errorf("config center type %s is not supported", config.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return newConfigCenter(name, p)
+}
+
+func newConfigCenter(name string, p provider) (*ConfigCenter, error) {
+	content, err := p.load()
+	if err != nil {
+		p.close()
+		return nil, fmt.Errorf("load config %s failed, err: %w", name, err)
+	}
+	properties, err := parseProperties(name, content)
+	if err != nil {
+		p.close()
+		return nil, fmt.Errorf("parse config %s failed, err: %w", name, err)
+	}
+	c := &ConfigCenter{
+		name:       name,
+		provider:   p,
+		content:    content,
+		properties: properties,
+		listeners:  make(map[string][]Listener),
+	}
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		p.watch(c.onChange)
+	}()
+	return c, nil
+}
+
+// Properties returns a copy of the current properties
+func (c *ConfigCenter) Properties() map[string]string {
+	c.rwLock.RLock()
+	defer c.rwLock.RUnlock()
+	properties := make(map[string]string, len(c.properties))
+	for k, v := range c.properties {
+		properties[k] = v
+	}
+	return properties
+}
+
+// Get returns the value of the key and whether the key exists
+func (c *ConfigCenter) Get(key string) (string, bool) {
+	c.rwLock.RLock()
+	defer c.rwLock.RUnlock()
+	value, ok := c.properties[strings.TrimPrefix(key, propertyPrefix)]
+	return value, ok
+}
+
+// AddListener notifies the listener once the value of the key changes
+func (c *ConfigCenter) AddListener(key string, listener Listener) {
+	if listener == nil {
+		return
+	}
+	key = strings.TrimPrefix(key, propertyPrefix)
+	c.listenerLock.Lock()
+	defer c.listenerLock.Unlock()
+	c.listeners[key] = append(c.listeners[key], listener)
+}
+
+func (c *ConfigCenter) RemoveListener(key string, listener Listener) {
+	key = strings.TrimPrefix(key, propertyPrefix)
+	c.listenerLock.Lock()
+	defer c.listenerLock.Unlock()
+	listeners := c.listeners[key]
+	for i, l := range listeners {
+		if l == listener {
+			listeners = append(listeners[:i:i], listeners[i+1:]...)
+			break
+		}
+	}
+	if len(listeners) == 0 {
+		delete(c.listeners, key)
+	} else {
+		c.listeners[key] = listeners
+	}
+}
+
+// Close stops watching the config center
+func (c *ConfigCenter) Close() {
+	c.closeOnce.Do(func() {
+		c.provider.close()
+		c.wg.Wait()
+	})
+}
+
+// onChange parses the content provided by the watching, a content that fails to parse is
+// ignored so the last good properties are kept
+func (c *ConfigCenter) onChange(content []byte) {
+	c.rwLock.RLock()
+	unchanged := (content == nil) == (c.content == nil) && bytes.Equal(content, c.content)
+	old := c.properties
+	c.rwLock.RUnlock()
+	if unchanged {
+		return
+	}
+
+	properties, err := parseProperties(c.name, content)
+	if err != nil {
+		log.Warnf("parse config %s failed, keep the last loaded, err: %v", c.name, err)
+		return
+	}
+	c.rwLock.Lock()
+	c.content = content
+	c.properties = properties
+	c.rwLock.Unlock()
+
+	events := diffProperties(old, properties)
+	log.Infof("config %s is changed, %d keys changed", c.name, len(events))
+	for _, event := range events {
+		c.notify(event)
+	}
+}
+
+func (c *ConfigCenter) notify(event ChangeEvent) {
+	c.listenerLock.RLock()
+	listeners := append([]Listener(nil), c.listeners[event.Key]...)
+	c.listenerLock.RUnlock()
+	for _, listener := range listeners {
+		listener.OnChange(event)
+	}
+}
+
+// diffProperties returns the changes from old to properties ordered by the key
+func diffProperties(old, properties map[string]string) []ChangeEvent {
+	events := make([]ChangeEvent, 0)
+	for key, value := range properties {
+		if oldValue, ok := old[key]; !ok {
+			events = append(events, ChangeEvent{Key: key, Type: ChangeTypeAdd, NewValue: value})
+		} else if oldValue != value {
+			events = append(events, ChangeEvent{Key: key, Type: ChangeTypeModify, OldValue: oldValue, NewValue: value})
+		}
+	}
+	for key, oldValue := range old {
+		if _, ok := properties[key]; !ok {
+			events = append(events, ChangeEvent{Key: key, Type: ChangeTypeDelete, OldValue: oldValue})
+		}
+	}
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].Key < events[j].Key
+	})
+	return events
+}
+
+// parseProperties flattens the content to the properties, the content is yaml if the name ends
+// with .yml or .yaml, and properties otherwise. The seata prefix of the keys is trimmed.
+func parseProperties(name string, content []byte) (map[string]string, error) {
+	properties := make(map[string]string)
+	if len(content) == 0 {
+		return properties, nil
+	}
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".yml", ".yaml":
+		k := koanf.New(".")
+		if err := k.Load(rawbytes.Provider(content), yaml.Parser()); err != nil {
+			return nil, err
+		}
+		for key, value := range k.All() {
+			properties[strings.TrimPrefix(key, propertyPrefix)] = formatValue(value)
+		}
+	default:
+		scanner := bufio.NewScanner(bytes.NewReader(content))
+		for lineNo := 1; scanner.Scan(); lineNo++ {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "!") {
+				continue
+			}
+			// the values may contain ':', so it is the separator only if there is no '='
+			idx := strings.Index(line, "=")
+			if idx < 0 {
+				idx = strings.Index(line, ":")
+			}
+			if idx <= 0 {
+				return nil, fmt.Errorf("invalid property at line %d: %s", lineNo, line)
+			}
+			key := strings.TrimPrefix(strings.TrimSpace(line[:idx]), propertyPrefix)
+			properties[key] = strings.TrimSpace(line[idx+1:])
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+	}
+	return properties, nil
+}
+
+// formatValue formats the yaml value as the properties value, the list is joined by ','
+func formatValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case []interface{}:
+		items := make([]string, 0, len(v))
+		for _, item := range v {
+			items = append(items, formatValue(item))
+		}
+		return strings.Join(items, ",")
+	default:
+		return fmt.Sprint(v)
+	}
+}
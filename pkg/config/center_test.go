@@ -0,0 +1,214 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package config
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeProvider provides the contents sent to changes
+type fakeProvider struct {
+	content   []byte
+	err       error
+	changes   chan []byte
+	stopCh    chan struct{}
+	closeOnce sync.Once
+}
+
+func newFakeProvider(content string) *fakeProvider {
+	return &fakeProvider{
+		content: []byte(content),
+		changes: make(chan []byte),
+		stopCh:  make(chan struct{}),
+	}
+}
+
+func (p *fakeProvider) load() ([]byte, error) {
+	return p.content, p.err
+}
+
+func (p *fakeProvider) watch(onChange func(content []byte)) {
+	for {
+		select {
+		case <-p.stopCh:
+			return
+		case content := <-p.changes:
+			onChange(content)
+		}
+	}
+}
+
+func (p *fakeProvider) close() {
+	p.closeOnce.Do(func() {
+		close(p.stopCh)
+	})
+}
+
+// recordListener records the events it is notified of
+type recordListener struct {
+	events chan ChangeEvent
+}
+
+func newRecordListener() *recordListener {
+	return &recordListener{events: make(chan ChangeEvent, 16)}
+}
+
+func (l *recordListener) OnChange(event ChangeEvent) {
+	l.events <- event
+}
+
+// take waits for the next event, the zero event if nothing is notified in time
+func (l *recordListener) take(t *testing.T) ChangeEvent {
+	select {
+	case event := <-l.events:
+		return event
+	case <-time.After(3 * time.Second):
+		t.Fatal("no change event is notified")
+		return ChangeEvent{}
+	}
+}
+
+func (l *recordListener) count() int {
+	return len(l.events)
+}
+
+func TestNewConfigCenter_Unsupported(t *testing.T) {
+	_, err := NewConfigCenter(&Config{Type: "apollo"})
+	assert.EqualError(t, err, "config center type apollo is not supported")
+
+	_, err = NewConfigCenter(nil)
+	assert.Error(t, err)
+}
+
+func TestNewConfigCenter_LoadFailed(t *testing.T) {
+	p := newFakeProvider("")
+	p.err = errors.New("connection refused")
+	_, err := newConfigCenter("seata.properties", p)
+	assert.EqualError(t, err, "load config seata.properties failed, err: connection refused")
+	// the provider is closed once the center fails to start
+	<-p.stopCh
+
+	p = newFakeProvider("invalid")
+	_, err = newConfigCenter("seata.properties", p)
+	assert.Error(t, err)
+	<-p.stopCh
+}
+
+func TestConfigCenter_Listener(t *testing.T) {
+	p := newFakeProvider("client.rm.lock.retry-times=10\nservice.disable-global-transaction=false\n")
+	center, err := newConfigCenter("seata.properties", p)
+	assert.NoError(t, err)
+	defer center.Close()
+
+	assert.Equal(t, map[string]string{
+		"client.rm.lock.retry-times":         "10",
+		"service.disable-global-transaction": "false",
+	}, center.Properties())
+	value, ok := center.Get("seata.client.rm.lock.retry-times")
+	assert.True(t, ok)
+	assert.Equal(t, "10", value)
+
+	retryTimes, disable, level := newRecordListener(), newRecordListener(), newRecordListener()
+	center.AddListener("client.rm.lock.retry-times", retryTimes)
+	center.AddListener("seata.service.disable-global-transaction", disable)
+	center.AddListener("log.level", level)
+
+	p.changes <- []byte("client.rm.lock.retry-times=30\nlog.level=debug\n")
+	assert.Equal(t, ChangeEvent{Key: "client.rm.lock.retry-times", Type: ChangeTypeModify, OldValue: "10", NewValue: "30"}, retryTimes.take(t))
+	assert.Equal(t, ChangeEvent{Key: "service.disable-global-transaction", Type: ChangeTypeDelete, OldValue: "false"}, disable.take(t))
+	assert.Equal(t, ChangeEvent{Key: "log.level", Type: ChangeTypeAdd, NewValue: "debug"}, level.take(t))
+
+	// the same content and the content failing to parse are not notified
+	p.changes <- []byte("client.rm.lock.retry-times=30\nlog.level=debug\n")
+	p.changes <- []byte("invalid")
+	assert.Equal(t, "30", center.Properties()["client.rm.lock.retry-times"])
+
+	center.RemoveListener("client.rm.lock.retry-times", retryTimes)
+	p.changes <- []byte("client.rm.lock.retry-times=5\nlog.level=info\n")
+	assert.Equal(t, ChangeEvent{Key: "log.level", Type: ChangeTypeModify, OldValue: "debug", NewValue: "info"}, level.take(t))
+	assert.Equal(t, 0, retryTimes.count())
+	assert.Equal(t, 0, disable.count())
+
+	center.Close()
+	// closing twice does nothing
+	center.Close()
+}
+
+func TestParseProperties(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		want    map[string]string
+		wantErr bool
+	}{
+		{
+			name:    "properties",
+			file:    "seata.properties",
+			content: "# lock\nseata.client.rm.lock.retry-interval = 10ms\n! comment\nlog.level: debug\nservice.grouplist.default=127.0.0.1:8091\n",
+			want: map[string]string{
+				"client.rm.lock.retry-interval": "10ms",
+				"log.level":                     "debug",
+				"service.grouplist.default":     "127.0.0.1:8091",
+			},
+		},
+		{
+			name:    "yaml",
+			file:    "seata.yml",
+			content: "seata:\n  client:\n    undo:\n      log-serialization: protobuf\n  service:\n    disable-global-transaction: true\n  tags: [a, b]\n",
+			want: map[string]string{
+				"client.undo.log-serialization":      "protobuf",
+				"service.disable-global-transaction": "true",
+				"tags":                               "a,b",
+			},
+		},
+		{
+			name: "empty",
+			file: "seata.yaml",
+			want: map[string]string{},
+		},
+		{
+			name:    "invalid properties",
+			file:    "seata.properties",
+			content: "log.level",
+			wantErr: true,
+		},
+		{
+			name:    "invalid yaml",
+			file:    "seata.yaml",
+			content: "log: [",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseProperties(tt.file, []byte(tt.content))
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,102 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package config
+
+import (
+	"flag"
+	"fmt"
+	"time"
+)
+
+const (
+	FILE  string = "file"
+	NACOS string = "nacos"
+	ETCD  string = "etcd"
+)
+
+// Config the config center to load the properties from, the properties are merged over the local
+// config, and the changes of them are notified to the listeners.
+type Config struct {
+	Type  string      `yaml:"type" json:"type" koanf:"type"`
+	File  FileConfig  `yaml:"file" json:"file" koanf:"file"`
+	Nacos NacosConfig `yaml:"nacos" json:"nacos" koanf:"nacos"`
+	Etcd3 Etcd3Config `yaml:"etcd3" json:"etcd3" koanf:"etcd3"`
+}
+
+func (cfg *Config) RegisterFlagsWithPrefix(prefix string, f *flag.FlagSet) {
+	f.StringVar(&cfg.Type, prefix+".type", FILE, "The config center type.")
+	cfg.File.RegisterFlagsWithPrefix(prefix+".file", f)
+	cfg.Nacos.RegisterFlagsWithPrefix(prefix+".nacos", f)
+	cfg.Etcd3.RegisterFlagsWithPrefix(prefix+".etcd3", f)
+}
+
+// ValidateWithPrefix checks the config center type is supported, the empty type disables the
+// config center
+func (cfg *Config) ValidateWithPrefix(prefix string) error {
+	switch cfg.Type {
+	case "", FILE, NACOS, ETCD:
+		return nil
+	default:
+		return fmt.Errorf("%s.type: unsupported config center type %q", prefix, cfg.Type)
+	}
+}
+
+type FileConfig struct {
+	// Name the config file, the yaml file if it ends with .yml or .yaml, the properties file otherwise
+	Name string `yaml:"name" json:"name" koanf:"name"`
+	// RefreshInterval the interval to check the config file for changes
+	RefreshInterval time.Duration `yaml:"refresh-interval" json:"refresh-interval" koanf:"refresh-interval"`
+}
+
+func (cfg *FileConfig) RegisterFlagsWithPrefix(prefix string, f *flag.FlagSet) {
+	f.StringVar(&cfg.Name, prefix+".name", "config.conf", "The file name of config.")
+	f.DurationVar(&cfg.RefreshInterval, prefix+".refresh-interval", time.Second, "The interval to check the config file for changes.")
+}
+
+type NacosConfig struct {
+	ServerAddr string `yaml:"server-addr" json:"server-addr" koanf:"server-addr"`
+	Namespace  string `yaml:"namespace" json:"namespace" koanf:"namespace"`
+	Group      string `yaml:"group" json:"group" koanf:"group"`
+	Username   string `yaml:"username" json:"username" koanf:"username"`
+	Password   string `yaml:"password" json:"password" koanf:"password"`
+	AccessKey  string `yaml:"access-key" json:"access-key" koanf:"access-key"`
+	SecretKey  string `yaml:"secret-key" json:"secret-key" koanf:"secret-key"`
+	DataId     string `yaml:"data-id" json:"data-id" koanf:"data-id"`
+}
+
+func (cfg *NacosConfig) RegisterFlagsWithPrefix(prefix string, f *flag.FlagSet) {
+	f.StringVar(&cfg.ServerAddr, prefix+".server-addr", "127.0.0.1:8848", "The server address of nacos, separated by ','.")
+	f.StringVar(&cfg.Namespace, prefix+".namespace", "", "The namespace of the config.")
+	f.StringVar(&cfg.Group, prefix+".group", "SEATA_GROUP", "The group of the config.")
+	f.StringVar(&cfg.Username, prefix+".username", "", "The username of nacos.")
+	f.StringVar(&cfg.Password, prefix+".password", "", "The password of nacos.")
+	f.StringVar(&cfg.AccessKey, prefix+".access-key", "", "The access key of nacos.")
+	f.StringVar(&cfg.SecretKey, prefix+".secret-key", "", "The secret key of nacos.")
+	f.StringVar(&cfg.DataId, prefix+".data-id", "seata.properties", "The data id of the config.")
+}
+
+type Etcd3Config struct {
+	ServerAddr string `yaml:"server-addr" json:"server-addr" koanf:"server-addr"`
+	// Key the key of the config, the value is yaml if the key ends with .yml or .yaml, properties otherwise
+	Key string `yaml:"key" json:"key" koanf:"key"`
+}
+
+func (cfg *Etcd3Config) RegisterFlagsWithPrefix(prefix string, f *flag.FlagSet) {
+	f.StringVar(&cfg.ServerAddr, prefix+".server-addr", "http://localhost:2379", "The server address of etcd, separated by ','.")
+	f.StringVar(&cfg.Key, prefix+".key", "seata.properties", "The key of the config.")
+}
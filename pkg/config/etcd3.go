@@ -0,0 +1,155 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package config
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	etcd3 "go.etcd.io/etcd/client/v3"
+
+	"seata.apache.org/seata-go/pkg/util/log"
+)
+
+const (
+	etcdServerAddrSplitChar = ","
+	etcdDialTimeout         = 5 * time.Second
+	etcdRequestTimeout      = 3 * time.Second
+	// etcdRewatchInterval the interval to watch again once the watching is broken
+	etcdRewatchInterval = 5 * time.Second
+)
+
+// etcdClient the part of the etcd client used by the provider
+type etcdClient interface {
+	Get(ctx context.Context, key string, opts ...etcd3.OpOption) (*etcd3.GetResponse, error)
+	Watch(ctx context.Context, key string, opts ...etcd3.OpOption) etcd3.WatchChan
+	Close() error
+}
+
+// etcdProvider reads the config from the value of the key in etcd, the key is watched from the
+// revision loaded so that no change is missed.
+type etcdProvider struct {
+	client          etcdClient
+	key             string
+	revision        int64
+	rewatchInterval time.Duration
+
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+func newEtcdProvider(config *Etcd3Config) (*etcdProvider, error) {
+	endpoints := make([]string, 0)
+	for _, addr := range strings.Split(config.ServerAddr, etcdServerAddrSplitChar) {
+		if addr = strings.TrimSpace(addr); addr != "" {
+			endpoints = append(endpoints, addr)
+		}
+	}
+	if len(endpoints) == 0 {
+		return nil, fmt.Errorf("etcd server addr is empty")
+	}
+	if config.Key == "" {
+		return nil, fmt.Errorf("etcd config key is empty")
+	}
+	client, err := etcd3.New(etcd3.Config{
+		Endpoints:   endpoints,
+		DialTimeout: etcdDialTimeout,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return newEtcdProviderWithClient(client, config.Key), nil
+}
+
+func newEtcdProviderWithClient(client etcdClient, key string) *etcdProvider {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &etcdProvider{
+		client:          client,
+		key:             key,
+		rewatchInterval: etcdRewatchInterval,
+		ctx:             ctx,
+		cancel:          cancel,
+	}
+}
+
+func (p *etcdProvider) load() ([]byte, error) {
+	ctx, cancel := context.WithTimeout(p.ctx, etcdRequestTimeout)
+	defer cancel()
+	resp, err := p.client.Get(ctx, p.key)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Header != nil {
+		p.revision = resp.Header.Revision
+	}
+	if len(resp.Kvs) == 0 {
+		return nil, nil
+	}
+	return resp.Kvs[0].Value, nil
+}
+
+func (p *etcdProvider) watch(onChange func(content []byte)) {
+	for {
+		p.watchOnce(onChange)
+		select {
+		case <-p.ctx.Done():
+			return
+		case <-time.After(p.rewatchInterval):
+		}
+		// the changes are not watched while the watching is broken, so load the current one
+		content, err := p.load()
+		if err != nil {
+			log.Warnf("load config %s from etcd failed, err: %v", p.key, err)
+			continue
+		}
+		onChange(content)
+	}
+}
+
+// watchOnce watches the key after the revision loaded, until the watching is broken
+func (p *etcdProvider) watchOnce(onChange func(content []byte)) {
+	ctx, cancel := context.WithCancel(p.ctx)
+	defer cancel()
+	opts := make([]etcd3.OpOption, 0)
+	if p.revision > 0 {
+		opts = append(opts, etcd3.WithRev(p.revision+1))
+	}
+	for resp := range p.client.Watch(ctx, p.key, opts...) {
+		if err := resp.Err(); err != nil {
+			log.Warnf("watch config %s from etcd failed, err: %v", p.key, err)
+			return
+		}
+		for _, event := range resp.Events {
+			p.revision = event.Kv.ModRevision
+			if event.Type == etcd3.EventTypePut {
+				onChange(event.Kv.Value)
+			} else {
+				onChange(nil)
+			}
+		}
+	}
+}
+
+func (p *etcdProvider) close() {
+	p.cancel()
+	if err := p.client.Close(); err != nil {
+		log.Warnf("close etcd client failed, err: %v", err)
+	}
+}
@@ -0,0 +1,120 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package config
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.etcd.io/etcd/api/v3/etcdserverpb"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	etcd3 "go.etcd.io/etcd/client/v3"
+)
+
+// fakeEtcdClient returns the value of the key and sends the watch responses to the latest watch
+type fakeEtcdClient struct {
+	value    []byte
+	revision int64
+
+	lock      sync.Mutex
+	watches   chan chan etcd3.WatchResponse
+	responses chan etcd3.WatchResponse
+	// watchRevs the revisions the watches start from
+	watchRevs []int64
+}
+
+func newFakeEtcdClient(value string, revision int64) *fakeEtcdClient {
+	return &fakeEtcdClient{
+		value:    []byte(value),
+		revision: revision,
+		watches:  make(chan chan etcd3.WatchResponse, 4),
+	}
+}
+
+func (c *fakeEtcdClient) Get(ctx context.Context, key string, opts ...etcd3.OpOption) (*etcd3.GetResponse, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	resp := &etcd3.GetResponse{Header: &etcdserverpb.ResponseHeader{Revision: c.revision}}
+	if c.value != nil {
+		resp.Kvs = []*mvccpb.KeyValue{{Key: []byte(key), Value: c.value, ModRevision: c.revision}}
+	}
+	return resp, nil
+}
+
+func (c *fakeEtcdClient) Watch(ctx context.Context, key string, opts ...etcd3.OpOption) etcd3.WatchChan {
+	op := etcd3.OpGet(key, opts...)
+	ch := make(chan etcd3.WatchResponse)
+	c.lock.Lock()
+	c.watchRevs = append(c.watchRevs, op.Rev())
+	c.lock.Unlock()
+	go func() {
+		<-ctx.Done()
+		close(ch)
+	}()
+	c.watches <- ch
+	return ch
+}
+
+func (c *fakeEtcdClient) Close() error {
+	return nil
+}
+
+func (c *fakeEtcdClient) put(value string, revision int64) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.value, c.revision = []byte(value), revision
+}
+
+func TestEtcdProvider_Watch(t *testing.T) {
+	client := newFakeEtcdClient("client.rm.lock.retry-times=10", 5)
+	p := newEtcdProviderWithClient(client, "seata.properties")
+	p.rewatchInterval = 10 * time.Millisecond
+	center, err := newConfigCenter("seata.properties", p)
+	assert.NoError(t, err)
+	defer center.Close()
+	assert.Equal(t, map[string]string{"client.rm.lock.retry-times": "10"}, center.Properties())
+
+	listener := newRecordListener()
+	center.AddListener("client.rm.lock.retry-times", listener)
+
+	watch := <-client.watches
+	watch <- etcd3.WatchResponse{Events: []*etcd3.Event{{
+		Type: mvccpb.PUT,
+		Kv:   &mvccpb.KeyValue{Value: []byte("client.rm.lock.retry-times=20"), ModRevision: 6},
+	}}}
+	assert.Equal(t, ChangeEvent{Key: "client.rm.lock.retry-times", Type: ChangeTypeModify, OldValue: "10", NewValue: "20"}, listener.take(t))
+
+	watch <- etcd3.WatchResponse{Events: []*etcd3.Event{{
+		Type: mvccpb.DELETE,
+		Kv:   &mvccpb.KeyValue{ModRevision: 7},
+	}}}
+	assert.Equal(t, ChangeEvent{Key: "client.rm.lock.retry-times", Type: ChangeTypeDelete, OldValue: "20"}, listener.take(t))
+
+	// the broken watching loads the current value and watches again after it
+	client.put("client.rm.lock.retry-times=30", 9)
+	watch <- etcd3.WatchResponse{Canceled: true, CompactRevision: 8}
+	assert.Equal(t, ChangeEvent{Key: "client.rm.lock.retry-times", Type: ChangeTypeAdd, NewValue: "30"}, listener.take(t))
+	<-client.watches
+
+	client.lock.Lock()
+	assert.Equal(t, []int64{6, 10}, client.watchRevs)
+	client.lock.Unlock()
+}
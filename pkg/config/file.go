@@ -0,0 +1,81 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package config
+
+import (
+	"os"
+	"sync"
+	"time"
+
+	"seata.apache.org/seata-go/pkg/util/log"
+)
+
+const defaultFileRefreshInterval = time.Second
+
+// fileProvider reads the config from the local file, the file is checked for changes periodically
+type fileProvider struct {
+	name     string
+	interval time.Duration
+
+	stopCh    chan struct{}
+	closeOnce sync.Once
+}
+
+func newFileProvider(config *FileConfig) *fileProvider {
+	interval := config.RefreshInterval
+	if interval <= 0 {
+		interval = defaultFileRefreshInterval
+	}
+	return &fileProvider{
+		name:     config.Name,
+		interval: interval,
+		stopCh:   make(chan struct{}),
+	}
+}
+
+func (p *fileProvider) load() ([]byte, error) {
+	content, err := os.ReadFile(p.name)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	return content, err
+}
+
+func (p *fileProvider) watch(onChange func(content []byte)) {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.stopCh:
+			return
+		case <-ticker.C:
+			content, err := p.load()
+			if err != nil {
+				log.Warnf("read config file %s failed, keep the last loaded, err: %v", p.name, err)
+				continue
+			}
+			onChange(content)
+		}
+	}
+}
+
+func (p *fileProvider) close() {
+	p.closeOnce.Do(func() {
+		close(p.stopCh)
+	})
+}
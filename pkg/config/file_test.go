@@ -0,0 +1,48 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileProvider_Watch(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "config.conf")
+	center, err := NewConfigCenter(&Config{
+		Type: FILE,
+		File: FileConfig{Name: name, RefreshInterval: 10 * time.Millisecond},
+	})
+	assert.NoError(t, err)
+	defer center.Close()
+	// the missing file provides no properties
+	assert.Empty(t, center.Properties())
+
+	listener := newRecordListener()
+	center.AddListener("client.undo.log-serialization", listener)
+
+	assert.NoError(t, os.WriteFile(name, []byte("client.undo.log-serialization=protobuf\n"), 0o644))
+	assert.Equal(t, ChangeEvent{Key: "client.undo.log-serialization", Type: ChangeTypeAdd, NewValue: "protobuf"}, listener.take(t))
+
+	assert.NoError(t, os.Remove(name))
+	assert.Equal(t, ChangeEvent{Key: "client.undo.log-serialization", Type: ChangeTypeDelete, OldValue: "protobuf"}, listener.take(t))
+}
@@ -0,0 +1,326 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package config
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"seata.apache.org/seata-go/pkg/util/log"
+)
+
+const (
+	nacosServerAddrSplitChar = ","
+	nacosDefaultContextPath  = "/nacos"
+	nacosConfigPath          = "/v1/cs/configs"
+	nacosListenerPath        = "/v1/cs/configs/listener"
+	nacosLoginPath           = "/v1/auth/login"
+	nacosHTTPTimeout         = 3 * time.Second
+	// nacosLongPollingTimeout the time the server holds the listening request if nothing changes
+	nacosLongPollingTimeout = 30 * time.Second
+	// nacosRetryInterval the interval to listen again once the listening fails
+	nacosRetryInterval = 5 * time.Second
+
+	nacosWordSeparator = "\x02"
+	nacosLineSeparator = "\x01"
+)
+
+type nacosLoginResult struct {
+	AccessToken string `json:"accessToken"`
+	TokenTtl    int64  `json:"tokenTtl"`
+}
+
+// nacosProvider reads the config of the data id from nacos, the changes are followed by the long
+// polling of the listener api with the md5 of the content loaded last time.
+type nacosProvider struct {
+	config      NacosConfig
+	serverAddrs []string
+	httpClient  *http.Client
+	now         func() time.Time
+
+	longPollingTimeout time.Duration
+	retryInterval      time.Duration
+
+	tokenLock   sync.Mutex
+	accessToken string
+	tokenExpire time.Time
+
+	// md5 the md5 of the content loaded last time, empty if the config does not exist
+	md5 string
+
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+func newNacosProvider(config *NacosConfig) (*nacosProvider, error) {
+	serverAddrs := make([]string, 0)
+	for _, addr := range strings.Split(config.ServerAddr, nacosServerAddrSplitChar) {
+		if addr = strings.TrimSpace(addr); addr != "" {
+			serverAddrs = append(serverAddrs, nacosBaseURL(addr))
+		}
+	}
+	if len(serverAddrs) == 0 {
+		return nil, fmt.Errorf("nacos server addr is empty")
+	}
+	if config.DataId == "" {
+		return nil, fmt.Errorf("nacos data id is empty")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &nacosProvider{
+		config:             *config,
+		serverAddrs:        serverAddrs,
+		httpClient:         &http.Client{},
+		now:                time.Now,
+		longPollingTimeout: nacosLongPollingTimeout,
+		retryInterval:      nacosRetryInterval,
+		ctx:                ctx,
+		cancel:             cancel,
+	}, nil
+}
+
+// nacosBaseURL adds the scheme and the default context path to the server addr if absent
+func nacosBaseURL(addr string) string {
+	if !strings.Contains(addr, "://") {
+		addr = "http://" + addr
+	}
+	addr = strings.TrimRight(addr, "/")
+	if u, err := url.Parse(addr); err == nil && u.Path == "" {
+		addr += nacosDefaultContextPath
+	}
+	return addr
+}
+
+func (p *nacosProvider) load() ([]byte, error) {
+	var lastErr error
+	for _, addr := range p.serverAddrs {
+		content, err := p.fetch(addr)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		p.md5 = ""
+		if content != nil {
+			sum := md5.Sum(content)
+			p.md5 = hex.EncodeToString(sum[:])
+		}
+		return content, nil
+	}
+	return nil, lastErr
+}
+
+// fetch gets the config from the server, nil if the config does not exist
+func (p *nacosProvider) fetch(addr string) ([]byte, error) {
+	params := url.Values{}
+	params.Set("dataId", p.config.DataId)
+	params.Set("group", p.config.Group)
+	if p.config.Namespace != "" {
+		params.Set("tenant", p.config.Namespace)
+	}
+	ctx, cancel := context.WithTimeout(p.ctx, nacosHTTPTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, addr+nacosConfigPath+"?"+params.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := p.do(addr, req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return io.ReadAll(resp.Body)
+	case http.StatusNotFound:
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("get nacos config %s failed with status code %d from %s", p.config.DataId, resp.StatusCode, addr)
+	}
+}
+
+func (p *nacosProvider) watch(onChange func(content []byte)) {
+	for {
+		select {
+		case <-p.ctx.Done():
+			return
+		default:
+		}
+		changed, err := p.listen()
+		if err == nil && changed {
+			var content []byte
+			if content, err = p.load(); err == nil {
+				onChange(content)
+			}
+		}
+		if err == nil {
+			continue
+		}
+		if p.ctx.Err() != nil {
+			return
+		}
+		log.Warnf("listen nacos config %s failed, err: %v", p.config.DataId, err)
+		select {
+		case <-p.ctx.Done():
+			return
+		case <-time.After(p.retryInterval):
+		}
+	}
+}
+
+// listen holds until the config differs from the md5 loaded last time or the long polling
+// times out, it returns whether the config is changed
+func (p *nacosProvider) listen() (bool, error) {
+	listening := p.config.DataId + nacosWordSeparator + p.config.Group + nacosWordSeparator + p.md5
+	if p.config.Namespace != "" {
+		listening += nacosWordSeparator + p.config.Namespace
+	}
+	listening += nacosLineSeparator
+
+	var lastErr error
+	for _, addr := range p.serverAddrs {
+		changed, err := p.listenAddr(addr, listening)
+		if err != nil {
+			if p.ctx.Err() != nil {
+				return false, err
+			}
+			lastErr = err
+			continue
+		}
+		return changed, nil
+	}
+	return false, lastErr
+}
+
+func (p *nacosProvider) listenAddr(addr, listening string) (bool, error) {
+	form := url.Values{}
+	form.Set("Listening-Configs", listening)
+	ctx, cancel := context.WithTimeout(p.ctx, p.longPollingTimeout+nacosHTTPTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, addr+nacosListenerPath, strings.NewReader(form.Encode()))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Long-Pulling-Timeout", strconv.FormatInt(p.longPollingTimeout.Milliseconds(), 10))
+	resp, err := p.do(addr, req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("listen nacos config %s failed with status code %d from %s", p.config.DataId, resp.StatusCode, addr)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return false, err
+	}
+	return strings.TrimSpace(string(body)) != "", nil
+}
+
+// do sends the request with the access token of the username/password auth, and the signature
+// of the access-key/secret-key auth
+func (p *nacosProvider) do(addr string, req *http.Request) (*http.Response, error) {
+	if p.config.Username != "" {
+		token, err := p.token(addr)
+		if err != nil {
+			return nil, err
+		}
+		query := req.URL.Query()
+		query.Set("accessToken", token)
+		req.URL.RawQuery = query.Encode()
+	}
+	if p.config.AccessKey != "" {
+		timestamp := strconv.FormatInt(p.now().UnixNano()/int64(time.Millisecond), 10)
+		resource := p.config.Group
+		if p.config.Namespace != "" {
+			resource = p.config.Namespace + "+" + resource
+		}
+		mac := hmac.New(sha1.New, []byte(p.config.SecretKey))
+		mac.Write([]byte(resource + "+" + timestamp))
+		req.Header.Set("Spas-AccessKey", p.config.AccessKey)
+		req.Header.Set("Timestamp", timestamp)
+		req.Header.Set("Spas-Signature", base64.StdEncoding.EncodeToString(mac.Sum(nil)))
+	}
+	resp, err := p.httpClient.Do(req)
+	if err == nil && resp.StatusCode == http.StatusForbidden {
+		// the token may be expired on the server, login again next time
+		p.resetToken()
+	}
+	return resp, err
+}
+
+// token returns the cached access token, it logins again a little before the token expires
+func (p *nacosProvider) token(addr string) (string, error) {
+	p.tokenLock.Lock()
+	defer p.tokenLock.Unlock()
+	if p.accessToken != "" && p.now().Before(p.tokenExpire) {
+		return p.accessToken, nil
+	}
+
+	form := url.Values{}
+	form.Set("username", p.config.Username)
+	form.Set("password", p.config.Password)
+	ctx, cancel := context.WithTimeout(p.ctx, nacosHTTPTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, addr+nacosLoginPath, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("nacos login failed with status code %d from %s", resp.StatusCode, addr)
+	}
+	result := &nacosLoginResult{}
+	if err = json.NewDecoder(resp.Body).Decode(result); err != nil {
+		return "", err
+	}
+	if result.AccessToken == "" {
+		return "", fmt.Errorf("nacos login returns no access token from %s", addr)
+	}
+	ttl := time.Duration(result.TokenTtl) * time.Second
+	p.accessToken = result.AccessToken
+	p.tokenExpire = p.now().Add(ttl - ttl/10)
+	return p.accessToken, nil
+}
+
+func (p *nacosProvider) resetToken() {
+	p.tokenLock.Lock()
+	defer p.tokenLock.Unlock()
+	p.accessToken = ""
+}
+
+func (p *nacosProvider) close() {
+	p.cancel()
+}
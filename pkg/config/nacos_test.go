@@ -0,0 +1,178 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package config
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeNacosServer serves the config api and the long polling listener api of nacos
+type fakeNacosServer struct {
+	lock    sync.Mutex
+	content string
+	exists  bool
+	changed chan struct{}
+	logins  int
+}
+
+func (s *fakeNacosServer) set(content string, exists bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.content, s.exists = content, exists
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
+
+func (s *fakeNacosServer) md5() string {
+	if !s.exists {
+		return ""
+	}
+	sum := md5.Sum([]byte(s.content))
+	return hex.EncodeToString(sum[:])
+}
+
+func (s *fakeNacosServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/nacos" + nacosLoginPath:
+		s.lock.Lock()
+		s.logins++
+		s.lock.Unlock()
+		_, _ = w.Write([]byte(`{"accessToken":"token","tokenTtl":18000}`))
+		return
+	case "/nacos" + nacosConfigPath:
+		if r.URL.Query().Get("accessToken") != "token" || r.URL.Query().Get("dataId") != "seata.properties" ||
+			r.URL.Query().Get("group") != "SEATA_GROUP" || r.URL.Query().Get("tenant") != "dev" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		s.lock.Lock()
+		defer s.lock.Unlock()
+		if !s.exists {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(s.content))
+	case "/nacos" + nacosListenerPath:
+		timeout, _ := strconv.Atoi(r.Header.Get("Long-Pulling-Timeout"))
+		listening := strings.Split(strings.TrimSuffix(r.PostFormValue("Listening-Configs"), nacosLineSeparator), nacosWordSeparator)
+		if len(listening) != 4 || listening[0] != "seata.properties" || listening[1] != "SEATA_GROUP" || listening[3] != "dev" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		s.lock.Lock()
+		md5, changed := s.md5(), s.changed
+		s.lock.Unlock()
+		if md5 == listening[2] {
+			select {
+			case <-changed:
+			case <-r.Context().Done():
+				return
+			case <-time.After(time.Duration(timeout) * time.Millisecond):
+				return
+			}
+		}
+		_, _ = w.Write([]byte("seata.properties%02SEATA_GROUP%02dev%01"))
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestNacosProvider_Watch(t *testing.T) {
+	server := &fakeNacosServer{content: "log.level=info", exists: true, changed: make(chan struct{})}
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	p, err := newNacosProvider(&NacosConfig{
+		// the unreachable server is skipped
+		ServerAddr: "127.0.0.1:1," + strings.TrimPrefix(ts.URL, "http://"),
+		Namespace:  "dev",
+		Group:      "SEATA_GROUP",
+		Username:   "nacos",
+		Password:   "nacos",
+		DataId:     "seata.properties",
+	})
+	assert.NoError(t, err)
+	p.longPollingTimeout = 50 * time.Millisecond
+	p.retryInterval = 10 * time.Millisecond
+	center, err := newConfigCenter("seata.properties", p)
+	assert.NoError(t, err)
+	defer center.Close()
+	assert.Equal(t, map[string]string{"log.level": "info"}, center.Properties())
+
+	listener := newRecordListener()
+	center.AddListener("log.level", listener)
+
+	server.set("log.level=debug", true)
+	assert.Equal(t, ChangeEvent{Key: "log.level", Type: ChangeTypeModify, OldValue: "info", NewValue: "debug"}, listener.take(t))
+
+	server.set("", false)
+	assert.Equal(t, ChangeEvent{Key: "log.level", Type: ChangeTypeDelete, OldValue: "debug"}, listener.take(t))
+
+	server.set("log.level=warn", true)
+	assert.Equal(t, ChangeEvent{Key: "log.level", Type: ChangeTypeAdd, NewValue: "warn"}, listener.take(t))
+
+	// the token is cached
+	server.lock.Lock()
+	assert.Equal(t, 1, server.logins)
+	server.lock.Unlock()
+}
+
+func TestNacosProvider_Sign(t *testing.T) {
+	var header http.Header
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header
+		_, _ = w.Write([]byte("log.level=info"))
+	}))
+	defer ts.Close()
+
+	p, err := newNacosProvider(&NacosConfig{
+		ServerAddr: ts.URL,
+		Group:      "SEATA_GROUP",
+		AccessKey:  "ak",
+		SecretKey:  "sk",
+		DataId:     "seata.properties",
+	})
+	assert.NoError(t, err)
+	p.now = func() time.Time {
+		return time.UnixMilli(1700000000000)
+	}
+	content, err := p.load()
+	assert.NoError(t, err)
+	assert.Equal(t, "log.level=info", string(content))
+	assert.Equal(t, "ak", header.Get("Spas-AccessKey"))
+	assert.Equal(t, "1700000000000", header.Get("Timestamp"))
+	// base64(hmac-sha1("sk", "SEATA_GROUP+1700000000000"))
+	assert.Equal(t, "VXX9dVbpvdqVst5bLYWOBDdMk7s=", header.Get("Spas-Signature"))
+}
+
+func TestNewNacosProvider_Invalid(t *testing.T) {
+	_, err := newNacosProvider(&NacosConfig{ServerAddr: " , ", DataId: "seata.properties"})
+	assert.EqualError(t, err, "nacos server addr is empty")
+	_, err = newNacosProvider(&NacosConfig{ServerAddr: "127.0.0.1:8848"})
+	assert.EqualError(t, err, "nacos data id is empty")
+}
@@ -20,6 +20,7 @@ package sql
 import (
 	"context"
 	"flag"
+	"sync"
 	"time"
 
 	"seata.apache.org/seata-go/pkg/rm"
@@ -68,6 +69,13 @@ type AsyncWorker struct {
 	doBranchCommitFailureTotal prometheus.Counter
 	receiveChanLength          prometheus.Gauge
 	rePutBackToQueue           prometheus.Counter
+	droppedTotal               prometheus.Counter
+
+	// stopCtx the ctx of Close, the flush on stop gives up once it is done
+	stopCtx  context.Context
+	stopCh   chan struct{}
+	doneCh   chan struct{}
+	stopOnce sync.Once
 }
 
 func NewAsyncWorker(prom prometheus.Registerer, conf AsyncWorkerConfig, sourceManager datasource.DataSourceManager) *AsyncWorker {
@@ -75,6 +83,8 @@ func NewAsyncWorker(prom prometheus.Registerer, conf AsyncWorkerConfig, sourceMa
 	asyncWorker.conf = conf
 	asyncWorker.commitQueue = make(chan phaseTwoContext, asyncWorker.conf.ReceiveChanSize)
 	asyncWorker.resourceMgr = sourceManager
+	asyncWorker.stopCh = make(chan struct{})
+	asyncWorker.doneCh = make(chan struct{})
 	asyncWorker.commitWorker = fanout.New("asyncWorker",
 		fanout.WithWorker(asyncWorker.conf.CommitWorkerCount),
 		fanout.WithBuffer(asyncWorker.conf.CommitWorkerBufferSize),
@@ -96,6 +106,10 @@ func NewAsyncWorker(prom prometheus.Registerer, conf AsyncWorkerConfig, sourceMa
 		Name: "async_worker_commit_failure_retry_counter",
 		Help: "the counter of commit failure retry counter",
 	})
+	asyncWorker.droppedTotal = promauto.With(prom).NewCounter(prometheus.CounterOpts{
+		Name: "async_worker_commit_failure_dropped_total",
+		Help: "the total count of failed branch commits dropped after the worker is stopped",
+	})
 
 	go asyncWorker.run()
 
@@ -122,8 +136,32 @@ func (aw *AsyncWorker) BranchCommit(ctx context.Context, req rm.BranchResource)
 	return branch.BranchStatusPhasetwoCommitted, nil
 }
 
+// Close stops the worker, the branch commits received before are flushed until ctx is done.
+func (aw *AsyncWorker) Close(ctx context.Context) error {
+	aw.stopOnce.Do(func() {
+		aw.stopCtx = ctx
+		close(aw.stopCh)
+	})
+
+	select {
+	case <-aw.doneCh:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	err := aw.commitWorker.Drain(ctx)
+	aw.commitWorker.Close()
+	if left := len(aw.commitQueue); left > 0 {
+		log.Warnf("async worker closed with %d branch commits left, the undo logs will be deleted by tc later", left)
+	}
+	return err
+}
+
 func (aw *AsyncWorker) run() {
+	defer close(aw.doneCh)
+
 	ticker := time.NewTicker(aw.conf.BufferCleanInterval)
+	defer ticker.Stop()
 	phaseCtxs := make([]phaseTwoContext, 0, aw.conf.BufferLimit)
 	for {
 		select {
@@ -134,10 +172,25 @@ func (aw *AsyncWorker) run() {
 			}
 		case <-ticker.C:
 			aw.doBranchCommit(&phaseCtxs)
+		case <-aw.stopCh:
+			aw.flush(aw.stopCtx, phaseCtxs)
+			return
 		}
 	}
 }
 
+// flush commits the buffered and queued branches in the current goroutine until ctx is done,
+// the failed ones are dropped and left to tc as nothing reads the queue any more.
+func (aw *AsyncWorker) flush(ctx context.Context, phaseCtxs []phaseTwoContext) {
+	for n := len(aw.commitQueue); n > 0; n-- {
+		phaseCtxs = append(phaseCtxs, <-aw.commitQueue)
+	}
+	aw.receiveChanLength.Set(0)
+	if dropped := aw.commitGroupedContexts(ctx, phaseCtxs); dropped > 0 {
+		log.Warnf("async worker dropped %d failed branch commits on close, the undo logs will be deleted by tc later", dropped)
+	}
+}
+
 func (aw *AsyncWorker) doBranchCommit(phaseCtxs *[]phaseTwoContext) {
 	if len(*phaseCtxs) == 0 {
 		return
@@ -148,66 +201,86 @@ func (aw *AsyncWorker) doBranchCommit(phaseCtxs *[]phaseTwoContext) {
 	*phaseCtxs = (*phaseCtxs)[:0]
 
 	doBranchCommit := func(ctx context.Context) {
-		groupCtxs := make(map[string][]phaseTwoContext, 16)
-		for i := range copyPhaseCtxs {
-			if copyPhaseCtxs[i].ResourceID == "" {
-				continue
-			}
+		aw.commitGroupedContexts(ctx, copyPhaseCtxs)
+	}
 
-			if _, ok := groupCtxs[copyPhaseCtxs[i].ResourceID]; !ok {
-				groupCtxs[copyPhaseCtxs[i].ResourceID] = make([]phaseTwoContext, 0, 4)
-			}
+	if err := aw.commitWorker.Do(context.Background(), doBranchCommit); err != nil {
+		aw.doBranchCommitFailureTotal.Add(1)
+		log.Errorf("do branch commit err:%v,phaseCtxs=%v", err, phaseCtxs)
+	}
+}
 
-			ctxs := groupCtxs[copyPhaseCtxs[i].ResourceID]
-			ctxs = append(ctxs, copyPhaseCtxs[i])
-			groupCtxs[copyPhaseCtxs[i].ResourceID] = ctxs
+// commitGroupedContexts deletes the undo logs of the branches by resource, it returns the count of
+// the failed branches dropped as the worker is stopped.
+func (aw *AsyncWorker) commitGroupedContexts(ctx context.Context, phaseCtxs []phaseTwoContext) int {
+	groupCtxs := make(map[string][]phaseTwoContext, 16)
+	for i := range phaseCtxs {
+		if phaseCtxs[i].ResourceID == "" {
+			continue
 		}
 
-		for k := range groupCtxs {
-			aw.dealWithGroupedContexts(k, groupCtxs[k])
+		if _, ok := groupCtxs[phaseCtxs[i].ResourceID]; !ok {
+			groupCtxs[phaseCtxs[i].ResourceID] = make([]phaseTwoContext, 0, 4)
 		}
+
+		ctxs := groupCtxs[phaseCtxs[i].ResourceID]
+		ctxs = append(ctxs, phaseCtxs[i])
+		groupCtxs[phaseCtxs[i].ResourceID] = ctxs
 	}
 
-	if err := aw.commitWorker.Do(context.Background(), doBranchCommit); err != nil {
-		aw.doBranchCommitFailureTotal.Add(1)
-		log.Errorf("do branch commit err:%v,phaseCtxs=%v", err, phaseCtxs)
+	dropped := 0
+	for k := range groupCtxs {
+		dropped += aw.dealWithGroupedContexts(ctx, k, groupCtxs[k])
 	}
+	return dropped
 }
 
-func (aw *AsyncWorker) dealWithGroupedContexts(resID string, phaseCtxs []phaseTwoContext) {
+func (aw *AsyncWorker) dealWithGroupedContexts(ctx context.Context, resID string, phaseCtxs []phaseTwoContext) int {
 	val, ok := aw.resourceMgr.GetCachedResources().Load(resID)
 	if !ok {
-		for i := range phaseCtxs {
-			aw.rePutBackToQueue.Add(1)
-			aw.commitQueue <- phaseCtxs[i]
-		}
-		return
+		return aw.putBack(phaseCtxs...)
 	}
 
 	res := val.(*DBResource)
-	conn, err := res.db.Conn(context.Background())
+	conn, err := res.db.Conn(ctx)
 	if err != nil {
-		for i := range phaseCtxs {
-			aw.commitQueue <- phaseCtxs[i]
-		}
+		return aw.putBack(phaseCtxs...)
 	}
 
 	defer conn.Close()
 
 	undoMgr, err := undo.GetUndoLogManager(res.dbType)
 	if err != nil {
-		for i := range phaseCtxs {
-			aw.rePutBackToQueue.Add(1)
-			aw.commitQueue <- phaseCtxs[i]
-		}
-		return
+		return aw.putBack(phaseCtxs...)
 	}
 
+	dropped := 0
 	for i := range phaseCtxs {
 		phaseCtx := phaseCtxs[i]
-		if err := undoMgr.BatchDeleteUndoLog([]string{phaseCtx.Xid}, []int64{phaseCtx.BranchID}, conn); err != nil {
+		if err := undoMgr.BatchDeleteUndoLog(ctx, []string{phaseCtx.Xid}, []int64{phaseCtx.BranchID}, conn); err != nil {
+			dropped += aw.putBack(phaseCtx)
+		}
+	}
+	return dropped
+}
+
+// putBack puts the failed branches back to the queue to retry, they are dropped once the worker
+// is stopped, as the queue is no longer read and may be full. It returns the count of the dropped.
+func (aw *AsyncWorker) putBack(phaseCtxs ...phaseTwoContext) int {
+	for i, phaseCtx := range phaseCtxs {
+		select {
+		case <-aw.stopCh:
+			aw.droppedTotal.Add(float64(len(phaseCtxs) - i))
+			return len(phaseCtxs) - i
+		default:
+		}
+		select {
+		case aw.commitQueue <- phaseCtx:
 			aw.rePutBackToQueue.Add(1)
-			aw.commitQueue <- phaseCtx
+		case <-aw.stopCh:
+			aw.droppedTotal.Add(float64(len(phaseCtxs) - i))
+			return len(phaseCtxs) - i
 		}
 	}
+	return 0
 }
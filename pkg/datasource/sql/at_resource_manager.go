@@ -22,6 +22,7 @@ import (
 	"database/sql"
 	"fmt"
 	"sync"
+	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 
@@ -31,9 +32,17 @@ import (
 	"seata.apache.org/seata-go/pkg/protocol/branch"
 	"seata.apache.org/seata-go/pkg/rm"
 	serr "seata.apache.org/seata-go/pkg/util/errors"
+	"seata.apache.org/seata-go/pkg/util/log"
 )
 
+const defaultUndoLogDeleteBatchRows = 3000
+
 func InitAT(cfg undo.Config, asyncCfg AsyncWorkerConfig) {
+	InitATWithRegisterer(prometheus.DefaultRegisterer, cfg, asyncCfg)
+}
+
+// InitATWithRegisterer inits the AT mode, the metrics of the async worker are registered to prom
+func InitATWithRegisterer(prom prometheus.Registerer, cfg undo.Config, asyncCfg AsyncWorkerConfig) {
 	atSourceManager := &ATSourceManager{
 		resourceCache: sync.Map{},
 		basic:         datasource.NewBasicSourceManager(),
@@ -41,7 +50,7 @@ func InitAT(cfg undo.Config, asyncCfg AsyncWorkerConfig) {
 	}
 
 	undo.InitUndoConfig(cfg)
-	atSourceManager.worker = NewAsyncWorker(prometheus.DefaultRegisterer, asyncCfg, atSourceManager)
+	atSourceManager.worker = NewAsyncWorker(prom, asyncCfg, atSourceManager)
 	rm.GetRmCacheInstance().RegisterResourceManager(atSourceManager)
 }
 
@@ -56,6 +65,11 @@ func (a *ATSourceManager) GetBranchType() branch.BranchType {
 	return branch.BranchTypeAT
 }
 
+// Close stops the async worker and flushes the pending branch commits
+func (a *ATSourceManager) Close(ctx context.Context) error {
+	return a.worker.Close(ctx)
+}
+
 // GetCachedResources get all resources managed by this manager
 func (a *ATSourceManager) GetCachedResources() *sync.Map {
 	return &a.resourceCache
@@ -109,6 +123,50 @@ func (a *ATSourceManager) BranchCommit(ctx context.Context, resource rm.BranchRe
 	return branch.BranchStatusPhasetwoCommitted, nil
 }
 
+// DeleteUndoLogByLogCreated deletes the undo logs created before logCreated in batches,
+// each batch deletes at most undo.log-delete-batch-rows rows.
+func (a *ATSourceManager) DeleteUndoLogByLogCreated(ctx context.Context, resourceId string, logCreated time.Time) error {
+	resource, ok := a.resourceCache.Load(resourceId)
+	if !ok {
+		return fmt.Errorf("DB resource is not exist, resourceId: %s", resourceId)
+	}
+	dbResource, _ := resource.(*DBResource)
+
+	undoMgr, err := undo.GetUndoLogManager(dbResource.dbType)
+	if err != nil {
+		return err
+	}
+
+	limitRows := undo.UndoConfig.LogDeleteBatchRows
+	if limitRows <= 0 {
+		limitRows = defaultUndoLogDeleteBatchRows
+	}
+
+	var total int64
+	for {
+		deleted, err := a.deleteUndoLogBatch(ctx, undoMgr, dbResource.db, logCreated, limitRows)
+		total += deleted
+		if err != nil {
+			return fmt.Errorf("delete undo log of resource %s fail, %d rows deleted: %w", resourceId, total, err)
+		}
+		if deleted < int64(limitRows) {
+			break
+		}
+	}
+	log.Infof("delete undo log of resource %s created before %v, %d rows deleted", resourceId, logCreated, total)
+	return nil
+}
+
+func (a *ATSourceManager) deleteUndoLogBatch(ctx context.Context, undoMgr undo.UndoLogManager, db *sql.DB, logCreated time.Time, limitRows int) (int64, error) {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+
+	return undoMgr.DeleteUndoLogByLogCreated(ctx, logCreated, limitRows, conn)
+}
+
 func (a *ATSourceManager) LockQuery(ctx context.Context, param rm.LockQueryParam) (bool, error) {
 	return a.rmRemoting.LockQuery(param)
 }
@@ -134,6 +134,7 @@ func (c *ATConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx,
 
 	if tm.IsGlobalTx(ctx) {
 		c.txCtx.XID = tm.GetXID(ctx)
+		c.txCtx.TxServiceGroup = tm.GetTxServiceGroup(ctx)
 		c.txCtx.TransactionMode = types.ATMode
 	}
 
@@ -153,6 +154,7 @@ func (c *ATConn) createOnceTxContext(ctx context.Context) bool {
 		c.txCtx.DBType = c.res.dbType
 		c.txCtx.ResourceID = c.res.resourceID
 		c.txCtx.XID = tm.GetXID(ctx)
+		c.txCtx.TxServiceGroup = tm.GetTxServiceGroup(ctx)
 		c.txCtx.TransactionMode = types.ATMode
 		c.txCtx.GlobalLockRequire = true
 	}
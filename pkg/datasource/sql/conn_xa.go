@@ -123,6 +123,7 @@ func (c *XAConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx,
 	c.txCtx.TxOpt = opts
 	c.txCtx.ResourceID = c.res.resourceID
 	c.txCtx.XID = tm.GetXID(ctx)
+	c.txCtx.TxServiceGroup = tm.GetTxServiceGroup(ctx)
 	c.txCtx.TransactionMode = types.XAMode
 
 	tx, err := c.Conn.BeginTx(ctx, opts)
@@ -168,6 +169,7 @@ func (c *XAConn) createOnceTxContext(ctx context.Context) bool {
 		c.txCtx.DBType = c.res.dbType
 		c.txCtx.ResourceID = c.res.resourceID
 		c.txCtx.XID = tm.GetXID(ctx)
+		c.txCtx.TxServiceGroup = tm.GetTxServiceGroup(ctx)
 		c.txCtx.TransactionMode = types.XAMode
 		c.txCtx.GlobalLockRequire = true
 	}
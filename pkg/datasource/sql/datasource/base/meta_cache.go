@@ -51,6 +51,7 @@ type BaseTableMetaCache struct {
 	size           int32
 	cache          map[string]*entry
 	cancel         context.CancelFunc
+	wg             sync.WaitGroup
 	trigger        trigger
 	db             *sql.DB
 	cfg            *mysql.Config
@@ -79,8 +80,15 @@ func NewBaseCache(capity int32, expireDuration time.Duration, trigger trigger, d
 
 // init
 func (c *BaseTableMetaCache) Init(ctx context.Context) error {
-	go c.refresh(ctx)
-	go c.scanExpire(ctx)
+	c.wg.Add(2)
+	go func() {
+		defer c.wg.Done()
+		c.refresh(ctx)
+	}()
+	go func() {
+		defer c.wg.Done()
+		c.scanExpire(ctx)
+	}()
 
 	return nil
 }
@@ -122,8 +130,13 @@ func (c *BaseTableMetaCache) refresh(ctx context.Context) {
 
 	ticker := time.NewTicker(time.Duration(1 * time.Minute))
 	defer ticker.Stop()
-	for range ticker.C {
-		f()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			f()
+		}
 	}
 }
 
@@ -131,7 +144,12 @@ func (c *BaseTableMetaCache) refresh(ctx context.Context) {
 func (c *BaseTableMetaCache) scanExpire(ctx context.Context) {
 	ticker := time.NewTicker(c.expireDuration)
 	defer ticker.Stop()
-	for range ticker.C {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
 
 		f := func() {
 			c.lock.Lock()
@@ -183,7 +201,9 @@ func (c *BaseTableMetaCache) GetTableMeta(ctx context.Context, dbName, tableName
 	return v.value, nil
 }
 
+// Destroy stops the refresh and expire goroutines and waits for them to exit
 func (c *BaseTableMetaCache) Destroy() error {
 	c.cancel()
+	c.wg.Wait()
 	return nil
 }
@@ -20,6 +20,7 @@ package datasource
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"sync"
 
@@ -31,17 +32,39 @@ import (
 var (
 	atOnce            sync.Once
 	tableMetaCacheMap = map[types.DBType]TableMetaCache{}
+	tableMetaCacheMu  sync.RWMutex
 )
 
 // RegisterTableCache register the table meta cache for at and xa
 func RegisterTableCache(dbType types.DBType, tableMetaCache TableMetaCache) {
+	tableMetaCacheMu.Lock()
+	defer tableMetaCacheMu.Unlock()
 	tableMetaCacheMap[dbType] = tableMetaCache
 }
 
 func GetTableCache(dbType types.DBType) TableMetaCache {
+	tableMetaCacheMu.RLock()
+	defer tableMetaCacheMu.RUnlock()
 	return tableMetaCacheMap[dbType]
 }
 
+// DestroyTableCaches destroy all registered table meta caches, the caches are unregistered
+// before destroyed so they are no longer returned by GetTableCache
+func DestroyTableCaches() error {
+	tableMetaCacheMu.Lock()
+	caches := tableMetaCacheMap
+	tableMetaCacheMap = map[types.DBType]TableMetaCache{}
+	tableMetaCacheMu.Unlock()
+
+	var errs []error
+	for dbType, cache := range caches {
+		if err := cache.Destroy(); err != nil {
+			errs = append(errs, fmt.Errorf("destroy table meta cache of %v: %w", dbType, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
 func GetDataSourceManager(branchType branch.BranchType) DataSourceManager {
 	resourceManager := rm.GetRmCacheInstance().GetResourceManager(branchType)
 	if resourceManager == nil {
@@ -113,7 +136,7 @@ type TableMetaCache interface {
 
 // buildResource
 func buildResource(ctx context.Context, dbType types.DBType, db *sql.DB) (*entry, error) {
-	cache := tableMetaCacheMap[dbType]
+	cache := GetTableCache(dbType)
 	if err := cache.Init(ctx, db); err != nil {
 		return nil, err
 	}
@@ -75,5 +75,5 @@ func (c *TableMetaCache) GetTableMeta(ctx context.Context, dbName, tableName str
 
 // Destroy
 func (c *TableMetaCache) Destroy() error {
-	return nil
+	return c.tableMetaCache.Destroy()
 }
@@ -17,6 +17,22 @@
 
 package at
 
-import "seata.apache.org/seata-go/pkg/rm"
+import (
+	"go.uber.org/atomic"
 
-var LockConfig rm.LockConfig
+	"seata.apache.org/seata-go/pkg/rm"
+)
+
+// lockConfig may be replaced by the config center while the sql is executing
+var lockConfig atomic.Value
+
+// SetLockConfig replaces the lock config, the sql executed later retries the global lock by it
+func SetLockConfig(cfg rm.LockConfig) {
+	lockConfig.Store(cfg)
+}
+
+// GetLockConfig returns the current lock config
+func GetLockConfig() rm.LockConfig {
+	cfg, _ := lockConfig.Load().(rm.LockConfig)
+	return cfg
+}
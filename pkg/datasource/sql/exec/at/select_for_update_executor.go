@@ -62,13 +62,14 @@ type selectForUpdateExecutor struct {
 }
 
 func NewSelectForUpdateExecutor(parserCtx *types.ParseContext, execContext *types.ExecContext, hooks []exec.SQLHook) executor {
+	lockConfig := GetLockConfig()
 	return &selectForUpdateExecutor{
 		baseExecutor: baseExecutor{
 			hooks: hooks,
 		},
 		parserCtx:   parserCtx,
 		execContext: execContext,
-		cfg:         &LockConfig,
+		cfg:         &lockConfig,
 	}
 }
 
@@ -196,10 +197,11 @@ func (s *selectForUpdateExecutor) doExecContext(ctx context.Context, f exec.Call
 
 	// check global lock
 	lockable, err := datasource.GetDataSourceManager(branch.BranchTypeAT).LockQuery(ctx, rm.LockQueryParam{
-		Xid:        s.execContext.TxCtx.XID,
-		BranchType: branch.BranchTypeAT,
-		ResourceId: s.execContext.TxCtx.ResourceID,
-		LockKeys:   lockKey,
+		Xid:            s.execContext.TxCtx.XID,
+		BranchType:     branch.BranchTypeAT,
+		ResourceId:     s.execContext.TxCtx.ResourceID,
+		LockKeys:       lockKey,
+		TxServiceGroup: s.execContext.TxCtx.TxServiceGroup,
 	})
 	if err != nil {
 		return nil, err
@@ -23,5 +23,5 @@ import (
 )
 
 func Init(config rm.LockConfig) {
-	at.LockConfig = config
+	at.SetLockConfig(config)
 }
@@ -128,10 +128,11 @@ func (s SelectForUpdateExecutor) ExecWithNamedValue(ctx context.Context, execCtx
 		}
 		// check global lock
 		lockable, err := datasource.GetDataSourceManager(branch.BranchTypeAT).LockQuery(ctx, rm.LockQueryParam{
-			Xid:        execCtx.TxCtx.XID,
-			BranchType: branch.BranchTypeAT,
-			ResourceId: execCtx.TxCtx.ResourceID,
-			LockKeys:   lockKey,
+			Xid:            execCtx.TxCtx.XID,
+			BranchType:     branch.BranchTypeAT,
+			ResourceId:     execCtx.TxCtx.ResourceID,
+			LockKeys:       lockKey,
+			TxServiceGroup: execCtx.TxCtx.TxServiceGroup,
 		})
 
 		// if obtained global lock
@@ -237,10 +238,11 @@ func (s SelectForUpdateExecutor) ExecWithValue(ctx context.Context, execCtx *typ
 		}
 		// check global lock
 		lockable, err := datasource.GetDataSourceManager(branch.BranchTypeAT).LockQuery(ctx, rm.LockQueryParam{
-			Xid:        execCtx.TxCtx.XID,
-			BranchType: branch.BranchTypeAT,
-			ResourceId: execCtx.TxCtx.ResourceID,
-			LockKeys:   lockKey,
+			Xid:            execCtx.TxCtx.XID,
+			BranchType:     branch.BranchTypeAT,
+			ResourceId:     execCtx.TxCtx.ResourceID,
+			LockKeys:       lockKey,
+			TxServiceGroup: execCtx.TxCtx.TxServiceGroup,
 		})
 
 		// has obtained global lock
@@ -156,9 +156,10 @@ func (tx *Tx) register(ctx *types.TransactionContext) error {
 	}
 
 	request := rm.BranchRegisterParam{
-		Xid:        ctx.XID,
-		BranchType: ctx.TransactionMode.BranchType(),
-		ResourceId: ctx.ResourceID,
+		Xid:            ctx.XID,
+		BranchType:     ctx.TransactionMode.BranchType(),
+		ResourceId:     ctx.ResourceID,
+		TxServiceGroup: ctx.TxServiceGroup,
 	}
 
 	var lockKey string
@@ -189,9 +190,11 @@ func (tx *Tx) report(success bool) error {
 	}
 	status := getStatus(success)
 	request := rm.BranchReportParam{
-		Xid:      tx.tranCtx.XID,
-		BranchId: int64(tx.tranCtx.BranchID),
-		Status:   status,
+		Xid:            tx.tranCtx.XID,
+		BranchId:       int64(tx.tranCtx.BranchID),
+		ResourceId:     tx.tranCtx.ResourceID,
+		Status:         status,
+		TxServiceGroup: tx.tranCtx.TxServiceGroup,
 	}
 	dataSourceManager := datasource.GetDataSourceManager(tx.tranCtx.TransactionMode.BranchType())
 	if dataSourceManager == nil {
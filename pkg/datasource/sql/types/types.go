@@ -139,6 +139,8 @@ type TransactionContext struct {
 	BranchID uint64
 	// XID global transaction id
 	XID string
+	// TxServiceGroup the tx service group the global transaction is begun on
+	TxServiceGroup string
 	// GlobalLockRequire
 	GlobalLockRequire bool
 	// RoundImages when run in AT mode, record before and after Row image
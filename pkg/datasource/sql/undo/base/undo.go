@@ -26,6 +26,7 @@ import (
 
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/arana-db/parser/mysql"
 
@@ -68,6 +69,10 @@ func getDeleteUndoLogSql() string {
 	return "DELETE FROM " + getUndoLogTableName() + " WHERE branch_id = ? AND xid = ?"
 }
 
+func getDeleteUndoLogByCreateSql() string {
+	return "DELETE FROM " + getUndoLogTableName() + " WHERE log_created <= ? LIMIT ?"
+}
+
 // undo log status
 const (
 	// UndoLogStatusNormal This state can be properly rolled back by services
@@ -132,7 +137,7 @@ func (m *BaseUndoLogManager) DeleteUndoLog(ctx context.Context, xid string, bran
 }
 
 // BatchDeleteUndoLog exec delete undo log operate
-func (m *BaseUndoLogManager) BatchDeleteUndoLog(xid []string, branchID []int64, conn *sql.Conn) error {
+func (m *BaseUndoLogManager) BatchDeleteUndoLog(ctx context.Context, xid []string, branchID []int64, conn *sql.Conn) error {
 	// build delete undo log sql
 	batchDeleteSql, err := m.getBatchDeleteUndoLogSql(xid, branchID)
 	if err != nil {
@@ -140,8 +145,6 @@ func (m *BaseUndoLogManager) BatchDeleteUndoLog(xid []string, branchID []int64,
 		return err
 	}
 
-	ctx := context.Background()
-
 	// prepare deal sql
 	stmt, err := conn.PrepareContext(ctx, batchDeleteSql)
 	if err != nil {
@@ -165,6 +168,23 @@ func (m *BaseUndoLogManager) BatchDeleteUndoLog(xid []string, branchID []int64,
 	return nil
 }
 
+// DeleteUndoLogByLogCreated delete the undo logs created before logCreated, at most limitRows rows are deleted
+func (m *BaseUndoLogManager) DeleteUndoLogByLogCreated(ctx context.Context, logCreated time.Time, limitRows int, conn *sql.Conn) (int64, error) {
+	stmt, err := conn.PrepareContext(ctx, getDeleteUndoLogByCreateSql())
+	if err != nil {
+		log.Errorf("[DeleteUndoLogByLogCreated] prepare sql fail, err: %v", err)
+		return 0, err
+	}
+	defer stmt.Close()
+
+	result, err := stmt.ExecContext(ctx, logCreated, limitRows)
+	if err != nil {
+		log.Errorf("[DeleteUndoLogByLogCreated] exec delete undo log fail, err: %v", err)
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 // FlushUndoLog flush undo log
 func (m *BaseUndoLogManager) FlushUndoLog(tranCtx *types.TransactionContext, conn driver.Conn) error {
 	if tranCtx.RoundImages.IsEmpty() {
@@ -225,7 +245,7 @@ func (m *BaseUndoLogManager) FlushUndoLog(tranCtx *types.TransactionContext, con
 	}
 
 	parseContext := make(map[string]string, 0)
-	parseContext[serializerKey] = undo.UndoConfig.LogSerialization
+	parseContext[serializerKey] = undo.GetLogSerialization()
 	parseContext[compressorTypeKey] = undo.UndoConfig.CompressConfig.Type
 	undoLogContent := m.encodeUndoLogCtx(parseContext)
 	rollbackInfo, err := m.serializeBranchUndoLog(&branchUndoLog, parseContext[serializerKey])
@@ -383,7 +403,7 @@ func (m *BaseUndoLogManager) Undo(ctx context.Context, dbType types.DBType, xid
 func (m *BaseUndoLogManager) insertUndoLogWithGlobalFinished(ctx context.Context, xid string, branchID uint64, conn *sql.Conn) error {
 	// todo use config to replace
 	parseContext := make(map[string]string, 0)
-	parseContext[serializerKey] = undo.UndoConfig.LogSerialization
+	parseContext[serializerKey] = undo.GetLogSerialization()
 	parseContext[compressorTypeKey] = undo.UndoConfig.CompressConfig.Type
 	undoLogContent := m.encodeUndoLogCtx(parseContext)
 
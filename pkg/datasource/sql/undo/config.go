@@ -20,15 +20,31 @@ package undo
 import (
 	"flag"
 
+	"go.uber.org/atomic"
+
 	"seata.apache.org/seata-go/pkg/compressor"
 )
 
 var (
 	UndoConfig Config
+	// logSerialization may be switched by the config center while the undo logs are encoded,
+	// read it by GetLogSerialization instead of UndoConfig.LogSerialization
+	logSerialization atomic.String
 )
 
 func InitUndoConfig(cfg Config) {
 	UndoConfig = cfg
+	logSerialization.Store(cfg.LogSerialization)
+}
+
+// SetLogSerialization switches the serialization of the undo logs written later
+func SetLogSerialization(serialization string) {
+	logSerialization.Store(serialization)
+}
+
+// GetLogSerialization returns the serialization of the undo logs to write
+func GetLogSerialization() string {
+	return logSerialization.Load()
 }
 
 type CompressConfig struct {
@@ -42,6 +58,7 @@ type Config struct {
 	LogSerialization      string         `yaml:"log-serialization" json:"log-serialization,omitempty" koanf:"log-serialization"`
 	LogTable              string         `yaml:"log-table" json:"log-table,omitempty" koanf:"log-table"`
 	OnlyCareUpdateColumns bool           `yaml:"only-care-update-columns" json:"only-care-update-columns,omitempty" koanf:"only-care-update-columns"`
+	LogDeleteBatchRows    int            `yaml:"log-delete-batch-rows" json:"log-delete-batch-rows,omitempty" koanf:"log-delete-batch-rows"`
 	CompressConfig        CompressConfig `yaml:"compress" json:"compress,omitempty" koanf:"compress"`
 }
 
@@ -50,6 +67,7 @@ func (u *Config) RegisterFlagsWithPrefix(prefix string, f *flag.FlagSet) {
 	f.StringVar(&u.LogSerialization, prefix+".log-serialization", "json", "Serialization method.")
 	f.StringVar(&u.LogTable, prefix+".log-table", "undo_log", "undo log table name.")
 	f.BoolVar(&u.OnlyCareUpdateColumns, prefix+".only-care-update-columns", true, "The switch for degrade check.")
+	f.IntVar(&u.LogDeleteBatchRows, prefix+".log-delete-batch-rows", 3000, "The maximum rows deleted by one batch when cleaning the expired undo logs.")
 	u.CompressConfig.RegisterFlagsWithPrefix(prefix+".compress", f)
 }
 
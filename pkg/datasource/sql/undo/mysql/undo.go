@@ -21,6 +21,7 @@ import (
 	"context"
 	"database/sql"
 	"database/sql/driver"
+	"time"
 
 	"seata.apache.org/seata-go/pkg/datasource/sql/types"
 	"seata.apache.org/seata-go/pkg/datasource/sql/undo"
@@ -47,8 +48,13 @@ func (m *undoLogManager) DeleteUndoLog(ctx context.Context, xid string, branchID
 }
 
 // BatchDeleteUndoLog
-func (m *undoLogManager) BatchDeleteUndoLog(xid []string, branchID []int64, conn *sql.Conn) error {
-	return m.Base.BatchDeleteUndoLog(xid, branchID, conn)
+func (m *undoLogManager) BatchDeleteUndoLog(ctx context.Context, xid []string, branchID []int64, conn *sql.Conn) error {
+	return m.Base.BatchDeleteUndoLog(ctx, xid, branchID, conn)
+}
+
+// DeleteUndoLogByLogCreated
+func (m *undoLogManager) DeleteUndoLogByLogCreated(ctx context.Context, logCreated time.Time, limitRows int, conn *sql.Conn) (int64, error) {
+	return m.Base.DeleteUndoLogByLogCreated(ctx, logCreated, limitRows, conn)
 }
 
 // FlushUndoLog
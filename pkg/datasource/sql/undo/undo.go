@@ -23,6 +23,7 @@ import (
 	"database/sql/driver"
 	"fmt"
 	"sync"
+	"time"
 
 	"seata.apache.org/seata-go/pkg/datasource/sql/types"
 )
@@ -68,7 +69,9 @@ type UndoLogManager interface {
 	// DeleteUndoLog
 	DeleteUndoLog(ctx context.Context, xid string, branchID int64, conn *sql.Conn) error
 	// BatchDeleteUndoLog
-	BatchDeleteUndoLog(xid []string, branchID []int64, conn *sql.Conn) error
+	BatchDeleteUndoLog(ctx context.Context, xid []string, branchID []int64, conn *sql.Conn) error
+	// DeleteUndoLogByLogCreated delete at most limitRows undo logs created before logCreated, and returns the deleted rows
+	DeleteUndoLogByLogCreated(ctx context.Context, logCreated time.Time, limitRows int, conn *sql.Conn) (int64, error)
 	//FlushUndoLog
 	FlushUndoLog(tranCtx *types.TransactionContext, conn driver.Conn) error
 	// RunUndo
@@ -21,9 +21,13 @@ import (
 	"context"
 	"database/sql"
 	"testing"
+	"time"
 
+	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/stretchr/testify/assert"
 
+	"seata.apache.org/seata-go/pkg/datasource/sql/types"
+	"seata.apache.org/seata-go/pkg/datasource/sql/undo"
 	"seata.apache.org/seata-go/pkg/datasource/sql/undo/base"
 	"seata.apache.org/seata-go/pkg/datasource/sql/undo/mysql"
 )
@@ -42,7 +46,7 @@ func TestBatchDeleteUndoLogs(t *testing.T) {
 
 		undoLogManager := new(base.BaseUndoLogManager)
 
-		err = undoLogManager.BatchDeleteUndoLog([]string{"1"}, []int64{1}, sqlConn)
+		err = undoLogManager.BatchDeleteUndoLog(context.Background(), []string{"1"}, []int64{1}, sqlConn)
 		assert.Nil(t, err)
 	}
 
@@ -74,6 +78,34 @@ func TestDeleteUndoLogs(t *testing.T) {
 	})
 }
 
+func TestDeleteUndoLogByLogCreated(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.Nil(t, err)
+	defer db.Close()
+
+	oldBatchRows := undo.UndoConfig.LogDeleteBatchRows
+	undo.UndoConfig.LogDeleteBatchRows = 2
+	defer func() {
+		undo.UndoConfig.LogDeleteBatchRows = oldBatchRows
+	}()
+
+	logCreated := time.Now().AddDate(0, 0, -7)
+	deleteSql := "DELETE FROM\\s+undo_log\\s+WHERE log_created <= \\? LIMIT \\?"
+	// the first batch deletes full rows, so the second batch is executed
+	mock.ExpectPrepare(deleteSql).ExpectExec().WithArgs(logCreated, 2).WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectPrepare(deleteSql).ExpectExec().WithArgs(logCreated, 2).WillReturnResult(sqlmock.NewResult(0, 1))
+
+	atSourceManager := &ATSourceManager{}
+	atSourceManager.resourceCache.Store("test_resource", &DBResource{db: db, dbType: types.DBTypeMySQL})
+
+	err = atSourceManager.DeleteUndoLogByLogCreated(context.Background(), "test_resource", logCreated)
+	assert.Nil(t, err)
+	assert.Nil(t, mock.ExpectationsWereMet())
+
+	err = atSourceManager.DeleteUndoLogByLogCreated(context.Background(), "not_exist_resource", logCreated)
+	assert.NotNil(t, err)
+}
+
 // TestHasUndoLogTable
 func TestHasUndoLogTable(t *testing.T) {
 	// local test can annotation t.SkipNow()
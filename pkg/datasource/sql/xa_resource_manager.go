@@ -61,6 +61,8 @@ func InitXA(config XAConfig) *XAResourceManager {
 		basic:         datasource.NewBasicSourceManager(),
 		rmRemoting:    rm.GetRMRemotingInstance(),
 		config:        config,
+		stopCh:        make(chan struct{}),
+		doneCh:        make(chan struct{}),
 	}
 
 	xaConnTimeout = config.xaConnConf.XaBranchExecutionTimeout
@@ -79,12 +81,32 @@ type XAResourceManager struct {
 	resourceCache sync.Map
 	basic         *datasource.BasicSourceManager
 	rmRemoting    *rm.RMRemoting
+	stopCh        chan struct{}
+	doneCh        chan struct{}
+	stopOnce      sync.Once
+}
+
+// Close stops the xa two phase timeout checker
+func (xaManager *XAResourceManager) Close(ctx context.Context) error {
+	xaManager.stopOnce.Do(func() {
+		close(xaManager.stopCh)
+	})
+	select {
+	case <-xaManager.doneCh:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 }
 
 func (xaManager *XAResourceManager) xaTwoPhaseTimeoutChecker() {
+	defer close(xaManager.doneCh)
 	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
 	for {
 		select {
+		case <-xaManager.stopCh:
+			return
 		case <-ticker.C:
 			xaManager.resourceCache.Range(func(key, value any) bool {
 				source, ok := value.(*DBResource)
@@ -17,23 +17,146 @@
 
 package discovery
 
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const (
+	FILE       string = "file"
+	NACOS      string = "nacos"
+	ETCD       string = "etcd"
+	EUREKA     string = "eureka"
+	REDIS      string = "redis"
+	ZK         string = "zk"
+	CONSUL     string = "consul"
+	SOFA       string = "sofa"
+	RAFT       string = "raft"
+	DNS        string = "dns"
+	KUBERNETES string = "kubernetes"
+)
+
 const (
-	FILE   string = "file"
-	NACOS  string = "nacos"
-	ETCD   string = "etcd"
-	EUREKA string = "eureka"
-	REDIS  string = "redis"
-	ZK     string = "zk"
-	CONSUL string = "consul"
-	SOFA   string = "sofa"
+	// MetadataWeight the metadata key of the instance weight, used by the weighted load balance
+	MetadataWeight = "weight"
+	// MetadataZone the metadata key of the availability zone of the instance, used by the zone affinity load balance
+	MetadataZone = "zone"
+	// MetadataRole the metadata key of the raft role of the tc node, e.g. LEADER, FOLLOWER, LEARNER
+	MetadataRole = "role"
+	// MetadataVersion the metadata key of the version of the seata server
+	MetadataVersion = "version"
+	// MetadataHealthy the metadata key of the health flag, the instance is unhealthy if it is false
+	MetadataHealthy = "healthy"
 )
 
+// DefaultWeight the weight of the instance which has no valid weight in its metadata
+const DefaultWeight = 1
+
+// metadataSplitChar separates the address and the metadata in form of ip:port?weight=10&zone=az1
+const metadataSplitChar = "?"
+
 type ServiceInstance struct {
-	Addr string
-	Port int
+	Addr     string
+	Port     int
+	Metadata map[string]string
 }
 
-type RegistryService interface {
+// GetMetadata returns the metadata value of the key, it is empty if the instance is nil
+func (i *ServiceInstance) GetMetadata(key string) string {
+	if i == nil {
+		return ""
+	}
+	return i.Metadata[key]
+}
+
+// Weight returns the positive weight of the instance, DefaultWeight if it is not set or invalid
+func (i *ServiceInstance) Weight() int {
+	weight, err := strconv.Atoi(i.GetMetadata(MetadataWeight))
+	if err != nil || weight <= 0 {
+		return DefaultWeight
+	}
+	return weight
+}
+
+// Zone returns the availability zone of the instance
+func (i *ServiceInstance) Zone() string {
+	return i.GetMetadata(MetadataZone)
+}
+
+// Version returns the version of the seata server
+func (i *ServiceInstance) Version() string {
+	return i.GetMetadata(MetadataVersion)
+}
+
+// Role returns the raft role of the tc node
+func (i *ServiceInstance) Role() string {
+	return i.GetMetadata(MetadataRole)
+}
+
+// Healthy reports whether the instance is healthy, it is healthy unless the health flag is false
+func (i *ServiceInstance) Healthy() bool {
+	healthy, err := strconv.ParseBool(i.GetMetadata(MetadataHealthy))
+	return err != nil || healthy
+}
+
+// LookupService looks up the tc instances of the vgroup key
+type LookupService interface {
 	Lookup(key string) ([]*ServiceInstance, error)
 	Close()
 }
+
+type RegistryService interface {
+	LookupService
+	// Subscribe registers the listener to the instance changes of the key, the current
+	// instances are notified to the listener as the add events before it returns.
+	Subscribe(key string, listener Listener) error
+	// Unsubscribe removes the listener registered to the key
+	Unsubscribe(key string, listener Listener) error
+}
+
+// LeaderRegistryService is implemented by the registry whose tc cluster only accepts
+// the transaction requests on the leader node, e.g. the raft mode cluster.
+type LeaderRegistryService interface {
+	RegistryService
+	// Leader returns the leader tc node of the cluster mapped by the key
+	Leader(key string) (*ServiceInstance, error)
+	// RefreshLeader fetches the latest cluster metadata, it is called when the leader changed
+	RefreshLeader(key string) error
+}
+
+// splitMetadata splits the address in form of ip:port?key=value&key=value into the address and
+// the metadata, the metadata is nil if there is no query part.
+func splitMetadata(addr string) (string, map[string]string, error) {
+	idx := strings.Index(addr, metadataSplitChar)
+	if idx < 0 {
+		return addr, nil, nil
+	}
+	values, err := url.ParseQuery(addr[idx+1:])
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid metadata of address %s: %w", addr, err)
+	}
+	metadata := make(map[string]string, len(values))
+	for key := range values {
+		metadata[key] = values.Get(key)
+	}
+	return addr[:idx], metadata, nil
+}
+
+// parseServiceInstance parses the instance from the address in form of ip:port
+func parseServiceInstance(addr string) (*ServiceInstance, error) {
+	host, portStr, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid port of address %s: %w", addr, err)
+	}
+	return &ServiceInstance{
+		Addr: host,
+		Port: port,
+	}, nil
+}
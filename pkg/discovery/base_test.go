@@ -0,0 +1,68 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package discovery
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServiceInstance_Metadata(t *testing.T) {
+	instance := &ServiceInstance{
+		Addr: "127.0.0.1",
+		Port: 8091,
+		Metadata: map[string]string{
+			MetadataWeight:  "10",
+			MetadataZone:    "az1",
+			MetadataVersion: "2.0.0",
+			MetadataRole:    "LEADER",
+			MetadataHealthy: "false",
+		},
+	}
+	assert.Equal(t, 10, instance.Weight())
+	assert.Equal(t, "az1", instance.Zone())
+	assert.Equal(t, "2.0.0", instance.Version())
+	assert.Equal(t, "LEADER", instance.Role())
+	assert.False(t, instance.Healthy())
+
+	instance.Metadata = map[string]string{MetadataWeight: "-1", MetadataHealthy: "unknown"}
+	assert.Equal(t, DefaultWeight, instance.Weight())
+	assert.True(t, instance.Healthy())
+
+	var empty *ServiceInstance
+	assert.Equal(t, DefaultWeight, empty.Weight())
+	assert.Equal(t, "", empty.Zone())
+	assert.True(t, empty.Healthy())
+}
+
+func TestSplitMetadata(t *testing.T) {
+	addr, metadata, err := splitMetadata("127.0.0.1:8091")
+	require.NoError(t, err)
+	assert.Equal(t, "127.0.0.1:8091", addr)
+	assert.Nil(t, metadata)
+
+	addr, metadata, err = splitMetadata("127.0.0.1:8091?weight=10&zone=az%201")
+	require.NoError(t, err)
+	assert.Equal(t, "127.0.0.1:8091", addr)
+	assert.Equal(t, map[string]string{MetadataWeight: "10", MetadataZone: "az 1"}, metadata)
+
+	_, _, err = splitMetadata("127.0.0.1:8091?weight=%zz")
+	assert.Error(t, err)
+}
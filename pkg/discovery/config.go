@@ -18,7 +18,12 @@
 package discovery
 
 import (
+	"errors"
 	"flag"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
 
 	"seata.apache.org/seata-go/pkg/util/flagext"
 )
@@ -37,11 +42,57 @@ func (cfg *ServiceConfig) RegisterFlagsWithPrefix(prefix string, f *flag.FlagSet
 	f.Var(&cfg.Grouplist, prefix+".grouplist", "The group list.")
 }
 
+// ValidateWithPrefix checks the endpoints of the grouplist are in form of ip:port with the
+// optional metadata
+func (cfg *ServiceConfig) ValidateWithPrefix(prefix string) error {
+	groups := make([]string, 0, len(cfg.Grouplist))
+	for group := range cfg.Grouplist {
+		groups = append(groups, group)
+	}
+	sort.Strings(groups)
+
+	var errs []error
+	for _, group := range groups {
+		for _, endpoint := range strings.Split(cfg.Grouplist[group], endPointSplitChar) {
+			if err := validateEndpoint(endpoint); err != nil {
+				errs = append(errs, fmt.Errorf("%s.grouplist.%s: invalid endpoint %q: %v", prefix, group, endpoint, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func validateEndpoint(endpoint string) error {
+	addr, _, err := splitMetadata(endpoint)
+	if err != nil {
+		return err
+	}
+	instance, err := parseServiceInstance(addr)
+	if err != nil {
+		return err
+	}
+	if instance.Addr == "" {
+		return fmt.Errorf("the host is empty")
+	}
+	if instance.Port <= 0 || instance.Port > 65535 {
+		return fmt.Errorf("the port %d is out of range", instance.Port)
+	}
+	return nil
+}
+
 type RegistryConfig struct {
-	Type  string      `yaml:"type" json:"type" koanf:"type"`
-	File  FileConfig  `yaml:"file" json:"file" koanf:"file"`
-	Nacos NacosConfig `yaml:"nacos" json:"nacos" koanf:"nacos"`
-	Etcd3 Etcd3Config `yaml:"etcd3" json:"etcd3" koanf:"etcd3"`
+	Type   string       `yaml:"type" json:"type" koanf:"type"`
+	File   FileConfig   `yaml:"file" json:"file" koanf:"file"`
+	Nacos  NacosConfig  `yaml:"nacos" json:"nacos" koanf:"nacos"`
+	Etcd3  Etcd3Config  `yaml:"etcd3" json:"etcd3" koanf:"etcd3"`
+	Raft   RaftConfig   `yaml:"raft" json:"raft" koanf:"raft"`
+	Consul ConsulConfig `yaml:"consul" json:"consul" koanf:"consul"`
+	Zk     ZkConfig     `yaml:"zk" json:"zk" koanf:"zk"`
+	Redis  RedisConfig  `yaml:"redis" json:"redis" koanf:"redis"`
+	Eureka EurekaConfig `yaml:"eureka" json:"eureka" koanf:"eureka"`
+	Dns    DnsConfig    `yaml:"dns" json:"dns" koanf:"dns"`
+
+	Kubernetes KubernetesConfig `yaml:"kubernetes" json:"kubernetes" koanf:"kubernetes"`
 }
 
 func (cfg *RegistryConfig) RegisterFlagsWithPrefix(prefix string, f *flag.FlagSet) {
@@ -49,14 +100,35 @@ func (cfg *RegistryConfig) RegisterFlagsWithPrefix(prefix string, f *flag.FlagSe
 	cfg.File.RegisterFlagsWithPrefix(prefix+".file", f)
 	cfg.Nacos.RegisterFlagsWithPrefix(prefix+".nacos", f)
 	cfg.Etcd3.RegisterFlagsWithPrefix(prefix+".etcd3", f)
+	cfg.Raft.RegisterFlagsWithPrefix(prefix+".raft", f)
+	cfg.Consul.RegisterFlagsWithPrefix(prefix+".consul", f)
+	cfg.Zk.RegisterFlagsWithPrefix(prefix+".zk", f)
+	cfg.Redis.RegisterFlagsWithPrefix(prefix+".redis", f)
+	cfg.Eureka.RegisterFlagsWithPrefix(prefix+".eureka", f)
+	cfg.Dns.RegisterFlagsWithPrefix(prefix+".dns", f)
+	cfg.Kubernetes.RegisterFlagsWithPrefix(prefix+".kubernetes", f)
+}
+
+// ValidateWithPrefix checks the registry type is supported
+func (cfg *RegistryConfig) ValidateWithPrefix(prefix string) error {
+	switch cfg.Type {
+	case FILE, ETCD, RAFT, NACOS, EUREKA, REDIS, ZK, CONSUL, DNS, KUBERNETES:
+		return nil
+	default:
+		return fmt.Errorf("%s.type: unsupported registry type %q", prefix, cfg.Type)
+	}
 }
 
 type FileConfig struct {
+	// Name the registry file, the yaml file if it ends with .yml or .yaml, the properties file otherwise
 	Name string `yaml:"name" json:"name" koanf:"name"`
+	// RefreshInterval the interval to check the registry file for changes
+	RefreshInterval time.Duration `yaml:"refresh-interval" json:"refresh-interval" koanf:"refresh-interval"`
 }
 
 func (cfg *FileConfig) RegisterFlagsWithPrefix(prefix string, f *flag.FlagSet) {
-	f.StringVar(&cfg.Name, prefix+".name", "registry.conf", "The file name of registry.")
+	f.StringVar(&cfg.Name, prefix+".name", "", "The file name of registry, the service config is used if it is empty.")
+	f.DurationVar(&cfg.RefreshInterval, prefix+".refresh-interval", time.Second, "The interval to check the registry file for changes.")
 }
 
 type NacosConfig struct {
@@ -90,3 +162,120 @@ func (cfg *Etcd3Config) RegisterFlagsWithPrefix(prefix string, f *flag.FlagSet)
 	f.StringVar(&cfg.Cluster, prefix+".cluster", "default", "The server address of registry.")
 	f.StringVar(&cfg.ServerAddr, prefix+".server-addr", "http://localhost:2379", "The server address of registry.")
 }
+
+type RaftConfig struct {
+	// ServerAddr the comma separated http addresses of the tc nodes, e.g. 127.0.0.1:7091,127.0.0.1:7092
+	ServerAddr     string        `yaml:"server-addr" json:"server-addr" koanf:"server-addr"`
+	MetadataMaxAge time.Duration `yaml:"metadata-max-age" json:"metadata-max-age" koanf:"metadata-max-age"`
+}
+
+func (cfg *RaftConfig) RegisterFlagsWithPrefix(prefix string, f *flag.FlagSet) {
+	f.StringVar(&cfg.ServerAddr, prefix+".server-addr", "", "The http server addresses of the tc raft cluster.")
+	f.DurationVar(&cfg.MetadataMaxAge, prefix+".metadata-max-age", 30*time.Second, "The interval to refresh the raft cluster metadata.")
+}
+
+type ConsulConfig struct {
+	ServerAddr string `yaml:"server-addr" json:"server-addr" koanf:"server-addr"`
+	AclToken   string `yaml:"acl-token" json:"acl-token" koanf:"acl-token"`
+	// WaitTime the max time a blocking query waits for the changes of the tc instances
+	WaitTime time.Duration `yaml:"wait-time" json:"wait-time" koanf:"wait-time"`
+}
+
+func (cfg *ConsulConfig) RegisterFlagsWithPrefix(prefix string, f *flag.FlagSet) {
+	f.StringVar(&cfg.ServerAddr, prefix+".server-addr", "127.0.0.1:8500", "The server address of consul.")
+	f.StringVar(&cfg.AclToken, prefix+".acl-token", "", "The acl token of consul.")
+	f.DurationVar(&cfg.WaitTime, prefix+".wait-time", time.Minute, "The max time a blocking query waits for the instance changes.")
+}
+
+type ZkConfig struct {
+	// ServerAddr the comma separated addresses of the zookeeper ensemble, e.g. 127.0.0.1:2181,127.0.0.1:2182
+	ServerAddr     string        `yaml:"server-addr" json:"server-addr" koanf:"server-addr"`
+	SessionTimeout time.Duration `yaml:"session-timeout" json:"session-timeout" koanf:"session-timeout"`
+	ConnectTimeout time.Duration `yaml:"connect-timeout" json:"connect-timeout" koanf:"connect-timeout"`
+	Username       string        `yaml:"username" json:"username" koanf:"username"`
+	Password       string        `yaml:"password" json:"password" koanf:"password"`
+}
+
+func (cfg *ZkConfig) RegisterFlagsWithPrefix(prefix string, f *flag.FlagSet) {
+	f.StringVar(&cfg.ServerAddr, prefix+".server-addr", "127.0.0.1:2181", "The server addresses of zookeeper.")
+	f.DurationVar(&cfg.SessionTimeout, prefix+".session-timeout", 6*time.Second, "The session timeout of zookeeper.")
+	f.DurationVar(&cfg.ConnectTimeout, prefix+".connect-timeout", 2*time.Second, "The timeout to establish the zookeeper session.")
+	f.StringVar(&cfg.Username, prefix+".username", "", "The username of the zookeeper digest auth.")
+	f.StringVar(&cfg.Password, prefix+".password", "", "The password of the zookeeper digest auth.")
+}
+
+type RedisConfig struct {
+	ServerAddr string `yaml:"server-addr" json:"server-addr" koanf:"server-addr"`
+	Password   string `yaml:"password" json:"password" koanf:"password"`
+	DB         int    `yaml:"db" json:"db" koanf:"db"`
+	// RefreshInterval the interval to rescan the tc instances, the instances whose keys expire
+	// without the unregister message are removed by the rescan
+	RefreshInterval time.Duration `yaml:"refresh-interval" json:"refresh-interval" koanf:"refresh-interval"`
+}
+
+func (cfg *RedisConfig) RegisterFlagsWithPrefix(prefix string, f *flag.FlagSet) {
+	f.StringVar(&cfg.ServerAddr, prefix+".server-addr", "127.0.0.1:6379", "The server address of redis.")
+	f.StringVar(&cfg.Password, prefix+".password", "", "The password of redis.")
+	f.IntVar(&cfg.DB, prefix+".db", 0, "The database of redis.")
+	f.DurationVar(&cfg.RefreshInterval, prefix+".refresh-interval", 30*time.Second, "The interval to rescan the tc instances in redis.")
+}
+
+type EurekaConfig struct {
+	// ServiceURL the comma separated service urls of eureka, e.g. http://127.0.0.1:8761/eureka
+	ServiceURL string `yaml:"service-url" json:"service-url" koanf:"service-url"`
+	// Application the application name registered by the seata server, the cluster mapped
+	// from the vgroup is used as the application name if it is empty
+	Application     string        `yaml:"application" json:"application" koanf:"application"`
+	Username        string        `yaml:"username" json:"username" koanf:"username"`
+	Password        string        `yaml:"password" json:"password" koanf:"password"`
+	RefreshInterval time.Duration `yaml:"refresh-interval" json:"refresh-interval" koanf:"refresh-interval"`
+}
+
+func (cfg *EurekaConfig) RegisterFlagsWithPrefix(prefix string, f *flag.FlagSet) {
+	f.StringVar(&cfg.ServiceURL, prefix+".service-url", "http://localhost:8761/eureka", "The service urls of eureka.")
+	f.StringVar(&cfg.Application, prefix+".application", "seata-server", "The application name of the seata server in eureka.")
+	f.StringVar(&cfg.Username, prefix+".username", "", "The username of the eureka basic auth.")
+	f.StringVar(&cfg.Password, prefix+".password", "", "The password of the eureka basic auth.")
+	f.DurationVar(&cfg.RefreshInterval, prefix+".refresh-interval", 30*time.Second, "The interval to fetch the registry delta from eureka.")
+}
+
+type DnsConfig struct {
+	// Names the domain names of the clusters, the SRV records are resolved for the names starting
+	// with '_', e.g. _seata._tcp.seata-server.default.svc.cluster.local, the A/AAAA records otherwise
+	Names flagext.StringMap `yaml:"names" json:"names" koanf:"names"`
+	// Port the transaction port of the tc instances resolved from the A/AAAA records
+	Port int `yaml:"port" json:"port" koanf:"port"`
+	// Server the comma separated dns servers, e.g. 10.96.0.10:53, the servers in /etc/resolv.conf are used if it is empty
+	Server string `yaml:"server" json:"server" koanf:"server"`
+	// RefreshInterval the max interval to resolve the names again, they are resolved earlier once the ttl expires
+	RefreshInterval time.Duration `yaml:"refresh-interval" json:"refresh-interval" koanf:"refresh-interval"`
+}
+
+func (cfg *DnsConfig) RegisterFlagsWithPrefix(prefix string, f *flag.FlagSet) {
+	f.Var(&cfg.Names, prefix+".names", "The domain names of the clusters.")
+	f.IntVar(&cfg.Port, prefix+".port", 8091, "The port of the tc instances resolved from the A/AAAA records.")
+	f.StringVar(&cfg.Server, prefix+".server", "", "The dns servers, the servers in /etc/resolv.conf are used if it is empty.")
+	f.DurationVar(&cfg.RefreshInterval, prefix+".refresh-interval", 30*time.Second, "The max interval to resolve the names again.")
+}
+
+type KubernetesConfig struct {
+	// Namespace the namespace of the tc service, the namespace of the pod is used if it is empty
+	Namespace string `yaml:"namespace" json:"namespace" koanf:"namespace"`
+	// Service the name of the tc service, the cluster mapped from the vgroup is used as the
+	// service name if it is empty
+	Service string `yaml:"service" json:"service" koanf:"service"`
+	// PortName the name of the transaction port in the endpoint slices, the first port is used if it is empty
+	PortName string `yaml:"port-name" json:"port-name" koanf:"port-name"`
+	// Kubeconfig the kubeconfig file, the in-cluster config is used if it is empty
+	Kubeconfig string `yaml:"kubeconfig" json:"kubeconfig" koanf:"kubeconfig"`
+	// SyncTimeout the timeout to wait for the endpoint slices listed on the first lookup
+	SyncTimeout time.Duration `yaml:"sync-timeout" json:"sync-timeout" koanf:"sync-timeout"`
+}
+
+func (cfg *KubernetesConfig) RegisterFlagsWithPrefix(prefix string, f *flag.FlagSet) {
+	f.StringVar(&cfg.Namespace, prefix+".namespace", "", "The namespace of the tc service.")
+	f.StringVar(&cfg.Service, prefix+".service", "seata-server", "The name of the tc service.")
+	f.StringVar(&cfg.PortName, prefix+".port-name", "", "The name of the transaction port of the tc service.")
+	f.StringVar(&cfg.Kubeconfig, prefix+".kubeconfig", "", "The kubeconfig file, the in-cluster config is used if it is empty.")
+	f.DurationVar(&cfg.SyncTimeout, prefix+".sync-timeout", 10*time.Second, "The timeout to wait for the endpoint slices listed.")
+}
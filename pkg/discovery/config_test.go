@@ -0,0 +1,49 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package discovery
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServiceConfig_Validate(t *testing.T) {
+	cfg := &ServiceConfig{Grouplist: map[string]string{
+		"default": "127.0.0.1:8091;127.0.0.2:8091?weight=10&zone=az1",
+		"ipv6":    "[::1]:8091",
+	}}
+	assert.NoError(t, cfg.ValidateWithPrefix("service"))
+
+	cfg = &ServiceConfig{Grouplist: map[string]string{
+		"b": "127.0.0.1",
+		"a": "127.0.0.1:8091;:8091;127.0.0.1:70000;127.0.0.1:port",
+	}}
+	err := cfg.ValidateWithPrefix("service")
+	assert.EqualError(t, err, `service.grouplist.a: invalid endpoint ":8091": the host is empty
+service.grouplist.a: invalid endpoint "127.0.0.1:70000": the port 70000 is out of range
+service.grouplist.a: invalid endpoint "127.0.0.1:port": invalid port of address 127.0.0.1:port: strconv.Atoi: parsing "port": invalid syntax
+service.grouplist.b: invalid endpoint "127.0.0.1": address 127.0.0.1: missing port in address`)
+}
+
+func TestRegistryConfig_Validate(t *testing.T) {
+	assert.NoError(t, (&RegistryConfig{Type: NACOS}).ValidateWithPrefix("registry"))
+	assert.EqualError(t, (&RegistryConfig{Type: "apollo"}).ValidateWithPrefix("registry"), `registry.type: unsupported registry type "apollo"`)
+	// sofa is not implemented yet
+	assert.Error(t, (&RegistryConfig{Type: SOFA}).ValidateWithPrefix("registry"))
+}
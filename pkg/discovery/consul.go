@@ -17,14 +17,265 @@
 
 package discovery
 
-type ConsulRegistryService struct{}
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"seata.apache.org/seata-go/pkg/util/log"
+)
+
+const (
+	consulHealthServicePath = "/v1/health/service/"
+	consulIndexHeader       = "X-Consul-Index"
+	consulTokenHeader       = "X-Consul-Token"
+	consulCheckPassing      = "passing"
+	consulRetryInterval     = time.Second
+	// consulRequestTimeout the timeout of the query besides the wait time of the blocking query,
+	// consul adds a random jitter up to wait time / 16 to the wait time
+	consulRequestTimeout = 5 * time.Second
+)
+
+type consulHealthService struct {
+	Node struct {
+		Address string `json:"Address"`
+	} `json:"Node"`
+	Service struct {
+		ID      string            `json:"ID"`
+		Service string            `json:"Service"`
+		Address string            `json:"Address"`
+		Port    int               `json:"Port"`
+		Meta    map[string]string `json:"Meta"`
+	} `json:"Service"`
+	Checks []consulCheck `json:"Checks"`
+}
+
+type consulCheck struct {
+	Status string `json:"Status"`
+}
+
+// ConsulRegistryService discovers the tc instances registered to consul by the seata server,
+// the service name is the cluster mapped from the vgroup. The clusters looked up are watched
+// by the blocking queries, so the instance changes are followed.
+type ConsulRegistryService struct {
+	serviceConfig *ServiceConfig
+	consulConfig  ConsulConfig
+	baseURL       string
+	httpClient    *http.Client
+
+	// cluster -> instances which pass all health checks
+	instances     map[string][]*ServiceInstance
+	rwLock        sync.RWMutex
+	subscriptions *subscriptions
+	// notifyLock orders the subscribing and the notifications of the watched changes
+	notifyLock sync.Mutex
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func newConsulRegistryService(config *ServiceConfig, consulConfig *ConsulConfig) (RegistryService, error) {
+	if config == nil || consulConfig == nil {
+		return nil, fmt.Errorf("consul registry config is nil")
+	}
+	if consulConfig.ServerAddr == "" {
+		return nil, fmt.Errorf("consul registry server addr is empty")
+	}
+	baseURL := consulConfig.ServerAddr
+	if !strings.Contains(baseURL, "://") {
+		baseURL = "http://" + baseURL
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &ConsulRegistryService{
+		serviceConfig: config,
+		consulConfig:  *consulConfig,
+		baseURL:       strings.TrimRight(baseURL, "/"),
+		httpClient:    &http.Client{},
+		instances:     make(map[string][]*ServiceInstance),
+		subscriptions: newSubscriptions(),
+		ctx:           ctx,
+		cancel:        cancel,
+	}
+	return s, nil
+}
 
 func (s *ConsulRegistryService) Lookup(key string) ([]*ServiceInstance, error) {
-	//TODO implement me
-	panic("implement me")
+	cluster := s.serviceConfig.VgroupMapping[key]
+	if cluster == "" {
+		return nil, fmt.Errorf("vgroup is empty. key: %s", key)
+	}
+	s.rwLock.RLock()
+	instances, ok := s.instances[cluster]
+	s.rwLock.RUnlock()
+	if ok {
+		return instances, nil
+	}
+
+	instances, index, err := s.query(cluster, 0)
+	if err != nil {
+		return nil, err
+	}
+	s.rwLock.Lock()
+	defer s.rwLock.Unlock()
+	if cached, ok := s.instances[cluster]; ok {
+		// watched by a concurrent lookup already
+		return cached, nil
+	}
+	s.instances[cluster] = instances
+	if s.ctx.Err() == nil {
+		s.wg.Add(1)
+		go s.watch(cluster, index)
+	}
+	return instances, nil
+}
+
+func (s *ConsulRegistryService) Subscribe(key string, listener Listener) error {
+	s.notifyLock.Lock()
+	defer s.notifyLock.Unlock()
+	instances, err := s.Lookup(key)
+	if err != nil {
+		return err
+	}
+	return s.subscriptions.subscribe(key, listener, instances)
+}
+
+func (s *ConsulRegistryService) Unsubscribe(key string, listener Listener) error {
+	_, err := s.subscriptions.unsubscribe(key, listener)
+	return err
+}
+
+// notify updates the subscriptions of the keys mapped to the cluster with its current instances
+func (s *ConsulRegistryService) notify(cluster string) {
+	s.notifyLock.Lock()
+	defer s.notifyLock.Unlock()
+	s.rwLock.RLock()
+	instances := s.instances[cluster]
+	s.rwLock.RUnlock()
+	for key, c := range s.serviceConfig.VgroupMapping {
+		if c == cluster {
+			s.subscriptions.update(key, instances)
+		}
+	}
 }
 
 func (s *ConsulRegistryService) Close() {
-	//TODO implement me
-	panic("implement me")
+	s.rwLock.Lock()
+	s.cancel()
+	s.rwLock.Unlock()
+	s.wg.Wait()
+}
+
+// watch follows the instance changes of the cluster by the blocking queries
+func (s *ConsulRegistryService) watch(cluster string, index uint64) {
+	defer s.wg.Done()
+	for s.ctx.Err() == nil {
+		instances, newIndex, err := s.query(cluster, index)
+		if err != nil {
+			if s.ctx.Err() != nil {
+				return
+			}
+			log.Warnf("watch consul service failed, cluster: %s, err: %v", cluster, err)
+			select {
+			case <-s.ctx.Done():
+				return
+			case <-time.After(consulRetryInterval):
+			}
+			continue
+		}
+		if newIndex == index {
+			// the wait time elapsed without any change
+			continue
+		}
+		// the index goes backwards when the consul state is reset, watch from the beginning
+		if newIndex < index {
+			newIndex = 0
+		}
+		index = newIndex
+
+		s.rwLock.Lock()
+		log.Infof("consul instances of cluster %s changed: %d -> %d", cluster, len(s.instances[cluster]), len(instances))
+		s.instances[cluster] = instances
+		s.rwLock.Unlock()
+		s.notify(cluster)
+	}
+}
+
+// query queries the passing instances of the cluster, it blocks until the index of the service
+// changes or the wait time elapses if the index is positive
+func (s *ConsulRegistryService) query(cluster string, index uint64) ([]*ServiceInstance, uint64, error) {
+	params := url.Values{}
+	params.Set("passing", "true")
+	timeout := consulRequestTimeout
+	if index > 0 {
+		params.Set("index", strconv.FormatUint(index, 10))
+		if s.consulConfig.WaitTime > 0 {
+			params.Set("wait", strconv.FormatInt(s.consulConfig.WaitTime.Milliseconds(), 10)+"ms")
+			timeout += s.consulConfig.WaitTime + s.consulConfig.WaitTime/16
+		}
+	}
+	ctx, cancel := context.WithTimeout(s.ctx, timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet,
+		s.baseURL+consulHealthServicePath+url.PathEscape(cluster)+"?"+params.Encode(), nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	if s.consulConfig.AclToken != "" {
+		req.Header.Set(consulTokenHeader, s.consulConfig.AclToken)
+	}
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, 0, fmt.Errorf("unexpected status code %d from consul %s", resp.StatusCode, s.baseURL)
+	}
+	newIndex, err := strconv.ParseUint(resp.Header.Get(consulIndexHeader), 10, 64)
+	if err != nil {
+		return nil, 0, fmt.Errorf("invalid consul index %q: %w", resp.Header.Get(consulIndexHeader), err)
+	}
+	services := make([]consulHealthService, 0)
+	if err = json.NewDecoder(resp.Body).Decode(&services); err != nil {
+		return nil, 0, err
+	}
+	instances := make([]*ServiceInstance, 0, len(services))
+	for _, service := range services {
+		if service.passing() {
+			instances = append(instances, service.instance())
+		}
+	}
+	return instances, newIndex, nil
+}
+
+func (s consulHealthService) passing() bool {
+	for _, check := range s.Checks {
+		if check.Status != consulCheckPassing {
+			return false
+		}
+	}
+	return true
+}
+
+func (s consulHealthService) instance() *ServiceInstance {
+	addr := s.Service.Address
+	if addr == "" {
+		addr = s.Node.Address
+	}
+	metadata := make(map[string]string, len(s.Service.Meta))
+	for k, v := range s.Service.Meta {
+		metadata[k] = v
+	}
+	return &ServiceInstance{
+		Addr:     addr,
+		Port:     s.Service.Port,
+		Metadata: metadata,
+	}
 }
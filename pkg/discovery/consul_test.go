@@ -0,0 +1,191 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package discovery
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeConsulInstance struct {
+	port   int
+	status string
+}
+
+// fakeConsulServer serves the health service api of consul with the blocking queries
+type fakeConsulServer struct {
+	*httptest.Server
+	mu        sync.Mutex
+	token     string
+	index     uint64
+	changed   chan struct{}
+	instances map[string][]fakeConsulInstance
+	requests  []*http.Request
+}
+
+func newFakeConsulServer() *fakeConsulServer {
+	s := &fakeConsulServer{
+		index:   10,
+		changed: make(chan struct{}),
+		instances: map[string][]fakeConsulInstance{
+			"default": {{port: 8091, status: consulCheckPassing}, {port: 8092, status: "critical"}},
+		},
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	return s
+}
+
+func (s *fakeConsulServer) serve(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.requests = append(s.requests, r)
+	if s.token != "" && r.Header.Get(consulTokenHeader) != s.token {
+		s.mu.Unlock()
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	cluster := r.URL.Path[len(consulHealthServicePath):]
+	index, _ := strconv.ParseUint(r.URL.Query().Get("index"), 10, 64)
+	if index > 0 && index == s.index {
+		wait, _ := time.ParseDuration(r.URL.Query().Get("wait"))
+		changed := s.changed
+		s.mu.Unlock()
+		select {
+		case <-changed:
+		case <-time.After(wait):
+		case <-r.Context().Done():
+			return
+		}
+		s.mu.Lock()
+	}
+	defer s.mu.Unlock()
+
+	services := make([]consulHealthService, 0)
+	for _, instance := range s.instances[cluster] {
+		if r.URL.Query().Get("passing") == "true" && instance.status != consulCheckPassing {
+			continue
+		}
+		service := consulHealthService{}
+		service.Node.Address = "10.0.0.1"
+		service.Service.Service = cluster
+		service.Service.Port = instance.port
+		service.Service.Meta = map[string]string{MetadataZone: "zone-a"}
+		service.Checks = append(service.Checks, consulCheck{Status: instance.status})
+		services = append(services, service)
+	}
+	w.Header().Set(consulIndexHeader, strconv.FormatUint(s.index, 10))
+	_ = json.NewEncoder(w).Encode(services)
+}
+
+func (s *fakeConsulServer) setInstances(cluster string, instances ...fakeConsulInstance) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.instances[cluster] = instances
+	s.index++
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
+
+func newTestConsulRegistryService(t *testing.T, consulConfig ConsulConfig) *ConsulRegistryService {
+	registry, err := newConsulRegistryService(&ServiceConfig{
+		VgroupMapping: map[string]string{"default_tx_group": "default"},
+	}, &consulConfig)
+	require.NoError(t, err)
+	t.Cleanup(registry.Close)
+	return registry.(*ConsulRegistryService)
+}
+
+func TestConsulRegistryService_Lookup(t *testing.T) {
+	server := newFakeConsulServer()
+	defer server.Close()
+	server.token = "acl-token"
+	registry := newTestConsulRegistryService(t, ConsulConfig{ServerAddr: server.URL, AclToken: "acl-token", WaitTime: time.Second})
+
+	instances, err := registry.Lookup("default_tx_group")
+	require.NoError(t, err)
+	assert.Equal(t, []*ServiceInstance{
+		{Addr: "10.0.0.1", Port: 8091, Metadata: map[string]string{MetadataZone: "zone-a"}},
+	}, instances)
+
+	_, err = registry.Lookup("unknown_tx_group")
+	assert.Error(t, err)
+
+	registry = newTestConsulRegistryService(t, ConsulConfig{ServerAddr: server.URL})
+	_, err = registry.Lookup("default_tx_group")
+	assert.Error(t, err)
+}
+
+func TestConsulRegistryService_Watch(t *testing.T) {
+	server := newFakeConsulServer()
+	defer server.Close()
+	registry := newTestConsulRegistryService(t, ConsulConfig{ServerAddr: server.URL, WaitTime: 5 * time.Second})
+	instances, err := registry.Lookup("default_tx_group")
+	require.NoError(t, err)
+	require.Len(t, instances, 1)
+	listener := &recordListener{}
+	require.NoError(t, registry.Subscribe("default_tx_group", listener))
+	assert.Len(t, listener.take(), 1)
+
+	server.setInstances("default", fakeConsulInstance{port: 8091, status: consulCheckPassing},
+		fakeConsulInstance{port: 8093, status: consulCheckPassing})
+	// the change is pushed to the blocking query long before the wait time elapses
+	assert.Eventually(t, func() bool {
+		instances, err := registry.Lookup("default_tx_group")
+		return err == nil && len(instances) == 2
+	}, 2*time.Second, 10*time.Millisecond)
+	// and notified to the subscribers by the watch
+	assert.Eventually(t, func() bool {
+		return assert.ObjectsAreEqual([]string{"ADD default_tx_group 10.0.0.1:8093"}, listener.take())
+	}, 500*time.Millisecond, 10*time.Millisecond)
+
+	server.mu.Lock()
+	last := server.requests[len(server.requests)-1].URL.Query()
+	server.mu.Unlock()
+	assert.Equal(t, "11", last.Get("index"))
+	assert.Equal(t, "5000ms", last.Get("wait"))
+
+	// close stops the blocking query
+	done := make(chan struct{})
+	go func() {
+		registry.Close()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("close is blocked by the watch")
+	}
+}
+
+func TestConsulHealthService_Passing(t *testing.T) {
+	service := consulHealthService{}
+	assert.True(t, service.passing())
+	service.Checks = append(service.Checks, consulCheck{Status: consulCheckPassing}, consulCheck{Status: "warning"})
+	assert.False(t, service.passing())
+
+	service.Service.Address = "10.0.0.2"
+	service.Node.Address = "10.0.0.1"
+	assert.Equal(t, "10.0.0.2", service.instance().Addr)
+}
@@ -0,0 +1,407 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package discovery
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+
+	"seata.apache.org/seata-go/pkg/util/log"
+)
+
+const (
+	dnsServerSplitChar = ","
+	dnsSRVPrefix       = "_"
+	dnsResolvConf      = "/etc/resolv.conf"
+	dnsDefaultPort     = "53"
+	dnsTimeout         = 5 * time.Second
+	// dnsMinRefreshInterval protects the dns servers from the records of a tiny ttl
+	dnsMinRefreshInterval = time.Second
+	// dnsRetryInterval the interval to resolve again after the resolving failed
+	dnsRetryInterval = 5 * time.Second
+)
+
+// dnsResolver resolves the records of the name, the min ttl of the records is returned with them
+type dnsResolver interface {
+	lookupIP(ctx context.Context, name string) ([]net.IP, time.Duration, error)
+	lookupSRV(ctx context.Context, name string) ([]*net.SRV, time.Duration, error)
+}
+
+type dnsEntry struct {
+	instances []*ServiceInstance
+	expire    time.Time
+}
+
+// DnsRegistryService discovers the tc instances by the dns records of the cluster names, e.g. the
+// headless service of the tc stateful set in kubernetes. The names are resolved again once the ttl
+// of the records expires or the refresh interval elapses.
+type DnsRegistryService struct {
+	// poller notifies the changes of the subscribed keys from the resolved instances
+	*poller
+	serviceConfig *ServiceConfig
+	dnsConfig     DnsConfig
+	resolver      dnsResolver
+	now           func() time.Time
+	// checkInterval the interval to check the expired entries
+	checkInterval time.Duration
+
+	// cluster -> resolved instances
+	entries map[string]*dnsEntry
+	rwLock  sync.RWMutex
+
+	stopCh    chan struct{}
+	closeOnce sync.Once
+	// startOnce starts refreshing once a cluster is resolved
+	startOnce sync.Once
+	wg        sync.WaitGroup
+}
+
+func newDnsRegistryService(config *ServiceConfig, dnsConfig *DnsConfig) (RegistryService, error) {
+	if config == nil || dnsConfig == nil {
+		return nil, fmt.Errorf("dns registry config is nil")
+	}
+	if len(dnsConfig.Names) == 0 {
+		return nil, fmt.Errorf("dns registry names are empty")
+	}
+	client, err := newDnsClient(dnsConfig.Server)
+	if err != nil {
+		return nil, err
+	}
+	return newDnsRegistryServiceWithResolver(config, dnsConfig, client), nil
+}
+
+func newDnsRegistryServiceWithResolver(config *ServiceConfig, dnsConfig *DnsConfig, resolver dnsResolver) *DnsRegistryService {
+	s := &DnsRegistryService{
+		serviceConfig: config,
+		dnsConfig:     *dnsConfig,
+		resolver:      resolver,
+		now:           time.Now,
+		checkInterval: dnsMinRefreshInterval,
+		entries:       make(map[string]*dnsEntry),
+		stopCh:        make(chan struct{}),
+	}
+	s.poller = newPoller(s.Lookup, 0)
+	return s
+}
+
+func (s *DnsRegistryService) Lookup(key string) ([]*ServiceInstance, error) {
+	cluster := s.serviceConfig.VgroupMapping[key]
+	if cluster == "" {
+		return nil, fmt.Errorf("vgroup is empty. key: %s", key)
+	}
+	s.rwLock.RLock()
+	entry, ok := s.entries[cluster]
+	s.rwLock.RUnlock()
+	if ok {
+		return entry.instances, nil
+	}
+
+	instances, err := s.refresh(cluster)
+	if err != nil {
+		return nil, err
+	}
+	s.startOnce.Do(func() {
+		s.wg.Add(1)
+		go s.refreshLoop()
+	})
+	return instances, nil
+}
+
+func (s *DnsRegistryService) Close() {
+	s.poller.close()
+	s.closeOnce.Do(func() {
+		close(s.stopCh)
+	})
+	// no refreshing is started after closed
+	s.startOnce.Do(func() {})
+	s.wg.Wait()
+}
+
+func (s *DnsRegistryService) refreshLoop() {
+	defer s.wg.Done()
+	ticker := time.NewTicker(s.checkInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.stopCh:
+			return
+		case <-ticker.C:
+			now := s.now()
+			s.rwLock.RLock()
+			expired := make([]string, 0)
+			for cluster, entry := range s.entries {
+				if !now.Before(entry.expire) {
+					expired = append(expired, cluster)
+				}
+			}
+			s.rwLock.RUnlock()
+			for _, cluster := range expired {
+				if _, err := s.refresh(cluster); err != nil {
+					log.Warnf("resolve the tc instances of cluster %s failed: %v", cluster, err)
+				}
+			}
+		}
+	}
+}
+
+// refresh resolves the name of the cluster and caches the instances until the ttl expires,
+// the cached instances are kept for a while if the resolving fails.
+func (s *DnsRegistryService) refresh(cluster string) ([]*ServiceInstance, error) {
+	name := s.dnsConfig.Names[cluster]
+	if name == "" {
+		return nil, fmt.Errorf("dns name of cluster %s is not configured", cluster)
+	}
+	instances, ttl, err := s.resolve(name)
+
+	s.rwLock.Lock()
+	defer s.rwLock.Unlock()
+	if err != nil {
+		if entry, ok := s.entries[cluster]; ok {
+			entry.expire = s.now().Add(s.interval(dnsRetryInterval))
+		}
+		return nil, err
+	}
+	if entry, ok := s.entries[cluster]; ok && len(entry.instances) != len(instances) {
+		log.Infof("dns instances of cluster %s changed: %d -> %d", cluster, len(entry.instances), len(instances))
+	}
+	s.entries[cluster] = &dnsEntry{
+		instances: instances,
+		expire:    s.now().Add(s.interval(ttl)),
+	}
+	return instances, nil
+}
+
+// interval returns the ttl bounded by the refresh interval and the min refresh interval
+func (s *DnsRegistryService) interval(ttl time.Duration) time.Duration {
+	interval := s.dnsConfig.RefreshInterval
+	if ttl > 0 && (interval <= 0 || ttl < interval) {
+		interval = ttl
+	}
+	if interval < dnsMinRefreshInterval {
+		interval = dnsMinRefreshInterval
+	}
+	return interval
+}
+
+func (s *DnsRegistryService) resolve(name string) ([]*ServiceInstance, time.Duration, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), dnsTimeout)
+	defer cancel()
+	if strings.HasPrefix(name, dnsSRVPrefix) {
+		return s.resolveSRV(ctx, name)
+	}
+	ips, ttl, err := s.resolver.lookupIP(ctx, name)
+	if err != nil {
+		return nil, 0, err
+	}
+	instances := make([]*ServiceInstance, 0, len(ips))
+	for _, ip := range ips {
+		instances = append(instances, &ServiceInstance{
+			Addr: ip.String(),
+			Port: s.dnsConfig.Port,
+		})
+	}
+	sortInstances(instances)
+	return instances, ttl, nil
+}
+
+// resolveSRV resolves the targets of the SRV records of the lowest priority, the weight of
+// the records is kept as the weight of the instances
+func (s *DnsRegistryService) resolveSRV(ctx context.Context, name string) ([]*ServiceInstance, time.Duration, error) {
+	records, ttl, err := s.resolver.lookupSRV(ctx, name)
+	if err != nil {
+		return nil, 0, err
+	}
+	instances := make([]*ServiceInstance, 0, len(records))
+	for _, record := range records {
+		if record.Priority != records[0].Priority {
+			continue
+		}
+		var metadata map[string]string
+		if record.Weight > 0 {
+			metadata = map[string]string{MetadataWeight: strconv.Itoa(int(record.Weight))}
+		}
+		ips := []net.IP{net.ParseIP(record.Target)}
+		if ips[0] == nil {
+			var targetTTL time.Duration
+			if ips, targetTTL, err = s.resolver.lookupIP(ctx, record.Target); err != nil {
+				log.Warnf("resolve the target %s of the SRV record of %s failed: %v", record.Target, name, err)
+				continue
+			}
+			ttl = minTTL(ttl, targetTTL)
+		}
+		for _, ip := range ips {
+			instances = append(instances, &ServiceInstance{
+				Addr:     ip.String(),
+				Port:     int(record.Port),
+				Metadata: metadata,
+			})
+		}
+	}
+	if len(instances) == 0 && len(records) > 0 {
+		return nil, 0, fmt.Errorf("no target of the SRV records of %s is resolved", name)
+	}
+	sortInstances(instances)
+	return instances, ttl, nil
+}
+
+// sortInstances keeps the order of the instances stable for the load balance
+func sortInstances(instances []*ServiceInstance) {
+	sort.Slice(instances, func(i, j int) bool {
+		if instances[i].Addr != instances[j].Addr {
+			return instances[i].Addr < instances[j].Addr
+		}
+		return instances[i].Port < instances[j].Port
+	})
+}
+
+func minTTL(a, b time.Duration) time.Duration {
+	if a <= 0 || (b > 0 && b < a) {
+		return b
+	}
+	return a
+}
+
+// dnsClient queries the dns servers directly, so the ttl of the records is known
+type dnsClient struct {
+	client  *dns.Client
+	servers []string
+	// config the search domains and ndots of resolv.conf, nil if the servers are configured
+	config *dns.ClientConfig
+}
+
+func newDnsClient(server string) (*dnsClient, error) {
+	c := &dnsClient{client: &dns.Client{Timeout: dnsTimeout}}
+	for _, addr := range strings.Split(server, dnsServerSplitChar) {
+		if addr = strings.TrimSpace(addr); addr == "" {
+			continue
+		}
+		if _, _, err := net.SplitHostPort(addr); err != nil {
+			addr = net.JoinHostPort(addr, dnsDefaultPort)
+		}
+		c.servers = append(c.servers, addr)
+	}
+	if len(c.servers) > 0 {
+		return c, nil
+	}
+
+	config, err := dns.ClientConfigFromFile(dnsResolvConf)
+	if err != nil {
+		return nil, fmt.Errorf("read dns servers from %s failed: %w", dnsResolvConf, err)
+	}
+	for _, host := range config.Servers {
+		c.servers = append(c.servers, net.JoinHostPort(host, config.Port))
+	}
+	if len(c.servers) == 0 {
+		return nil, fmt.Errorf("no dns server in %s", dnsResolvConf)
+	}
+	c.config = config
+	return c, nil
+}
+
+func (c *dnsClient) lookupIP(ctx context.Context, name string) ([]net.IP, time.Duration, error) {
+	ips := make([]net.IP, 0)
+	var ttl time.Duration
+	var lastErr error
+	succeeded := false
+	for _, qtype := range []uint16{dns.TypeA, dns.TypeAAAA} {
+		answers, err := c.query(ctx, name, qtype)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		succeeded = true
+		for _, answer := range answers {
+			switch record := answer.(type) {
+			case *dns.A:
+				ips = append(ips, record.A)
+			case *dns.AAAA:
+				ips = append(ips, record.AAAA)
+			default:
+				continue
+			}
+			ttl = minTTL(ttl, time.Duration(answer.Header().Ttl)*time.Second)
+		}
+	}
+	if !succeeded {
+		return nil, 0, lastErr
+	}
+	return ips, ttl, nil
+}
+
+func (c *dnsClient) lookupSRV(ctx context.Context, name string) ([]*net.SRV, time.Duration, error) {
+	answers, err := c.query(ctx, name, dns.TypeSRV)
+	if err != nil {
+		return nil, 0, err
+	}
+	records := make([]*net.SRV, 0, len(answers))
+	var ttl time.Duration
+	for _, answer := range answers {
+		if record, ok := answer.(*dns.SRV); ok {
+			records = append(records, &net.SRV{
+				Target:   strings.TrimSuffix(record.Target, "."),
+				Port:     record.Port,
+				Priority: record.Priority,
+				Weight:   record.Weight,
+			})
+			ttl = minTTL(ttl, time.Duration(record.Hdr.Ttl)*time.Second)
+		}
+	}
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].Priority < records[j].Priority
+	})
+	return records, ttl, nil
+}
+
+// query queries the name completed by the search domains in turn, every name is queried from
+// the servers in turn until one of them answers.
+func (c *dnsClient) query(ctx context.Context, name string, qtype uint16) ([]dns.RR, error) {
+	names := []string{dns.Fqdn(name)}
+	if c.config != nil {
+		names = c.config.NameList(name)
+	}
+	var lastErr error
+	for _, fqdn := range names {
+		for _, server := range c.servers {
+			msg := new(dns.Msg)
+			msg.SetQuestion(fqdn, qtype)
+			resp, _, err := c.client.ExchangeContext(ctx, msg, server)
+			if err != nil {
+				lastErr = err
+				continue
+			}
+			if resp.Rcode == dns.RcodeNameError {
+				lastErr = fmt.Errorf("no such host %s", fqdn)
+				break
+			}
+			if resp.Rcode != dns.RcodeSuccess {
+				lastErr = fmt.Errorf("query %s of %s failed: %s", dns.TypeToString[qtype], fqdn, dns.RcodeToString[resp.Rcode])
+				continue
+			}
+			return resp.Answer, nil
+		}
+	}
+	return nil, lastErr
+}
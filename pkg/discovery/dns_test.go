@@ -0,0 +1,268 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package discovery
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubResolver answers the records set by the tests
+type stubResolver struct {
+	mu    sync.Mutex
+	ips   map[string][]net.IP
+	srvs  map[string][]*net.SRV
+	ttl   time.Duration
+	err   error
+	calls map[string]int
+}
+
+func newStubResolver() *stubResolver {
+	return &stubResolver{
+		ips:   map[string][]net.IP{},
+		srvs:  map[string][]*net.SRV{},
+		calls: map[string]int{},
+	}
+}
+
+func (r *stubResolver) lookupIP(ctx context.Context, name string) ([]net.IP, time.Duration, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls[name]++
+	if r.err != nil {
+		return nil, 0, r.err
+	}
+	ips, ok := r.ips[name]
+	if !ok {
+		return nil, 0, fmt.Errorf("no such host %s", name)
+	}
+	return ips, r.ttl, nil
+}
+
+func (r *stubResolver) lookupSRV(ctx context.Context, name string) ([]*net.SRV, time.Duration, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls[name]++
+	if r.err != nil {
+		return nil, 0, r.err
+	}
+	return r.srvs[name], r.ttl, nil
+}
+
+func (r *stubResolver) set(fn func(r *stubResolver)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	fn(r)
+}
+
+func (r *stubResolver) callsOf(name string) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.calls[name]
+}
+
+// fakeClock the clock of the dns registry moved by the tests
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func newTestDnsRegistryService(resolver dnsResolver, names map[string]string) (*DnsRegistryService, *fakeClock) {
+	s := newDnsRegistryServiceWithResolver(&ServiceConfig{
+		VgroupMapping: map[string]string{"default_tx_group": "default"},
+	}, &DnsConfig{
+		Names:           names,
+		Port:            8091,
+		RefreshInterval: 30 * time.Second,
+	}, resolver)
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	s.now = clock.Now
+	s.checkInterval = 10 * time.Millisecond
+	return s, clock
+}
+
+func TestDnsRegistryService_LookupA(t *testing.T) {
+	resolver := newStubResolver()
+	resolver.ips["seata-server"] = []net.IP{net.ParseIP("127.0.0.2"), net.ParseIP("127.0.0.1")}
+	resolver.ttl = 5 * time.Second
+	s, clock := newTestDnsRegistryService(resolver, map[string]string{"default": "seata-server"})
+	defer s.Close()
+
+	instances, err := s.Lookup("default_tx_group")
+	require.NoError(t, err)
+	assert.Equal(t, []*ServiceInstance{{Addr: "127.0.0.1", Port: 8091}, {Addr: "127.0.0.2", Port: 8091}}, instances)
+
+	// cached until the ttl expires
+	_, err = s.Lookup("default_tx_group")
+	require.NoError(t, err)
+	assert.Equal(t, 1, resolver.callsOf("seata-server"))
+
+	resolver.set(func(r *stubResolver) {
+		r.ips["seata-server"] = []net.IP{net.ParseIP("127.0.0.3")}
+	})
+	clock.advance(5 * time.Second)
+	assert.Eventually(t, func() bool {
+		instances, err = s.Lookup("default_tx_group")
+		return err == nil && len(instances) == 1 && instances[0].Addr == "127.0.0.3"
+	}, time.Second, 10*time.Millisecond)
+
+	_, err = s.Lookup("unknown_tx_group")
+	assert.EqualError(t, err, "vgroup is empty. key: unknown_tx_group")
+}
+
+func TestDnsRegistryService_LookupSRV(t *testing.T) {
+	resolver := newStubResolver()
+	resolver.srvs["_seata._tcp.seata-server"] = []*net.SRV{
+		{Target: "tc-0.seata-server", Port: 8091, Priority: 10, Weight: 3},
+		{Target: "127.0.0.5", Port: 8092, Priority: 10},
+		{Target: "tc-backup.seata-server", Port: 8091, Priority: 20, Weight: 1},
+	}
+	resolver.ips["tc-0.seata-server"] = []net.IP{net.ParseIP("127.0.0.1")}
+	resolver.ips["tc-backup.seata-server"] = []net.IP{net.ParseIP("127.0.0.9")}
+	s, _ := newTestDnsRegistryService(resolver, map[string]string{"default": "_seata._tcp.seata-server"})
+	defer s.Close()
+
+	instances, err := s.Lookup("default_tx_group")
+	require.NoError(t, err)
+	assert.Equal(t, []*ServiceInstance{
+		{Addr: "127.0.0.1", Port: 8091, Metadata: map[string]string{MetadataWeight: "3"}},
+		{Addr: "127.0.0.5", Port: 8092},
+	}, instances)
+	assert.Equal(t, 3, instances[0].Weight())
+	assert.Equal(t, 0, resolver.callsOf("tc-backup.seata-server"))
+}
+
+func TestDnsRegistryService_ResolveFailed(t *testing.T) {
+	resolver := newStubResolver()
+	resolver.ips["seata-server"] = []net.IP{net.ParseIP("127.0.0.1")}
+	s, clock := newTestDnsRegistryService(resolver, map[string]string{"default": "seata-server", "other": "other-server"})
+	s.serviceConfig.VgroupMapping["other_tx_group"] = "other"
+	s.serviceConfig.VgroupMapping["nameless_tx_group"] = "nameless"
+	defer s.Close()
+
+	_, err := s.Lookup("other_tx_group")
+	assert.Error(t, err)
+	_, err = s.Lookup("nameless_tx_group")
+	assert.EqualError(t, err, "dns name of cluster nameless is not configured")
+
+	_, err = s.Lookup("default_tx_group")
+	require.NoError(t, err)
+
+	// the cached instances are kept and resolved again after the retry interval
+	resolver.set(func(r *stubResolver) { r.err = fmt.Errorf("timeout") })
+	clock.advance(30 * time.Second)
+	assert.Eventually(t, func() bool { return resolver.callsOf("seata-server") == 2 }, time.Second, 10*time.Millisecond)
+	instances, err := s.Lookup("default_tx_group")
+	require.NoError(t, err)
+	assert.Equal(t, []*ServiceInstance{{Addr: "127.0.0.1", Port: 8091}}, instances)
+
+	resolver.set(func(r *stubResolver) { r.err = nil })
+	clock.advance(dnsRetryInterval)
+	assert.Eventually(t, func() bool { return resolver.callsOf("seata-server") == 3 }, time.Second, 10*time.Millisecond)
+}
+
+func TestDnsRegistryService_Interval(t *testing.T) {
+	s, _ := newTestDnsRegistryService(newStubResolver(), map[string]string{})
+	assert.Equal(t, 30*time.Second, s.interval(0))
+	assert.Equal(t, 30*time.Second, s.interval(time.Minute))
+	assert.Equal(t, 5*time.Second, s.interval(5*time.Second))
+	assert.Equal(t, dnsMinRefreshInterval, s.interval(time.Millisecond))
+}
+
+func TestNewDnsRegistryService(t *testing.T) {
+	_, err := newDnsRegistryService(&ServiceConfig{}, &DnsConfig{})
+	assert.EqualError(t, err, "dns registry names are empty")
+
+	s, err := newDnsRegistryService(&ServiceConfig{}, &DnsConfig{
+		Names:  map[string]string{"default": "seata-server"},
+		Server: "10.96.0.10, 10.96.0.11:5353",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"10.96.0.10:53", "10.96.0.11:5353"}, s.(*DnsRegistryService).resolver.(*dnsClient).servers)
+	s.Close()
+}
+
+func TestDnsClient(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	mux := dns.NewServeMux()
+	mux.HandleFunc(".", func(w dns.ResponseWriter, req *dns.Msg) {
+		resp := new(dns.Msg)
+		resp.SetReply(req)
+		q := req.Question[0]
+		switch {
+		case q.Name == "seata-server.local." && q.Qtype == dns.TypeA:
+			resp.Answer = append(resp.Answer,
+				&dns.A{Hdr: dns.RR_Header{Name: q.Name, Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: 30}, A: net.ParseIP("127.0.0.1")},
+				&dns.A{Hdr: dns.RR_Header{Name: q.Name, Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: 10}, A: net.ParseIP("127.0.0.2")})
+		case q.Name == "seata-server.local." && q.Qtype == dns.TypeAAAA:
+			resp.Answer = append(resp.Answer,
+				&dns.AAAA{Hdr: dns.RR_Header{Name: q.Name, Rrtype: dns.TypeAAAA, Class: dns.ClassINET, Ttl: 20}, AAAA: net.ParseIP("::1")})
+		case q.Name == "_seata._tcp.local." && q.Qtype == dns.TypeSRV:
+			resp.Answer = append(resp.Answer,
+				&dns.SRV{Hdr: dns.RR_Header{Name: q.Name, Rrtype: dns.TypeSRV, Class: dns.ClassINET, Ttl: 15}, Priority: 20, Weight: 1, Port: 8092, Target: "tc-1.local."},
+				&dns.SRV{Hdr: dns.RR_Header{Name: q.Name, Rrtype: dns.TypeSRV, Class: dns.ClassINET, Ttl: 15}, Priority: 10, Weight: 2, Port: 8091, Target: "tc-0.local."})
+		default:
+			resp.Rcode = dns.RcodeNameError
+		}
+		_ = w.WriteMsg(resp)
+	})
+	server := &dns.Server{PacketConn: pc, Handler: mux}
+	go func() { _ = server.ActivateAndServe() }()
+	defer server.Shutdown()
+
+	client, err := newDnsClient(pc.LocalAddr().String())
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	ips, ttl, err := client.lookupIP(ctx, "seata-server.local")
+	require.NoError(t, err)
+	assert.Equal(t, []net.IP{net.ParseIP("127.0.0.1").To4(), net.ParseIP("127.0.0.2").To4(), net.ParseIP("::1")}, ips)
+	assert.Equal(t, 10*time.Second, ttl)
+
+	srvs, ttl, err := client.lookupSRV(ctx, "_seata._tcp.local")
+	require.NoError(t, err)
+	assert.Equal(t, []*net.SRV{
+		{Target: "tc-0.local", Port: 8091, Priority: 10, Weight: 2},
+		{Target: "tc-1.local", Port: 8092, Priority: 20, Weight: 1},
+	}, srvs)
+	assert.Equal(t, 15*time.Second, ttl)
+
+	_, _, err = client.lookupIP(ctx, "unknown.local")
+	assert.EqualError(t, err, "no such host unknown.local.")
+}
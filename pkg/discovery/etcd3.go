@@ -39,8 +39,14 @@ type EtcdRegistryService struct {
 	vgroupMapping map[string]string
 	grouplist     map[string][]*ServiceInstance
 	rwLock        sync.RWMutex
-
-	stopCh chan struct{}
+	subscriptions *subscriptions
+	// notifyLock orders the subscribing and the notifications of the watched changes
+	notifyLock sync.Mutex
+
+	stopCh    chan struct{}
+	closeOnce sync.Once
+	// closeClient closes the etcd client created by the registry itself
+	closeClient func() error
 }
 
 func newEtcdRegistryService(config *ServiceConfig, etcd3Config *Etcd3Config) RegistryService {
@@ -67,7 +73,9 @@ func newEtcdRegistryService(config *ServiceConfig, etcd3Config *Etcd3Config) Reg
 		cfg:           cfg,
 		vgroupMapping: vgroupMapping,
 		grouplist:     grouplist,
+		subscriptions: newSubscriptions(),
 		stopCh:        make(chan struct{}),
+		closeClient:   cli.Close,
 	}
 	go etcdRegistryService.watch(etcdClusterPrefix)
 
@@ -85,6 +93,7 @@ func (s *EtcdRegistryService) watch(key string) {
 	}
 
 	if resp != nil {
+		clusters := make(map[string]bool)
 		for _, kv := range resp.Kvs {
 			k := kv.Key
 			v := kv.Value
@@ -105,8 +114,11 @@ func (s *EtcdRegistryService) watch(key string) {
 				s.grouplist[clusterName] = append(s.grouplist[clusterName], serverInstance)
 			}
 			s.rwLock.Unlock()
+			clusters[clusterName] = true
+		}
+		for clusterName := range clusters {
+			s.notify(clusterName)
 		}
-
 	}
 	// watch the changes of endpoints
 	watchCh := s.client.Watch(ctx, key, etcd3.WithPrefix())
@@ -140,6 +152,7 @@ func (s *EtcdRegistryService) watch(key string) {
 					if s.grouplist[clusterName] == nil {
 						s.grouplist[clusterName] = []*ServiceInstance{serverInstance}
 						s.rwLock.Unlock()
+						s.notify(clusterName)
 						continue
 					}
 					if ifHaveSameServiceInstances(s.grouplist[clusterName], serverInstance) {
@@ -148,6 +161,7 @@ func (s *EtcdRegistryService) watch(key string) {
 					}
 					s.grouplist[clusterName] = append(s.grouplist[clusterName], serverInstance)
 					s.rwLock.Unlock()
+					s.notify(clusterName)
 
 				case etcd3.EventTypeDelete:
 					log.Infof("Key %s deleted.\n", event.Kv.Key)
@@ -167,6 +181,7 @@ func (s *EtcdRegistryService) watch(key string) {
 					}
 					s.grouplist[cluster] = removeValueFromList(serviceInstances, ip, port)
 					s.rwLock.Unlock()
+					s.notify(cluster)
 				}
 			}
 		case <-s.stopCh:
@@ -187,8 +202,13 @@ func getClusterName(key []byte) (string, error) {
 	return cluster, nil
 }
 
+// getServerInstance parses the value in form of ip:port, the metadata can be appended
+// as the query, e.g. ip:port?weight=10&zone=az1
 func getServerInstance(value []byte) (*ServiceInstance, error) {
-	stringValue := string(value)
+	stringValue, metadata, err := splitMetadata(string(value))
+	if err != nil {
+		return nil, err
+	}
 	valueSplit := strings.Split(stringValue, addressSplitChar)
 	if len(valueSplit) != 2 {
 		return nil, fmt.Errorf("etcd value has an incorrect format. value: %s", stringValue)
@@ -199,8 +219,9 @@ func getServerInstance(value []byte) (*ServiceInstance, error) {
 		return nil, fmt.Errorf("etcd port has an incorrect format. err: %w", err)
 	}
 	serverInstance := &ServiceInstance{
-		Addr: ip,
-		Port: port,
+		Addr:     ip,
+		Port:     port,
+		Metadata: metadata,
 	}
 
 	return serverInstance, nil
@@ -258,6 +279,43 @@ func (s *EtcdRegistryService) Lookup(key string) ([]*ServiceInstance, error) {
 	return list, nil
 }
 
+func (s *EtcdRegistryService) Subscribe(key string, listener Listener) error {
+	s.notifyLock.Lock()
+	defer s.notifyLock.Unlock()
+	instances, err := s.Lookup(key)
+	if err != nil {
+		return err
+	}
+	return s.subscriptions.subscribe(key, listener, instances)
+}
+
+func (s *EtcdRegistryService) Unsubscribe(key string, listener Listener) error {
+	_, err := s.subscriptions.unsubscribe(key, listener)
+	return err
+}
+
+// notify updates the subscriptions of the keys mapped to the cluster with its current instances
+func (s *EtcdRegistryService) notify(cluster string) {
+	s.notifyLock.Lock()
+	defer s.notifyLock.Unlock()
+	s.rwLock.RLock()
+	instances := append([]*ServiceInstance{}, s.grouplist[cluster]...)
+	s.rwLock.RUnlock()
+	for key, c := range s.vgroupMapping {
+		if c == cluster {
+			s.subscriptions.update(key, instances)
+		}
+	}
+}
+
 func (s *EtcdRegistryService) Close() {
-	s.stopCh <- struct{}{}
+	s.closeOnce.Do(func() {
+		close(s.stopCh)
+		if s.closeClient == nil {
+			return
+		}
+		if err := s.closeClient(); err != nil {
+			log.Warnf("close etcd client error: %v", err)
+		}
+	})
 }
@@ -120,8 +120,9 @@ func TestEtcd3RegistryService_Lookup(t *testing.T) {
 			vgroupMapping: map[string]string{
 				"default_tx_group": "default",
 			},
-			grouplist: make(map[string][]*ServiceInstance, 0),
-			stopCh:    make(chan struct{}),
+			grouplist:     make(map[string][]*ServiceInstance, 0),
+			subscriptions: newSubscriptions(),
+			stopCh:        make(chan struct{}),
 		}
 
 		mockEtcdClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).Return(tt.getResp, nil)
@@ -156,3 +157,83 @@ func TestEtcd3RegistryService_Lookup(t *testing.T) {
 		etcdRegistryService.Close()
 	}
 }
+
+func TestEtcd3RegistryService_Subscribe(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockEtcdClient := mock.NewMockEtcdClient(ctrl)
+	etcdRegistryService := &EtcdRegistryService{
+		client: &clientv3.Client{
+			KV:      mockEtcdClient,
+			Watcher: mockEtcdClient,
+		},
+		vgroupMapping: map[string]string{
+			"default_tx_group": "default",
+		},
+		grouplist:     make(map[string][]*ServiceInstance, 0),
+		subscriptions: newSubscriptions(),
+		stopCh:        make(chan struct{}),
+	}
+	defer etcdRegistryService.Close()
+
+	listener := &recordListener{}
+	// subscribed before the instances are loaded
+	assert.NoError(t, etcdRegistryService.Subscribe("default_tx_group", listener))
+	assert.Empty(t, listener.take())
+
+	mockEtcdClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).Return(&clientv3.GetResponse{
+		Kvs: []*mvccpb.KeyValue{
+			{
+				Key:   []byte("registry-seata-default-172.0.0.1:8091"),
+				Value: []byte("172.0.0.1:8091"),
+			},
+		},
+	}, nil)
+	ch := make(chan clientv3.WatchResponse)
+	mockEtcdClient.EXPECT().Watch(gomock.Any(), gomock.Any(), gomock.Any()).Return(ch)
+	go etcdRegistryService.watch("registry-seata")
+
+	events := make([]string, 0)
+	take := func(n int) func() bool {
+		return func() bool {
+			events = append(events, listener.take()...)
+			return len(events) >= n
+		}
+	}
+	assert.Eventually(t, take(1), time.Second, 10*time.Millisecond)
+	assert.Equal(t, []string{"ADD default_tx_group 172.0.0.1:8091"}, events)
+
+	ch <- clientv3.WatchResponse{
+		Events: []*clientv3.Event{
+			{
+				Type: clientv3.EventTypePut,
+				Kv: &mvccpb.KeyValue{
+					Key:   []byte("registry-seata-default-172.0.0.1:8092"),
+					Value: []byte("172.0.0.1:8092"),
+				},
+			},
+			{
+				Type: clientv3.EventTypeDelete,
+				Kv: &mvccpb.KeyValue{
+					Key: []byte("registry-seata-default-172.0.0.1:8091"),
+				},
+			},
+		},
+	}
+	events = events[:0]
+	assert.Eventually(t, take(2), time.Second, 10*time.Millisecond)
+	assert.Equal(t, []string{"ADD default_tx_group 172.0.0.1:8092", "REMOVE default_tx_group 172.0.0.1:8091"}, events)
+
+	assert.NoError(t, etcdRegistryService.Unsubscribe("default_tx_group", listener))
+}
+
+func TestGetServerInstance(t *testing.T) {
+	instance, err := getServerInstance([]byte("172.0.0.1:8091?weight=5&version=2.0.0"))
+	assert.NoError(t, err)
+	assert.Equal(t, "172.0.0.1", instance.Addr)
+	assert.Equal(t, 8091, instance.Port)
+	assert.Equal(t, 5, instance.Weight())
+	assert.Equal(t, "2.0.0", instance.Version())
+
+	_, err = getServerInstance([]byte("172.0.0.1"))
+	assert.Error(t, err)
+}
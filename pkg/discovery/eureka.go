@@ -17,14 +17,347 @@
 
 package discovery
 
-type EurekaRegistryService struct{}
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"seata.apache.org/seata-go/pkg/util/log"
+)
+
+const (
+	eurekaServiceURLSplitChar = ","
+	eurekaAppsPath            = "/apps"
+	eurekaDeltaPath           = "/apps/delta"
+	eurekaStatusUp            = "UP"
+	eurekaActionAdded         = "ADDED"
+	eurekaActionModified      = "MODIFIED"
+	eurekaActionDeleted       = "DELETED"
+	eurekaHTTPTimeout         = 5 * time.Second
+	// eurekaMetadataClass the type hint added to the metadata by the java eureka server
+	eurekaMetadataClass = "@class"
+)
+
+// eurekaPort the port of the instance, it is a number or a string in the json of the eureka server
+type eurekaPort struct {
+	Port int `json:"$"`
+}
+
+func (p *eurekaPort) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		Port json.RawMessage `json:"$"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	port := strings.Trim(string(raw.Port), `"`)
+	if port == "" {
+		return nil
+	}
+	var err error
+	p.Port, err = strconv.Atoi(port)
+	return err
+}
+
+type eurekaInstance struct {
+	InstanceID string            `json:"instanceId"`
+	App        string            `json:"app"`
+	IPAddr     string            `json:"ipAddr"`
+	Status     string            `json:"status"`
+	Port       eurekaPort        `json:"port"`
+	Metadata   map[string]string `json:"metadata"`
+	ActionType string            `json:"actionType"`
+}
+
+type eurekaApplication struct {
+	Name      string           `json:"name"`
+	Instances []eurekaInstance `json:"instance"`
+}
+
+// eurekaApplications the response of the full registry and the delta api of eureka
+type eurekaApplications struct {
+	Applications struct {
+		AppsHashCode string              `json:"apps__hashcode"`
+		Application  []eurekaApplication `json:"application"`
+	} `json:"applications"`
+}
+
+// EurekaRegistryService discovers the tc instances registered to eureka by the seata server.
+// The full registry is fetched at the first lookup, then the delta is fetched periodically
+// and applied, the full registry is fetched again once the local registry is out of sync.
+type EurekaRegistryService struct {
+	// poller notifies the changes of the subscribed keys from the refreshed registry
+	*poller
+	serviceConfig *ServiceConfig
+	eurekaConfig  EurekaConfig
+	serviceURLs   []string
+	httpClient    *http.Client
+
+	// application -> instance id -> instance, the application names are upper case
+	apps    map[string]map[string]eurekaInstance
+	fetched bool
+	rwLock  sync.RWMutex
+	// fetchLock serializes the fetches of the registry
+	fetchLock sync.Mutex
+
+	stopCh    chan struct{}
+	closeOnce sync.Once
+	// startOnce starts the delta refresh once the registry is fetched
+	startOnce sync.Once
+	wg        sync.WaitGroup
+}
+
+func newEurekaRegistryService(config *ServiceConfig, eurekaConfig *EurekaConfig) (RegistryService, error) {
+	if config == nil || eurekaConfig == nil {
+		return nil, fmt.Errorf("eureka registry config is nil")
+	}
+	serviceURLs := make([]string, 0)
+	for _, serviceURL := range strings.Split(eurekaConfig.ServiceURL, eurekaServiceURLSplitChar) {
+		if serviceURL = strings.TrimSpace(serviceURL); serviceURL != "" {
+			if !strings.Contains(serviceURL, "://") {
+				serviceURL = "http://" + serviceURL
+			}
+			serviceURLs = append(serviceURLs, strings.TrimRight(serviceURL, "/"))
+		}
+	}
+	if len(serviceURLs) == 0 {
+		return nil, fmt.Errorf("eureka registry service url is empty")
+	}
+	s := &EurekaRegistryService{
+		serviceConfig: config,
+		eurekaConfig:  *eurekaConfig,
+		serviceURLs:   serviceURLs,
+		httpClient:    &http.Client{Timeout: eurekaHTTPTimeout},
+		apps:          make(map[string]map[string]eurekaInstance),
+		stopCh:        make(chan struct{}),
+	}
+	s.poller = newPoller(s.Lookup, 0)
+	return s, nil
+}
 
 func (s *EurekaRegistryService) Lookup(key string) ([]*ServiceInstance, error) {
-	//TODO implement me
-	panic("implement me")
+	cluster := s.serviceConfig.VgroupMapping[key]
+	if cluster == "" {
+		return nil, fmt.Errorf("vgroup is empty. key: %s", key)
+	}
+	app := s.eurekaConfig.Application
+	if app == "" {
+		app = cluster
+	}
+
+	s.rwLock.RLock()
+	fetched := s.fetched
+	s.rwLock.RUnlock()
+	if !fetched {
+		if err := s.fetchFull(); err != nil {
+			return nil, err
+		}
+		s.startOnce.Do(func() {
+			if s.eurekaConfig.RefreshInterval > 0 {
+				s.wg.Add(1)
+				go s.refreshLoop()
+			}
+		})
+	}
+
+	s.rwLock.RLock()
+	defer s.rwLock.RUnlock()
+	instances := make([]*ServiceInstance, 0)
+	for _, instance := range s.apps[strings.ToUpper(app)] {
+		if instance.Status == eurekaStatusUp {
+			instances = append(instances, instance.instance())
+		}
+	}
+	// keep the order stable for the load balance
+	sort.Slice(instances, func(i, j int) bool {
+		if instances[i].Addr != instances[j].Addr {
+			return instances[i].Addr < instances[j].Addr
+		}
+		return instances[i].Port < instances[j].Port
+	})
+	return instances, nil
 }
 
 func (s *EurekaRegistryService) Close() {
-	//TODO implement me
-	panic("implement me")
+	s.poller.close()
+	s.closeOnce.Do(func() {
+		close(s.stopCh)
+	})
+	// no refresh is started after closed
+	s.startOnce.Do(func() {})
+	s.wg.Wait()
+}
+
+func (s *EurekaRegistryService) refreshLoop() {
+	defer s.wg.Done()
+	ticker := time.NewTicker(s.eurekaConfig.RefreshInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.stopCh:
+			return
+		case <-ticker.C:
+			if err := s.fetchDelta(); err != nil {
+				log.Warnf("fetch eureka registry delta failed, fetch the full registry instead: %v", err)
+				if err = s.fetchFull(); err != nil {
+					log.Warnf("fetch eureka registry failed: %v", err)
+				}
+			}
+		}
+	}
+}
+
+// fetchFull fetches the full registry and replaces the local one
+func (s *EurekaRegistryService) fetchFull() error {
+	s.fetchLock.Lock()
+	defer s.fetchLock.Unlock()
+	result, err := s.fetch(eurekaAppsPath)
+	if err != nil {
+		return fmt.Errorf("fetch eureka registry failed: %w", err)
+	}
+	apps := make(map[string]map[string]eurekaInstance, len(result.Applications.Application))
+	for _, app := range result.Applications.Application {
+		for _, instance := range app.Instances {
+			putEurekaInstance(apps, app.Name, instance)
+		}
+	}
+	s.rwLock.Lock()
+	s.apps = apps
+	s.fetched = true
+	s.rwLock.Unlock()
+	return nil
+}
+
+// fetchDelta applies the recent changes of the registry, an error is returned if the local
+// registry differs from the server after the changes are applied.
+func (s *EurekaRegistryService) fetchDelta() error {
+	s.fetchLock.Lock()
+	defer s.fetchLock.Unlock()
+	result, err := s.fetch(eurekaDeltaPath)
+	if err != nil {
+		return err
+	}
+
+	s.rwLock.Lock()
+	defer s.rwLock.Unlock()
+	// the local registry is copied on write, so it is kept if the delta is rejected
+	apps := make(map[string]map[string]eurekaInstance, len(s.apps))
+	for name, instances := range s.apps {
+		apps[name] = make(map[string]eurekaInstance, len(instances))
+		for id, instance := range instances {
+			apps[name][id] = instance
+		}
+	}
+	for _, app := range result.Applications.Application {
+		for _, instance := range app.Instances {
+			switch instance.ActionType {
+			case eurekaActionAdded, eurekaActionModified:
+				putEurekaInstance(apps, app.Name, instance)
+			case eurekaActionDeleted:
+				name := strings.ToUpper(app.Name)
+				delete(apps[name], instance.id())
+				if len(apps[name]) == 0 {
+					delete(apps, name)
+				}
+			}
+		}
+	}
+	if hashCode := eurekaHashCode(apps); hashCode != result.Applications.AppsHashCode {
+		return fmt.Errorf("eureka registry hash code mismatch, local: %s, remote: %s", hashCode, result.Applications.AppsHashCode)
+	}
+	s.apps = apps
+	return nil
+}
+
+// fetch gets the applications from the service urls in turn, the first successful response is returned
+func (s *EurekaRegistryService) fetch(path string) (*eurekaApplications, error) {
+	var lastErr error
+	for _, serviceURL := range s.serviceURLs {
+		result, err := s.get(serviceURL + path)
+		if err == nil {
+			return result, nil
+		}
+		lastErr = err
+	}
+	return nil, lastErr
+}
+
+func (s *EurekaRegistryService) get(rawURL string) (*eurekaApplications, error) {
+	req, err := http.NewRequest(http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	// the user info of the service url is used as the basic auth by the http client if no username is configured
+	if s.eurekaConfig.Username != "" {
+		req.SetBasicAuth(s.eurekaConfig.Username, s.eurekaConfig.Password)
+	}
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code %d from %s", resp.StatusCode, req.URL.Redacted())
+	}
+	result := &eurekaApplications{}
+	if err = json.NewDecoder(resp.Body).Decode(result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func putEurekaInstance(apps map[string]map[string]eurekaInstance, app string, instance eurekaInstance) {
+	name := strings.ToUpper(app)
+	if apps[name] == nil {
+		apps[name] = make(map[string]eurekaInstance)
+	}
+	apps[name][instance.id()] = instance
+}
+
+// eurekaHashCode computes the reconcile hash code like eureka, it is the counts of the instances
+// by status in the order of status, e.g. DOWN_1_UP_2_
+func eurekaHashCode(apps map[string]map[string]eurekaInstance) string {
+	counts := make(map[string]int)
+	for _, instances := range apps {
+		for _, instance := range instances {
+			counts[instance.Status]++
+		}
+	}
+	statuses := make([]string, 0, len(counts))
+	for status := range counts {
+		statuses = append(statuses, status)
+	}
+	sort.Strings(statuses)
+	var builder strings.Builder
+	for _, status := range statuses {
+		builder.WriteString(status + "_" + strconv.Itoa(counts[status]) + "_")
+	}
+	return builder.String()
+}
+
+func (i eurekaInstance) id() string {
+	if i.InstanceID != "" {
+		return i.InstanceID
+	}
+	return i.IPAddr + ":" + strconv.Itoa(i.Port.Port)
+}
+
+func (i eurekaInstance) instance() *ServiceInstance {
+	metadata := make(map[string]string, len(i.Metadata))
+	for k, v := range i.Metadata {
+		if k != eurekaMetadataClass {
+			metadata[k] = v
+		}
+	}
+	return &ServiceInstance{
+		Addr:     i.IPAddr,
+		Port:     i.Port.Port,
+		Metadata: metadata,
+	}
 }
@@ -0,0 +1,273 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package discovery
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeEurekaServer serves the full registry and the delta apis of eureka
+type fakeEurekaServer struct {
+	*httptest.Server
+	mu       sync.Mutex
+	apps     []eurekaApplication
+	delta    []eurekaApplication
+	hashCode string
+	requests map[string]int
+}
+
+func newFakeEurekaServer() *fakeEurekaServer {
+	s := &fakeEurekaServer{
+		apps: []eurekaApplication{
+			{Name: "SEATA-SERVER", Instances: []eurekaInstance{
+				newEurekaInstance("tc-1", "127.0.0.1", 8091, eurekaStatusUp, ""),
+				newEurekaInstance("tc-2", "127.0.0.1", 8092, "DOWN", ""),
+			}},
+			{Name: "ORDER-SERVICE", Instances: []eurekaInstance{
+				newEurekaInstance("order-1", "127.0.0.1", 8080, eurekaStatusUp, ""),
+			}},
+		},
+		requests: map[string]int{},
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	return s
+}
+
+func newEurekaInstance(id, ip string, port int, status, action string) eurekaInstance {
+	return eurekaInstance{
+		InstanceID: id,
+		IPAddr:     ip,
+		Status:     status,
+		Port:       eurekaPort{Port: port},
+		Metadata:   map[string]string{eurekaMetadataClass: "java.util.Collections$EmptyMap", MetadataZone: "zone-a"},
+		ActionType: action,
+	}
+}
+
+func (s *fakeEurekaServer) serve(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests[r.URL.Path]++
+	if username, password, ok := r.BasicAuth(); !ok || username != "eureka" || password != "secret" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	if r.Header.Get("Accept") != "application/json" {
+		w.WriteHeader(http.StatusNotAcceptable)
+		return
+	}
+	apps := s.apps
+	if r.URL.Path == "/eureka/apps/delta" {
+		apps = s.delta
+	} else if r.URL.Path != "/eureka/apps" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	result := map[string]interface{}{
+		"applications": map[string]interface{}{
+			"versions__delta": "1",
+			"apps__hashcode":  s.hashCode,
+			"application":     s.encode(apps),
+		},
+	}
+	_ = json.NewEncoder(w).Encode(result)
+}
+
+// encode writes the ports in form of {"$": 8091, "@enabled": "true"} like eureka
+func (s *fakeEurekaServer) encode(apps []eurekaApplication) []interface{} {
+	result := make([]interface{}, 0, len(apps))
+	for _, app := range apps {
+		instances := make([]interface{}, 0, len(app.Instances))
+		for _, instance := range app.Instances {
+			instances = append(instances, map[string]interface{}{
+				"instanceId": instance.InstanceID,
+				"app":        app.Name,
+				"ipAddr":     instance.IPAddr,
+				"status":     instance.Status,
+				"port":       map[string]interface{}{"$": instance.Port.Port, "@enabled": "true"},
+				"metadata":   instance.Metadata,
+				"actionType": instance.ActionType,
+			})
+		}
+		result = append(result, map[string]interface{}{"name": app.Name, "instance": instances})
+	}
+	return result
+}
+
+func (s *fakeEurekaServer) requestCount(path string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests[path]
+}
+
+func newTestEurekaRegistryService(t *testing.T, eurekaConfig *EurekaConfig) *EurekaRegistryService {
+	s, err := newEurekaRegistryService(&ServiceConfig{
+		VgroupMapping: map[string]string{"default_tx_group": "default"},
+	}, eurekaConfig)
+	require.NoError(t, err)
+	return s.(*EurekaRegistryService)
+}
+
+func TestEurekaPort_UnmarshalJSON(t *testing.T) {
+	for _, data := range []string{`{"$":8091,"@enabled":"true"}`, `{"$":"8091"}`} {
+		port := eurekaPort{}
+		require.NoError(t, json.Unmarshal([]byte(data), &port))
+		assert.Equal(t, 8091, port.Port)
+	}
+	port := eurekaPort{}
+	assert.Error(t, json.Unmarshal([]byte(`{"$":"port"}`), &port))
+}
+
+func TestEurekaHashCode(t *testing.T) {
+	apps := map[string]map[string]eurekaInstance{
+		"A": {"1": {Status: "UP"}, "2": {Status: "DOWN"}},
+		"B": {"3": {Status: "UP"}},
+	}
+	assert.Equal(t, "DOWN_1_UP_2_", eurekaHashCode(apps))
+	assert.Equal(t, "", eurekaHashCode(nil))
+}
+
+func TestEurekaRegistryService_Lookup(t *testing.T) {
+	server := newFakeEurekaServer()
+	defer server.Close()
+
+	// the first service url is unavailable
+	s := newTestEurekaRegistryService(t, &EurekaConfig{
+		ServiceURL:  "http://127.0.0.1:1/eureka," + server.URL + "/eureka/",
+		Application: "seata-server",
+		Username:    "eureka",
+		Password:    "secret",
+	})
+	defer s.Close()
+
+	instances, err := s.Lookup("default_tx_group")
+	require.NoError(t, err)
+	require.Len(t, instances, 1)
+	assert.Equal(t, "127.0.0.1", instances[0].Addr)
+	assert.Equal(t, 8091, instances[0].Port)
+	assert.Equal(t, map[string]string{MetadataZone: "zone-a"}, instances[0].Metadata)
+
+	_, err = s.Lookup("default_tx_group")
+	require.NoError(t, err)
+	assert.Equal(t, 1, server.requestCount("/eureka/apps"))
+
+	_, err = s.Lookup("unknown_group")
+	assert.Error(t, err)
+}
+
+func TestEurekaRegistryService_LookupAuth(t *testing.T) {
+	server := newFakeEurekaServer()
+	defer server.Close()
+
+	s := newTestEurekaRegistryService(t, &EurekaConfig{ServiceURL: server.URL + "/eureka"})
+	defer s.Close()
+	_, err := s.Lookup("default_tx_group")
+	assert.ErrorContains(t, err, "401")
+
+	// the user info of the service url is used as the basic auth
+	s = newTestEurekaRegistryService(t, &EurekaConfig{
+		ServiceURL: strings.Replace(server.URL, "http://", "http://eureka:secret@", 1) + "/eureka",
+	})
+	defer s.Close()
+	// the cluster is used as the application name, no instance of application DEFAULT
+	instances, err := s.Lookup("default_tx_group")
+	require.NoError(t, err)
+	assert.Empty(t, instances)
+}
+
+func TestEurekaRegistryService_Delta(t *testing.T) {
+	server := newFakeEurekaServer()
+	defer server.Close()
+
+	s := newTestEurekaRegistryService(t, &EurekaConfig{
+		ServiceURL:      server.URL + "/eureka",
+		Application:     "seata-server",
+		Username:        "eureka",
+		Password:        "secret",
+		RefreshInterval: 20 * time.Millisecond,
+	})
+	defer s.Close()
+
+	instances, err := s.Lookup("default_tx_group")
+	require.NoError(t, err)
+	require.Len(t, instances, 1)
+
+	lookupPorts := func() []int {
+		instances, err := s.Lookup("default_tx_group")
+		require.NoError(t, err)
+		ports := make([]int, 0, len(instances))
+		for _, instance := range instances {
+			ports = append(ports, instance.Port)
+		}
+		return ports
+	}
+
+	// tc-2 is up, tc-3 is added, and the order service is deleted
+	server.mu.Lock()
+	server.delta = []eurekaApplication{
+		{Name: "SEATA-SERVER", Instances: []eurekaInstance{
+			newEurekaInstance("tc-2", "127.0.0.1", 8092, eurekaStatusUp, eurekaActionModified),
+			newEurekaInstance("tc-3", "127.0.0.1", 8093, eurekaStatusUp, eurekaActionAdded),
+		}},
+		{Name: "ORDER-SERVICE", Instances: []eurekaInstance{
+			newEurekaInstance("order-1", "127.0.0.1", 8080, eurekaStatusUp, eurekaActionDeleted),
+		}},
+	}
+	server.hashCode = "UP_3_"
+	server.mu.Unlock()
+	assert.Eventually(t, func() bool {
+		return assert.ObjectsAreEqual([]int{8091, 8092, 8093}, lookupPorts())
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, 1, server.requestCount("/eureka/apps"))
+
+	// the full registry is fetched once the hash code mismatches
+	server.mu.Lock()
+	server.delta = nil
+	server.hashCode = "UP_1_"
+	server.apps = []eurekaApplication{
+		{Name: "SEATA-SERVER", Instances: []eurekaInstance{
+			newEurekaInstance("tc-3", "127.0.0.1", 8093, eurekaStatusUp, ""),
+		}},
+	}
+	server.mu.Unlock()
+	assert.Eventually(t, func() bool {
+		return assert.ObjectsAreEqual([]int{8093}, lookupPorts())
+	}, time.Second, 10*time.Millisecond)
+	assert.Greater(t, server.requestCount("/eureka/apps"), 1)
+}
+
+func TestNewEurekaRegistryService(t *testing.T) {
+	_, err := newEurekaRegistryService(&ServiceConfig{}, &EurekaConfig{ServiceURL: " , "})
+	assert.Error(t, err)
+	_, err = newEurekaRegistryService(nil, nil)
+	assert.Error(t, err)
+
+	s, err := newEurekaRegistryService(&ServiceConfig{}, &EurekaConfig{ServiceURL: "127.0.0.1:8761/eureka/"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"http://127.0.0.1:8761/eureka"}, s.(*EurekaRegistryService).serviceURLs)
+	s.Close()
+}
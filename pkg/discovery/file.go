@@ -18,9 +18,17 @@
 package discovery
 
 import (
+	"bufio"
+	"bytes"
 	"fmt"
+	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v2"
 
 	"seata.apache.org/seata-go/pkg/util/log"
 )
@@ -28,44 +36,93 @@ import (
 const (
 	endPointSplitChar = ";"
 	ipPortSplitChar   = ":"
+
+	fileServicePrefix          = "service."
+	fileVgroupMappingKey       = "vgroupMapping."
+	fileVgroupMappingDashKey   = "vgroup-mapping."
+	fileGrouplistKey           = "grouplist"
+	defaultFileRefreshInterval = time.Second
 )
 
+// FileRegistryService looks up the tc instances from the vgroup mapping and the grouplist. They are
+// read from the registry file if it exists, and fall back to the service config otherwise. The
+// registry file is checked for changes periodically, the changes are notified to the subscribers,
+// so that moving a tc to a new host only needs editing the registry file.
 type FileRegistryService struct {
 	serviceConfig *ServiceConfig
+	subscriptions *subscriptions
+	fileName      string
+	// content the content of the registry file loaded last time, nil if the file does not exist
+	content []byte
+	// invalid the content of the registry file failed to parse last time, it is not parsed and
+	// warned again until the file is modified
+	invalid []byte
+	// the vgroup mapping and the grouplist in the registry file
+	vgroupMapping map[string]string
+	grouplist     map[string]string
+	rwLock        sync.RWMutex
+
+	stopCh    chan struct{}
+	closeOnce sync.Once
+	wg        sync.WaitGroup
 }
 
-func newFileRegistryService(config *ServiceConfig) RegistryService {
+func newFileRegistryService(config *ServiceConfig, fileConfig *FileConfig) RegistryService {
 	if config == nil {
 		log.Fatalf("service config is nil")
 		panic("service config is nil")
 	}
-	return &FileRegistryService{
+	s := &FileRegistryService{
 		serviceConfig: config,
+		subscriptions: newSubscriptions(),
+		stopCh:        make(chan struct{}),
+	}
+	if fileConfig == nil || fileConfig.Name == "" {
+		return s
+	}
+	s.fileName = fileConfig.Name
+	if _, err := s.reload(); err != nil {
+		log.Errorf("load registry file %s failed, err: %v", s.fileName, err)
+	}
+	interval := fileConfig.RefreshInterval
+	if interval <= 0 {
+		interval = defaultFileRefreshInterval
 	}
+	s.wg.Add(1)
+	go s.watch(interval)
+	return s
 }
 
 func (s *FileRegistryService) Lookup(key string) ([]*ServiceInstance, error) {
-	var group string
-	if v, ok := s.serviceConfig.VgroupMapping[key]; ok {
-		group = v
+	s.rwLock.RLock()
+	group := s.vgroupMapping[key]
+	s.rwLock.RUnlock()
+	if group == "" {
+		group = s.serviceConfig.VgroupMapping[key]
 	}
 	if group == "" {
 		log.Errorf("vgroup is empty. key: %s", key)
 		return nil, fmt.Errorf("vgroup is empty. key: %s", key)
 	}
 
-	var addrStr string
-	if v, ok := s.serviceConfig.Grouplist[group]; ok {
-		addrStr = v
+	s.rwLock.RLock()
+	addrStr := s.grouplist[group]
+	s.rwLock.RUnlock()
+	if addrStr == "" {
+		addrStr = s.serviceConfig.Grouplist[group]
 	}
 	if addrStr == "" {
-		log.Errorf("endpoint is empty. key: %s group: %s", group)
+		log.Errorf("endpoint is empty. key: %s group: %s", key, group)
 		return nil, fmt.Errorf("endpoint is empty. key: %s group: %s", key, group)
 	}
 
 	addrs := strings.Split(addrStr, endPointSplitChar)
 	instances := make([]*ServiceInstance, 0)
 	for _, addr := range addrs {
+		addr, metadata, err := splitMetadata(addr)
+		if err != nil {
+			return nil, err
+		}
 		ipPort := strings.Split(addr, ipPortSplitChar)
 		if len(ipPort) != 2 {
 			return nil, fmt.Errorf("endpoint format should like ip:port. endpoint: %s", addr)
@@ -76,13 +133,161 @@ func (s *FileRegistryService) Lookup(key string) ([]*ServiceInstance, error) {
 			return nil, err
 		}
 		instances = append(instances, &ServiceInstance{
-			Addr: ip,
-			Port: port,
+			Addr:     ip,
+			Port:     port,
+			Metadata: metadata,
 		})
 	}
 	return instances, nil
 }
 
+// Subscribe notifies the listener of the instances in the grouplist, and the changes of them
+// once the registry file is modified
+func (s *FileRegistryService) Subscribe(key string, listener Listener) error {
+	instances, err := s.Lookup(key)
+	if err != nil {
+		return err
+	}
+	return s.subscriptions.subscribe(key, listener, instances)
+}
+
+func (s *FileRegistryService) Unsubscribe(key string, listener Listener) error {
+	_, err := s.subscriptions.unsubscribe(key, listener)
+	return err
+}
+
 func (s *FileRegistryService) Close() {
+	s.closeOnce.Do(func() {
+		close(s.stopCh)
+	})
+	s.wg.Wait()
+}
 
+// watch reloads the registry file every interval, and notifies the subscribers of the changes
+func (s *FileRegistryService) watch(interval time.Duration) {
+	defer s.wg.Done()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.stopCh:
+			return
+		case <-ticker.C:
+			changed, err := s.reload()
+			if err != nil {
+				log.Warnf("reload registry file %s failed, keep the last loaded, err: %v", s.fileName, err)
+				continue
+			}
+			if changed {
+				s.notify()
+			}
+		}
+	}
+}
+
+// reload loads the registry file if its content is changed, the file missing is not an error,
+// the service config is used in that case.
+func (s *FileRegistryService) reload() (bool, error) {
+	content, err := os.ReadFile(s.fileName)
+	if err != nil && !os.IsNotExist(err) {
+		return false, err
+	}
+	s.rwLock.RLock()
+	unchanged := (content == nil) == (s.content == nil) && bytes.Equal(content, s.content) ||
+		content != nil && bytes.Equal(content, s.invalid)
+	s.rwLock.RUnlock()
+	if unchanged {
+		return false, nil
+	}
+
+	var vgroupMapping, grouplist map[string]string
+	if content == nil {
+		log.Infof("registry file %s does not exist, use the service config", s.fileName)
+	} else if vgroupMapping, grouplist, err = parseRegistryFile(s.fileName, content); err != nil {
+		s.rwLock.Lock()
+		s.invalid = content
+		s.rwLock.Unlock()
+		return false, err
+	} else {
+		log.Infof("registry file %s is loaded, vgroup mapping: %v, grouplist: %v", s.fileName, vgroupMapping, grouplist)
+	}
+
+	s.rwLock.Lock()
+	defer s.rwLock.Unlock()
+	s.content = content
+	s.invalid = nil
+	s.vgroupMapping = vgroupMapping
+	s.grouplist = grouplist
+	return true, nil
+}
+
+func (s *FileRegistryService) notify() {
+	for _, key := range s.subscriptions.keys() {
+		instances, err := s.Lookup(key)
+		if err != nil {
+			log.Warnf("look up the instances of key %s from registry file %s failed, err: %v", key, s.fileName, err)
+			continue
+		}
+		s.subscriptions.update(key, instances)
+	}
+}
+
+// parseRegistryFile parses the registry file, the yaml file is in the format of the service config:
+//
+//	vgroup-mapping:
+//	  default_tx_group: default
+//	grouplist:
+//	  default: 127.0.0.1:8091;127.0.0.1:8092
+//
+// the other files are parsed as the properties in the format of seata java:
+//
+//	service.vgroupMapping.default_tx_group=default
+//	service.default.grouplist=127.0.0.1:8091;127.0.0.1:8092
+func parseRegistryFile(name string, content []byte) (map[string]string, map[string]string, error) {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".yml", ".yaml":
+		config := ServiceConfig{}
+		if err := yaml.Unmarshal(content, &config); err != nil {
+			return nil, nil, err
+		}
+		return config.VgroupMapping, config.Grouplist, nil
+	default:
+		return parseRegistryProperties(content)
+	}
+}
+
+func parseRegistryProperties(content []byte) (map[string]string, map[string]string, error) {
+	vgroupMapping := make(map[string]string)
+	grouplist := make(map[string]string)
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "!") {
+			continue
+		}
+		// the addresses contain ':', so it is the separator only if there is no '='
+		idx := strings.Index(line, "=")
+		if idx < 0 {
+			idx = strings.Index(line, ":")
+		}
+		if idx <= 0 {
+			return nil, nil, fmt.Errorf("invalid property at line %d: %s", lineNo, line)
+		}
+		key := strings.TrimPrefix(strings.TrimSpace(line[:idx]), fileServicePrefix)
+		value := strings.TrimSpace(line[idx+1:])
+		switch {
+		case strings.HasPrefix(key, fileVgroupMappingKey):
+			vgroupMapping[strings.TrimPrefix(key, fileVgroupMappingKey)] = value
+		case strings.HasPrefix(key, fileVgroupMappingDashKey):
+			vgroupMapping[strings.TrimPrefix(key, fileVgroupMappingDashKey)] = value
+		case strings.HasSuffix(key, "."+fileGrouplistKey):
+			grouplist[strings.TrimSuffix(key, "."+fileGrouplistKey)] = value
+		case strings.HasPrefix(key, fileGrouplistKey+"."):
+			grouplist[strings.TrimPrefix(key, fileGrouplistKey+".")] = value
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, err
+	}
+	return vgroupMapping, grouplist, nil
 }
@@ -18,8 +18,14 @@
 package discovery
 
 import (
+	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestFileRegistryService_Lookup(t *testing.T) {
@@ -87,6 +93,35 @@ func TestFileRegistryService_Lookup(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name: "endpoints with metadata.",
+			args: args{
+				key: "default_tx_group",
+			},
+			fields: fields{
+				serviceConfig: &ServiceConfig{
+					VgroupMapping: map[string]string{
+						"default_tx_group": "default",
+					},
+					Grouplist: map[string]string{
+						"default": "127.0.0.1:8091?weight=10&zone=az1;192.168.0.1:8092?healthy=false",
+					},
+				},
+			},
+			want: []*ServiceInstance{
+				{
+					Addr:     "127.0.0.1",
+					Port:     8091,
+					Metadata: map[string]string{MetadataWeight: "10", MetadataZone: "az1"},
+				},
+				{
+					Addr:     "192.168.0.1",
+					Port:     8092,
+					Metadata: map[string]string{MetadataHealthy: "false"},
+				},
+			},
+			wantErr: false,
+		},
 		{
 			name: "vgroup is empty.",
 			args: args{
@@ -157,6 +192,25 @@ func TestFileRegistryService_Lookup(t *testing.T) {
 			wantErr:    true,
 			wantErrMsg: "strconv.Atoi: parsing \"abc\": invalid syntax",
 		},
+		{
+			name: "metadata is invalid",
+			args: args{
+				key: "default_tx_group",
+			},
+			fields: fields{
+				serviceConfig: &ServiceConfig{
+					VgroupMapping: map[string]string{
+						"default_tx_group": "default",
+					},
+					Grouplist: map[string]string{
+						"default": "127.0.0.1:8091?weight=%zz",
+					},
+				},
+			},
+			want:       nil,
+			wantErr:    true,
+			wantErrMsg: "invalid metadata of address 127.0.0.1:8091?weight=%zz: invalid URL escape \"%zz\"",
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -176,3 +230,83 @@ func TestFileRegistryService_Lookup(t *testing.T) {
 		})
 	}
 }
+
+func TestFileRegistryService_Subscribe(t *testing.T) {
+	s := newFileRegistryService(&ServiceConfig{
+		VgroupMapping: map[string]string{
+			"default_tx_group": "default",
+		},
+		Grouplist: map[string]string{
+			"default": "127.0.0.1:8091;192.168.0.1:8092",
+		},
+	}, &FileConfig{})
+	defer s.Close()
+
+	listener := &recordListener{}
+	require.NoError(t, s.Subscribe("default_tx_group", listener))
+	assert.Equal(t, []string{"ADD default_tx_group 127.0.0.1:8091", "ADD default_tx_group 192.168.0.1:8092"}, listener.take())
+	assert.Error(t, s.Subscribe("unknown_group", listener))
+
+	require.NoError(t, s.Unsubscribe("default_tx_group", listener))
+	assert.Error(t, s.Unsubscribe("default_tx_group", listener))
+}
+
+func TestFileRegistryService_Watch(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "registry.yml")
+	require.NoError(t, os.WriteFile(name, []byte("vgroup-mapping:\n  default_tx_group: default\ngrouplist:\n  default: 127.0.0.1:8091\n"), 0o644))
+	s := newFileRegistryService(&ServiceConfig{
+		VgroupMapping: map[string]string{"default_tx_group": "default", "other_tx_group": "other"},
+		Grouplist:     map[string]string{"default": "127.0.0.1:8093", "other": "127.0.0.1:8094"},
+	}, &FileConfig{Name: name, RefreshInterval: 10 * time.Millisecond})
+	defer s.Close()
+
+	listener := &recordListener{}
+	require.NoError(t, s.Subscribe("default_tx_group", listener))
+	assert.Equal(t, []string{"ADD default_tx_group 127.0.0.1:8091"}, listener.take())
+	// the keys absent from the registry file fall back to the service config
+	instances, err := s.Lookup("other_tx_group")
+	require.NoError(t, err)
+	assert.Equal(t, []*ServiceInstance{{Addr: "127.0.0.1", Port: 8094}}, instances)
+
+	require.NoError(t, os.WriteFile(name, []byte("grouplist:\n  default: 127.0.0.1:8092?weight=2\n"), 0o644))
+	assert.Eventually(t, func() bool { return listener.count() == 2 }, time.Second, 10*time.Millisecond)
+	assert.Equal(t, []string{"ADD default_tx_group 127.0.0.1:8092", "REMOVE default_tx_group 127.0.0.1:8091"}, listener.take())
+
+	// the last loaded is kept if the registry file is invalid
+	require.NoError(t, os.WriteFile(name, []byte("grouplist: ["), 0o644))
+	time.Sleep(50 * time.Millisecond)
+	instances, err = s.Lookup("default_tx_group")
+	require.NoError(t, err)
+	assert.Equal(t, []*ServiceInstance{{Addr: "127.0.0.1", Port: 8092, Metadata: map[string]string{MetadataWeight: "2"}}}, instances)
+	// and the invalid content is not parsed again until the file is modified
+	changed, err := s.(*FileRegistryService).reload()
+	assert.NoError(t, err)
+	assert.False(t, changed)
+
+	require.NoError(t, os.Remove(name))
+	assert.Eventually(t, func() bool { return listener.count() == 2 }, time.Second, 10*time.Millisecond)
+	assert.Equal(t, []string{"ADD default_tx_group 127.0.0.1:8093", "REMOVE default_tx_group 127.0.0.1:8092"}, listener.take())
+}
+
+func TestParseRegistryFile(t *testing.T) {
+	vgroupMapping, grouplist, err := parseRegistryFile("registry.conf", []byte(`
+# seata java style
+service.vgroupMapping.default_tx_group = default
+service.default.grouplist=127.0.0.1:8091;127.0.0.1:8092
+! the flattened style
+vgroup-mapping.other_tx_group: other
+grouplist.other: 127.0.0.1:8093
+service.enableDegrade=false
+`))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"default_tx_group": "default", "other_tx_group": "other"}, vgroupMapping)
+	assert.Equal(t, map[string]string{"default": "127.0.0.1:8091;127.0.0.1:8092", "other": "127.0.0.1:8093"}, grouplist)
+
+	_, _, err = parseRegistryFile("registry.properties", []byte("service.default.grouplist\n"))
+	assert.EqualError(t, err, "invalid property at line 1: service.default.grouplist")
+
+	vgroupMapping, grouplist, err = parseRegistryFile("registry.yaml", []byte("vgroup-mapping:\n  default_tx_group: default\n"))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"default_tx_group": "default"}, vgroupMapping)
+	assert.Empty(t, grouplist)
+}
@@ -26,25 +26,49 @@ var (
 )
 
 func InitRegistry(serviceConfig *ServiceConfig, registryConfig *RegistryConfig) {
+	registryService, err := NewRegistryService(serviceConfig, registryConfig)
+	if err != nil {
+		panic(err)
+	}
+	registryServiceInstance = registryService
+}
+
+// NewRegistryService creates the registry service of the registry type, it returns the error
+// instead of panicking if the registry fails to init
+func NewRegistryService(serviceConfig *ServiceConfig, registryConfig *RegistryConfig) (RegistryService, error) {
 	var registryService RegistryService
 	var err error
 	switch registryConfig.Type {
 	case FILE:
 		//init file registry
-		registryService = newFileRegistryService(serviceConfig)
+		registryService = newFileRegistryService(serviceConfig, &registryConfig.File)
 	case ETCD:
 		//init etcd registry
 		registryService = newEtcdRegistryService(serviceConfig, &registryConfig.Etcd3)
+	case RAFT:
+		//init raft registry
+		registryService, err = newRaftRegistryService(serviceConfig, &registryConfig.Raft)
 	case NACOS:
-		//TODO: init nacos registry
+		//init nacos registry
+		registryService, err = newNacosRegistryService(serviceConfig, &registryConfig.Nacos)
 	case EUREKA:
-		//TODO: init eureka registry
+		//init eureka registry
+		registryService, err = newEurekaRegistryService(serviceConfig, &registryConfig.Eureka)
 	case REDIS:
-		//TODO: init redis registry
+		//init redis registry
+		registryService, err = newRedisRegistryService(serviceConfig, &registryConfig.Redis)
 	case ZK:
-		//TODO: init zk registry
+		//init zk registry
+		registryService, err = newZkRegistryService(serviceConfig, &registryConfig.Zk)
 	case CONSUL:
-		//TODO: init consul registry
+		//init consul registry
+		registryService, err = newConsulRegistryService(serviceConfig, &registryConfig.Consul)
+	case DNS:
+		//init dns registry
+		registryService, err = newDnsRegistryService(serviceConfig, &registryConfig.Dns)
+	case KUBERNETES:
+		//init kubernetes registry
+		registryService, err = newKubernetesRegistryService(serviceConfig, &registryConfig.Kubernetes)
 	case SOFA:
 		//TODO: init sofa registry
 	default:
@@ -52,8 +76,13 @@ func InitRegistry(serviceConfig *ServiceConfig, registryConfig *RegistryConfig)
 	}
 
 	if err != nil {
-		panic(fmt.Errorf("init service registry err:%v", err))
+		return nil, fmt.Errorf("init service registry err:%v", err)
 	}
+	return registryService, nil
+}
+
+// SetRegistry sets the registry service returned by GetRegistry
+func SetRegistry(registryService RegistryService) {
 	registryServiceInstance = registryService
 }
 
@@ -20,6 +20,8 @@ package discovery
 import (
 	"reflect"
 	"testing"
+
+	"github.com/stretchr/testify/assert"
 )
 
 func TestInitRegistry(t *testing.T) {
@@ -62,6 +64,107 @@ func TestInitRegistry(t *testing.T) {
 			hasPanic:     false,
 			expectedType: "EtcdRegistryService",
 		},
+		{
+			name: "nacos",
+			args: args{
+				serviceConfig: &ServiceConfig{
+					VgroupMapping: map[string]string{
+						"default_tx_group": "default",
+					},
+				},
+				registryConfig: &RegistryConfig{
+					Type: NACOS,
+					Nacos: NacosConfig{
+						ServerAddr:  "127.0.0.1:8848",
+						Application: "seata-server",
+					},
+				},
+			},
+			expectedType: "NacosRegistryService",
+		},
+		{
+			name: "nacos without server addr",
+			args: args{
+				serviceConfig: &ServiceConfig{},
+				registryConfig: &RegistryConfig{
+					Type: NACOS,
+				},
+			},
+			hasPanic: true,
+		},
+		{
+			name: "consul",
+			args: args{
+				serviceConfig: &ServiceConfig{},
+				registryConfig: &RegistryConfig{
+					Type:   CONSUL,
+					Consul: ConsulConfig{ServerAddr: "127.0.0.1:8500"},
+				},
+			},
+			expectedType: "ConsulRegistryService",
+		},
+		{
+			name: "zk without server addr",
+			args: args{
+				serviceConfig: &ServiceConfig{},
+				registryConfig: &RegistryConfig{
+					Type: ZK,
+				},
+			},
+			hasPanic: true,
+		},
+		{
+			name: "redis",
+			args: args{
+				serviceConfig: &ServiceConfig{},
+				registryConfig: &RegistryConfig{
+					Type:  REDIS,
+					Redis: RedisConfig{ServerAddr: "127.0.0.1:6379"},
+				},
+			},
+			expectedType: "RedisRegistryService",
+		},
+		{
+			name: "eureka",
+			args: args{
+				serviceConfig: &ServiceConfig{},
+				registryConfig: &RegistryConfig{
+					Type:   EUREKA,
+					Eureka: EurekaConfig{ServiceURL: "http://127.0.0.1:8761/eureka"},
+				},
+			},
+			expectedType: "EurekaRegistryService",
+		},
+		{
+			name: "dns",
+			args: args{
+				serviceConfig: &ServiceConfig{},
+				registryConfig: &RegistryConfig{
+					Type: DNS,
+					Dns: DnsConfig{
+						Names:  map[string]string{"default": "seata-server"},
+						Server: "127.0.0.1:53",
+					},
+				},
+			},
+			expectedType: "DnsRegistryService",
+		},
+		{
+			name: "kubernetes without in-cluster config",
+			args: args{
+				serviceConfig:  &ServiceConfig{},
+				registryConfig: &RegistryConfig{Type: KUBERNETES},
+			},
+			hasPanic: true,
+		},
+		{
+			name: "dns without names",
+			args: args{
+				serviceConfig:  &ServiceConfig{},
+				registryConfig: &RegistryConfig{Type: DNS},
+			},
+			hasPanic: true,
+		},
 		{
 			name: "unknown type",
 			args: args{
@@ -95,3 +198,14 @@ func TestInitRegistry(t *testing.T) {
 		})
 	}
 }
+
+func TestNewRegistryService(t *testing.T) {
+	registryService, err := NewRegistryService(&ServiceConfig{}, &RegistryConfig{Type: "unknown"})
+	assert.Nil(t, registryService)
+	assert.EqualError(t, err, "init service registry err:service registry not support registry type:unknown")
+
+	registryService, err = NewRegistryService(&ServiceConfig{}, &RegistryConfig{Type: FILE})
+	assert.NoError(t, err)
+	assert.IsType(t, &FileRegistryService{}, registryService)
+	registryService.Close()
+}
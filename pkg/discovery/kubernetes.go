@@ -0,0 +1,324 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package discovery
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	discoveryv1 "k8s.io/api/discovery/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	listersv1 "k8s.io/client-go/listers/discovery/v1"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/clientcmd"
+
+	"seata.apache.org/seata-go/pkg/util/log"
+)
+
+const (
+	kubernetesNamespaceFile    = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
+	kubernetesDefaultNamespace = "default"
+	kubernetesSyncTimeout      = 10 * time.Second
+)
+
+// kubernetesWatcher watches the endpoint slices of a service by an informer
+type kubernetesWatcher struct {
+	factory  informers.SharedInformerFactory
+	lister   listersv1.EndpointSliceNamespaceLister
+	selector labels.Selector
+	service  string
+	stopCh   chan struct{}
+}
+
+// KubernetesRegistryService discovers the tc instances by the endpoint slices of the tc service, the
+// endpoint slices are watched once the cluster is looked up, and the ready endpoints are the instances.
+type KubernetesRegistryService struct {
+	serviceConfig    *ServiceConfig
+	kubernetesConfig KubernetesConfig
+	client           kubernetes.Interface
+
+	// cluster -> instances
+	instances map[string][]*ServiceInstance
+	// cluster -> watcher
+	watchers map[string]*kubernetesWatcher
+	rwLock   sync.RWMutex
+	// watchLock orders starting the watchers and closing
+	watchLock sync.Mutex
+	closed    bool
+
+	subscriptions *subscriptions
+	// notifyLock orders the subscribing and the notifications of the watched changes
+	notifyLock sync.Mutex
+}
+
+func newKubernetesRegistryService(config *ServiceConfig, kubernetesConfig *KubernetesConfig) (RegistryService, error) {
+	if config == nil || kubernetesConfig == nil {
+		return nil, fmt.Errorf("kubernetes registry config is nil")
+	}
+	var (
+		restConfig *rest.Config
+		err        error
+	)
+	if kubernetesConfig.Kubeconfig != "" {
+		restConfig, err = clientcmd.BuildConfigFromFlags("", kubernetesConfig.Kubeconfig)
+	} else {
+		restConfig, err = rest.InClusterConfig()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("load kubernetes client config failed: %w", err)
+	}
+	client, err := kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		return nil, err
+	}
+	return newKubernetesRegistryServiceWithClient(config, kubernetesConfig, client), nil
+}
+
+func newKubernetesRegistryServiceWithClient(config *ServiceConfig, kubernetesConfig *KubernetesConfig, client kubernetes.Interface) *KubernetesRegistryService {
+	s := &KubernetesRegistryService{
+		serviceConfig:    config,
+		kubernetesConfig: *kubernetesConfig,
+		client:           client,
+		instances:        make(map[string][]*ServiceInstance),
+		watchers:         make(map[string]*kubernetesWatcher),
+		subscriptions:    newSubscriptions(),
+	}
+	if s.kubernetesConfig.Namespace == "" {
+		s.kubernetesConfig.Namespace = podNamespace()
+	}
+	if s.kubernetesConfig.SyncTimeout <= 0 {
+		s.kubernetesConfig.SyncTimeout = kubernetesSyncTimeout
+	}
+	return s
+}
+
+// podNamespace returns the namespace of the pod running in, the default namespace otherwise
+func podNamespace() string {
+	if data, err := os.ReadFile(kubernetesNamespaceFile); err == nil {
+		if namespace := strings.TrimSpace(string(data)); namespace != "" {
+			return namespace
+		}
+	}
+	return kubernetesDefaultNamespace
+}
+
+func (s *KubernetesRegistryService) Lookup(key string) ([]*ServiceInstance, error) {
+	cluster := s.serviceConfig.VgroupMapping[key]
+	if cluster == "" {
+		return nil, fmt.Errorf("vgroup is empty. key: %s", key)
+	}
+	s.rwLock.RLock()
+	instances, ok := s.instances[cluster]
+	s.rwLock.RUnlock()
+	if ok {
+		return instances, nil
+	}
+	return s.watch(cluster)
+}
+
+func (s *KubernetesRegistryService) Subscribe(key string, listener Listener) error {
+	s.notifyLock.Lock()
+	defer s.notifyLock.Unlock()
+	instances, err := s.Lookup(key)
+	if err != nil {
+		return err
+	}
+	return s.subscriptions.subscribe(key, listener, instances)
+}
+
+func (s *KubernetesRegistryService) Unsubscribe(key string, listener Listener) error {
+	_, err := s.subscriptions.unsubscribe(key, listener)
+	return err
+}
+
+func (s *KubernetesRegistryService) Close() {
+	s.watchLock.Lock()
+	defer s.watchLock.Unlock()
+	if s.closed {
+		return
+	}
+	s.closed = true
+	s.rwLock.RLock()
+	watchers := make([]*kubernetesWatcher, 0, len(s.watchers))
+	for _, watcher := range s.watchers {
+		watchers = append(watchers, watcher)
+	}
+	s.rwLock.RUnlock()
+	// the event handlers never wait for watchLock, so they exit once stopped
+	for _, watcher := range watchers {
+		close(watcher.stopCh)
+		watcher.factory.Shutdown()
+	}
+}
+
+// watch starts the informer of the endpoint slices of the service of the cluster, and waits for
+// the endpoint slices listed
+func (s *KubernetesRegistryService) watch(cluster string) ([]*ServiceInstance, error) {
+	s.watchLock.Lock()
+	defer s.watchLock.Unlock()
+	if s.closed {
+		return nil, fmt.Errorf("kubernetes registry is closed")
+	}
+	s.rwLock.RLock()
+	_, ok := s.watchers[cluster]
+	instances := s.instances[cluster]
+	s.rwLock.RUnlock()
+	if ok {
+		// watched by a concurrent lookup already
+		return instances, nil
+	}
+
+	service := s.kubernetesConfig.Service
+	if service == "" {
+		service = cluster
+	}
+	selector := labels.SelectorFromSet(labels.Set{discoveryv1.LabelServiceName: service})
+	factory := informers.NewSharedInformerFactoryWithOptions(s.client, 0,
+		informers.WithNamespace(s.kubernetesConfig.Namespace),
+		informers.WithTweakListOptions(func(options *metav1.ListOptions) {
+			options.LabelSelector = selector.String()
+		}))
+	slices := factory.Discovery().V1().EndpointSlices()
+	informer := slices.Informer()
+	onChange := func(interface{}) {
+		// the instances are refreshed once all the endpoint slices are listed
+		if informer.HasSynced() {
+			s.refresh(cluster)
+		}
+	}
+	if _, err := informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    onChange,
+		UpdateFunc: func(_, obj interface{}) { onChange(obj) },
+		DeleteFunc: onChange,
+	}); err != nil {
+		return nil, err
+	}
+	watcher := &kubernetesWatcher{
+		factory:  factory,
+		lister:   slices.Lister().EndpointSlices(s.kubernetesConfig.Namespace),
+		selector: selector,
+		service:  service,
+		stopCh:   make(chan struct{}),
+	}
+	factory.Start(watcher.stopCh)
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.kubernetesConfig.SyncTimeout)
+	defer cancel()
+	if !cache.WaitForCacheSync(ctx.Done(), informer.HasSynced) {
+		close(watcher.stopCh)
+		factory.Shutdown()
+		return nil, fmt.Errorf("list the endpoint slices of service %s/%s timeout", s.kubernetesConfig.Namespace, service)
+	}
+	s.rwLock.Lock()
+	s.watchers[cluster] = watcher
+	s.rwLock.Unlock()
+	return s.refreshLocked(cluster, watcher), nil
+}
+
+// refresh rebuilds the instances of the cluster from the watched endpoint slices, and notifies
+// the subscriptions of the keys mapped to the cluster
+func (s *KubernetesRegistryService) refresh(cluster string) {
+	s.rwLock.RLock()
+	watcher, ok := s.watchers[cluster]
+	s.rwLock.RUnlock()
+	if !ok {
+		// the first lookup refreshes it after the endpoint slices are listed
+		return
+	}
+
+	s.notifyLock.Lock()
+	defer s.notifyLock.Unlock()
+	instances := s.refreshLocked(cluster, watcher)
+	for _, key := range s.subscriptions.keys() {
+		if s.serviceConfig.VgroupMapping[key] == cluster {
+			s.subscriptions.update(key, instances)
+		}
+	}
+}
+
+func (s *KubernetesRegistryService) refreshLocked(cluster string, watcher *kubernetesWatcher) []*ServiceInstance {
+	slices, err := watcher.lister.List(watcher.selector)
+	if err != nil {
+		log.Warnf("list the endpoint slices of service %s failed: %v", watcher.service, err)
+		return nil
+	}
+	instances := s.endpointInstances(slices)
+	s.rwLock.Lock()
+	defer s.rwLock.Unlock()
+	if old, ok := s.instances[cluster]; ok && len(old) != len(instances) {
+		log.Infof("kubernetes instances of cluster %s changed: %d -> %d", cluster, len(old), len(instances))
+	}
+	s.instances[cluster] = instances
+	return instances
+}
+
+// endpointInstances maps the ready endpoints to the instances, the endpoint in several slices
+// during the transition of the slices is kept once
+func (s *KubernetesRegistryService) endpointInstances(slices []*discoveryv1.EndpointSlice) []*ServiceInstance {
+	instances := make([]*ServiceInstance, 0)
+	seen := make(map[string]bool)
+	for _, slice := range slices {
+		if slice.AddressType == discoveryv1.AddressTypeFQDN {
+			continue
+		}
+		port, ok := s.endpointPort(slice)
+		if !ok {
+			continue
+		}
+		for _, endpoint := range slice.Endpoints {
+			if endpoint.Conditions.Ready != nil && !*endpoint.Conditions.Ready {
+				continue
+			}
+			var metadata map[string]string
+			if endpoint.Zone != nil && *endpoint.Zone != "" {
+				metadata = map[string]string{MetadataZone: *endpoint.Zone}
+			}
+			for _, addr := range endpoint.Addresses {
+				instance := &ServiceInstance{Addr: addr, Port: port, Metadata: metadata}
+				if key := fmt.Sprintf("%s:%d", addr, port); !seen[key] {
+					seen[key] = true
+					instances = append(instances, instance)
+				}
+			}
+		}
+	}
+	sortInstances(instances)
+	return instances
+}
+
+// endpointPort returns the transaction port of the endpoint slice, the port named by the config
+// or the first port if no name is configured
+func (s *KubernetesRegistryService) endpointPort(slice *discoveryv1.EndpointSlice) (int, bool) {
+	for _, port := range slice.Ports {
+		if port.Port == nil {
+			continue
+		}
+		if s.kubernetesConfig.PortName == "" || (port.Name != nil && *port.Name == s.kubernetesConfig.PortName) {
+			return int(*port.Port), true
+		}
+	}
+	return 0, false
+}
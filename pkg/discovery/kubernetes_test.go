@@ -0,0 +1,174 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package discovery
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func newEndpointSlice(name, service string, port int32, endpoints ...discoveryv1.Endpoint) *discoveryv1.EndpointSlice {
+	portName := "transaction"
+	controlName := "control"
+	controlPort := port - 1000
+	return &discoveryv1.EndpointSlice{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "seata",
+			Labels:    map[string]string{discoveryv1.LabelServiceName: service},
+		},
+		AddressType: discoveryv1.AddressTypeIPv4,
+		Endpoints:   endpoints,
+		Ports: []discoveryv1.EndpointPort{
+			{Name: &controlName, Port: &controlPort},
+			{Name: &portName, Port: &port},
+		},
+	}
+}
+
+func newEndpoint(addr string, ready bool, zone string) discoveryv1.Endpoint {
+	endpoint := discoveryv1.Endpoint{
+		Addresses:  []string{addr},
+		Conditions: discoveryv1.EndpointConditions{Ready: &ready},
+	}
+	if zone != "" {
+		endpoint.Zone = &zone
+	}
+	return endpoint
+}
+
+func newTestKubernetesRegistryService(client *fake.Clientset) *KubernetesRegistryService {
+	return newKubernetesRegistryServiceWithClient(&ServiceConfig{
+		VgroupMapping: map[string]string{"default_tx_group": "default", "other_tx_group": "other"},
+	}, &KubernetesConfig{
+		Namespace: "seata",
+		Service:   "seata-server",
+		PortName:  "transaction",
+	}, client)
+}
+
+func TestKubernetesRegistryService_Lookup(t *testing.T) {
+	client := fake.NewSimpleClientset(
+		newEndpointSlice("seata-server-a", "seata-server", 8091,
+			newEndpoint("10.0.0.2", true, "az2"),
+			newEndpoint("10.0.0.1", true, "az1"),
+			newEndpoint("10.0.0.3", false, "az1")),
+		// the endpoint moving between the slices is kept once
+		newEndpointSlice("seata-server-b", "seata-server", 8091, newEndpoint("10.0.0.1", true, "az1")),
+		newEndpointSlice("other-a", "other-server", 8091, newEndpoint("10.0.1.1", true, "")),
+	)
+	s := newTestKubernetesRegistryService(client)
+	defer s.Close()
+
+	instances, err := s.Lookup("default_tx_group")
+	require.NoError(t, err)
+	assert.Equal(t, []*ServiceInstance{
+		{Addr: "10.0.0.1", Port: 8091, Metadata: map[string]string{MetadataZone: "az1"}},
+		{Addr: "10.0.0.2", Port: 8091, Metadata: map[string]string{MetadataZone: "az2"}},
+	}, instances)
+
+	_, err = s.Lookup("unknown_tx_group")
+	assert.EqualError(t, err, "vgroup is empty. key: unknown_tx_group")
+}
+
+func TestKubernetesRegistryService_Subscribe(t *testing.T) {
+	client := fake.NewSimpleClientset(newEndpointSlice("seata-server-a", "seata-server", 8091, newEndpoint("10.0.0.1", true, "")))
+	s := newTestKubernetesRegistryService(client)
+	defer s.Close()
+
+	listener := &recordListener{}
+	require.NoError(t, s.Subscribe("default_tx_group", listener))
+	assert.Equal(t, []string{"ADD default_tx_group 10.0.0.1:8091"}, listener.take())
+
+	ctx := context.Background()
+	slices := client.DiscoveryV1().EndpointSlices("seata")
+	_, err := slices.Create(ctx, newEndpointSlice("seata-server-b", "seata-server", 8091, newEndpoint("10.0.0.2", true, "")), metav1.CreateOptions{})
+	require.NoError(t, err)
+	assert.Eventually(t, func() bool { return listener.count() == 1 }, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, []string{"ADD default_tx_group 10.0.0.2:8091"}, listener.take())
+
+	// the endpoint becomes not ready
+	_, err = slices.Update(ctx, newEndpointSlice("seata-server-a", "seata-server", 8091, newEndpoint("10.0.0.1", false, "")), metav1.UpdateOptions{})
+	require.NoError(t, err)
+	assert.Eventually(t, func() bool { return listener.count() == 1 }, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, []string{"REMOVE default_tx_group 10.0.0.1:8091"}, listener.take())
+
+	require.NoError(t, slices.Delete(ctx, "seata-server-b", metav1.DeleteOptions{}))
+	assert.Eventually(t, func() bool { return listener.count() == 1 }, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, []string{"REMOVE default_tx_group 10.0.0.2:8091"}, listener.take())
+
+	require.NoError(t, s.Unsubscribe("default_tx_group", listener))
+	s.Close()
+	_, err = s.Lookup("other_tx_group")
+	assert.EqualError(t, err, "kubernetes registry is closed")
+}
+
+func TestKubernetesRegistryService_EndpointPort(t *testing.T) {
+	s := newTestKubernetesRegistryService(fake.NewSimpleClientset())
+	slice := newEndpointSlice("seata-server-a", "seata-server", 8091)
+	port, ok := s.endpointPort(slice)
+	assert.True(t, ok)
+	assert.Equal(t, 8091, port)
+
+	s.kubernetesConfig.PortName = ""
+	port, ok = s.endpointPort(slice)
+	assert.True(t, ok)
+	assert.Equal(t, 7091, port)
+
+	s.kubernetesConfig.PortName = "unknown"
+	_, ok = s.endpointPort(slice)
+	assert.False(t, ok)
+}
+
+func TestNewKubernetesRegistryService(t *testing.T) {
+	kubeconfig := filepath.Join(t.TempDir(), "kubeconfig")
+	require.NoError(t, os.WriteFile(kubeconfig, []byte(`apiVersion: v1
+kind: Config
+clusters:
+- name: test
+  cluster:
+    server: https://127.0.0.1:6443
+contexts:
+- name: test
+  context:
+    cluster: test
+    user: test
+current-context: test
+users:
+- name: test
+  user:
+    token: test-token
+`), 0o644))
+	s, err := newKubernetesRegistryService(&ServiceConfig{}, &KubernetesConfig{Kubeconfig: kubeconfig})
+	require.NoError(t, err)
+	assert.Equal(t, kubernetesDefaultNamespace, s.(*KubernetesRegistryService).kubernetesConfig.Namespace)
+	assert.Equal(t, kubernetesSyncTimeout, s.(*KubernetesRegistryService).kubernetesConfig.SyncTimeout)
+	s.Close()
+
+	_, err = newKubernetesRegistryService(&ServiceConfig{}, &KubernetesConfig{Kubeconfig: filepath.Join(t.TempDir(), "missing")})
+	assert.Error(t, err)
+}
@@ -17,14 +17,328 @@
 
 package discovery
 
-type NacosRegistryService struct{}
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"seata.apache.org/seata-go/pkg/util/log"
+)
+
+const (
+	nacosServerAddrSplitChar = ","
+	nacosDefaultContextPath  = "/nacos"
+	nacosInstanceListPath    = "/v1/ns/instance/list"
+	nacosLoginPath           = "/v1/auth/login"
+	nacosHTTPTimeout         = 3 * time.Second
+	// nacosDefaultRefreshInterval the interval to poll the subscribed clusters if the server
+	// does not tell the cacheMillis
+	nacosDefaultRefreshInterval = 10 * time.Second
+	nacosMinRefreshInterval     = time.Second
+)
+
+type nacosHost struct {
+	Ip          string            `json:"ip"`
+	Port        int               `json:"port"`
+	Weight      float64           `json:"weight"`
+	Healthy     bool              `json:"healthy"`
+	Enabled     bool              `json:"enabled"`
+	ClusterName string            `json:"clusterName"`
+	Metadata    map[string]string `json:"metadata"`
+}
+
+// nacosServiceInfo the response of the instance list api of nacos naming
+type nacosServiceInfo struct {
+	Name        string      `json:"name"`
+	Clusters    string      `json:"clusters"`
+	CacheMillis int64       `json:"cacheMillis"`
+	Hosts       []nacosHost `json:"hosts"`
+}
+
+type nacosLoginResult struct {
+	AccessToken string `json:"accessToken"`
+	TokenTtl    int64  `json:"tokenTtl"`
+}
+
+// NacosRegistryService discovers the tc instances registered to nacos by the seata server, the
+// service is the application and the cluster is mapped from the vgroup. The clusters looked up
+// are subscribed, they are polled in background so the instance changes are followed.
+type NacosRegistryService struct {
+	// poller notifies the changes of the subscribed keys from the polled instances
+	*poller
+	serviceConfig *ServiceConfig
+	nacosConfig   NacosConfig
+	serverAddrs   []string
+	httpClient    *http.Client
+	now           func() time.Time
+
+	tokenLock   sync.Mutex
+	accessToken string
+	tokenExpire time.Time
+
+	// cluster -> healthy instances
+	instances       map[string][]*ServiceInstance
+	refreshInterval time.Duration
+	rwLock          sync.RWMutex
+
+	stopCh    chan struct{}
+	closeOnce sync.Once
+	// startOnce starts polling once a cluster is subscribed
+	startOnce sync.Once
+	wg        sync.WaitGroup
+}
+
+func newNacosRegistryService(config *ServiceConfig, nacosConfig *NacosConfig) (RegistryService, error) {
+	if config == nil || nacosConfig == nil {
+		return nil, fmt.Errorf("nacos registry config is nil")
+	}
+	serverAddrs := make([]string, 0)
+	for _, addr := range strings.Split(nacosConfig.ServerAddr, nacosServerAddrSplitChar) {
+		if addr = strings.TrimSpace(addr); addr != "" {
+			serverAddrs = append(serverAddrs, nacosBaseURL(addr))
+		}
+	}
+	if len(serverAddrs) == 0 {
+		return nil, fmt.Errorf("nacos registry server addr is empty")
+	}
+	if nacosConfig.Application == "" {
+		return nil, fmt.Errorf("nacos registry application is empty")
+	}
+
+	s := &NacosRegistryService{
+		serviceConfig:   config,
+		nacosConfig:     *nacosConfig,
+		serverAddrs:     serverAddrs,
+		httpClient:      &http.Client{Timeout: nacosHTTPTimeout},
+		now:             time.Now,
+		instances:       make(map[string][]*ServiceInstance),
+		refreshInterval: nacosDefaultRefreshInterval,
+		stopCh:          make(chan struct{}),
+	}
+	s.poller = newPoller(s.Lookup, 0)
+	return s, nil
+}
+
+// nacosBaseURL completes the scheme and the context path of the server addr,
+// e.g. 127.0.0.1:8848 -> http://127.0.0.1:8848/nacos
+func nacosBaseURL(addr string) string {
+	if !strings.Contains(addr, "://") {
+		addr = "http://" + addr
+	}
+	addr = strings.TrimRight(addr, "/")
+	if u, err := url.Parse(addr); err == nil && u.Path == "" {
+		addr += nacosDefaultContextPath
+	}
+	return addr
+}
 
 func (s *NacosRegistryService) Lookup(key string) ([]*ServiceInstance, error) {
-	//TODO implement me
-	panic("implement me")
+	cluster := s.serviceConfig.VgroupMapping[key]
+	if cluster == "" {
+		return nil, fmt.Errorf("vgroup is empty. key: %s", key)
+	}
+	s.rwLock.RLock()
+	instances, ok := s.instances[cluster]
+	s.rwLock.RUnlock()
+	if ok {
+		return instances, nil
+	}
+	return s.refresh(cluster)
+}
+
+func (s *NacosRegistryService) Close() {
+	s.poller.close()
+	s.closeOnce.Do(func() {
+		close(s.stopCh)
+	})
+	// no polling is started after closed
+	s.startOnce.Do(func() {})
+	s.wg.Wait()
+}
+
+func (s *NacosRegistryService) refreshLoop() {
+	defer s.wg.Done()
+	for {
+		s.rwLock.RLock()
+		interval := s.refreshInterval
+		s.rwLock.RUnlock()
+		select {
+		case <-s.stopCh:
+			return
+		case <-time.After(interval):
+		}
+
+		s.rwLock.RLock()
+		clusters := make([]string, 0, len(s.instances))
+		for cluster := range s.instances {
+			clusters = append(clusters, cluster)
+		}
+		s.rwLock.RUnlock()
+		for _, cluster := range clusters {
+			if _, err := s.refresh(cluster); err != nil {
+				log.Warnf("refresh nacos instances failed, cluster: %s, err: %v", cluster, err)
+			}
+		}
+	}
+}
+
+// refresh fetches the instances of the cluster from the servers in turn, the first
+// successful response is cached and the cluster is subscribed since then.
+func (s *NacosRegistryService) refresh(cluster string) ([]*ServiceInstance, error) {
+	var lastErr error
+	for _, addr := range s.serverAddrs {
+		info, err := s.fetch(addr, cluster)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		instances := make([]*ServiceInstance, 0, len(info.Hosts))
+		for _, host := range info.Hosts {
+			if host.Healthy && host.Enabled && (host.ClusterName == "" || host.ClusterName == cluster) {
+				instances = append(instances, host.instance())
+			}
+		}
+
+		s.rwLock.Lock()
+		if old, ok := s.instances[cluster]; ok && !reflect.DeepEqual(old, instances) {
+			log.Infof("nacos instances of cluster %s changed: %d -> %d", cluster, len(old), len(instances))
+		}
+		s.instances[cluster] = instances
+		if info.CacheMillis > 0 {
+			s.refreshInterval = time.Duration(info.CacheMillis) * time.Millisecond
+			if s.refreshInterval < nacosMinRefreshInterval {
+				s.refreshInterval = nacosMinRefreshInterval
+			}
+		}
+		s.rwLock.Unlock()
+		s.startOnce.Do(func() {
+			s.wg.Add(1)
+			go s.refreshLoop()
+		})
+		return instances, nil
+	}
+	return nil, fmt.Errorf("fetch nacos instances failed, cluster: %s, err: %w", cluster, lastErr)
+}
+
+func (s *NacosRegistryService) fetch(addr, cluster string) (*nacosServiceInfo, error) {
+	params := url.Values{}
+	params.Set("serviceName", s.nacosConfig.Application)
+	params.Set("clusters", cluster)
+	params.Set("healthyOnly", "true")
+	if s.nacosConfig.Group != "" {
+		params.Set("groupName", s.nacosConfig.Group)
+	}
+	if s.nacosConfig.Namespace != "" {
+		params.Set("namespaceId", s.nacosConfig.Namespace)
+	}
+	if err := s.auth(addr, params); err != nil {
+		return nil, err
+	}
+
+	resp, err := s.httpClient.Get(addr + nacosInstanceListPath + "?" + params.Encode())
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		if resp.StatusCode == http.StatusForbidden {
+			// the token may be expired on the server side, login again next time
+			s.resetToken()
+		}
+		return nil, fmt.Errorf("unexpected status code %d from %s", resp.StatusCode, addr)
+	}
+	info := &nacosServiceInfo{}
+	if err = json.NewDecoder(resp.Body).Decode(info); err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+// auth adds the access token of the username/password auth, and the signature of the
+// access-key/secret-key auth to the params
+func (s *NacosRegistryService) auth(addr string, params url.Values) error {
+	if s.nacosConfig.Username != "" {
+		token, err := s.token(addr)
+		if err != nil {
+			return err
+		}
+		params.Set("accessToken", token)
+	}
+	if s.nacosConfig.AccessKey != "" {
+		data := strconv.FormatInt(s.now().UnixNano()/int64(time.Millisecond), 10) + "@@"
+		if s.nacosConfig.Group != "" {
+			data += s.nacosConfig.Group + "@@"
+		}
+		data += s.nacosConfig.Application
+		mac := hmac.New(sha1.New, []byte(s.nacosConfig.SecretKey))
+		mac.Write([]byte(data))
+		params.Set("ak", s.nacosConfig.AccessKey)
+		params.Set("data", data)
+		params.Set("signature", base64.StdEncoding.EncodeToString(mac.Sum(nil)))
+	}
+	return nil
+}
+
+// token returns the cached access token, it logins again a little before the token expires
+func (s *NacosRegistryService) token(addr string) (string, error) {
+	s.tokenLock.Lock()
+	defer s.tokenLock.Unlock()
+	if s.accessToken != "" && s.now().Before(s.tokenExpire) {
+		return s.accessToken, nil
+	}
+
+	form := url.Values{}
+	form.Set("username", s.nacosConfig.Username)
+	form.Set("password", s.nacosConfig.Password)
+	resp, err := s.httpClient.PostForm(addr+nacosLoginPath, form)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("nacos login failed with status code %d from %s", resp.StatusCode, addr)
+	}
+	result := &nacosLoginResult{}
+	if err = json.NewDecoder(resp.Body).Decode(result); err != nil {
+		return "", err
+	}
+	if result.AccessToken == "" {
+		return "", fmt.Errorf("nacos login returns no access token from %s", addr)
+	}
+	ttl := time.Duration(result.TokenTtl) * time.Second
+	s.accessToken = result.AccessToken
+	s.tokenExpire = s.now().Add(ttl - ttl/10)
+	return s.accessToken, nil
+}
+
+func (s *NacosRegistryService) resetToken() {
+	s.tokenLock.Lock()
+	defer s.tokenLock.Unlock()
+	s.accessToken = ""
 }
 
-func (NacosRegistryService) Close() {
-	//TODO implement me
-	panic("implement me")
+func (h nacosHost) instance() *ServiceInstance {
+	metadata := make(map[string]string, len(h.Metadata)+1)
+	for k, v := range h.Metadata {
+		metadata[k] = v
+	}
+	// the weight of nacos is a float, the weighted load balance takes the integer weight
+	if _, ok := metadata[MetadataWeight]; !ok && h.Weight > 0 {
+		metadata[MetadataWeight] = strconv.Itoa(int(math.Max(1, math.Round(h.Weight))))
+	}
+	return &ServiceInstance{
+		Addr:     h.Ip,
+		Port:     h.Port,
+		Metadata: metadata,
+	}
 }
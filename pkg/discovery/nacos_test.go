@@ -0,0 +1,238 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package discovery
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeNacosServer serves the login and instance list api of nacos naming
+type fakeNacosServer struct {
+	*httptest.Server
+	mu          sync.Mutex
+	username    string
+	password    string
+	secretKey   string
+	token       string
+	cacheMillis int64
+	hosts       []nacosHost
+	logins      int
+	queries     []url.Values
+}
+
+func newFakeNacosServer() *fakeNacosServer {
+	s := &fakeNacosServer{hosts: []nacosHost{
+		{Ip: "127.0.0.1", Port: 8091, Weight: 2, Healthy: true, Enabled: true, ClusterName: "default",
+			Metadata: map[string]string{MetadataZone: "zone-a"}},
+		{Ip: "127.0.0.1", Port: 8092, Weight: 0.4, Healthy: true, Enabled: true, ClusterName: "default"},
+		{Ip: "127.0.0.1", Port: 8093, Healthy: false, Enabled: true, ClusterName: "default"},
+		{Ip: "127.0.0.1", Port: 8094, Healthy: true, Enabled: false, ClusterName: "default"},
+		{Ip: "127.0.0.1", Port: 8095, Healthy: true, Enabled: true, ClusterName: "other"},
+	}}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	return s
+}
+
+func (s *fakeNacosServer) serve(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch r.URL.Path {
+	case nacosDefaultContextPath + nacosLoginPath:
+		if r.Method != http.MethodPost || r.FormValue("username") != s.username || r.FormValue("password") != s.password {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		s.logins++
+		_ = json.NewEncoder(w).Encode(nacosLoginResult{AccessToken: s.token, TokenTtl: 18000})
+	case nacosDefaultContextPath + nacosInstanceListPath:
+		query := r.URL.Query()
+		s.queries = append(s.queries, query)
+		if s.username != "" && query.Get("accessToken") != s.token {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		if s.secretKey != "" {
+			mac := hmac.New(sha1.New, []byte(s.secretKey))
+			mac.Write([]byte(query.Get("data")))
+			if query.Get("signature") != base64.StdEncoding.EncodeToString(mac.Sum(nil)) {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+		}
+		_ = json.NewEncoder(w).Encode(nacosServiceInfo{
+			Name:        query.Get("groupName") + "@@" + query.Get("serviceName"),
+			Clusters:    query.Get("clusters"),
+			CacheMillis: s.cacheMillis,
+			Hosts:       s.hosts,
+		})
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func (s *fakeNacosServer) lastQuery() url.Values {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.queries[len(s.queries)-1]
+}
+
+func newTestNacosRegistryService(t *testing.T, nacosConfig NacosConfig) *NacosRegistryService {
+	if nacosConfig.Application == "" {
+		nacosConfig.Application = "seata-server"
+	}
+	registry, err := newNacosRegistryService(&ServiceConfig{
+		VgroupMapping: map[string]string{"default_tx_group": "default"},
+	}, &nacosConfig)
+	require.NoError(t, err)
+	t.Cleanup(registry.Close)
+	return registry.(*NacosRegistryService)
+}
+
+func TestNacosRegistryService_Lookup(t *testing.T) {
+	server := newFakeNacosServer()
+	defer server.Close()
+	registry := newTestNacosRegistryService(t, NacosConfig{
+		ServerAddr: server.URL,
+		Group:      "SEATA_GROUP",
+		Namespace:  "dev",
+	})
+
+	instances, err := registry.Lookup("default_tx_group")
+	require.NoError(t, err)
+	assert.Equal(t, []*ServiceInstance{
+		{Addr: "127.0.0.1", Port: 8091, Metadata: map[string]string{MetadataZone: "zone-a", MetadataWeight: "2"}},
+		{Addr: "127.0.0.1", Port: 8092, Metadata: map[string]string{MetadataWeight: "1"}},
+	}, instances)
+
+	query := server.lastQuery()
+	assert.Equal(t, "seata-server", query.Get("serviceName"))
+	assert.Equal(t, "SEATA_GROUP", query.Get("groupName"))
+	assert.Equal(t, "dev", query.Get("namespaceId"))
+	assert.Equal(t, "default", query.Get("clusters"))
+	assert.Equal(t, "true", query.Get("healthyOnly"))
+
+	_, err = registry.Lookup("unknown_tx_group")
+	assert.Error(t, err)
+}
+
+func TestNacosRegistryService_ServerFallback(t *testing.T) {
+	down := newFakeNacosServer()
+	down.Close()
+	server := newFakeNacosServer()
+	defer server.Close()
+	registry := newTestNacosRegistryService(t, NacosConfig{ServerAddr: down.URL + "," + server.URL})
+
+	instances, err := registry.Lookup("default_tx_group")
+	require.NoError(t, err)
+	assert.Len(t, instances, 2)
+
+	registry = newTestNacosRegistryService(t, NacosConfig{ServerAddr: down.URL})
+	_, err = registry.Lookup("default_tx_group")
+	assert.Error(t, err)
+}
+
+func TestNacosRegistryService_UsernamePassword(t *testing.T) {
+	server := newFakeNacosServer()
+	defer server.Close()
+	server.username, server.password, server.token = "nacos", "secret", "token-1"
+	registry := newTestNacosRegistryService(t, NacosConfig{ServerAddr: server.URL, Username: "nacos", Password: "secret"})
+
+	_, err := registry.refresh("default")
+	require.NoError(t, err)
+	_, err = registry.refresh("default")
+	require.NoError(t, err)
+	// the token is cached
+	assert.Equal(t, 1, server.logins)
+
+	// the token expired on the server side is refreshed
+	server.mu.Lock()
+	server.token = "token-2"
+	server.mu.Unlock()
+	_, err = registry.refresh("default")
+	assert.Error(t, err)
+	_, err = registry.refresh("default")
+	require.NoError(t, err)
+	assert.Equal(t, 2, server.logins)
+	assert.Equal(t, "token-2", server.lastQuery().Get("accessToken"))
+
+	registry = newTestNacosRegistryService(t, NacosConfig{ServerAddr: server.URL, Username: "nacos", Password: "wrong"})
+	_, err = registry.refresh("default")
+	assert.Error(t, err)
+}
+
+func TestNacosRegistryService_AccessKey(t *testing.T) {
+	server := newFakeNacosServer()
+	defer server.Close()
+	server.secretKey = "sk"
+	registry := newTestNacosRegistryService(t, NacosConfig{
+		ServerAddr: server.URL,
+		Group:      "SEATA_GROUP",
+		AccessKey:  "ak",
+		SecretKey:  "sk",
+	})
+	registry.now = func() time.Time {
+		return time.Unix(1700000000, 0)
+	}
+
+	_, err := registry.refresh("default")
+	require.NoError(t, err)
+	query := server.lastQuery()
+	assert.Equal(t, "ak", query.Get("ak"))
+	assert.Equal(t, "1700000000000@@SEATA_GROUP@@seata-server", query.Get("data"))
+
+	registry.nacosConfig.SecretKey = "wrong"
+	_, err = registry.refresh("default")
+	assert.Error(t, err)
+}
+
+func TestNacosRegistryService_Subscribe(t *testing.T) {
+	server := newFakeNacosServer()
+	defer server.Close()
+	server.cacheMillis = 1
+	registry := newTestNacosRegistryService(t, NacosConfig{ServerAddr: server.URL})
+	instances, err := registry.Lookup("default_tx_group")
+	require.NoError(t, err)
+	assert.Len(t, instances, 2)
+
+	server.mu.Lock()
+	server.hosts = server.hosts[:1]
+	server.mu.Unlock()
+	// the subscribed cluster is polled in background
+	assert.Eventually(t, func() bool {
+		instances, err := registry.Lookup("default_tx_group")
+		return err == nil && len(instances) == 1
+	}, 5*time.Second, 50*time.Millisecond)
+}
+
+func TestNacosBaseURL(t *testing.T) {
+	assert.Equal(t, "http://127.0.0.1:8848/nacos", nacosBaseURL("127.0.0.1:8848"))
+	assert.Equal(t, "https://nacos.local/nacos", nacosBaseURL("https://nacos.local/"))
+	assert.Equal(t, "http://127.0.0.1:8848/custom", nacosBaseURL("127.0.0.1:8848/custom"))
+}
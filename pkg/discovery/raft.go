@@ -0,0 +1,272 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package discovery
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"seata.apache.org/seata-go/pkg/util/log"
+)
+
+const (
+	raftServerAddrSplitChar = ","
+	raftMetadataPath        = "/metadata/v1/cluster"
+	raftRoleLeader          = "LEADER"
+	raftHTTPTimeout         = 3 * time.Second
+)
+
+type raftEndpoint struct {
+	Host string `json:"host"`
+	Port int    `json:"port"`
+}
+
+type raftNode struct {
+	Control     raftEndpoint      `json:"control"`
+	Transaction raftEndpoint      `json:"transaction"`
+	Group       string            `json:"group"`
+	Role        string            `json:"role"`
+	Metadata    map[string]string `json:"metadata"`
+}
+
+// raftClusterMetadata the cluster metadata served by the tc nodes running in raft mode
+type raftClusterMetadata struct {
+	Nodes     []raftNode `json:"nodes"`
+	StoreMode string     `json:"storeMode"`
+	Term      int64      `json:"term"`
+}
+
+// RaftRegistryService discovers the tc nodes of a raft cluster by their metadata http api,
+// it keeps the metadata of every cluster it looked up fresh so the leader changes are followed.
+type RaftRegistryService struct {
+	// poller notifies the changes of the subscribed keys from the cached metadata
+	*poller
+	serviceConfig *ServiceConfig
+	serverAddrs   []string
+	httpClient    *http.Client
+	// cluster -> metadata
+	metadata map[string]*raftClusterMetadata
+	rwLock   sync.RWMutex
+
+	stopCh    chan struct{}
+	closeOnce sync.Once
+	wg        sync.WaitGroup
+}
+
+func newRaftRegistryService(config *ServiceConfig, raftConfig *RaftConfig) (RegistryService, error) {
+	if config == nil || raftConfig == nil {
+		return nil, fmt.Errorf("raft registry config is nil")
+	}
+	serverAddrs := make([]string, 0)
+	for _, addr := range strings.Split(raftConfig.ServerAddr, raftServerAddrSplitChar) {
+		if addr = strings.TrimSpace(addr); addr != "" {
+			serverAddrs = append(serverAddrs, addr)
+		}
+	}
+	if len(serverAddrs) == 0 {
+		return nil, fmt.Errorf("raft registry server addr is empty")
+	}
+
+	s := &RaftRegistryService{
+		serviceConfig: config,
+		serverAddrs:   serverAddrs,
+		httpClient:    &http.Client{Timeout: raftHTTPTimeout},
+		metadata:      make(map[string]*raftClusterMetadata),
+		stopCh:        make(chan struct{}),
+	}
+	s.poller = newPoller(s.Lookup, 0)
+	if raftConfig.MetadataMaxAge > 0 {
+		s.wg.Add(1)
+		go s.refreshLoop(raftConfig.MetadataMaxAge)
+	}
+	return s, nil
+}
+
+func (s *RaftRegistryService) Lookup(key string) ([]*ServiceInstance, error) {
+	metadata, err := s.clusterMetadata(key)
+	if err != nil {
+		return nil, err
+	}
+	instances := make([]*ServiceInstance, 0, len(metadata.Nodes))
+	for _, node := range metadata.Nodes {
+		instances = append(instances, node.instance())
+	}
+	return instances, nil
+}
+
+func (s *RaftRegistryService) Leader(key string) (*ServiceInstance, error) {
+	metadata, err := s.clusterMetadata(key)
+	if err != nil {
+		return nil, err
+	}
+	for _, node := range metadata.Nodes {
+		if node.Role == raftRoleLeader {
+			return node.instance(), nil
+		}
+	}
+	return nil, fmt.Errorf("no leader in the raft cluster of key: %s", key)
+}
+
+func (s *RaftRegistryService) RefreshLeader(key string) error {
+	cluster, err := s.cluster(key)
+	if err != nil {
+		return err
+	}
+	_, err = s.refresh(cluster)
+	return err
+}
+
+func (s *RaftRegistryService) Close() {
+	s.poller.close()
+	s.closeOnce.Do(func() {
+		close(s.stopCh)
+	})
+	s.wg.Wait()
+}
+
+func (s *RaftRegistryService) cluster(key string) (string, error) {
+	cluster := s.serviceConfig.VgroupMapping[key]
+	if cluster == "" {
+		return "", fmt.Errorf("vgroup is empty. key: %s", key)
+	}
+	return cluster, nil
+}
+
+func (s *RaftRegistryService) clusterMetadata(key string) (*raftClusterMetadata, error) {
+	cluster, err := s.cluster(key)
+	if err != nil {
+		return nil, err
+	}
+	s.rwLock.RLock()
+	metadata, ok := s.metadata[cluster]
+	s.rwLock.RUnlock()
+	if ok {
+		return metadata, nil
+	}
+	return s.refresh(cluster)
+}
+
+func (s *RaftRegistryService) refreshLoop(interval time.Duration) {
+	defer s.wg.Done()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.stopCh:
+			return
+		case <-ticker.C:
+			s.rwLock.RLock()
+			clusters := make([]string, 0, len(s.metadata))
+			for cluster := range s.metadata {
+				clusters = append(clusters, cluster)
+			}
+			s.rwLock.RUnlock()
+			for _, cluster := range clusters {
+				if _, err := s.refresh(cluster); err != nil {
+					log.Warnf("refresh raft cluster metadata failed, cluster: %s, err: %v", cluster, err)
+				}
+			}
+		}
+	}
+}
+
+// refresh fetches the metadata of the cluster from the configured servers and the known
+// nodes in turn, the first successful response is cached.
+func (s *RaftRegistryService) refresh(cluster string) (*raftClusterMetadata, error) {
+	var lastErr error
+	for _, addr := range s.metadataAddrs(cluster) {
+		metadata, err := s.fetch(addr, cluster)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		s.rwLock.Lock()
+		if old, ok := s.metadata[cluster]; !ok || old.Term <= metadata.Term {
+			s.metadata[cluster] = metadata
+		} else {
+			metadata = old
+		}
+		s.rwLock.Unlock()
+		return metadata, nil
+	}
+	return nil, fmt.Errorf("fetch raft cluster metadata failed, cluster: %s, err: %w", cluster, lastErr)
+}
+
+func (s *RaftRegistryService) metadataAddrs(cluster string) []string {
+	addrs := append([]string{}, s.serverAddrs...)
+	s.rwLock.RLock()
+	defer s.rwLock.RUnlock()
+	if metadata, ok := s.metadata[cluster]; ok {
+		for _, node := range metadata.Nodes {
+			addr := node.Control.Host + ":" + strconv.Itoa(node.Control.Port)
+			if !containsString(addrs, addr) {
+				addrs = append(addrs, addr)
+			}
+		}
+	}
+	return addrs
+}
+
+func (s *RaftRegistryService) fetch(addr, cluster string) (*raftClusterMetadata, error) {
+	if !strings.Contains(addr, "://") {
+		addr = "http://" + addr
+	}
+	resp, err := s.httpClient.Get(fmt.Sprintf("%s%s?group=%s", addr, raftMetadataPath, cluster))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code %d from %s", resp.StatusCode, addr)
+	}
+	metadata := &raftClusterMetadata{}
+	if err = json.NewDecoder(resp.Body).Decode(metadata); err != nil {
+		return nil, err
+	}
+	if len(metadata.Nodes) == 0 {
+		return nil, fmt.Errorf("no tc node in the metadata from %s", addr)
+	}
+	return metadata, nil
+}
+
+func (n raftNode) instance() *ServiceInstance {
+	metadata := make(map[string]string, len(n.Metadata)+1)
+	for k, v := range n.Metadata {
+		metadata[k] = v
+	}
+	metadata[MetadataRole] = n.Role
+	return &ServiceInstance{
+		Addr:     n.Transaction.Host,
+		Port:     n.Transaction.Port,
+		Metadata: metadata,
+	}
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
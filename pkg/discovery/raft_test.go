@@ -0,0 +1,157 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package discovery
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeRaftMetadataServer serves the cluster metadata like the tc nodes in raft mode
+type fakeRaftMetadataServer struct {
+	*httptest.Server
+	mu       sync.Mutex
+	metadata raftClusterMetadata
+	groups   []string
+}
+
+func newFakeRaftMetadataServer(leader int) *fakeRaftMetadataServer {
+	s := &fakeRaftMetadataServer{}
+	s.setLeader(1, leader)
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != raftMetadataPath {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.groups = append(s.groups, r.URL.Query().Get("group"))
+		_ = json.NewEncoder(w).Encode(s.metadata)
+	}))
+	return s
+}
+
+func (s *fakeRaftMetadataServer) setLeader(term int64, leader int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.metadata = raftClusterMetadata{StoreMode: "raft", Term: term}
+	for i := 0; i < 3; i++ {
+		role := "FOLLOWER"
+		if i == leader {
+			role = raftRoleLeader
+		}
+		s.metadata.Nodes = append(s.metadata.Nodes, raftNode{
+			Control:     raftEndpoint{Host: "127.0.0.1", Port: 7091 + i},
+			Transaction: raftEndpoint{Host: "127.0.0.1", Port: 8091 + i},
+			Group:       "default",
+			Role:        role,
+			Metadata:    map[string]string{MetadataZone: "zone-a"},
+		})
+	}
+}
+
+func newTestRaftRegistryService(t *testing.T, serverAddr string) *RaftRegistryService {
+	registry, err := newRaftRegistryService(&ServiceConfig{
+		VgroupMapping: map[string]string{"default_tx_group": "default"},
+	}, &RaftConfig{ServerAddr: serverAddr})
+	assert.Nil(t, err)
+	t.Cleanup(registry.Close)
+	return registry.(*RaftRegistryService)
+}
+
+func TestRaftRegistryService_Lookup(t *testing.T) {
+	server := newFakeRaftMetadataServer(0)
+	defer server.Close()
+	registry := newTestRaftRegistryService(t, strings.TrimPrefix(server.URL, "http://"))
+
+	instances, err := registry.Lookup("default_tx_group")
+	assert.Nil(t, err)
+	assert.Len(t, instances, 3)
+	assert.Equal(t, "127.0.0.1", instances[0].Addr)
+	assert.Equal(t, 8091, instances[0].Port)
+	assert.Equal(t, raftRoleLeader, instances[0].Metadata[MetadataRole])
+	assert.Equal(t, "zone-a", instances[0].Metadata[MetadataZone])
+	assert.Equal(t, "FOLLOWER", instances[1].Metadata[MetadataRole])
+	assert.Equal(t, []string{"default"}, server.groups)
+
+	_, err = registry.Lookup("unknown_tx_group")
+	assert.NotNil(t, err)
+}
+
+func TestRaftRegistryService_Leader(t *testing.T) {
+	server := newFakeRaftMetadataServer(0)
+	defer server.Close()
+	registry := newTestRaftRegistryService(t, server.URL)
+
+	leader, err := registry.Leader("default_tx_group")
+	assert.Nil(t, err)
+	assert.Equal(t, 8091, leader.Port)
+
+	// the cached metadata is used until the leader is refreshed
+	server.setLeader(2, 1)
+	leader, err = registry.Leader("default_tx_group")
+	assert.Nil(t, err)
+	assert.Equal(t, 8091, leader.Port)
+
+	assert.Nil(t, registry.RefreshLeader("default_tx_group"))
+	leader, err = registry.Leader("default_tx_group")
+	assert.Nil(t, err)
+	assert.Equal(t, 8092, leader.Port)
+
+	// the metadata of a stale term is ignored
+	server.setLeader(1, 2)
+	assert.Nil(t, registry.RefreshLeader("default_tx_group"))
+	leader, err = registry.Leader("default_tx_group")
+	assert.Nil(t, err)
+	assert.Equal(t, 8092, leader.Port)
+
+	// no leader during the election
+	server.setLeader(3, -1)
+	assert.Nil(t, registry.RefreshLeader("default_tx_group"))
+	_, err = registry.Leader("default_tx_group")
+	assert.NotNil(t, err)
+}
+
+func TestRaftRegistryService_FailOver(t *testing.T) {
+	server := newFakeRaftMetadataServer(0)
+	defer server.Close()
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+	registry := newTestRaftRegistryService(t, down.URL+" , "+server.URL)
+
+	leader, err := registry.Leader("default_tx_group")
+	assert.Nil(t, err)
+	assert.Equal(t, 8091, leader.Port)
+
+	server.Close()
+	assert.NotNil(t, registry.RefreshLeader("default_tx_group"))
+}
+
+func TestNewRaftRegistryService(t *testing.T) {
+	_, err := newRaftRegistryService(&ServiceConfig{}, &RaftConfig{ServerAddr: " , "})
+	assert.NotNil(t, err)
+
+	_, err = newRaftRegistryService(nil, &RaftConfig{ServerAddr: "127.0.0.1:7091"})
+	assert.NotNil(t, err)
+}
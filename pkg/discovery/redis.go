@@ -17,14 +17,277 @@
 
 package discovery
 
-type RedisRegistryService struct{}
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+
+	"seata.apache.org/seata-go/pkg/util/log"
+)
+
+const (
+	// redisKeyPrefix the seata server registers itself as the key <prefix><cluster>_<ip:port>,
+	// and publishes the changes to the channel <prefix><cluster>
+	redisKeyPrefix       = "registry.redis."
+	redisClusterSplit    = "_"
+	redisMessageSplit    = "-"
+	redisEventRegister   = "register"
+	redisEventUnregister = "unregister"
+	redisScanCount       = 100
+	redisRequestTimeout  = 5 * time.Second
+)
+
+// RedisRegistryService discovers the tc instances registered to redis by the seata server,
+// the instances are scanned at the first lookup, then the register and unregister messages
+// published by the seata server are followed.
+type RedisRegistryService struct {
+	serviceConfig   *ServiceConfig
+	client          *redis.Client
+	pubsub          *redis.PubSub
+	refreshInterval time.Duration
+
+	// cluster -> instances
+	instances     map[string][]*ServiceInstance
+	rwLock        sync.RWMutex
+	subscriptions *subscriptions
+	// notifyLock orders the subscribing and the notifications of the followed changes
+	notifyLock sync.Mutex
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	// startOnce starts listening once a cluster is subscribed
+	startOnce sync.Once
+	wg        sync.WaitGroup
+}
+
+func newRedisRegistryService(config *ServiceConfig, redisConfig *RedisConfig) (RegistryService, error) {
+	if config == nil || redisConfig == nil {
+		return nil, fmt.Errorf("redis registry config is nil")
+	}
+	if redisConfig.ServerAddr == "" {
+		return nil, fmt.Errorf("redis registry server addr is empty")
+	}
+	client := redis.NewClient(&redis.Options{
+		Addr:     redisConfig.ServerAddr,
+		Password: redisConfig.Password,
+		DB:       redisConfig.DB,
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &RedisRegistryService{
+		serviceConfig:   config,
+		client:          client,
+		pubsub:          client.Subscribe(ctx),
+		refreshInterval: redisConfig.RefreshInterval,
+		instances:       make(map[string][]*ServiceInstance),
+		subscriptions:   newSubscriptions(),
+		ctx:             ctx,
+		cancel:          cancel,
+	}
+	return s, nil
+}
 
 func (s *RedisRegistryService) Lookup(key string) ([]*ServiceInstance, error) {
-	//TODO implement me
-	panic("implement me")
+	cluster := s.serviceConfig.VgroupMapping[key]
+	if cluster == "" {
+		return nil, fmt.Errorf("vgroup is empty. key: %s", key)
+	}
+	s.rwLock.RLock()
+	instances, ok := s.instances[cluster]
+	s.rwLock.RUnlock()
+	if ok {
+		return instances, nil
+	}
+
+	// the messages are handled after the scan by holding the lock
+	s.rwLock.Lock()
+	defer s.rwLock.Unlock()
+	if instances, ok = s.instances[cluster]; ok {
+		return instances, nil
+	}
+	if s.ctx.Err() != nil {
+		return nil, fmt.Errorf("redis registry is closed")
+	}
+	// subscribe before scanning so the changes during the scan are not missed
+	if err := s.subscribe(cluster); err != nil {
+		return nil, fmt.Errorf("subscribe redis channel of cluster %s failed: %w", cluster, err)
+	}
+	instances, err := s.scan(cluster)
+	if err != nil {
+		return nil, fmt.Errorf("scan redis instances of cluster %s failed: %w", cluster, err)
+	}
+	s.instances[cluster] = instances
+	s.startOnce.Do(func() {
+		s.wg.Add(1)
+		go s.listen(s.pubsub.Channel())
+		if s.refreshInterval > 0 {
+			s.wg.Add(1)
+			go s.refreshLoop()
+		}
+	})
+	return instances, nil
+}
+
+func (s *RedisRegistryService) Subscribe(key string, listener Listener) error {
+	s.notifyLock.Lock()
+	defer s.notifyLock.Unlock()
+	instances, err := s.Lookup(key)
+	if err != nil {
+		return err
+	}
+	return s.subscriptions.subscribe(key, listener, instances)
+}
+
+func (s *RedisRegistryService) Unsubscribe(key string, listener Listener) error {
+	_, err := s.subscriptions.unsubscribe(key, listener)
+	return err
+}
+
+// notify updates the subscriptions of the keys mapped to the cluster with its current instances
+func (s *RedisRegistryService) notify(cluster string) {
+	s.notifyLock.Lock()
+	defer s.notifyLock.Unlock()
+	s.rwLock.RLock()
+	instances := s.instances[cluster]
+	s.rwLock.RUnlock()
+	for key, c := range s.serviceConfig.VgroupMapping {
+		if c == cluster {
+			s.subscriptions.update(key, instances)
+		}
+	}
+}
+
+func (s *RedisRegistryService) Close() {
+	s.rwLock.Lock()
+	s.cancel()
+	s.rwLock.Unlock()
+	// no listening is started after closed
+	s.startOnce.Do(func() {})
+	if err := s.pubsub.Close(); err != nil {
+		log.Warnf("close redis pubsub failed: %v", err)
+	}
+	s.wg.Wait()
+	if err := s.client.Close(); err != nil {
+		log.Warnf("close redis client failed: %v", err)
+	}
+}
+
+func (s *RedisRegistryService) subscribe(cluster string) error {
+	ctx, cancel := context.WithTimeout(s.ctx, redisRequestTimeout)
+	defer cancel()
+	return s.pubsub.Subscribe(ctx, redisKeyPrefix+cluster)
+}
+
+// scan scans the instance keys of the cluster, the address is the suffix of the key
+func (s *RedisRegistryService) scan(cluster string) ([]*ServiceInstance, error) {
+	ctx, cancel := context.WithTimeout(s.ctx, redisRequestTimeout)
+	defer cancel()
+	prefix := redisKeyPrefix + cluster + redisClusterSplit
+	instances := make([]*ServiceInstance, 0)
+	seen := make(map[string]bool)
+	iter := s.client.Scan(ctx, 0, prefix+"*", redisScanCount).Iterator()
+	for iter.Next(ctx) {
+		addr := strings.TrimPrefix(iter.Val(), prefix)
+		// the keys may be returned more than once during the rehashing
+		if seen[addr] {
+			continue
+		}
+		seen[addr] = true
+		instance, err := parseServiceInstance(addr)
+		if err != nil {
+			log.Warnf("invalid redis instance key %s: %v", iter.Val(), err)
+			continue
+		}
+		instances = append(instances, instance)
+	}
+	if err := iter.Err(); err != nil {
+		return nil, err
+	}
+	return instances, nil
+}
+
+// listen handles the messages until the pubsub is closed, the pubsub reconnects by itself
+func (s *RedisRegistryService) listen(ch <-chan *redis.Message) {
+	defer s.wg.Done()
+	for msg := range ch {
+		s.onMessage(msg)
+	}
+}
+
+// onMessage applies the message in form of ip:port-register or ip:port-unregister
+func (s *RedisRegistryService) onMessage(msg *redis.Message) {
+	cluster := strings.TrimPrefix(msg.Channel, redisKeyPrefix)
+	idx := strings.LastIndex(msg.Payload, redisMessageSplit)
+	if idx < 0 {
+		log.Warnf("invalid redis registry message %q of channel %s", msg.Payload, msg.Channel)
+		return
+	}
+	addr, event := msg.Payload[:idx], msg.Payload[idx+1:]
+	instance, err := parseServiceInstance(addr)
+	if err != nil {
+		log.Warnf("invalid redis registry message %q of channel %s: %v", msg.Payload, msg.Channel, err)
+		return
+	}
+
+	if event != redisEventRegister && event != redisEventUnregister {
+		log.Warnf("unknown redis registry event %q of channel %s", event, msg.Channel)
+		return
+	}
+
+	s.rwLock.Lock()
+	instances, ok := s.instances[cluster]
+	if !ok {
+		s.rwLock.Unlock()
+		return
+	}
+	// the cached slice is returned by the lookup, so it is copied on write
+	updated := make([]*ServiceInstance, 0, len(instances)+1)
+	for _, old := range instances {
+		if old.Addr != instance.Addr || old.Port != instance.Port {
+			updated = append(updated, old)
+		}
+	}
+	if event == redisEventRegister {
+		updated = append(updated, instance)
+	}
+	log.Infof("redis instance %s of cluster %s %sed", addr, cluster, event)
+	s.instances[cluster] = updated
+	s.rwLock.Unlock()
+	s.notify(cluster)
 }
 
-func (RedisRegistryService) Close() {
-	//TODO implement me
-	panic("implement me")
+func (s *RedisRegistryService) refreshLoop() {
+	defer s.wg.Done()
+	ticker := time.NewTicker(s.refreshInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+			s.rwLock.RLock()
+			clusters := make([]string, 0, len(s.instances))
+			for cluster := range s.instances {
+				clusters = append(clusters, cluster)
+			}
+			s.rwLock.RUnlock()
+			for _, cluster := range clusters {
+				instances, err := s.scan(cluster)
+				if err != nil {
+					log.Warnf("rescan redis instances failed, cluster: %s, err: %v", cluster, err)
+					continue
+				}
+				s.rwLock.Lock()
+				if len(s.instances[cluster]) != len(instances) {
+					log.Infof("redis instances of cluster %s changed: %d -> %d", cluster, len(s.instances[cluster]), len(instances))
+				}
+				s.instances[cluster] = instances
+				s.rwLock.Unlock()
+				s.notify(cluster)
+			}
+		}
+	}
 }
@@ -0,0 +1,164 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package discovery
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testRedisChannel = "registry.redis.default"
+
+func newTestRedisRegistryService(t *testing.T, redisConfig *RedisConfig) *RedisRegistryService {
+	s, err := newRedisRegistryService(&ServiceConfig{
+		VgroupMapping: map[string]string{"default_tx_group": "default", "other_tx_group": "other"},
+	}, redisConfig)
+	require.NoError(t, err)
+	return s.(*RedisRegistryService)
+}
+
+func TestRedisRegistryService_Lookup(t *testing.T) {
+	mr := miniredis.RunT(t)
+	mr.RequireAuth("redis-password")
+	db := mr.DB(2)
+	require.NoError(t, db.Set("registry.redis.default_127.0.0.1:8091", "1"))
+	require.NoError(t, db.Set("registry.redis.default_127.0.0.1:8092", "1"))
+	require.NoError(t, db.Set("registry.redis.default_invalid", "1"))
+	require.NoError(t, db.Set("registry.redis.other_127.0.0.1:8093", "1"))
+	require.NoError(t, mr.Set("registry.redis.default_127.0.0.1:8094", "1"))
+
+	s := newTestRedisRegistryService(t, &RedisConfig{
+		ServerAddr: mr.Addr(),
+		Password:   "redis-password",
+		DB:         2,
+	})
+	defer s.Close()
+
+	instances, err := s.Lookup("default_tx_group")
+	require.NoError(t, err)
+	ports := make([]int, 0, len(instances))
+	for _, instance := range instances {
+		assert.Equal(t, "127.0.0.1", instance.Addr)
+		ports = append(ports, instance.Port)
+	}
+	assert.ElementsMatch(t, []int{8091, 8092}, ports)
+
+	_, err = s.Lookup("unknown_group")
+	assert.Error(t, err)
+}
+
+func TestRedisRegistryService_LookupAuthFailed(t *testing.T) {
+	mr := miniredis.RunT(t)
+	mr.RequireAuth("redis-password")
+
+	s := newTestRedisRegistryService(t, &RedisConfig{
+		ServerAddr: mr.Addr(),
+		Password:   "wrong-password",
+	})
+	defer s.Close()
+
+	_, err := s.Lookup("default_tx_group")
+	assert.Error(t, err)
+}
+
+func TestRedisRegistryService_Subscribe(t *testing.T) {
+	mr := miniredis.RunT(t)
+	require.NoError(t, mr.Set("registry.redis.default_127.0.0.1:8091", "1"))
+
+	s := newTestRedisRegistryService(t, &RedisConfig{ServerAddr: mr.Addr()})
+	defer s.Close()
+
+	instances, err := s.Lookup("default_tx_group")
+	require.NoError(t, err)
+	require.Len(t, instances, 1)
+	require.Eventually(t, func() bool {
+		return mr.PubSubNumSub(testRedisChannel)[testRedisChannel] == 1
+	}, time.Second, 10*time.Millisecond)
+	listener := &recordListener{}
+	require.NoError(t, s.Subscribe("default_tx_group", listener))
+	assert.Equal(t, []string{"ADD default_tx_group 127.0.0.1:8091"}, listener.take())
+
+	lookupPorts := func() []int {
+		instances, err := s.Lookup("default_tx_group")
+		require.NoError(t, err)
+		ports := make([]int, 0, len(instances))
+		for _, instance := range instances {
+			ports = append(ports, instance.Port)
+		}
+		return ports
+	}
+
+	mr.Publish(testRedisChannel, "127.0.0.1:8092-register")
+	assert.Eventually(t, func() bool { return assert.ObjectsAreEqual([]int{8091, 8092}, lookupPorts()) }, time.Second, 10*time.Millisecond)
+
+	// the duplicated register and the invalid messages are ignored
+	mr.Publish(testRedisChannel, "127.0.0.1:8092-register")
+	mr.Publish(testRedisChannel, "127.0.0.1:8093")
+	mr.Publish(testRedisChannel, "127.0.0.1:8093-unknown")
+	mr.Publish(testRedisChannel, "127.0.0.1:8091-unregister")
+	assert.Eventually(t, func() bool { return assert.ObjectsAreEqual([]int{8092}, lookupPorts()) }, time.Second, 10*time.Millisecond)
+	// the messages are notified to the subscribers as they come
+	events := make([]string, 0)
+	assert.Eventually(t, func() bool {
+		events = append(events, listener.take()...)
+		return len(events) >= 2
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, []string{"ADD default_tx_group 127.0.0.1:8092", "REMOVE default_tx_group 127.0.0.1:8091"}, events)
+}
+
+func TestRedisRegistryService_Refresh(t *testing.T) {
+	mr := miniredis.RunT(t)
+	require.NoError(t, mr.Set("registry.redis.default_127.0.0.1:8091", "1"))
+
+	s := newTestRedisRegistryService(t, &RedisConfig{
+		ServerAddr:      mr.Addr(),
+		RefreshInterval: 50 * time.Millisecond,
+	})
+	defer s.Close()
+
+	instances, err := s.Lookup("default_tx_group")
+	require.NoError(t, err)
+	require.Len(t, instances, 1)
+
+	// the key of the crashed tc expires without the unregister message
+	mr.Del("registry.redis.default_127.0.0.1:8091")
+	require.NoError(t, mr.Set("registry.redis.default_127.0.0.1:8092", "1"))
+	assert.Eventually(t, func() bool {
+		instances, err := s.Lookup("default_tx_group")
+		return err == nil && len(instances) == 1 && instances[0].Port == 8092
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestRedisRegistryService_Close(t *testing.T) {
+	mr := miniredis.RunT(t)
+	s := newTestRedisRegistryService(t, &RedisConfig{ServerAddr: mr.Addr()})
+
+	_, err := s.Lookup("default_tx_group")
+	require.NoError(t, err)
+	s.Close()
+
+	_, err = s.Lookup("other_tx_group")
+	assert.ErrorContains(t, err, "closed")
+
+	_, err = newRedisRegistryService(&ServiceConfig{}, &RedisConfig{})
+	assert.Error(t, err)
+}
@@ -24,6 +24,16 @@ func (s *SofaRegistryService) Lookup(key string) ([]*ServiceInstance, error) {
 	panic("implement me")
 }
 
+func (s *SofaRegistryService) Subscribe(key string, listener Listener) error {
+	//TODO implement me
+	panic("implement me")
+}
+
+func (s *SofaRegistryService) Unsubscribe(key string, listener Listener) error {
+	//TODO implement me
+	panic("implement me")
+}
+
 func (s *SofaRegistryService) Close() {
 	//TODO implement me
 	panic("implement me")
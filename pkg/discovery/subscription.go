@@ -0,0 +1,294 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package discovery
+
+import (
+	"fmt"
+	"net"
+	"reflect"
+	"strconv"
+	"sync"
+	"time"
+
+	"seata.apache.org/seata-go/pkg/util/log"
+)
+
+const defaultPollInterval = time.Second
+
+type EventType int
+
+const (
+	// EventTypeAdd the instance is added to the key
+	EventTypeAdd EventType = iota
+	// EventTypeRemove the instance is removed from the key
+	EventTypeRemove
+	// EventTypeUpdate the metadata of the instance is changed
+	EventTypeUpdate
+)
+
+func (t EventType) String() string {
+	switch t {
+	case EventTypeAdd:
+		return "ADD"
+	case EventTypeRemove:
+		return "REMOVE"
+	case EventTypeUpdate:
+		return "UPDATE"
+	default:
+		return "UNKNOWN"
+	}
+}
+
+// Event the change of a tc instance of the subscribed key
+type Event struct {
+	Type     EventType
+	Key      string
+	Instance *ServiceInstance
+}
+
+// Listener is notified of the instance changes of the subscribed keys. It is called synchronously
+// in the order of the changes, so it should return quickly and must not subscribe or unsubscribe
+// in OnEvent. The listener is compared on unsubscribing, so it should be a pointer.
+type Listener interface {
+	OnEvent(event Event)
+}
+
+// subscriptions keeps the listeners of the subscribed keys and the instances notified to them,
+// the changes are computed against the notified instances so all listeners see the same events.
+type subscriptions struct {
+	mu        sync.Mutex
+	listeners map[string][]Listener
+	// key -> ip:port -> instance
+	instances map[string]map[string]*ServiceInstance
+}
+
+func newSubscriptions() *subscriptions {
+	return &subscriptions{
+		listeners: make(map[string][]Listener),
+		instances: make(map[string]map[string]*ServiceInstance),
+	}
+}
+
+// subscribe applies the current instances of the key, then adds the listener and notifies
+// it of all the instances as the add events
+func (s *subscriptions) subscribe(key string, listener Listener, instances []*ServiceInstance) error {
+	if listener == nil {
+		return fmt.Errorf("listener is nil")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.listeners[key]; ok {
+		s.updateLocked(key, instances)
+	} else {
+		s.instances[key] = instanceSet(instances)
+	}
+	s.listeners[key] = append(s.listeners[key], listener)
+	for _, instance := range s.instances[key] {
+		listener.OnEvent(Event{Type: EventTypeAdd, Key: key, Instance: instance})
+	}
+	return nil
+}
+
+// unsubscribe removes the listener, it returns whether the key is still subscribed
+func (s *subscriptions) unsubscribe(key string, listener Listener) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	listeners := s.listeners[key]
+	for i, l := range listeners {
+		if l != listener {
+			continue
+		}
+		listeners = append(listeners[:i:i], listeners[i+1:]...)
+		if len(listeners) == 0 {
+			delete(s.listeners, key)
+			delete(s.instances, key)
+			return false, nil
+		}
+		s.listeners[key] = listeners
+		return true, nil
+	}
+	return len(listeners) > 0, fmt.Errorf("listener is not subscribed to key: %s", key)
+}
+
+// update notifies the listeners of the key of the changes from the instances notified last time
+func (s *subscriptions) update(key string, instances []*ServiceInstance) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.updateLocked(key, instances)
+}
+
+func (s *subscriptions) updateLocked(key string, instances []*ServiceInstance) {
+	listeners, ok := s.listeners[key]
+	if !ok {
+		return
+	}
+	old, current := s.instances[key], instanceSet(instances)
+	events := make([]Event, 0)
+	for addr, instance := range old {
+		if _, ok := current[addr]; !ok {
+			events = append(events, Event{Type: EventTypeRemove, Key: key, Instance: instance})
+		}
+	}
+	for addr, instance := range current {
+		if oldInstance, ok := old[addr]; !ok {
+			events = append(events, Event{Type: EventTypeAdd, Key: key, Instance: instance})
+		} else if !reflect.DeepEqual(oldInstance.Metadata, instance.Metadata) {
+			events = append(events, Event{Type: EventTypeUpdate, Key: key, Instance: instance})
+		}
+	}
+	s.instances[key] = current
+	for _, event := range events {
+		for _, listener := range listeners {
+			listener.OnEvent(event)
+		}
+	}
+}
+
+func (s *subscriptions) keys() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	keys := make([]string, 0, len(s.listeners))
+	for key := range s.listeners {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+func instanceSet(instances []*ServiceInstance) map[string]*ServiceInstance {
+	set := make(map[string]*ServiceInstance, len(instances))
+	for _, instance := range instances {
+		set[net.JoinHostPort(instance.Addr, strconv.Itoa(instance.Port))] = instance
+	}
+	return set
+}
+
+// poller notifies the changes of the subscribed keys by looking them up periodically, it serves
+// the registries which keep the instances fresh in background but do not push the changes.
+type poller struct {
+	lookup        func(key string) ([]*ServiceInstance, error)
+	interval      time.Duration
+	subscriptions *subscriptions
+	// pollLock orders the lookups and the notifications of them
+	pollLock sync.Mutex
+	// failed the keys whose last lookup failed, the failure is logged once
+	failed map[string]bool
+
+	stopCh    chan struct{}
+	closeOnce sync.Once
+	// startOnce starts polling once a key is subscribed
+	startOnce sync.Once
+	wg        sync.WaitGroup
+}
+
+func newPoller(lookup func(key string) ([]*ServiceInstance, error), interval time.Duration) *poller {
+	if interval <= 0 {
+		interval = defaultPollInterval
+	}
+	return &poller{
+		lookup:        lookup,
+		interval:      interval,
+		subscriptions: newSubscriptions(),
+		failed:        make(map[string]bool),
+		stopCh:        make(chan struct{}),
+	}
+}
+
+func (p *poller) Subscribe(key string, listener Listener) error {
+	p.pollLock.Lock()
+	defer p.pollLock.Unlock()
+	instances, err := p.lookup(key)
+	if err != nil {
+		return err
+	}
+	if err = p.subscriptions.subscribe(key, listener, instances); err != nil {
+		return err
+	}
+	p.startOnce.Do(func() {
+		p.wg.Add(1)
+		go p.pollLoop()
+	})
+	return nil
+}
+
+func (p *poller) Unsubscribe(key string, listener Listener) error {
+	_, err := p.subscriptions.unsubscribe(key, listener)
+	return err
+}
+
+func (p *poller) close() {
+	p.closeOnce.Do(func() {
+		close(p.stopCh)
+	})
+	// no polling is started after closed
+	p.startOnce.Do(func() {})
+	p.wg.Wait()
+}
+
+func (p *poller) pollLoop() {
+	defer p.wg.Done()
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.stopCh:
+			return
+		case <-ticker.C:
+			for _, key := range p.subscriptions.keys() {
+				p.poll(key)
+			}
+		}
+	}
+}
+
+func (p *poller) poll(key string) {
+	p.pollLock.Lock()
+	defer p.pollLock.Unlock()
+	instances, err := p.lookup(key)
+	if err != nil {
+		if !p.failed[key] {
+			log.Warnf("poll the instances of key %s failed: %v", key, err)
+			p.failed[key] = true
+		}
+		return
+	}
+	delete(p.failed, key)
+	p.subscriptions.update(key, instances)
+}
+
+// PollingRegistryService adapts the registry which can only be looked up to the subscription
+// api, the subscribed keys are looked up periodically and the changes are notified.
+type PollingRegistryService struct {
+	*poller
+	registry LookupService
+}
+
+func NewPollingRegistryService(registry LookupService, interval time.Duration) *PollingRegistryService {
+	return &PollingRegistryService{
+		poller:   newPoller(registry.Lookup, interval),
+		registry: registry,
+	}
+}
+
+func (s *PollingRegistryService) Lookup(key string) ([]*ServiceInstance, error) {
+	return s.registry.Lookup(key)
+}
+
+func (s *PollingRegistryService) Close() {
+	s.poller.close()
+	s.registry.Close()
+}
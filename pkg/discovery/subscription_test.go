@@ -0,0 +1,179 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package discovery
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordListener records the events in form of TYPE key ip:port
+type recordListener struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (l *recordListener) OnEvent(event Event) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.events = append(l.events, fmt.Sprintf("%s %s %s:%d", event.Type, event.Key, event.Instance.Addr, event.Instance.Port))
+}
+
+// take returns the sorted events recorded and resets them
+func (l *recordListener) take() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	events := l.events
+	l.events = nil
+	sort.Strings(events)
+	return events
+}
+
+// count returns the number of the events recorded
+func (l *recordListener) count() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.events)
+}
+
+func instancesOf(ports ...int) []*ServiceInstance {
+	instances := make([]*ServiceInstance, 0, len(ports))
+	for _, port := range ports {
+		instances = append(instances, &ServiceInstance{Addr: "127.0.0.1", Port: port})
+	}
+	return instances
+}
+
+func TestSubscriptions(t *testing.T) {
+	subs := newSubscriptions()
+	l1, l2 := &recordListener{}, &recordListener{}
+
+	assert.Error(t, subs.subscribe("key", nil, nil))
+	require.NoError(t, subs.subscribe("key", l1, instancesOf(8091, 8092)))
+	assert.Equal(t, []string{"ADD key 127.0.0.1:8091", "ADD key 127.0.0.1:8092"}, l1.take())
+
+	// the keys not subscribed are ignored
+	subs.update("other", instancesOf(8093))
+	assert.Empty(t, l1.take())
+
+	updated := instancesOf(8092, 8093)
+	updated[0].Metadata = map[string]string{MetadataWeight: "10"}
+	subs.update("key", updated)
+	assert.Equal(t, []string{"ADD key 127.0.0.1:8093", "REMOVE key 127.0.0.1:8091", "UPDATE key 127.0.0.1:8092"}, l1.take())
+	subs.update("key", updated)
+	assert.Empty(t, l1.take())
+
+	// the existing listener is notified of the changes before the new listener is added
+	require.NoError(t, subs.subscribe("key", l2, instancesOf(8093)))
+	assert.Equal(t, []string{"REMOVE key 127.0.0.1:8092"}, l1.take())
+	assert.Equal(t, []string{"ADD key 127.0.0.1:8093"}, l2.take())
+	assert.Equal(t, []string{"key"}, subs.keys())
+
+	subscribed, err := subs.unsubscribe("key", l1)
+	require.NoError(t, err)
+	assert.True(t, subscribed)
+	_, err = subs.unsubscribe("key", l1)
+	assert.Error(t, err)
+	subs.update("key", nil)
+	assert.Empty(t, l1.take())
+	assert.Equal(t, []string{"REMOVE key 127.0.0.1:8093"}, l2.take())
+
+	subscribed, err = subs.unsubscribe("key", l2)
+	require.NoError(t, err)
+	assert.False(t, subscribed)
+	assert.Empty(t, subs.keys())
+}
+
+// fakeLookupService returns the instances set by the test
+type fakeLookupService struct {
+	mu        sync.Mutex
+	instances []*ServiceInstance
+	err       error
+	closed    bool
+}
+
+func (s *fakeLookupService) Lookup(key string) ([]*ServiceInstance, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.instances, s.err
+}
+
+func (s *fakeLookupService) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+}
+
+func (s *fakeLookupService) set(instances []*ServiceInstance, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.instances, s.err = instances, err
+}
+
+func TestPollingRegistryService(t *testing.T) {
+	registry := &fakeLookupService{instances: instancesOf(8091)}
+	s := NewPollingRegistryService(registry, 10*time.Millisecond)
+	listener := &recordListener{}
+
+	registry.set(nil, errors.New("lookup failed"))
+	assert.Error(t, s.Subscribe("key", listener))
+	assert.Error(t, s.Unsubscribe("key", listener))
+
+	registry.set(instancesOf(8091), nil)
+	require.NoError(t, s.Subscribe("key", listener))
+	assert.Equal(t, []string{"ADD key 127.0.0.1:8091"}, listener.take())
+	instances, err := s.Lookup("key")
+	require.NoError(t, err)
+	assert.Len(t, instances, 1)
+
+	var events []string
+	take := func(n int) func() bool {
+		return func() bool {
+			events = append(events, listener.take()...)
+			return len(events) >= n
+		}
+	}
+
+	registry.set(instancesOf(8091, 8092), nil)
+	require.Eventually(t, take(1), time.Second, 5*time.Millisecond)
+	assert.Equal(t, []string{"ADD key 127.0.0.1:8092"}, events)
+
+	// the instances are kept while the lookup fails
+	events = nil
+	registry.set(nil, errors.New("lookup failed"))
+	time.Sleep(50 * time.Millisecond)
+	registry.set(instancesOf(8092), nil)
+	require.Eventually(t, take(1), time.Second, 5*time.Millisecond)
+	assert.Equal(t, []string{"REMOVE key 127.0.0.1:8091"}, events)
+
+	require.NoError(t, s.Unsubscribe("key", listener))
+	registry.set(instancesOf(8093), nil)
+	time.Sleep(50 * time.Millisecond)
+	assert.Empty(t, listener.take())
+
+	s.Close()
+	s.Close()
+	assert.True(t, registry.closed)
+}
@@ -17,14 +17,290 @@
 
 package discovery
 
-type ZkRegistryService struct{}
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-zookeeper/zk"
+
+	"seata.apache.org/seata-go/pkg/util/log"
+)
+
+const (
+	zkServerAddrSplitChar = ","
+	// zkRootPath the seata server registers itself as the ephemeral node <root>/<cluster>/ip:port
+	zkRootPath       = "/registry/zk"
+	zkAuthScheme     = "digest"
+	zkRetryInterval  = time.Second
+	zkDefaultTimeout = 2 * time.Second
+	zkDefaultSession = 6 * time.Second
+)
+
+// zkConn the operations of the zookeeper connection used by the registry
+type zkConn interface {
+	ChildrenW(path string) ([]string, *zk.Stat, <-chan zk.Event, error)
+	Close()
+}
+
+// ZkRegistryService discovers the tc instances registered to zookeeper by the seata server,
+// the children of the cluster node are watched so the membership changes are followed.
+type ZkRegistryService struct {
+	serviceConfig *ServiceConfig
+	conn          zkConn
+
+	// cluster -> instances
+	instances     map[string][]*ServiceInstance
+	rwLock        sync.RWMutex
+	subscriptions *subscriptions
+	// notifyLock orders the subscribing and the notifications of the watched changes
+	notifyLock sync.Mutex
+
+	stopCh    chan struct{}
+	closeOnce sync.Once
+	wg        sync.WaitGroup
+}
+
+func newZkRegistryService(config *ServiceConfig, zkConfig *ZkConfig) (RegistryService, error) {
+	if config == nil || zkConfig == nil {
+		return nil, fmt.Errorf("zk registry config is nil")
+	}
+	conn, events, err := connectZk(zkConfig)
+	if err != nil {
+		return nil, err
+	}
+	s := newZkRegistryServiceWithConn(config, conn)
+	s.wg.Add(1)
+	go s.logSessionEvents(events)
+	return s, nil
+}
+
+func newZkRegistryServiceWithConn(config *ServiceConfig, conn zkConn) *ZkRegistryService {
+	s := &ZkRegistryService{
+		serviceConfig: config,
+		conn:          conn,
+		instances:     make(map[string][]*ServiceInstance),
+		subscriptions: newSubscriptions(),
+		stopCh:        make(chan struct{}),
+	}
+	return s
+}
+
+// connectZk connects to the zookeeper ensemble and waits until the session is established,
+// the digest auth is added to the session if the username is configured.
+func connectZk(zkConfig *ZkConfig) (*zk.Conn, <-chan zk.Event, error) {
+	servers := make([]string, 0)
+	for _, addr := range strings.Split(zkConfig.ServerAddr, zkServerAddrSplitChar) {
+		if addr = strings.TrimSpace(addr); addr != "" {
+			servers = append(servers, addr)
+		}
+	}
+	if len(servers) == 0 {
+		return nil, nil, fmt.Errorf("zk registry server addr is empty")
+	}
+	sessionTimeout := zkConfig.SessionTimeout
+	if sessionTimeout <= 0 {
+		sessionTimeout = zkDefaultSession
+	}
+	connectTimeout := zkConfig.ConnectTimeout
+	if connectTimeout <= 0 {
+		connectTimeout = zkDefaultTimeout
+	}
+
+	conn, events, err := zk.Connect(servers, sessionTimeout, zk.WithLogger(zkLogger{}))
+	if err != nil {
+		return nil, nil, err
+	}
+	timer := time.NewTimer(connectTimeout)
+	defer timer.Stop()
+	for connected := false; !connected; {
+		select {
+		case event := <-events:
+			switch event.State {
+			case zk.StateHasSession:
+				connected = true
+			case zk.StateAuthFailed:
+				conn.Close()
+				return nil, nil, fmt.Errorf("zk auth failed, servers: %v", servers)
+			}
+		case <-timer.C:
+			conn.Close()
+			return nil, nil, fmt.Errorf("connect to zk timeout after %v, servers: %v", connectTimeout, servers)
+		}
+	}
+	if zkConfig.Username != "" {
+		auth := []byte(zkConfig.Username + ":" + zkConfig.Password)
+		if err = conn.AddAuth(zkAuthScheme, auth); err != nil {
+			conn.Close()
+			return nil, nil, fmt.Errorf("add zk digest auth failed: %w", err)
+		}
+	}
+	return conn, events, nil
+}
 
 func (s *ZkRegistryService) Lookup(key string) ([]*ServiceInstance, error) {
-	//TODO implement me
-	panic("implement me")
+	cluster := s.serviceConfig.VgroupMapping[key]
+	if cluster == "" {
+		return nil, fmt.Errorf("vgroup is empty. key: %s", key)
+	}
+	s.rwLock.RLock()
+	instances, ok := s.instances[cluster]
+	s.rwLock.RUnlock()
+	if ok {
+		return instances, nil
+	}
+
+	children, _, events, err := s.conn.ChildrenW(zkClusterPath(cluster))
+	if err != nil {
+		return nil, fmt.Errorf("get zk children of cluster %s failed: %w", cluster, err)
+	}
+	instances = zkInstances(children)
+	s.rwLock.Lock()
+	defer s.rwLock.Unlock()
+	if cached, ok := s.instances[cluster]; ok {
+		// watched by a concurrent lookup already
+		return cached, nil
+	}
+	s.instances[cluster] = instances
+	select {
+	case <-s.stopCh:
+	default:
+		s.wg.Add(1)
+		go s.watch(cluster, events)
+	}
+	return instances, nil
+}
+
+func (s *ZkRegistryService) Subscribe(key string, listener Listener) error {
+	s.notifyLock.Lock()
+	defer s.notifyLock.Unlock()
+	instances, err := s.Lookup(key)
+	if err != nil {
+		return err
+	}
+	return s.subscriptions.subscribe(key, listener, instances)
+}
+
+func (s *ZkRegistryService) Unsubscribe(key string, listener Listener) error {
+	_, err := s.subscriptions.unsubscribe(key, listener)
+	return err
+}
+
+// notify updates the subscriptions of the keys mapped to the cluster with its current instances
+func (s *ZkRegistryService) notify(cluster string) {
+	s.notifyLock.Lock()
+	defer s.notifyLock.Unlock()
+	s.rwLock.RLock()
+	instances := s.instances[cluster]
+	s.rwLock.RUnlock()
+	for key, c := range s.serviceConfig.VgroupMapping {
+		if c == cluster {
+			s.subscriptions.update(key, instances)
+		}
+	}
 }
 
 func (s *ZkRegistryService) Close() {
-	//TODO implement me
-	panic("implement me")
+	s.closeOnce.Do(func() {
+		s.rwLock.Lock()
+		close(s.stopCh)
+		s.rwLock.Unlock()
+		s.conn.Close()
+	})
+	s.wg.Wait()
+}
+
+// watch re-arms the child watch of the cluster node every time it fires, the watch fires once
+// the children change, or it is dropped when the session expires.
+func (s *ZkRegistryService) watch(cluster string, events <-chan zk.Event) {
+	defer s.wg.Done()
+	path := zkClusterPath(cluster)
+	for {
+		select {
+		case <-s.stopCh:
+			return
+		case event := <-events:
+			if event.Err != nil {
+				log.Warnf("zk watch of %s is dropped: %v", path, event.Err)
+			}
+		}
+
+		for {
+			children, _, next, err := s.conn.ChildrenW(path)
+			if err == nil {
+				s.update(cluster, zkInstances(children))
+				events = next
+				break
+			}
+			if errors.Is(err, zk.ErrClosing) || errors.Is(err, zk.ErrConnectionClosed) {
+				return
+			}
+			if errors.Is(err, zk.ErrNoNode) {
+				// the cluster node is removed, wait for the tc to register again
+				s.update(cluster, []*ServiceInstance{})
+			} else {
+				log.Warnf("watch zk children of %s failed: %v", path, err)
+			}
+			select {
+			case <-s.stopCh:
+				return
+			case <-time.After(zkRetryInterval):
+			}
+		}
+	}
+}
+
+func (s *ZkRegistryService) update(cluster string, instances []*ServiceInstance) {
+	s.rwLock.Lock()
+	if len(s.instances[cluster]) != len(instances) {
+		log.Infof("zk instances of cluster %s changed: %d -> %d", cluster, len(s.instances[cluster]), len(instances))
+	}
+	s.instances[cluster] = instances
+	s.rwLock.Unlock()
+	s.notify(cluster)
+}
+
+// logSessionEvents drains the session events until the connection is closed
+func (s *ZkRegistryService) logSessionEvents(events <-chan zk.Event) {
+	defer s.wg.Done()
+	for event := range events {
+		if event.Type != zk.EventSession {
+			continue
+		}
+		switch event.State {
+		case zk.StateExpired:
+			log.Warnf("zk session expired, server: %s", event.Server)
+		case zk.StateDisconnected:
+			log.Warnf("zk disconnected, server: %s", event.Server)
+		case zk.StateHasSession:
+			log.Infof("zk session established, server: %s", event.Server)
+		}
+	}
+}
+
+func zkClusterPath(cluster string) string {
+	return zkRootPath + "/" + cluster
+}
+
+// zkInstances parses the ip:port children of the cluster node, the invalid ones are skipped
+func zkInstances(children []string) []*ServiceInstance {
+	instances := make([]*ServiceInstance, 0, len(children))
+	for _, child := range children {
+		instance, err := parseServiceInstance(child)
+		if err != nil {
+			log.Warnf("invalid zk instance node %s: %v", child, err)
+			continue
+		}
+		instances = append(instances, instance)
+	}
+	return instances
+}
+
+// zkLogger routes the logs of the zookeeper client to the seata logger
+type zkLogger struct{}
+
+func (zkLogger) Printf(format string, args ...interface{}) {
+	log.Infof(format, args...)
 }
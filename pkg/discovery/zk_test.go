@@ -0,0 +1,251 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package discovery
+
+import (
+	"net"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-zookeeper/zk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeZkConn keeps the children of the nodes in memory and fires the child watches like zookeeper
+type fakeZkConn struct {
+	mu       sync.Mutex
+	children map[string][]string
+	watchers map[string][]chan zk.Event
+	calls    int
+	closed   bool
+}
+
+func newFakeZkConn() *fakeZkConn {
+	return &fakeZkConn{
+		children: map[string][]string{},
+		watchers: map[string][]chan zk.Event{},
+	}
+}
+
+func (c *fakeZkConn) ChildrenW(path string) ([]string, *zk.Stat, <-chan zk.Event, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.calls++
+	if c.closed {
+		return nil, nil, nil, zk.ErrClosing
+	}
+	children, ok := c.children[path]
+	if !ok {
+		return nil, nil, nil, zk.ErrNoNode
+	}
+	ch := make(chan zk.Event, 1)
+	c.watchers[path] = append(c.watchers[path], ch)
+	return append([]string{}, children...), &zk.Stat{}, ch, nil
+}
+
+func (c *fakeZkConn) Close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.closed = true
+	c.fire(zk.Event{Type: zk.EventNotWatching, Err: zk.ErrClosing}, "")
+}
+
+func (c *fakeZkConn) setChildren(path string, children ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.children[path] = children
+	c.fire(zk.Event{Type: zk.EventNodeChildrenChanged, Path: path}, path)
+}
+
+func (c *fakeZkConn) deleteNode(path string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.children, path)
+	c.fire(zk.Event{Type: zk.EventNodeDeleted, Path: path}, path)
+}
+
+// expire drops all the watches like the session expiration does
+func (c *fakeZkConn) expire() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.fire(zk.Event{Type: zk.EventNotWatching, Err: zk.ErrSessionExpired}, "")
+}
+
+func (c *fakeZkConn) fire(event zk.Event, path string) {
+	for p, watchers := range c.watchers {
+		if path != "" && p != path {
+			continue
+		}
+		for _, ch := range watchers {
+			event.Path = p
+			ch <- event
+			close(ch)
+		}
+		delete(c.watchers, p)
+	}
+}
+
+func (c *fakeZkConn) watching(path string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.watchers[path]) > 0
+}
+
+func newTestZkRegistryService(conn zkConn) *ZkRegistryService {
+	return newZkRegistryServiceWithConn(&ServiceConfig{
+		VgroupMapping: map[string]string{"default_tx_group": "default"},
+	}, conn)
+}
+
+func TestZkRegistryService_Lookup(t *testing.T) {
+	conn := newFakeZkConn()
+	conn.setChildren("/registry/zk/default", "127.0.0.1:8091", "invalid", "127.0.0.1:port", "[::1]:8092")
+	s := newTestZkRegistryService(conn)
+	defer s.Close()
+
+	instances, err := s.Lookup("default_tx_group")
+	require.NoError(t, err)
+	require.Len(t, instances, 2)
+	assert.Equal(t, "127.0.0.1", instances[0].Addr)
+	assert.Equal(t, 8091, instances[0].Port)
+	assert.Equal(t, "::1", instances[1].Addr)
+	assert.Equal(t, 8092, instances[1].Port)
+
+	// served from the cache
+	_, err = s.Lookup("default_tx_group")
+	require.NoError(t, err)
+	conn.mu.Lock()
+	assert.Equal(t, 1, conn.calls)
+	conn.mu.Unlock()
+
+	_, err = s.Lookup("unknown_group")
+	assert.Error(t, err)
+}
+
+func TestZkRegistryService_LookupNoNode(t *testing.T) {
+	s := newTestZkRegistryService(newFakeZkConn())
+	defer s.Close()
+
+	_, err := s.Lookup("default_tx_group")
+	assert.ErrorIs(t, err, zk.ErrNoNode)
+}
+
+func TestZkRegistryService_Watch(t *testing.T) {
+	conn := newFakeZkConn()
+	conn.setChildren("/registry/zk/default", "127.0.0.1:8091")
+	s := newTestZkRegistryService(conn)
+	defer s.Close()
+
+	instances, err := s.Lookup("default_tx_group")
+	require.NoError(t, err)
+	require.Len(t, instances, 1)
+
+	lookupLen := func() int {
+		instances, err := s.Lookup("default_tx_group")
+		require.NoError(t, err)
+		return len(instances)
+	}
+
+	conn.setChildren("/registry/zk/default", "127.0.0.1:8091", "127.0.0.1:8092")
+	assert.Eventually(t, func() bool { return lookupLen() == 2 }, time.Second, 10*time.Millisecond)
+
+	// the watch is re-armed after the session expiration drops it
+	assert.Eventually(t, func() bool { return conn.watching("/registry/zk/default") }, time.Second, 10*time.Millisecond)
+	conn.expire()
+	assert.Eventually(t, func() bool { return conn.watching("/registry/zk/default") }, time.Second, 10*time.Millisecond)
+	conn.setChildren("/registry/zk/default", "127.0.0.1:8093")
+	assert.Eventually(t, func() bool { return lookupLen() == 1 }, time.Second, 10*time.Millisecond)
+
+	// the cluster node is removed and registered again
+	conn.deleteNode("/registry/zk/default")
+	assert.Eventually(t, func() bool { return lookupLen() == 0 }, time.Second, 10*time.Millisecond)
+	conn.setChildren("/registry/zk/default", "127.0.0.1:8091", "127.0.0.1:8092")
+	assert.Eventually(t, func() bool { return lookupLen() == 2 }, 3*time.Second, 10*time.Millisecond)
+}
+
+func TestZkRegistryService_Close(t *testing.T) {
+	conn := newFakeZkConn()
+	conn.setChildren("/registry/zk/default", "127.0.0.1:8091")
+	s := newTestZkRegistryService(conn)
+
+	_, err := s.Lookup("default_tx_group")
+	require.NoError(t, err)
+
+	done := make(chan struct{})
+	go func() {
+		s.Close()
+		s.Close()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("close is blocked by the watch")
+	}
+	assert.True(t, conn.closed)
+}
+
+func TestConnectZk(t *testing.T) {
+	_, _, err := connectZk(&ZkConfig{ServerAddr: " , "})
+	assert.Error(t, err)
+
+	// the server accepts the connection but never establishes the session
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	_, _, err = connectZk(&ZkConfig{
+		ServerAddr:     listener.Addr().String(),
+		SessionTimeout: time.Second,
+		ConnectTimeout: 200 * time.Millisecond,
+	})
+	assert.ErrorContains(t, err, "timeout")
+}
+
+func TestZkRegistryService_Subscribe(t *testing.T) {
+	conn := newFakeZkConn()
+	conn.setChildren("/registry/zk/default", "127.0.0.1:8091")
+	s := newTestZkRegistryService(conn)
+	defer s.Close()
+
+	listener := &recordListener{}
+	require.NoError(t, s.Subscribe("default_tx_group", listener))
+	assert.Equal(t, []string{"ADD default_tx_group 127.0.0.1:8091"}, listener.take())
+
+	// the changes are notified by the watch as soon as it fires
+	conn.setChildren("/registry/zk/default", "127.0.0.1:8092")
+	events := make([]string, 0)
+	assert.Eventually(t, func() bool {
+		events = append(events, listener.take()...)
+		return len(events) >= 2
+	}, 500*time.Millisecond, 10*time.Millisecond)
+	sort.Strings(events)
+	assert.Equal(t, []string{"ADD default_tx_group 127.0.0.1:8092", "REMOVE default_tx_group 127.0.0.1:8091"}, events)
+}
@@ -47,17 +47,29 @@ var (
 	onceCodecManager = &sync.Once{}
 )
 
+// GetCodecManager returns the codec manager used by the default remoting client
 func GetCodecManager() *CodecManager {
 	if codecManager == nil {
 		onceCodecManager.Do(func() {
-			codecManager = &CodecManager{
-				codecMap: make(map[CodecType]map[message.MessageType]Codec, 0),
-			}
+			codecManager = newCodecManager()
 		})
 	}
 	return codecManager
 }
 
+// NewCodecManager creates a codec manager with all the codecs of the seata protocol registered
+func NewCodecManager() *CodecManager {
+	manager := newCodecManager()
+	registerCodecs(manager)
+	return manager
+}
+
+func newCodecManager() *CodecManager {
+	return &CodecManager{
+		codecMap: make(map[CodecType]map[message.MessageType]Codec, 0),
+	}
+}
+
 type CodecManager struct {
 	mutex    sync.Mutex
 	codecMap map[CodecType]map[message.MessageType]Codec
@@ -113,34 +125,39 @@ func (c *CodecManager) Encode(codecType CodecType, in interface{}) []byte {
 }
 
 func Init() {
+	registerCodecs(GetCodecManager())
+}
+
+func registerCodecs(manager *CodecManager) {
 	// Global
-	GetCodecManager().RegisterCodec(CodecTypeSeata, &GlobalReportResponseCodec{})
-	GetCodecManager().RegisterCodec(CodecTypeSeata, &GlobalBeginRequestCodec{})
-	GetCodecManager().RegisterCodec(CodecTypeSeata, &GlobalBeginResponseCodec{})
-	GetCodecManager().RegisterCodec(CodecTypeSeata, &GlobalCommitRequestCodec{})
-	GetCodecManager().RegisterCodec(CodecTypeSeata, &GlobalCommitResponseCodec{})
-	GetCodecManager().RegisterCodec(CodecTypeSeata, &GlobalLockQueryRequestCodec{})
-	GetCodecManager().RegisterCodec(CodecTypeSeata, &GlobalLockQueryResponseCodec{})
-	GetCodecManager().RegisterCodec(CodecTypeSeata, &GlobalRollbackRequestCodec{})
-	GetCodecManager().RegisterCodec(CodecTypeSeata, &GlobalRollbackResponseCodec{})
-	GetCodecManager().RegisterCodec(CodecTypeSeata, &GlobalStatusRequestCodec{})
-	GetCodecManager().RegisterCodec(CodecTypeSeata, &GlobalStatusResponseCodec{})
+	manager.RegisterCodec(CodecTypeSeata, &GlobalReportResponseCodec{})
+	manager.RegisterCodec(CodecTypeSeata, &GlobalBeginRequestCodec{})
+	manager.RegisterCodec(CodecTypeSeata, &GlobalBeginResponseCodec{})
+	manager.RegisterCodec(CodecTypeSeata, &GlobalCommitRequestCodec{})
+	manager.RegisterCodec(CodecTypeSeata, &GlobalCommitResponseCodec{})
+	manager.RegisterCodec(CodecTypeSeata, &GlobalLockQueryRequestCodec{})
+	manager.RegisterCodec(CodecTypeSeata, &GlobalLockQueryResponseCodec{})
+	manager.RegisterCodec(CodecTypeSeata, &GlobalRollbackRequestCodec{})
+	manager.RegisterCodec(CodecTypeSeata, &GlobalRollbackResponseCodec{})
+	manager.RegisterCodec(CodecTypeSeata, &GlobalStatusRequestCodec{})
+	manager.RegisterCodec(CodecTypeSeata, &GlobalStatusResponseCodec{})
 
 	// Branch
-	GetCodecManager().RegisterCodec(CodecTypeSeata, &BranchCommitRequestCodec{})
-	GetCodecManager().RegisterCodec(CodecTypeSeata, &BranchCommitResponseCodec{})
-	GetCodecManager().RegisterCodec(CodecTypeSeata, &BranchRegisterRequestCodec{})
-	GetCodecManager().RegisterCodec(CodecTypeSeata, &BranchRegisterResponseCodec{})
-	GetCodecManager().RegisterCodec(CodecTypeSeata, &BranchReportRequestCodec{})
-	GetCodecManager().RegisterCodec(CodecTypeSeata, &BranchRollbackRequestCodec{})
-	GetCodecManager().RegisterCodec(CodecTypeSeata, &BranchRollbackResponseCodec{})
-	GetCodecManager().RegisterCodec(CodecTypeSeata, &BranchReportResponseCodec{})
+	manager.RegisterCodec(CodecTypeSeata, &BranchCommitRequestCodec{})
+	manager.RegisterCodec(CodecTypeSeata, &BranchCommitResponseCodec{})
+	manager.RegisterCodec(CodecTypeSeata, &BranchRegisterRequestCodec{})
+	manager.RegisterCodec(CodecTypeSeata, &BranchRegisterResponseCodec{})
+	manager.RegisterCodec(CodecTypeSeata, &BranchReportRequestCodec{})
+	manager.RegisterCodec(CodecTypeSeata, &BranchRollbackRequestCodec{})
+	manager.RegisterCodec(CodecTypeSeata, &BranchRollbackResponseCodec{})
+	manager.RegisterCodec(CodecTypeSeata, &BranchReportResponseCodec{})
 
 	// RM
-	GetCodecManager().RegisterCodec(CodecTypeSeata, &RegisterRMRequestCodec{})
-	GetCodecManager().RegisterCodec(CodecTypeSeata, &RegisterRMResponseCodec{})
+	manager.RegisterCodec(CodecTypeSeata, &RegisterRMRequestCodec{})
+	manager.RegisterCodec(CodecTypeSeata, &RegisterRMResponseCodec{})
+	manager.RegisterCodec(CodecTypeSeata, &UndoLogDeleteRequestCodec{})
 
 	// TM
-	GetCodecManager().RegisterCodec(CodecTypeSeata, &RegisterTMRequestCodec{})
-	GetCodecManager().RegisterCodec(CodecTypeSeata, &RegisterTMResponseCodec{})
+	manager.RegisterCodec(CodecTypeSeata, &RegisterTMRequestCodec{})
+	manager.RegisterCodec(CodecTypeSeata, &RegisterTMResponseCodec{})
 }
@@ -22,6 +22,14 @@ type AbstractResultMessage struct {
 	Msg        string
 }
 
+func (m AbstractResultMessage) GetResultCode() ResultCode {
+	return m.ResultCode
+}
+
+func (m AbstractResultMessage) GetMsg() string {
+	return m.Msg
+}
+
 type AbstractIdentifyRequest struct {
 	Version                 string
 	ApplicationId           string `json:"applicationId"`
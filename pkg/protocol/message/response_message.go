@@ -27,6 +27,10 @@ type AbstractTransactionResponse struct {
 	TransactionErrorCode errors.TransactionErrorCode
 }
 
+func (resp AbstractTransactionResponse) GetTransactionErrorCode() errors.TransactionErrorCode {
+	return resp.TransactionErrorCode
+}
+
 type AbstractBranchEndResponse struct {
 	AbstractTransactionResponse
 	Xid          string
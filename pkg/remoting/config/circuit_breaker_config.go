@@ -0,0 +1,43 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package config
+
+import (
+	"flag"
+	"time"
+)
+
+// CircuitBreakerConfig the per tc node circuit breaker config
+type CircuitBreakerConfig struct {
+	Enable           bool          `yaml:"enable" json:"enable" koanf:"enable"`
+	FailureThreshold int           `yaml:"failure-threshold" json:"failure-threshold" koanf:"failure-threshold"`
+	TimeoutRatio     float64       `yaml:"timeout-ratio" json:"timeout-ratio" koanf:"timeout-ratio"`
+	WindowSize       int           `yaml:"window-size" json:"window-size" koanf:"window-size"`
+	MinRequests      int           `yaml:"min-requests" json:"min-requests" koanf:"min-requests"`
+	Cooldown         time.Duration `yaml:"cooldown" json:"cooldown" koanf:"cooldown"`
+}
+
+// RegisterFlagsWithPrefix for CircuitBreakerConfig.
+func (cfg *CircuitBreakerConfig) RegisterFlagsWithPrefix(prefix string, f *flag.FlagSet) {
+	f.BoolVar(&cfg.Enable, prefix+".enable", true, "Whether eject the failing tc node from session selection.")
+	f.IntVar(&cfg.FailureThreshold, prefix+".failure-threshold", 5, "The consecutive rpc failures to eject a tc node.")
+	f.Float64Var(&cfg.TimeoutRatio, prefix+".timeout-ratio", 0.5, "The ratio of timeout requests in the window to eject a tc node.")
+	f.IntVar(&cfg.WindowSize, prefix+".window-size", 20, "The count of latest requests used to calculate the timeout ratio.")
+	f.IntVar(&cfg.MinRequests, prefix+".min-requests", 10, "The minimum requests in the window before the timeout ratio is checked.")
+	f.DurationVar(&cfg.Cooldown, prefix+".cooldown", 30*time.Second, "The duration an ejected tc node is kept out before a probe request is allowed.")
+}
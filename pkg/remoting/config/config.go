@@ -18,7 +18,10 @@
 package config
 
 import (
+	"errors"
 	"flag"
+	"fmt"
+	"strings"
 	"time"
 
 	"seata.apache.org/seata-go/pkg/util/flagext"
@@ -30,7 +33,10 @@ type Config struct {
 	ReconnectInterval int           `yaml:"reconnect-interval" json:"reconnect-interval" koanf:"reconnect-interval"`
 	ConnectionNum     int           `yaml:"connection-num" json:"connection-num" koanf:"connection-num"`
 	LoadBalanceType   string        `yaml:"load-balance-type" json:"load-balance-type" koanf:"load-balance-type"`
+	Zone              string        `yaml:"zone" json:"zone" koanf:"zone"`
 	SessionConfig     SessionConfig `yaml:"session" json:"session" koanf:"session"`
+
+	CircuitBreakerConfig CircuitBreakerConfig `yaml:"circuit-breaker" json:"circuit-breaker" koanf:"circuit-breaker"`
 }
 
 // RegisterFlagsWithPrefix for Config.
@@ -38,11 +44,13 @@ func (cfg *Config) RegisterFlagsWithPrefix(prefix string, f *flag.FlagSet) {
 	f.IntVar(&cfg.ReconnectInterval, prefix+".reconnect-interval", 0, "Reconnect interval.")
 	f.IntVar(&cfg.ConnectionNum, prefix+".connection-num", 1, "The getty_session pool.")
 	f.StringVar(&cfg.LoadBalanceType, prefix+".load-balance-type", "XID", "default load balance type")
+	f.StringVar(&cfg.Zone, prefix+".zone", "", "The availability zone of the client, the tc nodes in the same zone are preferred by ZoneAffinityLoadBalance.")
 	cfg.SessionConfig.RegisterFlagsWithPrefix(prefix+".session", f)
+	cfg.CircuitBreakerConfig.RegisterFlagsWithPrefix(prefix+".circuit-breaker", f)
 }
 
 type ShutdownConfig struct {
-	Wait time.Duration `yaml:"wait" json:"wait" konaf:"wait"`
+	Wait time.Duration `yaml:"wait" json:"wait" koanf:"wait"`
 }
 
 func (cfg *ShutdownConfig) RegisterFlagsWithPrefix(prefix string, f *flag.FlagSet) {
@@ -75,6 +83,19 @@ func (cfg *TransportConfig) RegisterFlagsWithPrefix(prefix string, f *flag.FlagS
 	f.DurationVar(&cfg.RPCTmRequestTimeout, prefix+".rpc-tm-request-timeout", 30*time.Second, "TM send request timeout.")
 }
 
+// ValidateWithPrefix checks the serialization and the compressor are supported by the getty
+// transport, which only encodes the messages by the seata codec without compression for now
+func (cfg *TransportConfig) ValidateWithPrefix(prefix string) error {
+	var errs []error
+	if !strings.EqualFold(cfg.Serialization, "seata") {
+		errs = append(errs, fmt.Errorf("%s.serialization: unsupported serialization %q", prefix, cfg.Serialization))
+	}
+	if !strings.EqualFold(cfg.Compressor, "none") {
+		errs = append(errs, fmt.Errorf("%s.compressor: unsupported compressor %q", prefix, cfg.Compressor))
+	}
+	return errors.Join(errs...)
+}
+
 // todo refactor config
 type SeataConfig struct {
 	ApplicationID        string
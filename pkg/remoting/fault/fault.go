@@ -0,0 +1,301 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package fault injects faults into the messages between the getty client and the tc, so that
+// the retry and compensation logic can be tested against a misbehaving tc. The faults are driven
+// by rules matched on the message type and the xid, e.g.
+//
+//	rules:
+//	  - name: commit-unreachable
+//	    message-types: [GlobalCommit]
+//	    action: error
+//	    times: 2
+//
+// and enabled on the getty client by getty.SetFaultInjector.
+package fault
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"os"
+	"path"
+	"reflect"
+	"strconv"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v2"
+
+	"seata.apache.org/seata-go/pkg/protocol/message"
+	serror "seata.apache.org/seata-go/pkg/util/errors"
+)
+
+// ErrInjected is wrapped by the errors caused by the injected faults
+var ErrInjected = errors.New("injected fault")
+
+type Action string
+
+const (
+	// ActionDrop drops the message, the sync request waits until it is timeout
+	ActionDrop Action = "drop"
+	// ActionDelay delays the message for the delay of the rule
+	ActionDelay Action = "delay"
+	// ActionDuplicate delivers the message twice
+	ActionDuplicate Action = "duplicate"
+	// ActionReorder holds the message until the next message of the same session is delivered,
+	// or the delay of the rule elapses, one second by default
+	ActionReorder Action = "reorder"
+	// ActionFail replaces the response by a failed one with the error code and msg of the rule
+	ActionFail Action = "fail"
+	// ActionError fails to send the request with an error wrapping ErrInjected
+	ActionError Action = "error"
+	// ActionClose closes the session which the message is sent or received by
+	ActionClose Action = "close"
+)
+
+type Direction string
+
+const (
+	// DirectionSend the messages sent by the client, the default direction
+	DirectionSend Direction = "send"
+	// DirectionReceive the messages received by the client
+	DirectionReceive Direction = "receive"
+)
+
+const defaultReorderWait = time.Second
+
+// Rule decides which messages the fault is injected into and how
+type Rule struct {
+	// Name identifies the rule in the logs and Hits
+	Name      string    `yaml:"name" json:"name"`
+	Direction Direction `yaml:"direction" json:"direction"`
+	// MessageTypes the type names without the MessageType prefix, e.g. GlobalCommit and
+	// GlobalCommitResult, or the type codes. Empty matches all messages except the heartbeats.
+	MessageTypes []string `yaml:"message-types" json:"message-types"`
+	// Xid the pattern of the xid in the syntax of path.Match, empty matches all messages
+	Xid    string        `yaml:"xid" json:"xid"`
+	Action Action        `yaml:"action" json:"action"`
+	Delay  time.Duration `yaml:"delay" json:"delay"`
+	// Probability the probability the fault is injected into a matched message, 0 means always
+	Probability float64 `yaml:"probability" json:"probability"`
+	// Times the max times the fault is injected, 0 means unlimited
+	Times     int                         `yaml:"times" json:"times"`
+	ErrorCode serror.TransactionErrorCode `yaml:"error-code" json:"error-code"`
+	Msg       string                      `yaml:"msg" json:"msg"`
+}
+
+type rule struct {
+	Rule
+	types map[message.MessageType]struct{}
+	hits  int
+}
+
+func (r *rule) match(direction Direction, msgType message.MessageType, xid string) bool {
+	if r.Direction != direction {
+		return false
+	}
+	if len(r.types) == 0 {
+		if msgType == message.MessageTypeHeartbeatMsg {
+			return false
+		}
+	} else if _, ok := r.types[msgType]; !ok {
+		return false
+	}
+	if r.Xid != "" {
+		if ok, _ := path.Match(r.Xid, xid); !ok {
+			return false
+		}
+	}
+	return r.Times <= 0 || r.hits < r.Times
+}
+
+// Injector matches the messages against the rules in order, the first matched rule wins
+type Injector struct {
+	mu    sync.Mutex
+	rules []*rule
+	rand  *rand.Rand
+}
+
+type rulesConfig struct {
+	Rules []Rule `yaml:"rules"`
+}
+
+func NewInjector(rules ...Rule) (*Injector, error) {
+	injector := &Injector{rand: rand.New(rand.NewSource(time.Now().UnixNano()))}
+	for i, r := range rules {
+		compiled, err := compile(r)
+		if err != nil {
+			return nil, fmt.Errorf("fault rule %d %s: %w", i, r.Name, err)
+		}
+		injector.rules = append(injector.rules, compiled)
+	}
+	return injector, nil
+}
+
+// Load creates the injector by the rules in yaml
+func Load(data []byte) (*Injector, error) {
+	var cfg rulesConfig
+	if err := yaml.UnmarshalStrict(data, &cfg); err != nil {
+		return nil, err
+	}
+	return NewInjector(cfg.Rules...)
+}
+
+// LoadFile creates the injector by the rules in the yaml file
+func LoadFile(file string) (*Injector, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	return Load(data)
+}
+
+func compile(r Rule) (*rule, error) {
+	if r.Direction == "" {
+		r.Direction = DirectionSend
+	}
+	if r.Direction != DirectionSend && r.Direction != DirectionReceive {
+		return nil, fmt.Errorf("unknown direction %s", r.Direction)
+	}
+	switch r.Action {
+	case ActionDrop, ActionDuplicate, ActionFail, ActionClose:
+	case ActionDelay:
+		if r.Delay <= 0 {
+			return nil, fmt.Errorf("the delay of action delay should be positive")
+		}
+	case ActionReorder:
+		if r.Delay <= 0 {
+			r.Delay = defaultReorderWait
+		}
+	case ActionError:
+		if r.Direction != DirectionSend {
+			return nil, fmt.Errorf("action error only applies to the messages sent")
+		}
+	default:
+		return nil, fmt.Errorf("unknown action %q", r.Action)
+	}
+	if r.Probability < 0 || r.Probability > 1 {
+		return nil, fmt.Errorf("probability %v is out of [0, 1]", r.Probability)
+	}
+	if _, err := path.Match(r.Xid, ""); err != nil {
+		return nil, fmt.Errorf("xid pattern %s: %w", r.Xid, err)
+	}
+	compiled := &rule{Rule: r, types: make(map[message.MessageType]struct{}, len(r.MessageTypes))}
+	for _, name := range r.MessageTypes {
+		msgType, err := parseMessageType(name)
+		if err != nil {
+			return nil, err
+		}
+		compiled.types[msgType] = struct{}{}
+	}
+	return compiled, nil
+}
+
+// Match returns the rule of the fault to inject into the message, the times of the rule is
+// consumed if it is matched
+func (i *Injector) Match(direction Direction, body interface{}) (Rule, bool) {
+	typeAware, ok := body.(message.MessageTypeAware)
+	if !ok {
+		return Rule{}, false
+	}
+	msgType, xid := typeAware.GetTypeCode(), xidOf(body)
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	for _, r := range i.rules {
+		if !r.match(direction, msgType, xid) {
+			continue
+		}
+		if r.Probability > 0 && i.rand.Float64() >= r.Probability {
+			continue
+		}
+		r.hits++
+		return r.Rule, true
+	}
+	return Rule{}, false
+}
+
+// Hits returns the times the faults of the rule are injected
+func (i *Injector) Hits(name string) int {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	hits := 0
+	for _, r := range i.rules {
+		if r.Name == name {
+			hits += r.hits
+		}
+	}
+	return hits
+}
+
+// xidOf returns the Xid field of the message, the field of the embedded struct included
+func xidOf(body interface{}) string {
+	v := reflect.ValueOf(body)
+	if v.Kind() == reflect.Ptr {
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return ""
+	}
+	if f := v.FieldByName("Xid"); f.IsValid() && f.Kind() == reflect.String {
+		return f.String()
+	}
+	return ""
+}
+
+var messageTypeNames = map[string]message.MessageType{
+	"GlobalBegin":              message.MessageTypeGlobalBegin,
+	"GlobalBeginResult":        message.MessageTypeGlobalBeginResult,
+	"BranchCommit":             message.MessageTypeBranchCommit,
+	"BranchCommitResult":       message.MessageTypeBranchCommitResult,
+	"BranchRollback":           message.MessageTypeBranchRollback,
+	"BranchRollbackResult":     message.MessageTypeBranchRollbackResult,
+	"GlobalCommit":             message.MessageTypeGlobalCommit,
+	"GlobalCommitResult":       message.MessageTypeGlobalCommitResult,
+	"GlobalRollback":           message.MessageTypeGlobalRollback,
+	"GlobalRollbackResult":     message.MessageTypeGlobalRollbackResult,
+	"BranchRegister":           message.MessageTypeBranchRegister,
+	"BranchRegisterResult":     message.MessageTypeBranchRegisterResult,
+	"BranchStatusReport":       message.MessageTypeBranchStatusReport,
+	"BranchStatusReportResult": message.MessageTypeBranchStatusReportResult,
+	"GlobalStatus":             message.MessageTypeGlobalStatus,
+	"GlobalStatusResult":       message.MessageTypeGlobalStatusResult,
+	"GlobalReport":             message.MessageTypeGlobalReport,
+	"GlobalReportResult":       message.MessageTypeGlobalReportResult,
+	"GlobalLockQuery":          message.MessageTypeGlobalLockQuery,
+	"GlobalLockQueryResult":    message.MessageTypeGlobalLockQueryResult,
+	"SeataMerge":               message.MessageTypeSeataMerge,
+	"SeataMergeResult":         message.MessageTypeSeataMergeResult,
+	"RegClt":                   message.MessageTypeRegClt,
+	"RegCltResult":             message.MessageTypeRegCltResult,
+	"RegRm":                    message.MessageTypeRegRm,
+	"RegRmResult":              message.MessageTypeRegRmResult,
+	"RmDeleteUndolog":          message.MessageTypeRmDeleteUndolog,
+	"HeartbeatMsg":             message.MessageTypeHeartbeatMsg,
+	"BatchResultMsg":           message.MessageTypeBatchResultMsg,
+}
+
+func parseMessageType(name string) (message.MessageType, error) {
+	if msgType, ok := messageTypeNames[name]; ok {
+		return msgType, nil
+	}
+	if code, err := strconv.Atoi(name); err == nil {
+		return message.MessageType(code), nil
+	}
+	return 0, fmt.Errorf("unknown message type %s", name)
+}
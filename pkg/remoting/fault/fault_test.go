@@ -0,0 +1,156 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package fault
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"seata.apache.org/seata-go/pkg/protocol/message"
+	serror "seata.apache.org/seata-go/pkg/util/errors"
+)
+
+func TestLoadFile(t *testing.T) {
+	injector, err := LoadFile("testdata/faults.yml")
+	require.NoError(t, err)
+	require.Len(t, injector.rules, 3)
+
+	commit := injector.rules[0]
+	assert.Equal(t, "commit-unreachable", commit.Name)
+	assert.Equal(t, DirectionSend, commit.Direction)
+	assert.Equal(t, ActionError, commit.Action)
+	assert.Equal(t, 2, commit.Times)
+	assert.Equal(t, map[message.MessageType]struct{}{message.MessageTypeGlobalCommit: {}}, commit.types)
+
+	report := injector.rules[1]
+	assert.Equal(t, ActionDelay, report.Action)
+	assert.Equal(t, 200*time.Millisecond, report.Delay)
+	assert.Equal(t, "127.0.0.1:8091:*", report.Xid)
+
+	failed := injector.rules[2]
+	assert.Equal(t, DirectionReceive, failed.Direction)
+	assert.Equal(t, serror.TransactionErrorCodeBranchReportFailed, failed.ErrorCode)
+	assert.Equal(t, "branch report failed", failed.Msg)
+	assert.Equal(t, 0.5, failed.Probability)
+	assert.Len(t, failed.types, 1)
+
+	_, err = LoadFile("testdata/unknown.yml")
+	assert.Error(t, err)
+	_, err = Load([]byte("rules:\n  - action: drop\n    unknown: 1\n"))
+	assert.Error(t, err)
+}
+
+func TestNewInjector_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		rule Rule
+	}{
+		{name: "unknown action", rule: Rule{Action: "panic"}},
+		{name: "unknown direction", rule: Rule{Action: ActionDrop, Direction: "both"}},
+		{name: "delay without duration", rule: Rule{Action: ActionDelay}},
+		{name: "error on receive", rule: Rule{Action: ActionError, Direction: DirectionReceive}},
+		{name: "probability out of range", rule: Rule{Action: ActionDrop, Probability: 1.5}},
+		{name: "bad xid pattern", rule: Rule{Action: ActionDrop, Xid: "["}},
+		{name: "unknown message type", rule: Rule{Action: ActionDrop, MessageTypes: []string{"GlobalUnknown"}}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := NewInjector(test.rule)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestInjector_Match(t *testing.T) {
+	injector, err := NewInjector(
+		Rule{Name: "commit", MessageTypes: []string{"GlobalCommit"}, Xid: "tc:8091:1*", Action: ActionDrop, Times: 1},
+		Rule{Name: "receive", Direction: DirectionReceive, Action: ActionReorder},
+		Rule{Name: "never", Action: ActionDuplicate, Probability: 0.000001},
+		Rule{Name: "heartbeat", MessageTypes: []string{"HeartbeatMsg"}, Action: ActionDelay, Delay: time.Second},
+	)
+	require.NoError(t, err)
+
+	commit := message.GlobalCommitRequest{AbstractGlobalEndRequest: message.AbstractGlobalEndRequest{Xid: "tc:8091:12"}}
+	rule, ok := injector.Match(DirectionSend, commit)
+	assert.True(t, ok)
+	assert.Equal(t, "commit", rule.Name)
+	// the times of the rule is exhausted
+	_, ok = injector.Match(DirectionSend, commit)
+	assert.False(t, ok)
+	_, ok = injector.Match(DirectionSend, message.GlobalCommitRequest{
+		AbstractGlobalEndRequest: message.AbstractGlobalEndRequest{Xid: "tc:8091:2"}})
+	assert.False(t, ok)
+	assert.Equal(t, 1, injector.Hits("commit"))
+
+	rule, ok = injector.Match(DirectionReceive, message.GlobalCommitResponse{})
+	assert.True(t, ok)
+	assert.Equal(t, ActionReorder, rule.Action)
+	assert.Equal(t, defaultReorderWait, rule.Delay)
+
+	// the rule without message types does not match the heartbeats
+	rule, ok = injector.Match(DirectionSend, message.HeartBeatMessagePing)
+	assert.True(t, ok)
+	assert.Equal(t, "heartbeat", rule.Name)
+	_, ok = injector.Match(DirectionReceive, message.HeartBeatMessagePong)
+	assert.False(t, ok)
+
+	// the rule is skipped unless the probability hits
+	_, ok = injector.Match(DirectionSend, message.GlobalBeginRequest{})
+	assert.False(t, ok)
+	_, ok = injector.Match(DirectionSend, "not a message")
+	assert.False(t, ok)
+}
+
+func TestXidOf(t *testing.T) {
+	assert.Equal(t, "xid-1", xidOf(message.BranchCommitRequest{
+		AbstractBranchEndRequest: message.AbstractBranchEndRequest{Xid: "xid-1"}}))
+	assert.Equal(t, "xid-2", xidOf(&message.GlobalBeginResponse{Xid: "xid-2"}))
+	assert.Equal(t, "", xidOf(message.HeartBeatMessagePing))
+	assert.Equal(t, "", xidOf(nil))
+}
+
+func TestFailedResponse(t *testing.T) {
+	resp, ok := FailedResponse(message.GlobalCommitRequest{}, serror.TransactionErrorCodeFailedToSendBranchCommitRequest, "failed")
+	require.True(t, ok)
+	commit := resp.(message.GlobalCommitResponse)
+	assert.Equal(t, message.ResultCodeFailed, commit.ResultCode)
+	assert.Equal(t, serror.TransactionErrorCodeFailedToSendBranchCommitRequest, commit.TransactionErrorCode)
+	assert.Equal(t, "failed", commit.Msg)
+
+	resp, ok = FailedResponse(message.BranchReportResponse{}, serror.TransactionErrorCodeBranchReportFailed, "")
+	require.True(t, ok)
+	assert.Equal(t, message.ResultCodeFailed, resp.(message.BranchReportResponse).ResultCode)
+
+	for _, body := range []interface{}{
+		message.GlobalBeginRequest{}, message.GlobalRollbackRequest{}, message.GlobalStatusRequest{},
+		message.GlobalReportRequest{}, message.GlobalLockQueryRequest{}, message.BranchRegisterRequest{},
+		message.BranchCommitRequest{}, message.BranchRollbackRequest{},
+	} {
+		resp, ok = FailedResponse(body, serror.TransactionErrorCodeUnknown, "")
+		require.True(t, ok)
+		assert.Equal(t, message.ResultCodeFailed, resp.(interface {
+			GetResultCode() message.ResultCode
+		}).GetResultCode(), "%T", body)
+	}
+
+	_, ok = FailedResponse(message.HeartBeatMessagePing, serror.TransactionErrorCodeUnknown, "")
+	assert.False(t, ok)
+}
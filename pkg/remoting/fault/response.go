@@ -0,0 +1,59 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package fault
+
+import (
+	"seata.apache.org/seata-go/pkg/protocol/message"
+	serror "seata.apache.org/seata-go/pkg/util/errors"
+)
+
+// FailedResponse returns the failed response with the error code and msg of the request or the
+// response, false if the message is not a transaction request or response
+func FailedResponse(body interface{}, code serror.TransactionErrorCode, msg string) (interface{}, bool) {
+	failed := message.AbstractTransactionResponse{
+		AbstractResultMessage: message.AbstractResultMessage{ResultCode: message.ResultCodeFailed, Msg: msg},
+		TransactionErrorCode:  code,
+	}
+	globalEnd := message.AbstractGlobalEndResponse{AbstractTransactionResponse: failed}
+	branchEnd := message.AbstractBranchEndResponse{AbstractTransactionResponse: failed}
+
+	switch body.(type) {
+	case message.GlobalBeginRequest, message.GlobalBeginResponse:
+		return message.GlobalBeginResponse{AbstractTransactionResponse: failed}, true
+	case message.GlobalCommitRequest, message.GlobalCommitResponse:
+		return message.GlobalCommitResponse{AbstractGlobalEndResponse: globalEnd}, true
+	case message.GlobalRollbackRequest, message.GlobalRollbackResponse:
+		return message.GlobalRollbackResponse{AbstractGlobalEndResponse: globalEnd}, true
+	case message.GlobalStatusRequest, message.GlobalStatusResponse:
+		return message.GlobalStatusResponse{AbstractGlobalEndResponse: globalEnd}, true
+	case message.GlobalReportRequest, message.GlobalReportResponse:
+		return message.GlobalReportResponse{AbstractGlobalEndResponse: globalEnd}, true
+	case message.GlobalLockQueryRequest, message.GlobalLockQueryResponse:
+		return message.GlobalLockQueryResponse{AbstractTransactionResponse: failed}, true
+	case message.BranchRegisterRequest, message.BranchRegisterResponse:
+		return message.BranchRegisterResponse{AbstractTransactionResponse: failed}, true
+	case message.BranchReportRequest, message.BranchReportResponse:
+		return message.BranchReportResponse{AbstractTransactionResponse: failed}, true
+	case message.BranchCommitRequest, message.BranchCommitResponse:
+		return message.BranchCommitResponse{AbstractBranchEndResponse: branchEnd}, true
+	case message.BranchRollbackRequest, message.BranchRollbackResponse:
+		return message.BranchRollbackResponse{AbstractBranchEndResponse: branchEnd}, true
+	default:
+		return nil, false
+	}
+}
@@ -0,0 +1,256 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package getty
+
+import (
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"seata.apache.org/seata-go/pkg/remoting/config"
+	"seata.apache.org/seata-go/pkg/util/log"
+)
+
+type breakerState int
+
+const (
+	// breakerClosed the tc node is healthy and can be selected
+	breakerClosed breakerState = iota
+	// breakerOpen the tc node is ejected from selection until the cooldown is over
+	breakerOpen
+	// breakerHalfOpen one probe request is allowed to decide whether to readmit the tc node
+	breakerHalfOpen
+)
+
+func (s breakerState) String() string {
+	switch s {
+	case breakerClosed:
+		return "closed"
+	case breakerOpen:
+		return "open"
+	case breakerHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+// nodeBreaker is the circuit breaker of one tc node
+type nodeBreaker struct {
+	state               breakerState
+	consecutiveFailures int
+	openedAt            time.Time
+	probing             bool
+	probeStartedAt      time.Time
+	// ring buffer of the latest requests, true means the request is timeout
+	window   []bool
+	next     int
+	filled   int
+	timeouts int
+}
+
+func (b *nodeBreaker) record(timeout bool) {
+	if b.filled == len(b.window) {
+		if b.window[b.next] {
+			b.timeouts--
+		}
+	} else {
+		b.filled++
+	}
+	b.window[b.next] = timeout
+	if timeout {
+		b.timeouts++
+	}
+	b.next = (b.next + 1) % len(b.window)
+}
+
+func (b *nodeBreaker) reset() {
+	b.consecutiveFailures = 0
+	b.probing = false
+	b.next, b.filled, b.timeouts = 0, 0, 0
+	for i := range b.window {
+		b.window[i] = false
+	}
+}
+
+// circuitBreakers keeps the circuit breaker of each tc node by address, the failing node
+// is ejected from session selection for a cooldown period, then a probe request readmits it.
+type circuitBreakers struct {
+	conf config.CircuitBreakerConfig
+	now  func() time.Time
+
+	mu       sync.Mutex
+	breakers map[string]*nodeBreaker
+
+	state      *prometheus.GaugeVec
+	ejectTotal *prometheus.CounterVec
+}
+
+func newCircuitBreakers(prom prometheus.Registerer, conf config.CircuitBreakerConfig) *circuitBreakers {
+	if conf.WindowSize <= 0 {
+		conf.WindowSize = 1
+	}
+	return &circuitBreakers{
+		conf:     conf,
+		now:      time.Now,
+		breakers: make(map[string]*nodeBreaker),
+		state: promauto.With(prom).NewGaugeVec(prometheus.GaugeOpts{
+			Name: "getty_circuit_breaker_state",
+			Help: "the circuit breaker state of the tc node, 0: closed, 1: open, 2: half-open",
+		}, []string{"address"}),
+		ejectTotal: promauto.With(prom).NewCounterVec(prometheus.CounterOpts{
+			Name: "getty_circuit_breaker_eject_total",
+			Help: "the total count of the tc node ejected by the circuit breaker",
+		}, []string{"address"}),
+	}
+}
+
+func (c *circuitBreakers) enabled() bool {
+	return c != nil && c.conf.Enable
+}
+
+// getBreaker must be called with c.mu held
+func (c *circuitBreakers) getBreaker(addr string) *nodeBreaker {
+	b, ok := c.breakers[addr]
+	if !ok {
+		b = &nodeBreaker{window: make([]bool, c.conf.WindowSize)}
+		c.breakers[addr] = b
+		c.state.WithLabelValues(addr).Set(float64(breakerClosed))
+	}
+	return b
+}
+
+// setState must be called with c.mu held
+func (c *circuitBreakers) setState(addr string, b *nodeBreaker, state breakerState) {
+	if b.state == state {
+		return
+	}
+	log.Infof("circuit breaker of tc node %s changes from %v to %v", addr, b.state, state)
+	b.state = state
+	c.state.WithLabelValues(addr).Set(float64(state))
+	switch state {
+	case breakerOpen:
+		b.openedAt = c.now()
+		b.probing = false
+		c.ejectTotal.WithLabelValues(addr).Inc()
+	case breakerClosed:
+		b.reset()
+	}
+}
+
+// available reports whether the tc node can be selected, it only reopens the breaker whose probe
+// is expired, see expireProbe.
+func (c *circuitBreakers) available(addr string) bool {
+	if !c.enabled() {
+		return true
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	b, ok := c.breakers[addr]
+	if !ok {
+		return true
+	}
+	c.expireProbe(addr, b)
+	switch b.state {
+	case breakerOpen:
+		return c.now().Sub(b.openedAt) >= c.conf.Cooldown
+	case breakerHalfOpen:
+		return !b.probing
+	default:
+		return true
+	}
+}
+
+// onSelected is called once the tc node is selected. Only the request whose result is reported,
+// i.e. the sync request waiting for the response, can be the probe, the one-way messages and the
+// responses never end the probe. The request becomes the probe if the cooldown of the open
+// breaker is over.
+func (c *circuitBreakers) onSelected(addr string, probe bool) {
+	if !c.enabled() || !probe {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	b := c.getBreaker(addr)
+	c.expireProbe(addr, b)
+	if b.state == breakerOpen && c.now().Sub(b.openedAt) >= c.conf.Cooldown {
+		c.setState(addr, b, breakerHalfOpen)
+	}
+	if b.state == breakerHalfOpen && !b.probing {
+		b.probing = true
+		b.probeStartedAt = c.now()
+	}
+}
+
+// expireProbe reopens the half-open breaker whose probe gets no result within the cooldown, so that
+// the tc node is probed again later instead of being unavailable forever. It must be called with
+// c.mu held.
+func (c *circuitBreakers) expireProbe(addr string, b *nodeBreaker) {
+	if b.state == breakerHalfOpen && b.probing && c.now().Sub(b.probeStartedAt) >= c.conf.Cooldown {
+		log.Warnf("the probe of tc node %s gets no result within %v", addr, c.conf.Cooldown)
+		c.setState(addr, b, breakerOpen)
+	}
+}
+
+// onSuccess records a successful request of the tc node
+func (c *circuitBreakers) onSuccess(addr string) {
+	if !c.enabled() {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	b := c.getBreaker(addr)
+	switch b.state {
+	case breakerHalfOpen:
+		c.setState(addr, b, breakerClosed)
+	case breakerClosed:
+		b.consecutiveFailures = 0
+		b.record(false)
+		if c.timeoutRatioExceeded(b) {
+			c.setState(addr, b, breakerOpen)
+		}
+	}
+}
+
+// onFailure records a failed request of the tc node, timeout means the request got no response in time
+func (c *circuitBreakers) onFailure(addr string, timeout bool) {
+	if !c.enabled() {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	b := c.getBreaker(addr)
+	switch b.state {
+	case breakerHalfOpen:
+		c.setState(addr, b, breakerOpen)
+	case breakerClosed:
+		b.consecutiveFailures++
+		b.record(timeout)
+		if c.conf.FailureThreshold > 0 && b.consecutiveFailures >= c.conf.FailureThreshold || c.timeoutRatioExceeded(b) {
+			c.setState(addr, b, breakerOpen)
+		}
+	}
+}
+
+// timeoutRatioExceeded reports whether the timeout ratio in the window reaches the threshold
+func (c *circuitBreakers) timeoutRatioExceeded(b *nodeBreaker) bool {
+	return c.conf.TimeoutRatio > 0 && b.filled >= c.conf.MinRequests &&
+		float64(b.timeouts)/float64(b.filled) >= c.conf.TimeoutRatio
+}
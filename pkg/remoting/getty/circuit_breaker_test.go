@@ -0,0 +1,166 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package getty
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+
+	"seata.apache.org/seata-go/pkg/remoting/config"
+	"seata.apache.org/seata-go/pkg/remoting/mock"
+)
+
+func newTestCircuitBreakers(now *time.Time) *circuitBreakers {
+	breakers := newCircuitBreakers(prometheus.NewRegistry(), config.CircuitBreakerConfig{
+		Enable:           true,
+		FailureThreshold: 3,
+		TimeoutRatio:     0.5,
+		WindowSize:       4,
+		MinRequests:      4,
+		Cooldown:         10 * time.Second,
+	})
+	breakers.now = func() time.Time { return *now }
+	return breakers
+}
+
+func TestCircuitBreakers_ConsecutiveFailures(t *testing.T) {
+	now := time.Now()
+	breakers := newTestCircuitBreakers(&now)
+	addr := "127.0.0.1:8091"
+
+	breakers.onFailure(addr, false)
+	breakers.onFailure(addr, false)
+	assert.True(t, breakers.available(addr))
+	breakers.onFailure(addr, false)
+	assert.False(t, breakers.available(addr))
+	assert.Equal(t, float64(breakerOpen), testutil.ToFloat64(breakers.state.WithLabelValues(addr)))
+	assert.Equal(t, float64(1), testutil.ToFloat64(breakers.ejectTotal.WithLabelValues(addr)))
+
+	// the cooldown is over, only one probe is allowed
+	now = now.Add(10 * time.Second)
+	assert.True(t, breakers.available(addr))
+	breakers.onSelected(addr, true)
+	assert.Equal(t, float64(breakerHalfOpen), testutil.ToFloat64(breakers.state.WithLabelValues(addr)))
+	assert.False(t, breakers.available(addr))
+
+	// the probe fails, eject again
+	breakers.onFailure(addr, true)
+	assert.False(t, breakers.available(addr))
+	assert.Equal(t, float64(2), testutil.ToFloat64(breakers.ejectTotal.WithLabelValues(addr)))
+
+	// the probe succeeds, readmit the node
+	now = now.Add(10 * time.Second)
+	breakers.onSelected(addr, true)
+	breakers.onSuccess(addr)
+	assert.True(t, breakers.available(addr))
+	assert.Equal(t, float64(breakerClosed), testutil.ToFloat64(breakers.state.WithLabelValues(addr)))
+}
+
+func TestCircuitBreakers_Probe(t *testing.T) {
+	now := time.Now()
+	breakers := newTestCircuitBreakers(&now)
+	addr := "127.0.0.1:8091"
+	for i := 0; i < 3; i++ {
+		breakers.onFailure(addr, false)
+	}
+
+	// the async message never reports its result, so it does not become the probe
+	now = now.Add(10 * time.Second)
+	breakers.onSelected(addr, false)
+	assert.Equal(t, float64(breakerOpen), testutil.ToFloat64(breakers.state.WithLabelValues(addr)))
+	assert.True(t, breakers.available(addr))
+
+	// the probe gets no result within the cooldown, reopen the breaker to probe again later
+	breakers.onSelected(addr, true)
+	assert.False(t, breakers.available(addr))
+	now = now.Add(10 * time.Second)
+	assert.False(t, breakers.available(addr))
+	assert.Equal(t, float64(breakerOpen), testutil.ToFloat64(breakers.state.WithLabelValues(addr)))
+	now = now.Add(10 * time.Second)
+	assert.True(t, breakers.available(addr))
+	breakers.onSelected(addr, true)
+	breakers.onSuccess(addr)
+	assert.Equal(t, float64(breakerClosed), testutil.ToFloat64(breakers.state.WithLabelValues(addr)))
+}
+
+func TestCircuitBreakers_TimeoutRatio(t *testing.T) {
+	now := time.Now()
+	breakers := newTestCircuitBreakers(&now)
+	addr := "127.0.0.1:8091"
+
+	// success resets the consecutive failures, but the timeouts are kept in the window
+	breakers.onFailure(addr, true)
+	breakers.onSuccess(addr)
+	breakers.onFailure(addr, true)
+	assert.True(t, breakers.available(addr))
+	breakers.onSuccess(addr)
+	// 2 of 4 requests are timeout
+	assert.False(t, breakers.available(addr))
+}
+
+func TestCircuitBreakers_Disabled(t *testing.T) {
+	var breakers *circuitBreakers
+	breakers.onFailure("127.0.0.1:8091", true)
+	breakers.onSelected("127.0.0.1:8091", true)
+	assert.True(t, breakers.available("127.0.0.1:8091"))
+
+	breakers = newCircuitBreakers(prometheus.NewRegistry(), config.CircuitBreakerConfig{FailureThreshold: 1})
+	breakers.onFailure("127.0.0.1:8091", false)
+	assert.True(t, breakers.available("127.0.0.1:8091"))
+}
+
+func TestSessionManager_AvailableSessions(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	now := time.Now()
+	manager := &SessionManager{breakers: newTestCircuitBreakers(&now)}
+	for i := 0; i < 2; i++ {
+		session := mock.NewMockTestSession(ctrl)
+		session.EXPECT().IsClosed().Return(false).AnyTimes()
+		session.EXPECT().RemoteAddr().Return(fmt.Sprintf("127.0.0.1:809%d", i)).AnyTimes()
+		manager.allSessions.Store(session, true)
+	}
+
+	for i := 0; i < 3; i++ {
+		manager.breakers.onFailure("127.0.0.1:8090", false)
+	}
+	assert.Equal(t, []string{"127.0.0.1:8091"}, sessionAddrs(manager.availableSessions()))
+
+	// all nodes are ejected, fall back to all sessions
+	for i := 0; i < 3; i++ {
+		manager.breakers.onFailure("127.0.0.1:8091", false)
+	}
+	assert.Equal(t, []string{"127.0.0.1:8090", "127.0.0.1:8091"}, sessionAddrs(manager.availableSessions()))
+}
+
+func sessionAddrs(sessions *sync.Map) []string {
+	addrs := make([]string, 0)
+	sessions.Range(func(key, value interface{}) bool {
+		addrs = append(addrs, key.(interface{ RemoteAddr() string }).RemoteAddr())
+		return true
+	})
+	sort.Strings(addrs)
+	return addrs
+}
@@ -0,0 +1,195 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package getty
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	getty "github.com/apache/dubbo-getty"
+
+	"seata.apache.org/seata-go/pkg/protocol/message"
+	"seata.apache.org/seata-go/pkg/remoting/fault"
+	"seata.apache.org/seata-go/pkg/util/log"
+)
+
+var (
+	faultInjectorLock sync.RWMutex
+	faultInjector     *fault.Injector
+
+	sendReorder    = newReorderBuffer()
+	receiveReorder = newReorderBuffer()
+)
+
+// SetFaultInjector injects the faults into the messages sent and received by the getty client,
+// nil disables the fault injection. It is meant for tests only.
+func SetFaultInjector(injector *fault.Injector) {
+	faultInjectorLock.Lock()
+	defer faultInjectorLock.Unlock()
+	faultInjector = injector
+}
+
+func getFaultInjector() *fault.Injector {
+	faultInjectorLock.RLock()
+	defer faultInjectorLock.RUnlock()
+	return faultInjector
+}
+
+type heldMessage struct {
+	deliver func()
+	timer   *time.Timer
+}
+
+// reorderBuffer holds a message per session, which is delivered after the next message of the session
+type reorderBuffer struct {
+	mu   sync.Mutex
+	held map[getty.Session]*heldMessage
+}
+
+func newReorderBuffer() *reorderBuffer {
+	return &reorderBuffer{held: make(map[getty.Session]*heldMessage)}
+}
+
+// hold holds the message until release is called or the wait elapses, the message held
+// before is released first
+func (b *reorderBuffer) hold(session getty.Session, deliver func(), wait time.Duration) {
+	b.release(session)
+	h := &heldMessage{deliver: deliver}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.held[session] = h
+	h.timer = time.AfterFunc(wait, func() {
+		b.mu.Lock()
+		if b.held[session] != h {
+			b.mu.Unlock()
+			return
+		}
+		delete(b.held, session)
+		b.mu.Unlock()
+		h.deliver()
+	})
+}
+
+// release delivers the message held for the session, the one who removes it delivers it
+func (b *reorderBuffer) release(session getty.Session) {
+	b.mu.Lock()
+	h, ok := b.held[session]
+	delete(b.held, session)
+	b.mu.Unlock()
+	if ok {
+		h.timer.Stop()
+		h.deliver()
+	}
+}
+
+// writePkg writes the message to the session, the faults are injected if the injector is set
+func (g *GettyRemoting) writePkg(session getty.Session, msg message.RpcMessage, future *message.MessageFuture) error {
+	write := func() error {
+		_, _, err := session.WritePkg(msg, time.Duration(0))
+		return err
+	}
+	injector := getFaultInjector()
+	if injector == nil {
+		return write()
+	}
+	rule, ok := injector.Match(fault.DirectionSend, msg.Body)
+	if ok {
+		log.Infof("inject fault %s of rule %s into the message sent: %#v", rule.Action, rule.Name, msg)
+		switch rule.Action {
+		case fault.ActionDrop:
+			return nil
+		case fault.ActionDelay:
+			time.Sleep(rule.Delay)
+		case fault.ActionDuplicate:
+			if err := write(); err != nil {
+				return err
+			}
+		case fault.ActionReorder:
+			sendReorder.hold(session, func() {
+				if err := write(); err != nil {
+					log.Errorf("send reordered message: %#v, session: %s, error: %v", msg, session.Stat(), err)
+				}
+			}, rule.Delay)
+			return nil
+		case fault.ActionFail:
+			g.fail(msg, future, rule)
+			return nil
+		case fault.ActionError:
+			return fmt.Errorf("%w: %s", fault.ErrInjected, rule.Msg)
+		case fault.ActionClose:
+			session.Close()
+			return fmt.Errorf("%w: session is closed", fault.ErrInjected)
+		}
+	}
+	err := write()
+	sendReorder.release(session)
+	return err
+}
+
+// fail responds the sync request with the failed response rather than sending it
+func (g *GettyRemoting) fail(msg message.RpcMessage, future *message.MessageFuture, rule fault.Rule) {
+	resp, ok := fault.FailedResponse(msg.Body, rule.ErrorCode, rule.Msg)
+	if !ok || msg.Type != message.GettyRequestTypeRequestSync {
+		// nothing responds, the message is dropped
+		return
+	}
+	g.futures.Delete(msg.ID)
+	go func() {
+		future.Response = resp
+		select {
+		case future.Done <- struct{}{}:
+		case <-g.done:
+		}
+	}()
+}
+
+// injectReceive dispatches the message received with the faults injected
+func (g *gettyClientHandler) injectReceive(injector *fault.Injector, session getty.Session, rpcMessage message.RpcMessage) {
+	rule, ok := injector.Match(fault.DirectionReceive, rpcMessage.Body)
+	if ok {
+		log.Infof("inject fault %s of rule %s into the message received: %#v", rule.Action, rule.Name, rpcMessage)
+		switch rule.Action {
+		case fault.ActionDrop:
+			return
+		case fault.ActionDelay:
+			// deliver it out of the read loop, so that the messages behind it are not delayed
+			time.AfterFunc(rule.Delay, func() {
+				g.dispatch(session, rpcMessage)
+			})
+			return
+		case fault.ActionDuplicate:
+			g.dispatch(session, rpcMessage)
+		case fault.ActionReorder:
+			receiveReorder.hold(session, func() {
+				g.dispatch(session, rpcMessage)
+			}, rule.Delay)
+			return
+		case fault.ActionFail:
+			if resp, ok := fault.FailedResponse(rpcMessage.Body, rule.ErrorCode, rule.Msg); ok &&
+				rpcMessage.Type == message.GettyRequestTypeResponse {
+				rpcMessage.Body = resp
+			}
+		case fault.ActionClose:
+			session.Close()
+			return
+		}
+	}
+	g.dispatch(session, rpcMessage)
+	receiveReorder.release(session)
+}
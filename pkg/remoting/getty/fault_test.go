@@ -0,0 +1,270 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package getty
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"seata.apache.org/seata-go/pkg/protocol/message"
+	"seata.apache.org/seata-go/pkg/remoting/fault"
+	"seata.apache.org/seata-go/pkg/remoting/mock"
+	"seata.apache.org/seata-go/pkg/remoting/processor"
+	serror "seata.apache.org/seata-go/pkg/util/errors"
+)
+
+// recordSession records the ids of the messages written to the session
+type recordSession struct {
+	*mock.MockTestSession
+	mu  sync.Mutex
+	ids []int32
+}
+
+func newRecordSession(ctrl *gomock.Controller) *recordSession {
+	s := &recordSession{MockTestSession: mock.NewMockTestSession(ctrl)}
+	s.EXPECT().IsClosed().Return(false).AnyTimes()
+	s.EXPECT().Stat().Return("fault-test").AnyTimes()
+	s.EXPECT().GetAttribute(gomock.Any()).Return(nil).AnyTimes()
+	s.EXPECT().WritePkg(gomock.Any(), gomock.Any()).DoAndReturn(func(pkg interface{}, timeout time.Duration) (int, int, error) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.ids = append(s.ids, pkg.(message.RpcMessage).ID)
+		return 0, 0, nil
+	}).AnyTimes()
+	return s
+}
+
+func (s *recordSession) written() []int32 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]int32{}, s.ids...)
+}
+
+func setFaultRules(t *testing.T, rules ...fault.Rule) *fault.Injector {
+	injector, err := fault.NewInjector(rules...)
+	require.NoError(t, err)
+	SetFaultInjector(injector)
+	t.Cleanup(func() {
+		SetFaultInjector(nil)
+	})
+	return injector
+}
+
+func commitMessage(id int32) message.RpcMessage {
+	return message.RpcMessage{
+		ID:   id,
+		Type: message.GettyRequestTypeRequestSync,
+		Body: message.GlobalCommitRequest{AbstractGlobalEndRequest: message.AbstractGlobalEndRequest{Xid: "xid"}},
+	}
+}
+
+func waitResponse(reqMsg message.RpcMessage, respMsg *message.MessageFuture) (interface{}, error) {
+	select {
+	case <-respMsg.Done:
+		return respMsg.Response, respMsg.Err
+	case <-time.After(time.Second):
+		return nil, ErrRequestTimeout
+	}
+}
+
+func TestFaultInjection_Send(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	t.Run("drop", func(t *testing.T) {
+		setFaultRules(t, fault.Rule{MessageTypes: []string{"GlobalCommit"}, Action: fault.ActionDrop, Times: 1})
+		session := newRecordSession(ctrl)
+		remoting := newGettyRemoting()
+		_, err := remoting.sendAsync(session, commitMessage(1), waitResponse)
+		assert.ErrorIs(t, err, ErrRequestTimeout)
+		_, err = remoting.sendAsync(session, commitMessage(2), nil)
+		assert.NoError(t, err)
+		assert.Equal(t, []int32{2}, session.written())
+	})
+
+	t.Run("delay", func(t *testing.T) {
+		setFaultRules(t, fault.Rule{Action: fault.ActionDelay, Delay: 50 * time.Millisecond})
+		session := newRecordSession(ctrl)
+		start := time.Now()
+		_, err := newGettyRemoting().sendAsync(session, commitMessage(1), nil)
+		assert.NoError(t, err)
+		assert.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+		assert.Equal(t, []int32{1}, session.written())
+	})
+
+	t.Run("duplicate", func(t *testing.T) {
+		setFaultRules(t, fault.Rule{Action: fault.ActionDuplicate})
+		session := newRecordSession(ctrl)
+		_, err := newGettyRemoting().sendAsync(session, commitMessage(1), nil)
+		assert.NoError(t, err)
+		assert.Equal(t, []int32{1, 1}, session.written())
+	})
+
+	t.Run("reorder", func(t *testing.T) {
+		setFaultRules(t, fault.Rule{Xid: "xid", Action: fault.ActionReorder, Times: 2, Delay: 50 * time.Millisecond})
+		session := newRecordSession(ctrl)
+		remoting := newGettyRemoting()
+		for id := int32(1); id <= 3; id++ {
+			_, err := remoting.sendAsync(session, commitMessage(id), nil)
+			assert.NoError(t, err)
+		}
+		// the first is released by the second held, the second is released by the third
+		assert.Equal(t, []int32{1, 3, 2}, session.written())
+
+		setFaultRules(t, fault.Rule{Action: fault.ActionReorder, Delay: 50 * time.Millisecond})
+		_, err := remoting.sendAsync(session, commitMessage(4), nil)
+		assert.NoError(t, err)
+		// the message is released once the wait elapses
+		assert.Eventually(t, func() bool {
+			return len(session.written()) == 4
+		}, time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("fail", func(t *testing.T) {
+		setFaultRules(t, fault.Rule{Action: fault.ActionFail, ErrorCode: serror.TransactionErrorCodeFailedToSendBranchCommitRequest, Msg: "injected"})
+		session := newRecordSession(ctrl)
+		remoting := newGettyRemoting()
+		resp, err := remoting.sendAsync(session, commitMessage(1), waitResponse)
+		require.NoError(t, err)
+		commit := resp.(message.GlobalCommitResponse)
+		assert.Equal(t, message.ResultCodeFailed, commit.ResultCode)
+		assert.Equal(t, serror.TransactionErrorCodeFailedToSendBranchCommitRequest, commit.TransactionErrorCode)
+		assert.Equal(t, "injected", commit.Msg)
+		assert.Nil(t, remoting.GetMessageFuture(1))
+		assert.Empty(t, session.written())
+	})
+
+	t.Run("error", func(t *testing.T) {
+		setFaultRules(t, fault.Rule{Action: fault.ActionError, Msg: "tc unreachable"})
+		session := newRecordSession(ctrl)
+		remoting := newGettyRemoting()
+		_, err := remoting.sendAsync(session, commitMessage(1), waitResponse)
+		assert.ErrorIs(t, err, fault.ErrInjected)
+		assert.Nil(t, remoting.GetMessageFuture(1))
+		assert.Empty(t, session.written())
+	})
+
+	t.Run("close", func(t *testing.T) {
+		setFaultRules(t, fault.Rule{Action: fault.ActionClose})
+		session := newRecordSession(ctrl)
+		session.EXPECT().Close().Times(1)
+		_, err := newGettyRemoting().sendAsync(session, commitMessage(1), nil)
+		assert.ErrorIs(t, err, fault.ErrInjected)
+		assert.Empty(t, session.written())
+	})
+}
+
+// recordProcessor records the messages processed
+type recordProcessor struct {
+	mu       sync.Mutex
+	messages []message.RpcMessage
+}
+
+func (p *recordProcessor) Process(ctx context.Context, rpcMessage message.RpcMessage) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.messages = append(p.messages, rpcMessage)
+	return nil
+}
+
+func (p *recordProcessor) processed() []message.RpcMessage {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]message.RpcMessage{}, p.messages...)
+}
+
+func TestFaultInjection_Receive(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	newHandler := func() (*gettyClientHandler, *recordProcessor) {
+		recorder := &recordProcessor{}
+		handler := &gettyClientHandler{processorMap: map[message.MessageType]processor.RemotingProcessor{
+			message.MessageTypeGlobalCommitResult: recorder,
+		}}
+		return handler, recorder
+	}
+	response := func(id int32) message.RpcMessage {
+		return message.RpcMessage{
+			ID:   id,
+			Type: message.GettyRequestTypeResponse,
+			Body: message.GlobalCommitResponse{AbstractGlobalEndResponse: message.AbstractGlobalEndResponse{
+				GlobalStatus: message.GlobalStatusCommitted}},
+		}
+	}
+
+	t.Run("drop", func(t *testing.T) {
+		setFaultRules(t, fault.Rule{Direction: fault.DirectionReceive, Action: fault.ActionDrop, Times: 1})
+		handler, processor := newHandler()
+		handler.OnMessage(newRecordSession(ctrl), response(1))
+		handler.OnMessage(newRecordSession(ctrl), response(2))
+		require.Len(t, processor.processed(), 1)
+		assert.Equal(t, int32(2), processor.processed()[0].ID)
+	})
+
+	t.Run("delay", func(t *testing.T) {
+		setFaultRules(t, fault.Rule{Direction: fault.DirectionReceive, Action: fault.ActionDelay, Delay: 50 * time.Millisecond})
+		handler, processor := newHandler()
+		handler.OnMessage(newRecordSession(ctrl), response(1))
+		assert.Empty(t, processor.processed())
+		assert.Eventually(t, func() bool {
+			return len(processor.processed()) == 1
+		}, time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("duplicate and reorder", func(t *testing.T) {
+		setFaultRules(t,
+			fault.Rule{Direction: fault.DirectionReceive, Action: fault.ActionReorder, Times: 1},
+			fault.Rule{Direction: fault.DirectionReceive, Action: fault.ActionDuplicate, Times: 1})
+		handler, processor := newHandler()
+		session := newRecordSession(ctrl)
+		handler.OnMessage(session, response(1))
+		handler.OnMessage(session, response(2))
+		ids := make([]int32, 0)
+		for _, msg := range processor.processed() {
+			ids = append(ids, msg.ID)
+		}
+		assert.Equal(t, []int32{2, 2, 1}, ids)
+	})
+
+	t.Run("fail", func(t *testing.T) {
+		setFaultRules(t, fault.Rule{Direction: fault.DirectionReceive, Action: fault.ActionFail,
+			ErrorCode: serror.TransactionErrorCodeFailedToSendBranchCommitRequest})
+		handler, processor := newHandler()
+		handler.OnMessage(newRecordSession(ctrl), response(1))
+		require.Len(t, processor.processed(), 1)
+		commit := processor.processed()[0].Body.(message.GlobalCommitResponse)
+		assert.Equal(t, message.ResultCodeFailed, commit.ResultCode)
+		assert.Equal(t, serror.TransactionErrorCodeFailedToSendBranchCommitRequest, commit.TransactionErrorCode)
+	})
+
+	t.Run("close", func(t *testing.T) {
+		setFaultRules(t, fault.Rule{Direction: fault.DirectionReceive, Action: fault.ActionClose})
+		handler, processor := newHandler()
+		session := newRecordSession(ctrl)
+		session.EXPECT().Close().Times(1)
+		handler.OnMessage(session, response(1))
+		assert.Empty(t, processor.processed())
+	})
+}
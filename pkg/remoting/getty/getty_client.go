@@ -18,17 +18,28 @@
 package getty
 
 import (
+	"context"
 	"fmt"
 	"sync"
+	"time"
 
+	getty "github.com/apache/dubbo-getty"
 	gxtime "github.com/dubbogo/gost/time"
+	"github.com/prometheus/client_golang/prometheus"
 	"go.uber.org/atomic"
 
 	"seata.apache.org/seata-go/pkg/protocol/codec"
 	"seata.apache.org/seata-go/pkg/protocol/message"
+	"seata.apache.org/seata-go/pkg/remoting/config"
+	"seata.apache.org/seata-go/pkg/remoting/processor"
 	"seata.apache.org/seata-go/pkg/util/log"
 )
 
+const (
+	maxNotLeaderRetryTimes = 3
+	notLeaderRetryInterval = 100 * time.Millisecond
+)
+
 var (
 	gettyRemotingClient     *GettyRemotingClient
 	onceGettyRemotingClient = &sync.Once{}
@@ -37,21 +48,141 @@ var (
 type GettyRemotingClient struct {
 	idGenerator   *atomic.Uint32
 	gettyRemoting *GettyRemoting
+	handler       *gettyClientHandler
+	pkgHandler    *RpcPackageHandler
+	codecManager  *codec.CodecManager
+	// seataConfig is the one of the default remoting client if it is nil
+	seataConfig *config.SeataConfig
+	initOnce    sync.Once
+	// groupManagers tx service group -> the session manager of a group other than the one in
+	// seataConfig, it is created and connects to the tc cluster of the group on the first use
+	groupManagers sync.Map
+	groupsLock    sync.Mutex
 }
 
+// GetGettyRemotingClient returns the default remoting client, it is the one the package level
+// functions work on.
 func GetGettyRemotingClient() *GettyRemotingClient {
 	if gettyRemotingClient == nil {
 		onceGettyRemotingClient.Do(func() {
-			gettyRemotingClient = &GettyRemotingClient{
-				idGenerator:   &atomic.Uint32{},
-				gettyRemoting: newGettyRemoting(),
-			}
+			gettyRemotingClient = newGettyRemotingClient(codec.GetCodecManager())
 		})
 	}
 	return gettyRemotingClient
 }
 
+// NewGettyRemotingClient creates a remoting client which owns its sessions, processors and codecs,
+// so that several clients connect to different tc clusters in one process. It connects to the tc
+// after Init is called.
+func NewGettyRemotingClient() *GettyRemotingClient {
+	return newGettyRemotingClient(codec.NewCodecManager())
+}
+
+func newGettyRemotingClient(codecManager *codec.CodecManager) *GettyRemotingClient {
+	client := &GettyRemotingClient{
+		idGenerator:   &atomic.Uint32{},
+		gettyRemoting: newGettyRemoting(),
+		pkgHandler:    &RpcPackageHandler{codecManager: codecManager},
+		codecManager:  codecManager,
+	}
+	client.handler = newGettyClientHandler(client)
+	return client
+}
+
+// Init connects to the tc nodes of the tx service group in seataConfig, it does nothing if the
+// client has been initialized. The metrics of the client created by NewGettyRemotingClient are
+// registered to a registry of its own unless WithRegisterer is given.
+func (client *GettyRemotingClient) Init(gettyConfig *config.Config, seataConfig *config.SeataConfig, opts ...Option) {
+	client.initOnce.Do(func() {
+		if client != gettyRemotingClient {
+			opts = append([]Option{WithRegisterer(prometheus.NewRegistry())}, opts...)
+		}
+		o := newOptions(opts)
+		client.seataConfig = seataConfig
+		client.gettyRemoting.sessionManager = newSessionManager(client, gettyConfig, seataConfig, o)
+		client.gettyRemoting.sessionManager.init()
+	})
+}
+
+// Shutdown waits for the inflight requests until ctx is done, then rejects new
+// requests and closes all sessions to the seata server.
+func (client *GettyRemotingClient) Shutdown(ctx context.Context) error {
+	remoting := client.gettyRemoting
+	err := remoting.WaitInflight(ctx)
+	if err != nil {
+		log.Warnf("shutdown getty remoting with %d inflight requests: %v", remoting.Inflight(), err)
+	}
+	remoting.Close()
+	managers := []*SessionManager{remoting.sessionManager}
+	client.groupsLock.Lock()
+	client.groupManagers.Range(func(key, value interface{}) bool {
+		managers = append(managers, value.(*SessionManager))
+		client.groupManagers.Delete(key)
+		return true
+	})
+	client.groupsLock.Unlock()
+	for _, manager := range managers {
+		if manager == nil {
+			continue
+		}
+		if closeErr := manager.close(ctx); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
+
+// getSessionManager returns the session manager connecting to the tc cluster of the tx service
+// group, it is the one of seataConfig if txServiceGroup is empty.
+func (client *GettyRemotingClient) getSessionManager(txServiceGroup string) *SessionManager {
+	manager := client.gettyRemoting.sessionManager
+	if manager == nil || txServiceGroup == "" || txServiceGroup == manager.getTxServiceGroup() {
+		return manager
+	}
+	if groupManager, ok := client.groupManagers.Load(txServiceGroup); ok {
+		return groupManager.(*SessionManager)
+	}
+
+	client.groupsLock.Lock()
+	defer client.groupsLock.Unlock()
+	if groupManager, ok := client.groupManagers.Load(txServiceGroup); ok {
+		return groupManager.(*SessionManager)
+	}
+	// the request is rejected as the remoting is closed, no need to connect to the tc cluster
+	if client.gettyRemoting.IsClosed() {
+		return manager
+	}
+	log.Infof("connect to the tc cluster of tx service group %s", txServiceGroup)
+	groupManager := manager.newGroupSessionManager(txServiceGroup)
+	groupManager.init()
+	client.groupManagers.Store(txServiceGroup, groupManager)
+	return groupManager
+}
+
+// RegisterProcessor processes the messages of msgType received by the client with the processor
+func (client *GettyRemotingClient) RegisterProcessor(msgType message.MessageType, processor processor.RemotingProcessor) {
+	client.handler.RegisterProcessor(msgType, processor)
+}
+
+// RegisterSessionOpenListener registers a listener which is called on every new session of the client
+func (client *GettyRemotingClient) RegisterSessionOpenListener(listener SessionOpenListener) {
+	client.handler.RegisterSessionOpenListener(listener)
+}
+
+func (client *GettyRemotingClient) getSeataConfig() *config.SeataConfig {
+	if client.seataConfig == nil {
+		return config.GetSeataConfig()
+	}
+	return client.seataConfig
+}
+
 func (client *GettyRemotingClient) SendAsyncRequest(msg interface{}) error {
+	return client.SendAsyncRequestToGroup("", msg)
+}
+
+// SendAsyncRequestToGroup sends the async request to the tc cluster of the tx service group, it
+// is the one in the seata config if txServiceGroup is empty.
+func (client *GettyRemotingClient) SendAsyncRequestToGroup(txServiceGroup string, msg interface{}) error {
 	var msgType message.GettyRequestType
 	if _, ok := msg.(message.HeartBeatMessage); ok {
 		msgType = message.GettyRequestTypeHeartbeatRequest
@@ -65,10 +196,25 @@ func (client *GettyRemotingClient) SendAsyncRequest(msg interface{}) error {
 		Compressor: 0,
 		Body:       msg,
 	}
-	return client.gettyRemoting.SendAsync(rpcMessage, nil, client.asyncCallback)
+	return client.gettyRemoting.sendAsyncBy(client.getSessionManager(txServiceGroup), rpcMessage, nil, client.asyncCallback)
 }
 
 func (client *GettyRemotingClient) SendAsyncResponse(msgID int32, msg interface{}) error {
+	return client.sendAsyncResponse(client.gettyRemoting.sessionManager, nil, msgID, msg)
+}
+
+// SendAsyncResponseWithContext sends the response on the session the request processed with ctx
+// is received by, the tc node waits for the response on that session only. The response is sent
+// to the tc cluster in the seata config if ctx does not come from a received message.
+func (client *GettyRemotingClient) SendAsyncResponseWithContext(ctx context.Context, msgID int32, msg interface{}) error {
+	session := getSessionFromContext(ctx)
+	if session == nil {
+		return client.SendAsyncResponse(msgID, msg)
+	}
+	return client.sendAsyncResponse(client.handler.sessionManager(session), session, msgID, msg)
+}
+
+func (client *GettyRemotingClient) sendAsyncResponse(manager *SessionManager, session getty.Session, msgID int32, msg interface{}) error {
 	rpcMessage := message.RpcMessage{
 		ID:         msgID,
 		Type:       message.GettyRequestTypeResponse,
@@ -76,10 +222,40 @@ func (client *GettyRemotingClient) SendAsyncResponse(msgID int32, msg interface{
 		Compressor: 0,
 		Body:       msg,
 	}
-	return client.gettyRemoting.SendAsync(rpcMessage, nil, nil)
+	return client.gettyRemoting.sendAsyncBy(manager, rpcMessage, session, nil)
 }
 
+// SendSyncRequest send the sync request to the session selected by the load balance, the request
+// is retried on the new leader if it is rejected by a tc node which is not the raft leader.
 func (client *GettyRemotingClient) SendSyncRequest(msg interface{}) (interface{}, error) {
+	return client.SendSyncRequestToGroup("", msg)
+}
+
+// SendSyncRequestToGroup sends the sync request to the tc cluster of the tx service group, it is
+// the one in the seata config if txServiceGroup is empty. The tc nodes of the group are connected
+// the first time the group is used.
+func (client *GettyRemotingClient) SendSyncRequestToGroup(txServiceGroup string, msg interface{}) (interface{}, error) {
+	manager := client.getSessionManager(txServiceGroup)
+	for retry := 0; ; retry++ {
+		rpcMessage := message.RpcMessage{
+			ID:         int32(client.idGenerator.Inc()),
+			Type:       message.GettyRequestTypeRequestSync,
+			Codec:      byte(codec.CodecTypeSeata),
+			Compressor: 0,
+			Body:       msg,
+		}
+		resp, err := client.gettyRemoting.sendSyncBy(manager, rpcMessage, nil, client.syncCallback)
+		if err != nil || retry >= maxNotLeaderRetryTimes || !manager.isNotLeaderResponse(resp) {
+			return resp, err
+		}
+		log.Warnf("tc node is not the leader, retry the request on the new leader, retry times: %d", retry+1)
+		time.Sleep(notLeaderRetryInterval * time.Duration(retry+1))
+	}
+}
+
+// SendSyncRequestWithSession send the sync request by the given session, the session
+// is selected by the load balance if it is nil.
+func (client *GettyRemotingClient) SendSyncRequestWithSession(session getty.Session, msg interface{}) (interface{}, error) {
 	rpcMessage := message.RpcMessage{
 		ID:         int32(client.idGenerator.Inc()),
 		Type:       message.GettyRequestTypeRequestSync,
@@ -87,11 +263,15 @@ func (client *GettyRemotingClient) SendSyncRequest(msg interface{}) (interface{}
 		Compressor: 0,
 		Body:       msg,
 	}
-	return client.gettyRemoting.SendSync(rpcMessage, nil, client.syncCallback)
+	return client.gettyRemoting.SendSync(rpcMessage, session, client.syncCallback)
 }
 
 func (g *GettyRemotingClient) asyncCallback(reqMsg message.RpcMessage, respMsg *message.MessageFuture) (interface{}, error) {
-	go g.syncCallback(reqMsg, respMsg)
+	g.gettyRemoting.inflight.Inc()
+	go func() {
+		defer g.gettyRemoting.inflight.Dec()
+		g.syncCallback(reqMsg, respMsg)
+	}()
 	return nil, nil
 }
 
@@ -100,9 +280,12 @@ func (g *GettyRemotingClient) syncCallback(reqMsg message.RpcMessage, respMsg *m
 	case <-gxtime.GetDefaultTimerWheel().After(RpcRequestTimeout):
 		g.gettyRemoting.RemoveMergedMessageFuture(reqMsg.ID)
 		log.Errorf("wait resp timeout: %#v", reqMsg)
-		return nil, fmt.Errorf("wait response timeout, request: %#v", reqMsg)
+		return nil, fmt.Errorf("%w, request: %#v", ErrRequestTimeout, reqMsg)
 	case <-respMsg.Done:
 		return respMsg.Response, respMsg.Err
+	case <-g.gettyRemoting.done:
+		g.gettyRemoting.RemoveMessageFuture(reqMsg.ID)
+		return nil, ErrRemotingClosed
 	}
 }
 
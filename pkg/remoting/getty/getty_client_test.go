@@ -18,16 +18,22 @@
 package getty
 
 import (
+	"context"
 	"fmt"
-	"reflect"
 	"testing"
+	"time"
 
 	"github.com/agiledragon/gomonkey/v2"
 	getty "github.com/apache/dubbo-getty"
+	"github.com/golang/mock/gomock"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/stretchr/testify/assert"
 
+	"seata.apache.org/seata-go/pkg/discovery"
 	"seata.apache.org/seata-go/pkg/protocol/codec"
 	"seata.apache.org/seata-go/pkg/protocol/message"
+	"seata.apache.org/seata-go/pkg/remoting/config"
+	"seata.apache.org/seata-go/pkg/remoting/mock"
 	"seata.apache.org/seata-go/pkg/util/log"
 )
 
@@ -40,8 +46,8 @@ func TestGettyRemotingClient_SendSyncRequest(t *testing.T) {
 			},
 		},
 	}
-	gomonkey.ApplyMethod(reflect.TypeOf(GetGettyRemotingClient().gettyRemoting), "SendSync",
-		func(_ *GettyRemoting, msg message.RpcMessage, s getty.Session, callback callbackMethod) (interface{},
+	gomonkey.ApplyPrivateMethod(GetGettyRemotingClient().gettyRemoting, "sendSyncBy",
+		func(_ *GettyRemoting, _ *SessionManager, msg message.RpcMessage, s getty.Session, callback callbackMethod) (interface{},
 			error) {
 			return respMsg, nil
 		})
@@ -52,14 +58,37 @@ func TestGettyRemotingClient_SendSyncRequest(t *testing.T) {
 
 // TestGettyRemotingClient_SendAsyncResponse unit test for SendAsyncResponse function
 func TestGettyRemotingClient_SendAsyncResponse(t *testing.T) {
-	gomonkey.ApplyMethod(reflect.TypeOf(GetGettyRemotingClient().gettyRemoting), "SendAsync",
-		func(_ *GettyRemoting, msg message.RpcMessage, s getty.Session, callback callbackMethod) error {
+	gomonkey.ApplyPrivateMethod(GetGettyRemotingClient().gettyRemoting, "sendAsyncBy",
+		func(_ *GettyRemoting, _ *SessionManager, msg message.RpcMessage, s getty.Session, callback callbackMethod) error {
 			return nil
 		})
 	err := GetGettyRemotingClient().SendAsyncResponse(1, "message")
 	assert.Empty(t, err)
 }
 
+func TestGettyRemotingClient_SendAsyncResponseWithContext(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	session := mock.NewMockTestSession(ctrl)
+	session.EXPECT().GetAttribute(gomock.Any()).Return(nil).AnyTimes()
+
+	var sent getty.Session
+	stub := gomonkey.ApplyPrivateMethod(GetGettyRemotingClient().gettyRemoting, "sendAsyncBy",
+		func(_ *GettyRemoting, _ *SessionManager, msg message.RpcMessage, s getty.Session, callback callbackMethod) error {
+			sent = s
+			return nil
+		})
+	defer stub.Reset()
+
+	// the response is sent on the session which receives the request
+	ctx := context.WithValue(context.Background(), sessionKey{}, session)
+	assert.NoError(t, GetGettyRemotingClient().SendAsyncResponseWithContext(ctx, 1, "message"))
+	assert.Equal(t, session, sent)
+
+	// the session is selected by the session manager if the request is not received by a session
+	assert.NoError(t, GetGettyRemotingClient().SendAsyncResponseWithContext(context.Background(), 1, "message"))
+	assert.Nil(t, sent)
+}
+
 // TestGettyRemotingClient_SendAsyncRequest unit test for SendAsyncRequest function
 func TestGettyRemotingClient_SendAsyncRequest(t *testing.T) {
 	tests := []struct {
@@ -77,8 +106,8 @@ func TestGettyRemotingClient_SendAsyncRequest(t *testing.T) {
 	}
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			gomonkey.ApplyMethod(reflect.TypeOf(GetGettyRemotingClient().gettyRemoting), "SendAsync",
-				func(_ *GettyRemoting, msg message.RpcMessage, s getty.Session, callback callbackMethod) error {
+			gomonkey.ApplyPrivateMethod(GetGettyRemotingClient().gettyRemoting, "sendAsyncBy",
+				func(_ *GettyRemoting, _ *SessionManager, msg message.RpcMessage, s getty.Session, callback callbackMethod) error {
 					return nil
 				})
 			err := GetGettyRemotingClient().SendAsyncRequest(test.message)
@@ -163,3 +192,46 @@ func Test_asyncCallback(t *testing.T) {
 		})
 	}
 }
+
+type nopProcessor struct{}
+
+func (p *nopProcessor) Process(ctx context.Context, rpcMessage message.RpcMessage) error {
+	return nil
+}
+
+func TestNewGettyRemotingClient(t *testing.T) {
+	client := NewGettyRemotingClient()
+	assert.NotSame(t, GetGettyRemotingClient(), client)
+	assert.NotSame(t, GetGettyClientHandlerInstance(), client.handler)
+	assert.NotSame(t, codec.GetCodecManager(), client.codecManager)
+	assert.NotNil(t, client.codecManager.GetCodec(codec.CodecTypeSeata, message.MessageTypeGlobalBegin))
+
+	// the processor is registered to the client only
+	processor := &nopProcessor{}
+	client.RegisterProcessor(message.MessageTypeGlobalBeginResult, processor)
+	assert.Equal(t, processor, client.handler.processorMap[message.MessageTypeGlobalBeginResult])
+	assert.NotEqual(t, processor, GetGettyClientHandlerInstance().processorMap[message.MessageTypeGlobalBeginResult])
+
+	// the client looks up the tc by its own registry and config
+	registry, err := discovery.NewRegistryService(&discovery.ServiceConfig{
+		VgroupMapping: map[string]string{"group_a": "cluster_a"},
+		Grouplist:     map[string]string{"cluster_a": "127.0.0.1:18191"},
+	}, &discovery.RegistryConfig{Type: discovery.FILE})
+	assert.NoError(t, err)
+	client.Init(&config.Config{ReconnectInterval: 100}, &config.SeataConfig{TxServiceGroup: "group_a"},
+		WithRegistry(registry), WithRegisterer(prometheus.NewRegistry()))
+	manager := client.gettyRemoting.sessionManager
+	assert.Same(t, client, manager.client)
+	assert.Equal(t, registry, manager.getRegistry())
+	assert.Equal(t, "group_a", manager.getSeataConfig().TxServiceGroup)
+	manager.clientsLock.Lock()
+	assert.Contains(t, manager.connected, "127.0.0.1:18191")
+	manager.clientsLock.Unlock()
+	assert.Nil(t, GetGettyRemotingClient().gettyRemoting.sessionManager)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	assert.NoError(t, client.Shutdown(ctx))
+	assert.True(t, client.gettyRemoting.IsClosed())
+	assert.False(t, GetGettyRemotingClient().gettyRemoting.IsClosed())
+}
@@ -18,12 +18,67 @@
 package getty
 
 import (
+	"context"
+	"crypto/tls"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"seata.apache.org/seata-go/pkg/discovery"
 	"seata.apache.org/seata-go/pkg/protocol/codec"
 	"seata.apache.org/seata-go/pkg/remoting/config"
 )
 
-func InitGetty(gettyConfig *config.Config, seataConfig *config.SeataConfig) {
+type options struct {
+	registerer prometheus.Registerer
+	tlsConfig  *tls.Config
+	registry   discovery.RegistryService
+}
+
+type Option func(*options)
+
+// WithRegisterer registers the metrics of the circuit breakers to the registerer instead of the
+// default registerer of prometheus
+func WithRegisterer(registerer prometheus.Registerer) Option {
+	return func(o *options) {
+		o.registerer = registerer
+	}
+}
+
+// WithRegistry looks up the tc nodes by the registry instead of the default one
+func WithRegistry(registry discovery.RegistryService) Option {
+	return func(o *options) {
+		o.registry = registry
+	}
+}
+
+// WithTLSConfig connects to the tc nodes over tls with the config
+func WithTLSConfig(tlsConfig *tls.Config) Option {
+	return func(o *options) {
+		o.tlsConfig = tlsConfig
+	}
+}
+
+// newOptions applies opts, the metrics are registered to the default registerer of prometheus
+// by default.
+func newOptions(opts []Option) *options {
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if o.registerer == nil {
+		o.registerer = prometheus.DefaultRegisterer
+	}
+	return o
+}
+
+// InitGetty inits the default remoting client
+func InitGetty(gettyConfig *config.Config, seataConfig *config.SeataConfig, opts ...Option) {
 	config.InitConfig(seataConfig)
 	codec.Init()
-	initSessionManager(gettyConfig)
+	GetGettyRemotingClient().Init(gettyConfig, seataConfig, opts...)
+}
+
+// Shutdown shuts down the default remoting client
+func Shutdown(ctx context.Context) error {
+	return GetGettyRemotingClient().Shutdown(ctx)
 }
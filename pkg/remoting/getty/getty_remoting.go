@@ -18,11 +18,14 @@
 package getty
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"sync"
 	"time"
 
 	getty "github.com/apache/dubbo-getty"
+	"go.uber.org/atomic"
 
 	"seata.apache.org/seata-go/pkg/protocol/message"
 	"seata.apache.org/seata-go/pkg/remoting/rpc"
@@ -38,23 +41,50 @@ type (
 	GettyRemoting  struct {
 		futures     *sync.Map
 		mergeMsgMap *sync.Map
+		// inflight counts the requests which are sending or waiting for a response
+		inflight  *atomic.Int32
+		done      chan struct{}
+		closeOnce sync.Once
+		// sessionManager selects the session to send the message which has no session given
+		sessionManager *SessionManager
 	}
 )
 
+var (
+	ErrRemotingClosed     = errors.New("getty remoting is closed")
+	ErrRequestTimeout     = errors.New("wait response timeout")
+	ErrNoAvailableSession = errors.New("no available session to seata server")
+)
+
 func newGettyRemoting() *GettyRemoting {
 	return &GettyRemoting{
 		futures:     &sync.Map{},
 		mergeMsgMap: &sync.Map{},
+		inflight:    &atomic.Int32{},
+		done:        make(chan struct{}),
 	}
 }
 
 func (g *GettyRemoting) SendSync(msg message.RpcMessage, s getty.Session, callback callbackMethod) (interface{}, error) {
+	return g.sendSyncBy(g.sessionManager, msg, s, callback)
+}
+
+// sendSyncBy sends the message by the session, it is selected from the sessions of the manager if it is nil
+func (g *GettyRemoting) sendSyncBy(manager *SessionManager, msg message.RpcMessage, s getty.Session, callback callbackMethod) (interface{}, error) {
+	g.inflight.Inc()
+	defer g.inflight.Dec()
+	if g.IsClosed() {
+		return nil, ErrRemotingClosed
+	}
 	if s == nil {
-		s = sessionManager.selectSession(msg)
+		if s = manager.selectSession(msg); s == nil {
+			return nil, ErrNoAvailableSession
+		}
 	}
 	rpc.BeginCount(s.RemoteAddr())
 	result, err := g.sendAsync(s, msg, callback)
 	rpc.EndCount(s.RemoteAddr())
+	manager.onResult(s, err)
 	if err != nil {
 		log.Errorf("send message: %#v, session: %s", msg, s.Stat())
 		return nil, err
@@ -63,13 +93,26 @@ func (g *GettyRemoting) SendSync(msg message.RpcMessage, s getty.Session, callba
 }
 
 func (g *GettyRemoting) SendAsync(msg message.RpcMessage, s getty.Session, callback callbackMethod) error {
+	return g.sendAsyncBy(g.sessionManager, msg, s, callback)
+}
+
+// sendAsyncBy sends the message by the session, it is selected from the sessions of the manager if it is nil
+func (g *GettyRemoting) sendAsyncBy(manager *SessionManager, msg message.RpcMessage, s getty.Session, callback callbackMethod) error {
+	g.inflight.Inc()
+	defer g.inflight.Dec()
+	if g.IsClosed() {
+		return ErrRemotingClosed
+	}
 	if s == nil {
-		s = sessionManager.selectSession(msg)
+		if s = manager.selectSession(msg); s == nil {
+			return ErrNoAvailableSession
+		}
 	}
 	rpc.BeginCount(s.RemoteAddr())
 	_, err := g.sendAsync(s, msg, callback)
 	rpc.EndCount(s.RemoteAddr())
 	if err != nil {
+		manager.onResult(s, err)
 		log.Errorf("send message: %#v, session: %s", msg, s.Stat())
 	}
 	return err
@@ -88,8 +131,7 @@ func (g *GettyRemoting) sendAsync(session getty.Session, msg message.RpcMessage,
 	}
 	resp := message.NewMessageFuture(msg)
 	g.futures.Store(msg.ID, resp)
-	_, _, err = session.WritePkg(msg, time.Duration(0))
-	if err != nil {
+	if err = g.writePkg(session, msg, resp); err != nil {
 		g.futures.Delete(msg.ID)
 		log.Errorf("send message: %#v, session: %s", msg, session.Stat())
 		return nil, err
@@ -100,6 +142,41 @@ func (g *GettyRemoting) sendAsync(session getty.Session, msg message.RpcMessage,
 	return nil, nil
 }
 
+// Inflight returns the number of requests which have not finished yet
+func (g *GettyRemoting) Inflight() int32 {
+	return g.inflight.Load()
+}
+
+// WaitInflight blocks until all inflight requests are finished or ctx is done
+func (g *GettyRemoting) WaitInflight(ctx context.Context) error {
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+	for g.inflight.Load() > 0 {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+	return nil
+}
+
+// Close rejects new requests and wakes up the goroutines waiting for responses
+func (g *GettyRemoting) Close() {
+	g.closeOnce.Do(func() {
+		close(g.done)
+	})
+}
+
+func (g *GettyRemoting) IsClosed() bool {
+	select {
+	case <-g.done:
+		return true
+	default:
+		return false
+	}
+}
+
 func (g *GettyRemoting) GetMessageFuture(msgID int32) *message.MessageFuture {
 	if msg, ok := g.futures.Load(msgID); ok {
 		return msg.(*message.MessageFuture)
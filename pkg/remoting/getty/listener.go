@@ -27,66 +27,95 @@ import (
 	"seata.apache.org/seata-go/pkg/constant"
 	"seata.apache.org/seata-go/pkg/protocol/codec"
 	"seata.apache.org/seata-go/pkg/protocol/message"
-	"seata.apache.org/seata-go/pkg/remoting/config"
 	"seata.apache.org/seata-go/pkg/remoting/processor"
 	"seata.apache.org/seata-go/pkg/util/log"
 )
 
-var (
-	clientHandler     *gettyClientHandler
-	onceClientHandler = &sync.Once{}
-)
+// SessionOpenListener is called in a new goroutine after a session to the seata server is opened
+type SessionOpenListener func(session getty.Session)
 
 type gettyClientHandler struct {
-	idGenerator  *atomic.Uint32
-	processorMap map[message.MessageType]processor.RemotingProcessor
+	// client owns the handler, the handler works on its sessions
+	client        *GettyRemotingClient
+	idGenerator   *atomic.Uint32
+	processorMap  map[message.MessageType]processor.RemotingProcessor
+	listenersLock sync.RWMutex
+	openListeners []SessionOpenListener
 }
 
+// GetGettyClientHandlerInstance returns the handler of the default remoting client
 func GetGettyClientHandlerInstance() *gettyClientHandler {
-	if clientHandler == nil {
-		onceClientHandler.Do(func() {
-			clientHandler = &gettyClientHandler{
-				idGenerator:  &atomic.Uint32{},
-				processorMap: make(map[message.MessageType]processor.RemotingProcessor, 0),
-			}
-		})
+	return GetGettyRemotingClient().handler
+}
+
+func newGettyClientHandler(client *GettyRemotingClient) *gettyClientHandler {
+	return &gettyClientHandler{
+		client:       client,
+		idGenerator:  &atomic.Uint32{},
+		processorMap: make(map[message.MessageType]processor.RemotingProcessor, 0),
 	}
-	return clientHandler
+}
+
+type sessionKey struct{}
+
+// getSessionFromContext returns the session which receives the message processed with ctx
+func getSessionFromContext(ctx context.Context) getty.Session {
+	session, _ := ctx.Value(sessionKey{}).(getty.Session)
+	return session
+}
+
+// sessionManager returns the session manager the session belongs to, it is the one of the tx
+// service group in the seata config if the session is not opened by a session manager.
+func (g *gettyClientHandler) sessionManager(session getty.Session) *SessionManager {
+	if manager, ok := session.GetAttribute(sessionManagerKey).(*SessionManager); ok {
+		return manager
+	}
+	return g.client.gettyRemoting.sessionManager
 }
 
 func (g *gettyClientHandler) OnOpen(session getty.Session) error {
 	log.Infof("Open new getty session ")
-	sessionManager.registerSession(session)
-	conf := config.GetSeataConfig()
+	manager := g.sessionManager(session)
+	manager.registerSession(session)
+	conf := g.client.getSeataConfig()
+	txServiceGroup := GetTxServiceGroup(session)
+	if txServiceGroup == "" {
+		txServiceGroup = conf.TxServiceGroup
+	}
 	go func() {
 		request := message.RegisterTMRequest{AbstractIdentifyRequest: message.AbstractIdentifyRequest{
 			Version:                 constant.SeataVersion,
 			ApplicationId:           conf.ApplicationID,
-			TransactionServiceGroup: conf.TxServiceGroup,
+			TransactionServiceGroup: txServiceGroup,
 		}}
-		err := GetGettyRemotingClient().SendAsyncRequest(request)
+		err := g.client.SendAsyncRequestToGroup(txServiceGroup, request)
 		if err != nil {
 			log.Errorf("OnOpen error: {%#v}", err.Error())
-			sessionManager.releaseSession(session)
+			manager.releaseSession(session)
 			return
 		}
 	}()
 
+	g.listenersLock.RLock()
+	defer g.listenersLock.RUnlock()
+	for _, listener := range g.openListeners {
+		go listener(session)
+	}
+
 	return nil
 }
 
 func (g *gettyClientHandler) OnError(session getty.Session, err error) {
 	log.Infof("session{%s} got error{%v}, will be closed.", session.Stat(), err)
-	sessionManager.releaseSession(session)
+	g.sessionManager(session).releaseSession(session)
 }
 
 func (g *gettyClientHandler) OnClose(session getty.Session) {
 	log.Infof("session{%s} is closing......", session.Stat())
-	sessionManager.releaseSession(session)
+	g.sessionManager(session).releaseSession(session)
 }
 
 func (g *gettyClientHandler) OnMessage(session getty.Session, pkg interface{}) {
-	ctx := context.Background()
 	log.Debug("received message: {%#v}", pkg)
 
 	rpcMessage, ok := pkg.(message.RpcMessage)
@@ -95,6 +124,16 @@ func (g *gettyClientHandler) OnMessage(session getty.Session, pkg interface{}) {
 		return
 	}
 
+	if injector := getFaultInjector(); injector != nil {
+		g.injectReceive(injector, session, rpcMessage)
+		return
+	}
+	g.dispatch(session, rpcMessage)
+}
+
+// dispatch processes the message received by the session by the processor of its type
+func (g *gettyClientHandler) dispatch(session getty.Session, rpcMessage message.RpcMessage) {
+	ctx := context.WithValue(context.Background(), sessionKey{}, session)
 	if mm, ok := rpcMessage.Body.(message.MessageTypeAware); ok {
 		processor := g.processorMap[mm.GetTypeCode()]
 		if processor != nil {
@@ -117,7 +156,7 @@ func (g *gettyClientHandler) OnCron(session getty.Session) {
 			if retryTimes >= maxHeartBeatRetryTimes {
 				log.Warnf("heartbeat retry times exceed default max retry times{%d}, close the session{%s}",
 					maxHeartBeatRetryTimes, session.Stat())
-				sessionManager.releaseSession(session)
+				g.sessionManager(session).releaseSession(session)
 				return
 			}
 			session.SetAttribute(heartBeatRetryTimesKey, retryTimes+1)
@@ -135,7 +174,17 @@ func (g *gettyClientHandler) transferHeartBeat(session getty.Session, msg messag
 		Compressor: 0,
 		Body:       msg,
 	}
-	return GetGettyRemotingClient().gettyRemoting.SendAsync(rpcMessage, session, nil)
+	return g.client.gettyRemoting.SendAsync(rpcMessage, session, nil)
+}
+
+// RegisterSessionOpenListener register a listener which is called on every new session to the seata server
+func (g *gettyClientHandler) RegisterSessionOpenListener(listener SessionOpenListener) {
+	if listener == nil {
+		return
+	}
+	g.listenersLock.Lock()
+	defer g.listenersLock.Unlock()
+	g.openListeners = append(g.openListeners, listener)
 }
 
 func (g *gettyClientHandler) RegisterProcessor(msgType message.MessageType, processor processor.RemotingProcessor) {
@@ -51,10 +51,7 @@ const (
 	Seatav1HeaderLength = 16
 )
 
-var (
-	magics        = []uint8{0xda, 0xda}
-	rpcPkgHandler = &RpcPackageHandler{}
-)
+var magics = []uint8{0xda, 0xda}
 
 var (
 	ErrNotEnoughStream = errors.New("packet stream is not enough")
@@ -63,7 +60,17 @@ var (
 	ErrIllegalMagic    = errors.New("package magic is not right")
 )
 
-type RpcPackageHandler struct{}
+type RpcPackageHandler struct {
+	// codecManager encodes and decodes the message bodies, the default one is used if it is nil
+	codecManager *codec.CodecManager
+}
+
+func (p *RpcPackageHandler) getCodecManager() *codec.CodecManager {
+	if p.codecManager == nil {
+		return codec.GetCodecManager()
+	}
+	return p.codecManager
+}
 
 type SeataV1PackageHeader struct {
 	Magic0       byte
@@ -122,7 +129,7 @@ func (p *RpcPackageHandler) Read(ss getty.Session, data []byte) (interface{}, in
 		rpcMessage.Body = message.HeartBeatMessagePong
 	} else {
 		if header.BodyLength > 0 {
-			msg := codec.GetCodecManager().Decode(codec.CodecType(header.CodecType), data[header.HeadLength:])
+			msg := p.getCodecManager().Decode(codec.CodecType(header.CodecType), data[header.HeadLength:])
 			rpcMessage.Body = msg
 		}
 	}
@@ -151,7 +158,7 @@ func (p *RpcPackageHandler) Write(ss getty.Session, pkg interface{}) ([]byte, er
 	var bodyBytes []byte
 	if msg.Type != message.GettyRequestTypeHeartbeatRequest &&
 		msg.Type != message.GettyRequestTypeHeartbeatResponse {
-		bodyBytes = codec.GetCodecManager().Encode(codec.CodecType(msg.Codec), msg.Body)
+		bodyBytes = p.getCodecManager().Encode(codec.CodecType(msg.Codec), msg.Body)
 		totalLength += len(bodyBytes)
 	}
 
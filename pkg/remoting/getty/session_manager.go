@@ -18,7 +18,9 @@
 package getty
 
 import (
+	"context"
 	"crypto/tls"
+	"errors"
 	"fmt"
 	"net"
 	"reflect"
@@ -33,6 +35,7 @@ import (
 	"seata.apache.org/seata-go/pkg/protocol/message"
 	"seata.apache.org/seata-go/pkg/remoting/config"
 	"seata.apache.org/seata-go/pkg/remoting/loadbalance"
+	serror "seata.apache.org/seata-go/pkg/util/errors"
 	"seata.apache.org/seata-go/pkg/util/log"
 )
 
@@ -40,13 +43,14 @@ const (
 	maxCheckAliveRetry     = 600
 	checkAliveInternal     = 100
 	heartBeatRetryTimesKey = "heartbeat-retry-times"
+	sessionManagerKey      = "session-manager"
 	maxHeartBeatRetryTimes = 3
 )
 
-var (
-	sessionManager     *SessionManager
-	onceSessionManager = &sync.Once{}
-)
+type transactionResult interface {
+	GetResultCode() message.ResultCode
+	GetTransactionErrorCode() serror.TransactionErrorCode
+}
 
 type SessionManager struct {
 	// serverAddress -> rpc_client.Session -> bool
@@ -54,57 +58,271 @@ type SessionManager struct {
 	allSessions    sync.Map
 	sessionSize    int32
 	gettyConf      *config.Config
+	// client owns the session manager, the sessions dispatch the received messages to its handler
+	client *GettyRemotingClient
+	// seataConfig and registry are the ones of the default remoting client if they are nil
+	seataConfig *config.SeataConfig
+	registry    discovery.RegistryService
+	// txServiceGroup the tc cluster of the group is connected, it is the one in seataConfig if it is empty
+	txServiceGroup string
+	// tlsConfig connects to the tc nodes over tls if it is not nil
+	tlsConfig *tls.Config
+	breakers  *circuitBreakers
+	// zoneAffinity prefers the tc nodes in the zone of the client, see loadbalance.ZoneAffinityLoadBalance
+	zoneAffinity loadbalance.LoadBalance
+	clientsLock  sync.Mutex
+	// connected the address of the tc node -> the getty client connects to it
+	connected map[string]getty.Client
+	// closed no tc node is connected any more once the session manager is closed
+	closed     bool
+	eventLoops sync.WaitGroup
+	// listener follows the tc instances changed in the registry
+	listener *serverListener
 }
 
-func initSessionManager(gettyConfig *config.Config) {
-	if sessionManager == nil {
-		onceSessionManager.Do(func() {
-			sessionManager = &SessionManager{
-				allSessions:    sync.Map{},
-				serverSessions: sync.Map{},
-				gettyConf:      gettyConfig,
-			}
-			sessionManager.init()
-		})
+// serverListener connects to the tc instances added to the registry and disconnects from the
+// removed or unhealthy ones, so that the tc moved to a new host is followed without restarting.
+type serverListener struct {
+	manager *SessionManager
+}
+
+func (l *serverListener) OnEvent(event discovery.Event) {
+	instance := event.Instance
+	switch {
+	case event.Type == discovery.EventTypeRemove:
+		l.manager.disconnect(instance)
+	case !instance.Healthy():
+		log.Warnf("tc instance %s:%d becomes unhealthy", instance.Addr, instance.Port)
+		l.manager.disconnect(instance)
+	case event.Type == discovery.EventTypeUpdate:
+		l.manager.updateInstance(instance)
+		l.manager.connect(instance)
+	default:
+		l.manager.connect(instance)
+	}
+}
+
+func newSessionManager(client *GettyRemotingClient, gettyConfig *config.Config, seataConfig *config.SeataConfig, o *options) *SessionManager {
+	return &SessionManager{
+		allSessions:    sync.Map{},
+		serverSessions: sync.Map{},
+		gettyConf:      gettyConfig,
+		client:         client,
+		seataConfig:    seataConfig,
+		registry:       o.registry,
+		tlsConfig:      o.tlsConfig,
+		connected:      make(map[string]getty.Client),
+		breakers:       newCircuitBreakers(o.registerer, gettyConfig.CircuitBreakerConfig),
+		zoneAffinity:   loadbalance.NewZoneAffinityLoadBalance(gettyConfig.Zone, nil),
+	}
+}
+
+func (g *SessionManager) getClient() *GettyRemotingClient {
+	if g.client == nil {
+		return GetGettyRemotingClient()
+	}
+	return g.client
+}
+
+func (g *SessionManager) getSeataConfig() *config.SeataConfig {
+	if g.seataConfig == nil {
+		return config.GetSeataConfig()
+	}
+	return g.seataConfig
+}
+
+func (g *SessionManager) getRegistry() discovery.RegistryService {
+	if g.registry == nil {
+		return discovery.GetRegistry()
+	}
+	return g.registry
+}
+
+func (g *SessionManager) getTxServiceGroup() string {
+	if g.txServiceGroup == "" {
+		return g.getSeataConfig().TxServiceGroup
+	}
+	return g.txServiceGroup
+}
+
+// newGroupSessionManager creates the session manager connecting to the tc cluster of another tx
+// service group, it shares the configs and the circuit breakers with g.
+func (g *SessionManager) newGroupSessionManager(txServiceGroup string) *SessionManager {
+	return &SessionManager{
+		gettyConf:      g.gettyConf,
+		client:         g.client,
+		seataConfig:    g.seataConfig,
+		registry:       g.registry,
+		txServiceGroup: txServiceGroup,
+		tlsConfig:      g.tlsConfig,
+		connected:      make(map[string]getty.Client),
+		breakers:       g.breakers,
+		zoneAffinity:   g.zoneAffinity,
+	}
+}
+
+// GetTxServiceGroup returns the tx service group of the tc cluster the session connects to, it
+// is empty if the session is not opened by a remoting client.
+func GetTxServiceGroup(session getty.Session) string {
+	if manager, ok := session.GetAttribute(sessionManagerKey).(*SessionManager); ok {
+		return manager.getTxServiceGroup()
 	}
+	return ""
 }
 
 func (g *SessionManager) init() {
 	addressList := g.getAvailServerList()
 	if len(addressList) == 0 {
-		log.Warn("no have valid seata server list")
+		log.Warnf("no have valid seata server list of tx service group %s", g.getTxServiceGroup())
 	}
 	for _, address := range addressList {
-		gettyClient := getty.NewTCPClient(
-			getty.WithServerAddress(fmt.Sprintf("%s:%d", address.Addr, address.Port)),
-			// todo if read c.gettyConf.ConnectionNum, will cause the connect to fail
-			getty.WithConnectionNumber(1),
-			getty.WithReconnectInterval(g.gettyConf.ReconnectInterval),
-			getty.WithClientTaskPool(gxsync.NewTaskPoolSimple(0)),
-		)
-		go gettyClient.RunEventLoop(g.newSession)
+		g.connect(address)
+	}
+	g.listener = &serverListener{manager: g}
+	if err := g.getRegistry().Subscribe(g.getTxServiceGroup(), g.listener); err != nil {
+		log.Warnf("subscribe the tc instances failed, the changes of them are not followed, err: %v", err)
+		g.listener = nil
+	}
+}
+
+// tlsConfigBuilder provides the tls config given by the user to the getty client
+type tlsConfigBuilder struct {
+	config *tls.Config
+}
+
+func (b *tlsConfigBuilder) BuildTlsConfig() (*tls.Config, error) {
+	return b.config.Clone(), nil
+}
+
+// connect starts a getty client to the tc node of the instance, it does nothing if connected
+// already or the session manager is closed.
+func (g *SessionManager) connect(instance *discovery.ServiceInstance) {
+	serverAddress := fmt.Sprintf("%s:%d", instance.Addr, instance.Port)
+	g.clientsLock.Lock()
+	if _, ok := g.connected[serverAddress]; ok || g.closed {
+		g.clientsLock.Unlock()
+		return
+	}
+	clientOpts := []getty.ClientOption{
+		getty.WithServerAddress(serverAddress),
+		// todo if read c.gettyConf.ConnectionNum, will cause the connect to fail
+		getty.WithConnectionNumber(1),
+		getty.WithReconnectInterval(g.gettyConf.ReconnectInterval),
+		getty.WithClientTaskPool(gxsync.NewTaskPoolSimple(0)),
+	}
+	if g.tlsConfig != nil {
+		clientOpts = append(clientOpts,
+			getty.WithClientSslEnabled(true),
+			getty.WithClientTlsConfigBuilder(&tlsConfigBuilder{config: g.tlsConfig}))
+	}
+	gettyClient := getty.NewTCPClient(clientOpts...)
+	g.connected[serverAddress] = gettyClient
+	g.clientsLock.Unlock()
+	log.Infof("connect to tc %s, version: %s, zone: %s, weight: %d",
+		serverAddress, instance.Version(), instance.Zone(), instance.Weight())
+
+	g.eventLoops.Add(1)
+	go func() {
+		defer g.eventLoops.Done()
+		gettyClient.RunEventLoop(func(session getty.Session) error {
+			loadbalance.SetSessionInstance(session, instance)
+			return g.newSession(session)
+		})
+	}()
+}
+
+// disconnect closes the getty client to the tc node of the instance, its sessions are
+// released once they are closed.
+func (g *SessionManager) disconnect(instance *discovery.ServiceInstance) {
+	serverAddress := fmt.Sprintf("%s:%d", instance.Addr, instance.Port)
+	g.clientsLock.Lock()
+	gettyClient, ok := g.connected[serverAddress]
+	delete(g.connected, serverAddress)
+	g.clientsLock.Unlock()
+	if ok {
+		log.Infof("disconnect from tc %s", serverAddress)
+		gettyClient.Close()
+	}
+}
+
+// updateInstance refreshes the instance kept by the sessions to the tc node, so that the
+// load balance sees the new metadata of it.
+func (g *SessionManager) updateInstance(instance *discovery.ServiceInstance) {
+	g.allSessions.Range(func(key, value interface{}) bool {
+		session := key.(getty.Session)
+		if old := loadbalance.GetSessionInstance(session); old != nil &&
+			old.Addr == instance.Addr && old.Port == instance.Port {
+			loadbalance.SetSessionInstance(session, instance)
+		}
+		return true
+	})
+}
+
+// close stops the event loops of all getty clients and closes their sessions,
+// it waits for the event loops to exit until ctx is done.
+func (g *SessionManager) close(ctx context.Context) error {
+	if g.listener != nil {
+		if err := g.getRegistry().Unsubscribe(g.getTxServiceGroup(), g.listener); err != nil {
+			log.Warnf("unsubscribe the tc instances failed, err: %v", err)
+		}
+		g.listener = nil
+	}
+	g.clientsLock.Lock()
+	clients := g.connected
+	g.connected = make(map[string]getty.Client)
+	g.closed = true
+	g.clientsLock.Unlock()
+
+	for _, gettyClient := range clients {
+		gettyClient.Close()
+	}
+	g.allSessions.Range(func(key, value interface{}) bool {
+		g.releaseSession(key.(getty.Session))
+		return true
+	})
+
+	done := make(chan struct{})
+	go func() {
+		g.eventLoops.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
 	}
 }
 
+// getAvailServerList looks up the tc instances of the tx service group, the instances marked
+// unhealthy by the registry are skipped.
 func (g *SessionManager) getAvailServerList() []*discovery.ServiceInstance {
-	registryService := discovery.GetRegistry()
-	instances, err := registryService.Lookup(config.GetSeataConfig().TxServiceGroup)
+	instances, err := g.getRegistry().Lookup(g.getTxServiceGroup())
 	if err != nil {
 		return nil
 	}
-	return instances
+	available := make([]*discovery.ServiceInstance, 0, len(instances))
+	for _, instance := range instances {
+		if !instance.Healthy() {
+			log.Warnf("skip the unhealthy tc instance %s:%d", instance.Addr, instance.Port)
+			continue
+		}
+		available = append(available, instance)
+	}
+	return available
 }
 
 func (g *SessionManager) setSessionConfig(session getty.Session) {
 	session.SetName(g.gettyConf.SessionConfig.SessionName)
 	session.SetMaxMsgLen(g.gettyConf.SessionConfig.MaxMsgLen)
-	session.SetPkgHandler(rpcPkgHandler)
-	session.SetEventListener(GetGettyClientHandlerInstance())
+	session.SetPkgHandler(g.getClient().pkgHandler)
+	session.SetEventListener(g.getClient().handler)
 	session.SetReadTimeout(g.gettyConf.SessionConfig.TCPReadTimeout)
 	session.SetWriteTimeout(g.gettyConf.SessionConfig.TCPWriteTimeout)
 	session.SetCronPeriod((int)(g.gettyConf.SessionConfig.CronPeriod.Milliseconds()))
 	session.SetWaitTime(g.gettyConf.SessionConfig.WaitTimeout)
 	session.SetAttribute(heartBeatRetryTimesKey, 0)
+	session.SetAttribute(sessionManagerKey, g)
 }
 
 func (g *SessionManager) newSession(session getty.Session) error {
@@ -156,13 +374,34 @@ func (g *SessionManager) newSession(session getty.Session) error {
 	return nil
 }
 
+// loadBalance returns the load balance of the type in the seata config, the zone affinity one
+// of the session manager is used unless another is registered by loadbalance.RegisterLoadBalance.
+func (g *SessionManager) loadBalance() loadbalance.LoadBalance {
+	loadBalanceType := g.getSeataConfig().LoadBalanceType
+	if loadBalance := loadbalance.GetLoadBalance(loadBalanceType); loadBalance != nil {
+		return loadBalance
+	}
+	if loadBalanceType == loadbalance.ZoneAffinityLoadBalance && g.zoneAffinity != nil {
+		return g.zoneAffinity
+	}
+	return loadbalance.LoadBalanceFunc(loadbalance.RandomLoadBalance)
+}
+
 func (g *SessionManager) selectSession(msg interface{}) getty.Session {
-	session := loadbalance.Select(config.GetSeataConfig().LoadBalanceType, &g.allSessions, g.getXid(msg))
+	// only the sync request reports its result to the circuit breaker, so it is the only probe
+	rpcMessage, ok := msg.(message.RpcMessage)
+	probe := ok && rpcMessage.Type == message.GettyRequestTypeRequestSync
+	if session := g.selectLeaderSession(); session != nil {
+		g.breakers.onSelected(session.RemoteAddr(), probe)
+		return session
+	}
+	session := g.loadBalance().Select(g.availableSessions(), g.getXid(msg))
 	if session != nil {
+		g.breakers.onSelected(session.RemoteAddr(), probe)
 		return session
 	}
 
-	if g.sessionSize == 0 {
+	if atomic.LoadInt32(&g.sessionSize) == 0 {
 		ticker := time.NewTicker(time.Duration(checkAliveInternal) * time.Millisecond)
 		defer ticker.Stop()
 		for i := 0; i < maxCheckAliveRetry; i++ {
@@ -184,6 +423,100 @@ func (g *SessionManager) selectSession(msg interface{}) getty.Session {
 	return nil
 }
 
+// selectLeaderSession returns the session to the leader tc node if the registry is leader aware,
+// nil if the registry is not or the session to the leader is not ready yet. A getty client is
+// started for the leader which is not connected, e.g. the leader moved to a new node.
+func (g *SessionManager) selectLeaderSession() getty.Session {
+	registry, ok := g.getRegistry().(discovery.LeaderRegistryService)
+	if !ok {
+		return nil
+	}
+	leader, err := registry.Leader(g.getTxServiceGroup())
+	if err != nil {
+		log.Warnf("get the leader of tc cluster failed, select by the load balance, err: %v", err)
+		return nil
+	}
+	var session getty.Session
+	g.allSessions.Range(func(key, value interface{}) bool {
+		s := key.(getty.Session)
+		if instance := loadbalance.GetSessionInstance(s); !s.IsClosed() && instance != nil &&
+			instance.Addr == leader.Addr && instance.Port == leader.Port {
+			session = s
+			return false
+		}
+		return true
+	})
+	if session == nil {
+		g.connect(leader)
+	}
+	return session
+}
+
+// isNotLeaderResponse reports whether the response is rejected by a tc node which is not the leader,
+// the leader is refreshed from the registry in this case so that the retry is sent to the new leader.
+func (g *SessionManager) isNotLeaderResponse(resp interface{}) bool {
+	if g == nil {
+		return false
+	}
+	registry, ok := g.getRegistry().(discovery.LeaderRegistryService)
+	if !ok {
+		return false
+	}
+	result, ok := resp.(transactionResult)
+	if !ok || result.GetResultCode() != message.ResultCodeFailed ||
+		result.GetTransactionErrorCode() != serror.TransactionErrorCodeNotRaftLeader {
+		return false
+	}
+	if err := registry.RefreshLeader(g.getTxServiceGroup()); err != nil {
+		log.Warnf("refresh the leader of tc cluster failed, err: %v", err)
+	}
+	return true
+}
+
+// availableSessions returns the sessions whose tc node is not ejected by the circuit breaker,
+// all sessions are returned if every tc node is ejected, so that the requests still have a try.
+func (g *SessionManager) availableSessions() *sync.Map {
+	if !g.breakers.enabled() {
+		return &g.allSessions
+	}
+	var (
+		available  sync.Map
+		hasAvail   bool
+		hasEjected bool
+	)
+	g.allSessions.Range(func(key, value interface{}) bool {
+		session := key.(getty.Session)
+		if session.IsClosed() {
+			g.allSessions.Delete(key)
+		} else if g.breakers.available(session.RemoteAddr()) {
+			available.Store(key, value)
+			hasAvail = true
+		} else {
+			hasEjected = true
+		}
+		return true
+	})
+	if !hasAvail && hasEjected {
+		log.Warn("all tc nodes are ejected by the circuit breaker, select from all sessions")
+		return &g.allSessions
+	}
+	return &available
+}
+
+// onResult feeds the result of the request sent by session to the circuit breaker
+func (g *SessionManager) onResult(session getty.Session, err error) {
+	if g == nil {
+		return
+	}
+	switch {
+	case err == nil:
+		g.breakers.onSuccess(session.RemoteAddr())
+	case errors.Is(err, ErrRemotingClosed):
+	default:
+		g.breakers.onFailure(session.RemoteAddr(), errors.Is(err, ErrRequestTimeout))
+	}
+}
+
 func (g *SessionManager) getXid(msg interface{}) string {
 	var xid string
 	if tmpMsg, ok := msg.(message.AbstractGlobalEndRequest); ok {
@@ -0,0 +1,315 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package getty
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/agiledragon/gomonkey/v2"
+	getty "github.com/apache/dubbo-getty"
+	"github.com/golang/mock/gomock"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"seata.apache.org/seata-go/pkg/discovery"
+	"seata.apache.org/seata-go/pkg/protocol/message"
+	"seata.apache.org/seata-go/pkg/remoting/config"
+	"seata.apache.org/seata-go/pkg/remoting/loadbalance"
+	"seata.apache.org/seata-go/pkg/remoting/mock"
+	serror "seata.apache.org/seata-go/pkg/util/errors"
+)
+
+// initRaftRegistry inits the raft registry with a fake metadata endpoint whose leader is leaderPort
+func initRaftRegistry(t *testing.T, leaderPort *int32) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		leader := atomic.LoadInt32(leaderPort)
+		nodes := ""
+		for port := 8091; port <= 8092; port++ {
+			role := "FOLLOWER"
+			if int32(port) == leader {
+				role = "LEADER"
+			}
+			if nodes != "" {
+				nodes += ","
+			}
+			nodes += fmt.Sprintf(`{"control":{"host":"127.0.0.1","port":%d},"transaction":{"host":"127.0.0.1","port":%d},"role":"%s"}`,
+				port-1000, port, role)
+		}
+		fmt.Fprintf(w, `{"nodes":[%s],"storeMode":"raft","term":1}`, nodes)
+	}))
+	config.InitConfig(&config.SeataConfig{TxServiceGroup: "default_tx_group"})
+	discovery.InitRegistry(&discovery.ServiceConfig{
+		VgroupMapping: map[string]string{"default_tx_group": "default"},
+	}, &discovery.RegistryConfig{
+		Type: discovery.RAFT,
+		Raft: discovery.RaftConfig{ServerAddr: server.URL},
+	})
+	t.Cleanup(func() {
+		discovery.GetRegistry().Close()
+		server.Close()
+		discovery.InitRegistry(&discovery.ServiceConfig{}, &discovery.RegistryConfig{Type: discovery.FILE})
+	})
+}
+
+func TestSessionManager_SelectLeaderSession(t *testing.T) {
+	leaderPort := int32(8092)
+	initRaftRegistry(t, &leaderPort)
+
+	ctrl := gomock.NewController(t)
+	manager := &SessionManager{}
+	for _, port := range []int{8091, 8092} {
+		session := mock.NewMockTestSession(ctrl)
+		session.EXPECT().IsClosed().Return(false).AnyTimes()
+		session.EXPECT().RemoteAddr().Return(fmt.Sprintf("127.0.0.1:%d", port)).AnyTimes()
+		session.EXPECT().GetAttribute(gomock.Any()).Return(&discovery.ServiceInstance{Addr: "127.0.0.1", Port: port}).AnyTimes()
+		manager.allSessions.Store(session, true)
+	}
+
+	for i := 0; i < 5; i++ {
+		assert.Equal(t, "127.0.0.1:8092", manager.selectSession(message.GlobalBeginRequest{}).RemoteAddr())
+	}
+
+	// follow the leader after refreshed
+	atomic.StoreInt32(&leaderPort, 8091)
+	notLeader := message.GlobalBeginResponse{AbstractTransactionResponse: message.AbstractTransactionResponse{
+		AbstractResultMessage: message.AbstractResultMessage{ResultCode: message.ResultCodeFailed},
+		TransactionErrorCode:  serror.TransactionErrorCodeNotRaftLeader,
+	}}
+	assert.True(t, manager.isNotLeaderResponse(notLeader))
+	assert.Equal(t, "127.0.0.1:8091", manager.selectSession(message.GlobalBeginRequest{}).RemoteAddr())
+
+	notLeader.TransactionErrorCode = serror.TransactionErrorCodeBeginFailed
+	assert.False(t, manager.isNotLeaderResponse(notLeader))
+	assert.False(t, manager.isNotLeaderResponse(message.GlobalBeginResponse{}))
+}
+
+func TestSessionManager_IsNotLeaderResponseWithoutRaft(t *testing.T) {
+	discovery.InitRegistry(&discovery.ServiceConfig{}, &discovery.RegistryConfig{Type: discovery.FILE})
+	resp := message.GlobalBeginResponse{AbstractTransactionResponse: message.AbstractTransactionResponse{
+		TransactionErrorCode: serror.LockKeyConflictFailFast,
+	}}
+	assert.False(t, (&SessionManager{}).isNotLeaderResponse(resp))
+	assert.Nil(t, (&SessionManager{}).selectLeaderSession())
+}
+
+func TestGettyRemotingClient_SendSyncRequestNotLeader(t *testing.T) {
+	leaderPort := int32(8091)
+	initRaftRegistry(t, &leaderPort)
+	if remoting := GetGettyRemotingClient().gettyRemoting; remoting.sessionManager == nil {
+		remoting.sessionManager = &SessionManager{}
+		defer func() { remoting.sessionManager = nil }()
+	}
+
+	notLeader := message.GlobalBeginResponse{AbstractTransactionResponse: message.AbstractTransactionResponse{
+		AbstractResultMessage: message.AbstractResultMessage{ResultCode: message.ResultCodeFailed},
+		TransactionErrorCode:  serror.TransactionErrorCodeNotRaftLeader,
+	}}
+	success := message.GlobalBeginResponse{Xid: "xid"}
+	var (
+		calls           int32
+		alwaysNotLeader int32
+	)
+	patches := gomonkey.ApplyPrivateMethod(GetGettyRemotingClient().gettyRemoting, "sendSyncBy",
+		func(_ *GettyRemoting, _ *SessionManager, msg message.RpcMessage, s getty.Session, callback callbackMethod) (interface{}, error) {
+			if atomic.AddInt32(&calls, 1) == 1 || atomic.LoadInt32(&alwaysNotLeader) == 1 {
+				return notLeader, nil
+			}
+			return success, nil
+		})
+	defer patches.Reset()
+
+	resp, err := GetGettyRemotingClient().SendSyncRequest(message.GlobalBeginRequest{})
+	assert.Nil(t, err)
+	assert.Equal(t, success, resp)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+
+	// give up after the max retry times
+	atomic.StoreInt32(&calls, 0)
+	atomic.StoreInt32(&alwaysNotLeader, 1)
+	resp, err = GetGettyRemotingClient().SendSyncRequest(message.GlobalBeginRequest{})
+	assert.Nil(t, err)
+	assert.Equal(t, notLeader, resp)
+	assert.Equal(t, int32(maxNotLeaderRetryTimes+1), atomic.LoadInt32(&calls))
+}
+
+func TestSessionManager_GetAvailServerList(t *testing.T) {
+	config.InitConfig(&config.SeataConfig{TxServiceGroup: "default_tx_group"})
+	discovery.InitRegistry(&discovery.ServiceConfig{
+		VgroupMapping: map[string]string{"default_tx_group": "default"},
+		Grouplist: map[string]string{
+			"default": "127.0.0.1:8091?weight=10&zone=az1&version=2.0.0;127.0.0.1:8092?healthy=false;127.0.0.1:8093",
+		},
+	}, &discovery.RegistryConfig{Type: discovery.FILE})
+	defer discovery.InitRegistry(&discovery.ServiceConfig{}, &discovery.RegistryConfig{Type: discovery.FILE})
+
+	instances := (&SessionManager{}).getAvailServerList()
+	assert.Len(t, instances, 2)
+	assert.Equal(t, 8091, instances[0].Port)
+	assert.Equal(t, 10, instances[0].Weight())
+	assert.Equal(t, "az1", instances[0].Zone())
+	assert.Equal(t, "2.0.0", instances[0].Version())
+	assert.Equal(t, 8093, instances[1].Port)
+	assert.Equal(t, discovery.DefaultWeight, instances[1].Weight())
+}
+
+func TestSessionManager_FollowRegistry(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "registry.conf")
+	require.NoError(t, os.WriteFile(name, []byte("service.default.grouplist=127.0.0.1:18091;127.0.0.1:18092\n"), 0o644))
+	config.InitConfig(&config.SeataConfig{TxServiceGroup: "default_tx_group"})
+	discovery.InitRegistry(&discovery.ServiceConfig{
+		VgroupMapping: map[string]string{"default_tx_group": "default"},
+	}, &discovery.RegistryConfig{
+		Type: discovery.FILE,
+		File: discovery.FileConfig{Name: name, RefreshInterval: 10 * time.Millisecond},
+	})
+	defer func() {
+		discovery.GetRegistry().Close()
+		discovery.InitRegistry(&discovery.ServiceConfig{}, &discovery.RegistryConfig{Type: discovery.FILE})
+	}()
+
+	manager := &SessionManager{
+		gettyConf: &config.Config{ReconnectInterval: 100},
+		connected: make(map[string]getty.Client),
+	}
+	connected := func() []string {
+		manager.clientsLock.Lock()
+		defer manager.clientsLock.Unlock()
+		addrs := make([]string, 0, len(manager.connected))
+		for addr := range manager.connected {
+			addrs = append(addrs, addr)
+		}
+		sort.Strings(addrs)
+		return addrs
+	}
+	manager.init()
+	assert.Equal(t, []string{"127.0.0.1:18091", "127.0.0.1:18092"}, connected())
+
+	// move the tc from 18092 to 18093, and mark 18091 unhealthy
+	require.NoError(t, os.WriteFile(name, []byte("service.default.grouplist=127.0.0.1:18091?healthy=false;127.0.0.1:18093\n"), 0o644))
+	assert.Eventually(t, func() bool {
+		return reflect.DeepEqual([]string{"127.0.0.1:18093"}, connected())
+	}, 2*time.Second, 10*time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	assert.NoError(t, manager.close(ctx))
+	assert.Nil(t, manager.listener)
+	assert.Empty(t, connected())
+
+	// the tc node is not connected again once the manager is closed, e.g. by the leader lookup
+	manager.connect(&discovery.ServiceInstance{Addr: "127.0.0.1", Port: 18093})
+	assert.Empty(t, connected())
+}
+
+func TestTlsConfigBuilder(t *testing.T) {
+	config := &tls.Config{ServerName: "seata-server", MinVersion: tls.VersionTLS12}
+	builder := &tlsConfigBuilder{config: config}
+
+	built, err := builder.BuildTlsConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, "seata-server", built.ServerName)
+	assert.Equal(t, uint16(tls.VersionTLS12), built.MinVersion)
+	// getty gets a copy so the config of the caller is never modified
+	built.ServerName = "other"
+	assert.Equal(t, "seata-server", config.ServerName)
+}
+
+func TestSessionManager_TxServiceGroup(t *testing.T) {
+	registry, err := discovery.NewRegistryService(&discovery.ServiceConfig{
+		VgroupMapping: map[string]string{"order_tx_group": "order", "stock_tx_group": "stock"},
+		Grouplist:     map[string]string{"order": "127.0.0.1:18291", "stock": "127.0.0.1:18292"},
+	}, &discovery.RegistryConfig{Type: discovery.FILE})
+	require.NoError(t, err)
+	client := NewGettyRemotingClient()
+	client.Init(&config.Config{ReconnectInterval: 100}, &config.SeataConfig{TxServiceGroup: "order_tx_group"},
+		WithRegistry(registry), WithRegisterer(prometheus.NewRegistry()))
+
+	manager := client.gettyRemoting.sessionManager
+	assert.Same(t, manager, client.getSessionManager(""))
+	assert.Same(t, manager, client.getSessionManager("order_tx_group"))
+
+	// the manager of another group connects to the tc cluster mapped from the group
+	stockManager := client.getSessionManager("stock_tx_group")
+	assert.NotSame(t, manager, stockManager)
+	assert.Same(t, stockManager, client.getSessionManager("stock_tx_group"))
+	assert.Equal(t, "stock_tx_group", stockManager.getTxServiceGroup())
+	assert.Same(t, manager.breakers, stockManager.breakers)
+	stockManager.clientsLock.Lock()
+	assert.Contains(t, stockManager.connected, "127.0.0.1:18292")
+	assert.NotContains(t, stockManager.connected, "127.0.0.1:18291")
+	stockManager.clientsLock.Unlock()
+
+	ctl := gomock.NewController(t)
+	session := mock.NewMockTestSession(ctl)
+	session.EXPECT().GetAttribute(sessionManagerKey).Return(stockManager).AnyTimes()
+	assert.Equal(t, "stock_tx_group", GetTxServiceGroup(session))
+	assert.Same(t, stockManager, client.handler.sessionManager(session))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	assert.NoError(t, client.Shutdown(ctx))
+	stockManager.clientsLock.Lock()
+	assert.Empty(t, stockManager.connected)
+	stockManager.clientsLock.Unlock()
+	// no new tc cluster is connected after shutdown
+	assert.Same(t, manager, client.getSessionManager("other_tx_group"))
+}
+
+func TestSessionManager_ZoneAffinity(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	seataConfig := &config.SeataConfig{LoadBalanceType: loadbalance.ZoneAffinityLoadBalance}
+	newManager := func(zone string) *SessionManager {
+		manager := newSessionManager(nil, &config.Config{Zone: zone}, seataConfig,
+			newOptions([]Option{WithRegisterer(prometheus.NewRegistry())}))
+		for i, zone := range []string{"dc1", "dc2"} {
+			session := mock.NewMockTestSession(ctrl)
+			session.EXPECT().IsClosed().Return(false).AnyTimes()
+			session.EXPECT().RemoteAddr().Return(fmt.Sprintf("127.0.0.1:809%d", i)).AnyTimes()
+			session.EXPECT().GetAttribute(gomock.Any()).Return(&discovery.ServiceInstance{
+				Metadata: map[string]string{discovery.MetadataZone: zone},
+			}).AnyTimes()
+			manager.allSessions.Store(session, true)
+		}
+		return manager
+	}
+
+	// the clients in different zones keep their own zone
+	dc1, dc2 := newManager("dc1"), newManager("dc2")
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, "127.0.0.1:8090", dc1.loadBalance().Select(dc1.availableSessions(), "xid").RemoteAddr())
+		assert.Equal(t, "127.0.0.1:8091", dc2.loadBalance().Select(dc2.availableSessions(), "xid").RemoteAddr())
+	}
+
+	// the zone affinity registered by the user replaces the one of the clients
+	custom := loadbalance.NewZoneAffinityLoadBalance("dc2", nil)
+	loadbalance.RegisterLoadBalance(loadbalance.ZoneAffinityLoadBalance, custom)
+	defer loadbalance.RegisterLoadBalance(loadbalance.ZoneAffinityLoadBalance, nil)
+	assert.Equal(t, custom, dc1.loadBalance())
+}
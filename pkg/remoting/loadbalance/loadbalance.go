@@ -21,29 +21,79 @@ import (
 	"sync"
 
 	getty "github.com/apache/dubbo-getty"
+
+	"seata.apache.org/seata-go/pkg/discovery"
 )
 
 const (
-	randomLoadBalance         = "RandomLoadBalance"
-	xidLoadBalance            = "XID"
-	roundRobinLoadBalance     = "RoundRobinLoadBalance"
-	consistentHashLoadBalance = "ConsistentHashLoadBalance"
-	leastActiveLoadBalance    = "LeastActiveLoadBalance"
+	randomLoadBalance             = "RandomLoadBalance"
+	xidLoadBalance                = "XID"
+	roundRobinLoadBalance         = "RoundRobinLoadBalance"
+	consistentHashLoadBalance     = "ConsistentHashLoadBalance"
+	leastActiveLoadBalance        = "LeastActiveLoadBalance"
+	weightedRoundRobinLoadBalance = "WeightedRoundRobinLoadBalance"
+	// ZoneAffinityLoadBalance depends on the zone of the client, it is not registered, every remoting
+	// client creates its own by NewZoneAffinityLoadBalance unless one is registered by this name.
+	ZoneAffinityLoadBalance = "ZoneAffinityLoadBalance"
+)
+
+// sessionInstanceKey the session attribute key of the registry instance which the session connects to
+const sessionInstanceKey = "seata-service-instance"
+
+// LoadBalance selects one session from the sessions to send the request of xid
+type LoadBalance interface {
+	Select(sessions *sync.Map, xid string) getty.Session
+}
+
+// LoadBalanceFunc is an adapter to allow the use of ordinary functions as LoadBalance
+type LoadBalanceFunc func(sessions *sync.Map, xid string) getty.Session
+
+func (f LoadBalanceFunc) Select(sessions *sync.Map, xid string) getty.Session {
+	return f(sessions, xid)
+}
+
+var (
+	loadBalances     = make(map[string]LoadBalance)
+	loadBalancesLock sync.RWMutex
 )
 
+func init() {
+	RegisterLoadBalance(randomLoadBalance, LoadBalanceFunc(RandomLoadBalance))
+	RegisterLoadBalance(xidLoadBalance, LoadBalanceFunc(XidLoadBalance))
+	RegisterLoadBalance(roundRobinLoadBalance, LoadBalanceFunc(RoundRobinLoadBalance))
+	RegisterLoadBalance(consistentHashLoadBalance, LoadBalanceFunc(ConsistentHashLoadBalance))
+	RegisterLoadBalance(leastActiveLoadBalance, LoadBalanceFunc(LeastActiveLoadBalance))
+	RegisterLoadBalance(weightedRoundRobinLoadBalance, NewWeightedRoundRobinLoadBalance())
+}
+
+// RegisterLoadBalance registers the load balance by name, the registered one with the same name is replaced
+func RegisterLoadBalance(name string, loadBalance LoadBalance) {
+	loadBalancesLock.Lock()
+	defer loadBalancesLock.Unlock()
+	loadBalances[name] = loadBalance
+}
+
+// GetLoadBalance returns the load balance registered by name, nil is returned if not found
+func GetLoadBalance(name string) LoadBalance {
+	loadBalancesLock.RLock()
+	defer loadBalancesLock.RUnlock()
+	return loadBalances[name]
+}
+
 func Select(loadBalanceType string, sessions *sync.Map, xid string) getty.Session {
-	switch loadBalanceType {
-	case randomLoadBalance:
-		return RandomLoadBalance(sessions, xid)
-	case xidLoadBalance:
-		return XidLoadBalance(sessions, xid)
-	case consistentHashLoadBalance:
-		return ConsistentHashLoadBalance(sessions, xid)
-	case leastActiveLoadBalance:
-		return LeastActiveLoadBalance(sessions, xid)
-	case roundRobinLoadBalance:
-		return RoundRobinLoadBalance(sessions, xid)
-	default:
-		return RandomLoadBalance(sessions, xid)
+	if loadBalance := GetLoadBalance(loadBalanceType); loadBalance != nil {
+		return loadBalance.Select(sessions, xid)
 	}
+	return RandomLoadBalance(sessions, xid)
+}
+
+// SetSessionInstance binds the registry instance which the session connects to
+func SetSessionInstance(session getty.Session, instance *discovery.ServiceInstance) {
+	session.SetAttribute(sessionInstanceKey, instance)
+}
+
+// GetSessionInstance returns the registry instance which the session connects to, nil if not bound
+func GetSessionInstance(session getty.Session) *discovery.ServiceInstance {
+	instance, _ := session.GetAttribute(sessionInstanceKey).(*discovery.ServiceInstance)
+	return instance
 }
@@ -0,0 +1,81 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package loadbalance
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	getty "github.com/apache/dubbo-getty"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+
+	"seata.apache.org/seata-go/pkg/discovery"
+	"seata.apache.org/seata-go/pkg/remoting/mock"
+)
+
+// newMetadataSessions creates the sessions with the given registry metadata, the remote address is the index
+func newMetadataSessions(ctrl *gomock.Controller, metadata ...map[string]string) *sync.Map {
+	sessions := &sync.Map{}
+	for i, md := range metadata {
+		session := mock.NewMockTestSession(ctrl)
+		session.EXPECT().IsClosed().Return(false).AnyTimes()
+		session.EXPECT().RemoteAddr().Return(fmt.Sprintf("%d", i)).AnyTimes()
+		session.EXPECT().GetAttribute(sessionInstanceKey).Return(&discovery.ServiceInstance{Metadata: md}).AnyTimes()
+		sessions.Store(session, fmt.Sprintf("session-%d", i))
+	}
+	return sessions
+}
+
+func TestRegisterLoadBalance(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	sessions := newMetadataSessions(ctrl, nil, nil)
+
+	RegisterLoadBalance("FirstLoadBalance", LoadBalanceFunc(func(sessions *sync.Map, xid string) getty.Session {
+		var first getty.Session
+		sessions.Range(func(key, value interface{}) bool {
+			if session := key.(getty.Session); first == nil || session.RemoteAddr() < first.RemoteAddr() {
+				first = session
+			}
+			return true
+		})
+		return first
+	}))
+	defer func() {
+		loadBalancesLock.Lock()
+		delete(loadBalances, "FirstLoadBalance")
+		loadBalancesLock.Unlock()
+	}()
+
+	assert.NotNil(t, GetLoadBalance("FirstLoadBalance"))
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, "0", Select("FirstLoadBalance", sessions, "some_xid").RemoteAddr())
+	}
+
+	// unknown load balance falls back to random
+	assert.Nil(t, GetLoadBalance("UnknownLoadBalance"))
+	assert.NotNil(t, Select("UnknownLoadBalance", sessions, "some_xid"))
+
+	for _, name := range []string{randomLoadBalance, xidLoadBalance, roundRobinLoadBalance, consistentHashLoadBalance,
+		leastActiveLoadBalance, weightedRoundRobinLoadBalance} {
+		assert.NotNil(t, GetLoadBalance(name), name)
+	}
+	// the zone affinity is created by every client for its own zone
+	assert.Nil(t, GetLoadBalance(ZoneAffinityLoadBalance))
+}
@@ -0,0 +1,82 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package loadbalance
+
+import (
+	"sort"
+	"sync"
+
+	getty "github.com/apache/dubbo-getty"
+)
+
+// WeightedRoundRobin is the smooth weighted round-robin load balance, the weight of each
+// session is read from the registry metadata of the instance it connects to.
+type WeightedRoundRobin struct {
+	mu sync.Mutex
+	// remote address -> current weight
+	currentWeights map[string]int
+}
+
+func NewWeightedRoundRobinLoadBalance() *WeightedRoundRobin {
+	return &WeightedRoundRobin{currentWeights: make(map[string]int)}
+}
+
+func (w *WeightedRoundRobin) Select(sessions *sync.Map, xid string) getty.Session {
+	// collect the available sessions sorted by address to keep the sequence stable
+	adderToSession := make(map[string]getty.Session)
+	adders := make([]string, 0)
+	sessions.Range(func(key, value interface{}) bool {
+		session := key.(getty.Session)
+		if session.IsClosed() {
+			sessions.Delete(key)
+		} else {
+			adderToSession[session.RemoteAddr()] = session
+			adders = append(adders, session.RemoteAddr())
+		}
+		return true
+	})
+	if len(adders) == 0 {
+		return nil
+	}
+	sort.Strings(adders)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	var (
+		selected    string
+		totalWeight int
+	)
+	for _, addr := range adders {
+		weight := GetSessionInstance(adderToSession[addr]).Weight()
+		totalWeight += weight
+		w.currentWeights[addr] += weight
+		if selected == "" || w.currentWeights[addr] > w.currentWeights[selected] {
+			selected = addr
+		}
+	}
+	w.currentWeights[selected] -= totalWeight
+
+	// forget the sessions which are gone
+	for addr := range w.currentWeights {
+		if _, ok := adderToSession[addr]; !ok {
+			delete(w.currentWeights, addr)
+		}
+	}
+	return adderToSession[selected]
+}
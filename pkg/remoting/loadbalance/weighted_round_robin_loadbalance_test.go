@@ -0,0 +1,55 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package loadbalance
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+
+	"seata.apache.org/seata-go/pkg/discovery"
+)
+
+func TestWeightedRoundRobinLoadBalance(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	sessions := newMetadataSessions(ctrl,
+		map[string]string{discovery.MetadataWeight: "5"},
+		map[string]string{discovery.MetadataWeight: "1"},
+		// invalid weight is treated as the default weight 1
+		map[string]string{discovery.MetadataWeight: "abc"},
+	)
+
+	lb := NewWeightedRoundRobinLoadBalance()
+	counts := make(map[string]int)
+	sequence := make([]string, 0)
+	for i := 0; i < 14; i++ {
+		session := lb.Select(sessions, "some_xid")
+		counts[session.RemoteAddr()]++
+		sequence = append(sequence, session.RemoteAddr())
+	}
+	assert.Equal(t, map[string]int{"0": 10, "1": 2, "2": 2}, counts)
+	// smooth weighted round-robin interleaves the heavy node with others
+	assert.Equal(t, []string{"0", "0", "1", "0", "2", "0", "0"}, sequence[:7])
+}
+
+func TestWeightedRoundRobinLoadBalance_Empty(t *testing.T) {
+	lb := NewWeightedRoundRobinLoadBalance()
+	assert.Nil(t, lb.Select(&sync.Map{}, "some_xid"))
+}
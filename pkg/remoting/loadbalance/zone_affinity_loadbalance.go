@@ -0,0 +1,68 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package loadbalance
+
+import (
+	"sync"
+
+	getty "github.com/apache/dubbo-getty"
+)
+
+// ZoneAffinity prefers the sessions to the tc nodes in the same availability zone as the client,
+// and fails over to the nodes of other zones if no session of the local zone is available.
+type ZoneAffinity struct {
+	zone     string
+	delegate LoadBalance
+}
+
+// NewZoneAffinityLoadBalance creates the zone affinity load balance for the client in zone, the
+// session is selected by delegate among the candidates, round-robin is used if delegate is nil.
+func NewZoneAffinityLoadBalance(zone string, delegate LoadBalance) *ZoneAffinity {
+	if delegate == nil {
+		delegate = LoadBalanceFunc(RoundRobinLoadBalance)
+	}
+	return &ZoneAffinity{zone: zone, delegate: delegate}
+}
+
+func (z *ZoneAffinity) Select(sessions *sync.Map, xid string) getty.Session {
+	if z.zone == "" {
+		return z.delegate.Select(sessions, xid)
+	}
+
+	localSessions := &sync.Map{}
+	hasLocal := false
+	sessions.Range(func(key, value interface{}) bool {
+		session := key.(getty.Session)
+		if session.IsClosed() {
+			sessions.Delete(key)
+			return true
+		}
+		if GetSessionInstance(session).Zone() == z.zone {
+			localSessions.Store(key, value)
+			hasLocal = true
+		}
+		return true
+	})
+
+	if hasLocal {
+		if session := z.delegate.Select(localSessions, xid); session != nil {
+			return session
+		}
+	}
+	return z.delegate.Select(sessions, xid)
+}
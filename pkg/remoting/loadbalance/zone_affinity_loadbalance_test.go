@@ -0,0 +1,76 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package loadbalance
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+
+	"seata.apache.org/seata-go/pkg/discovery"
+	"seata.apache.org/seata-go/pkg/remoting/mock"
+)
+
+func TestZoneAffinityLoadBalance(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	sessions := &sync.Map{}
+	closed := make(map[string]bool)
+	var mu sync.Mutex
+	zones := []string{"dc1", "dc2", "dc1", "dc2"}
+	for i, zone := range zones {
+		addr := fmt.Sprintf("%d", i)
+		session := mock.NewMockTestSession(ctrl)
+		session.EXPECT().IsClosed().DoAndReturn(func() bool {
+			mu.Lock()
+			defer mu.Unlock()
+			return closed[addr]
+		}).AnyTimes()
+		session.EXPECT().RemoteAddr().Return(addr).AnyTimes()
+		session.EXPECT().GetAttribute(sessionInstanceKey).Return(&discovery.ServiceInstance{
+			Metadata: map[string]string{discovery.MetadataZone: zone},
+		}).AnyTimes()
+		sessions.Store(session, addr)
+	}
+
+	lb := NewZoneAffinityLoadBalance("dc1", nil)
+	for i := 0; i < 10; i++ {
+		session := lb.Select(sessions, "some_xid")
+		assert.Contains(t, []string{"0", "2"}, session.RemoteAddr())
+	}
+
+	// fail over to the other zone when all nodes of the local zone are gone
+	mu.Lock()
+	closed["0"], closed["2"] = true, true
+	mu.Unlock()
+	for i := 0; i < 10; i++ {
+		session := lb.Select(sessions, "some_xid")
+		assert.Contains(t, []string{"1", "3"}, session.RemoteAddr())
+	}
+}
+
+func TestZoneAffinityLoadBalance_NoZone(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	sessions := newMetadataSessions(ctrl, nil, nil)
+
+	lb := NewZoneAffinityLoadBalance("", nil)
+	assert.NotNil(t, lb.Select(sessions, "some_xid"))
+	assert.Nil(t, lb.Select(&sync.Map{}, "some_xid"))
+}
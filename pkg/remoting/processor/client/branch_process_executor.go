@@ -0,0 +1,112 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client
+
+import (
+	"context"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"seata.apache.org/seata-go/pkg/util/fanout"
+	"seata.apache.org/seata-go/pkg/util/log"
+)
+
+const (
+	defaultBranchProcessWorkerCount = 16
+	defaultBranchProcessQueueSize   = 1024
+)
+
+var (
+	branchExecutor     *branchProcessExecutor
+	onceBranchExecutor = &sync.Once{}
+)
+
+// branchProcessExecutor runs the branch commit and rollback requests from tc on a bounded
+// worker pool, so that the getty read goroutine is never blocked by the resource managers.
+type branchProcessExecutor struct {
+	worker *fanout.Fanout
+
+	queueLength   prometheus.Gauge
+	rejectedTotal prometheus.Counter
+}
+
+func initBranchProcessExecutor(workerCount, queueSize int) *branchProcessExecutor {
+	onceBranchExecutor.Do(func() {
+		branchExecutor = newBranchProcessExecutor(prometheus.DefaultRegisterer, workerCount, queueSize)
+	})
+	return branchExecutor
+}
+
+func getBranchProcessExecutor() *branchProcessExecutor {
+	return initBranchProcessExecutor(defaultBranchProcessWorkerCount, defaultBranchProcessQueueSize)
+}
+
+func newBranchProcessExecutor(prom prometheus.Registerer, workerCount, queueSize int) *branchProcessExecutor {
+	if workerCount <= 0 {
+		workerCount = defaultBranchProcessWorkerCount
+	}
+	if queueSize <= 0 {
+		queueSize = defaultBranchProcessQueueSize
+	}
+	return &branchProcessExecutor{
+		worker: fanout.New("branchProcessor", fanout.WithWorker(workerCount), fanout.WithBuffer(queueSize)),
+		queueLength: promauto.With(prom).NewGauge(prometheus.GaugeOpts{
+			Name: "rm_branch_process_queue_length",
+			Help: "the current count of branch commit and rollback requests waiting to be processed",
+		}),
+		rejectedTotal: promauto.With(prom).NewCounter(prometheus.CounterOpts{
+			Name: "rm_branch_process_rejected_total",
+			Help: "the total count of branch commit and rollback requests rejected by the full queue",
+		}),
+	}
+}
+
+// submit put the task into the queue without blocking, an error is returned if the queue
+// is full or the executor is closed.
+func (e *branchProcessExecutor) submit(ctx context.Context, task func(ctx context.Context)) error {
+	e.queueLength.Inc()
+	err := e.worker.TryDo(ctx, func(ctx context.Context) {
+		e.queueLength.Dec()
+		task(ctx)
+	})
+	if err != nil {
+		e.queueLength.Dec()
+		e.rejectedTotal.Inc()
+		return err
+	}
+	return nil
+}
+
+// close waits for the queued tasks to be picked up by the workers, then stops the workers.
+func (e *branchProcessExecutor) close(ctx context.Context) error {
+	if err := e.worker.Drain(ctx); err != nil {
+		log.Warnf("branch process executor closed with %d requests left", e.worker.Len())
+	}
+	return e.worker.Close()
+}
+
+// Shutdown stops the worker pool of the default remoting client which processes the branch
+// commit and rollback requests.
+func Shutdown(ctx context.Context) error {
+	if branchExecutor == nil {
+		return nil
+	}
+	return branchExecutor.close(ctx)
+}
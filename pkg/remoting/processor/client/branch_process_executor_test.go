@@ -0,0 +1,57 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client
+
+import (
+	"context"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+
+	"seata.apache.org/seata-go/pkg/util/fanout"
+)
+
+func TestBranchProcessExecutor_Submit(t *testing.T) {
+	executor := newBranchProcessExecutor(prometheus.NewRegistry(), 1, 1)
+
+	block := make(chan struct{})
+	started := make(chan struct{})
+	assert.Nil(t, executor.submit(context.Background(), func(ctx context.Context) {
+		close(started)
+		<-block
+	}))
+	<-started
+
+	done := make(chan struct{})
+	assert.Nil(t, executor.submit(context.Background(), func(ctx context.Context) {
+		close(done)
+	}))
+	assert.Equal(t, float64(1), testutil.ToFloat64(executor.queueLength))
+
+	// the queue is full
+	assert.Equal(t, fanout.ErrFull, executor.submit(context.Background(), func(ctx context.Context) {}))
+	assert.Equal(t, float64(1), testutil.ToFloat64(executor.rejectedTotal))
+	assert.Equal(t, float64(1), testutil.ToFloat64(executor.queueLength))
+
+	close(block)
+	<-done
+	assert.Equal(t, float64(0), testutil.ToFloat64(executor.queueLength))
+	assert.Nil(t, executor.close(context.Background()))
+}
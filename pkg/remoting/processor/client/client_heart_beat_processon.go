@@ -23,14 +23,15 @@ import (
 	"seata.apache.org/seata-go/pkg/util/log"
 
 	"seata.apache.org/seata-go/pkg/protocol/message"
-	"seata.apache.org/seata-go/pkg/remoting/getty"
 )
 
-func initHeartBeat() {
-	getty.GetGettyClientHandlerInstance().RegisterProcessor(message.MessageTypeHeartbeatMsg, &clientHeartBeatProcessor{})
+func initHeartBeat(base processorBase) {
+	base.getRemotingClient().RegisterProcessor(message.MessageTypeHeartbeatMsg, &clientHeartBeatProcessor{processorBase: base})
 }
 
-type clientHeartBeatProcessor struct{}
+type clientHeartBeatProcessor struct {
+	processorBase
+}
 
 func (f *clientHeartBeatProcessor) Process(ctx context.Context, rpcMessage message.RpcMessage) error {
 	if msg, ok := rpcMessage.Body.(message.HeartBeatMessage); ok {
@@ -38,9 +39,9 @@ func (f *clientHeartBeatProcessor) Process(ctx context.Context, rpcMessage messa
 			log.Debug("received PONG from {}", ctx)
 		}
 	}
-	msgFuture := getty.GetGettyRemotingClient().GetMessageFuture(rpcMessage.ID)
+	msgFuture := f.getRemotingClient().GetMessageFuture(rpcMessage.ID)
 	if msgFuture != nil {
-		getty.GetGettyRemotingClient().RemoveMessageFuture(rpcMessage.ID)
+		f.getRemotingClient().RemoveMessageFuture(rpcMessage.ID)
 	}
 	return nil
 }
@@ -22,31 +22,31 @@ import (
 
 	"seata.apache.org/seata-go/pkg/protocol/message"
 	"seata.apache.org/seata-go/pkg/util/log"
-
-	"seata.apache.org/seata-go/pkg/remoting/getty"
 )
 
-func initOnResponse() {
-	clientOnResponseProcessor := &clientOnResponseProcessor{}
-	getty.GetGettyClientHandlerInstance().RegisterProcessor(message.MessageTypeSeataMergeResult, clientOnResponseProcessor)
-	getty.GetGettyClientHandlerInstance().RegisterProcessor(message.MessageTypeBranchRegisterResult, clientOnResponseProcessor)
-	getty.GetGettyClientHandlerInstance().RegisterProcessor(message.MessageTypeBranchStatusReportResult, clientOnResponseProcessor)
-	getty.GetGettyClientHandlerInstance().RegisterProcessor(message.MessageTypeGlobalLockQueryResult, clientOnResponseProcessor)
-	getty.GetGettyClientHandlerInstance().RegisterProcessor(message.MessageTypeRegRmResult, clientOnResponseProcessor)
-	getty.GetGettyClientHandlerInstance().RegisterProcessor(message.MessageTypeGlobalBeginResult, clientOnResponseProcessor)
-	getty.GetGettyClientHandlerInstance().RegisterProcessor(message.MessageTypeGlobalCommitResult, clientOnResponseProcessor)
+func initOnResponse(base processorBase) {
+	clientOnResponseProcessor := &clientOnResponseProcessor{processorBase: base}
+	base.getRemotingClient().RegisterProcessor(message.MessageTypeSeataMergeResult, clientOnResponseProcessor)
+	base.getRemotingClient().RegisterProcessor(message.MessageTypeBranchRegisterResult, clientOnResponseProcessor)
+	base.getRemotingClient().RegisterProcessor(message.MessageTypeBranchStatusReportResult, clientOnResponseProcessor)
+	base.getRemotingClient().RegisterProcessor(message.MessageTypeGlobalLockQueryResult, clientOnResponseProcessor)
+	base.getRemotingClient().RegisterProcessor(message.MessageTypeRegRmResult, clientOnResponseProcessor)
+	base.getRemotingClient().RegisterProcessor(message.MessageTypeGlobalBeginResult, clientOnResponseProcessor)
+	base.getRemotingClient().RegisterProcessor(message.MessageTypeGlobalCommitResult, clientOnResponseProcessor)
 
-	getty.GetGettyClientHandlerInstance().RegisterProcessor(message.MessageTypeGlobalReportResult, clientOnResponseProcessor)
-	getty.GetGettyClientHandlerInstance().RegisterProcessor(message.MessageTypeGlobalRollbackResult, clientOnResponseProcessor)
-	getty.GetGettyClientHandlerInstance().RegisterProcessor(message.MessageTypeGlobalStatusResult, clientOnResponseProcessor)
-	getty.GetGettyClientHandlerInstance().RegisterProcessor(message.MessageTypeRegCltResult, clientOnResponseProcessor)
+	base.getRemotingClient().RegisterProcessor(message.MessageTypeGlobalReportResult, clientOnResponseProcessor)
+	base.getRemotingClient().RegisterProcessor(message.MessageTypeGlobalRollbackResult, clientOnResponseProcessor)
+	base.getRemotingClient().RegisterProcessor(message.MessageTypeGlobalStatusResult, clientOnResponseProcessor)
+	base.getRemotingClient().RegisterProcessor(message.MessageTypeRegCltResult, clientOnResponseProcessor)
 }
 
-type clientOnResponseProcessor struct{}
+type clientOnResponseProcessor struct {
+	processorBase
+}
 
 func (f *clientOnResponseProcessor) Process(ctx context.Context, rpcMessage message.RpcMessage) error {
 	log.Infof("the rm client received  clientOnResponse msg %#v from tc server.", rpcMessage)
-	gettyRemotingClient := getty.GetGettyRemotingClient()
+	gettyRemotingClient := f.getRemotingClient()
 	if mergedResult, ok := rpcMessage.Body.(message.MergeResultMessage); ok {
 		mergedMessage := gettyRemotingClient.GetMergedMessage(rpcMessage.ID)
 		if mergedMessage != nil {
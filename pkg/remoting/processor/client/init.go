@@ -17,10 +17,65 @@
 
 package client
 
-// RegisterProcessor register processor
-func RegisterProcessor() {
-	initHeartBeat()
-	initOnResponse()
-	initBranchCommit()
-	initBranchRollback()
+import (
+	"context"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"seata.apache.org/seata-go/pkg/remoting/getty"
+	"seata.apache.org/seata-go/pkg/rm"
+)
+
+// processorBase holds the components which the processors work with, the ones of the default
+// client are used if they are nil.
+type processorBase struct {
+	remotingClient *getty.GettyRemotingClient
+	rmCache        *rm.ResourceManagerCache
+	executor       *branchProcessExecutor
+}
+
+func (b processorBase) getRemotingClient() *getty.GettyRemotingClient {
+	if b.remotingClient == nil {
+		return getty.GetGettyRemotingClient()
+	}
+	return b.remotingClient
+}
+
+func (b processorBase) getRmCache() *rm.ResourceManagerCache {
+	if b.rmCache == nil {
+		return rm.GetRmCacheInstance()
+	}
+	return b.rmCache
+}
+
+func (b processorBase) getExecutor() *branchProcessExecutor {
+	if b.executor == nil {
+		return getBranchProcessExecutor()
+	}
+	return b.executor
+}
+
+// RegisterProcessor register processor to the default remoting client
+func RegisterProcessor(cfg rm.Config) {
+	initBranchProcessExecutor(cfg.PhaseTwoWorkerCount, cfg.PhaseTwoQueueSize)
+	registerProcessors(processorBase{})
+}
+
+// RegisterProcessorTo registers the processors to the remoting client, the branch requests are
+// processed by the resource managers in rmCache on a worker pool of its own, whose metrics are
+// registered to prom. It returns the function which stops the worker pool.
+func RegisterProcessorTo(remotingClient *getty.GettyRemotingClient, rmCache *rm.ResourceManagerCache,
+	prom prometheus.Registerer, cfg rm.Config,
+) (shutdown func(ctx context.Context) error) {
+	executor := newBranchProcessExecutor(prom, cfg.PhaseTwoWorkerCount, cfg.PhaseTwoQueueSize)
+	registerProcessors(processorBase{remotingClient: remotingClient, rmCache: rmCache, executor: executor})
+	return executor.close
+}
+
+func registerProcessors(base processorBase) {
+	initHeartBeat(base)
+	initOnResponse(base)
+	initBranchCommit(base)
+	initBranchRollback(base)
+	initUndoLogDelete(base)
 }
@@ -19,43 +19,87 @@ package client
 
 import (
 	"context"
+	"fmt"
 
+	"seata.apache.org/seata-go/pkg/protocol/branch"
 	"seata.apache.org/seata-go/pkg/protocol/message"
 	"seata.apache.org/seata-go/pkg/util/log"
 
-	"seata.apache.org/seata-go/pkg/remoting/getty"
 	"seata.apache.org/seata-go/pkg/rm"
 )
 
-func initBranchCommit() {
-	rmBranchCommitProcessor := &rmBranchCommitProcessor{}
-	getty.GetGettyClientHandlerInstance().RegisterProcessor(message.MessageTypeBranchCommit, rmBranchCommitProcessor)
+func initBranchCommit(base processorBase) {
+	rmBranchCommitProcessor := &rmBranchCommitProcessor{processorBase: base}
+	base.getRemotingClient().RegisterProcessor(message.MessageTypeBranchCommit, rmBranchCommitProcessor)
 }
 
-type rmBranchCommitProcessor struct{}
+type rmBranchCommitProcessor struct {
+	processorBase
+}
 
+// Process hands the branch commit request over to the branch process executor, the request
+// is replied as failed retryable directly if the executor can not accept it.
 func (f *rmBranchCommitProcessor) Process(ctx context.Context, rpcMessage message.RpcMessage) error {
 	log.Infof("the rm client received  rmBranchCommit msg %#v from tc server.", rpcMessage)
 	request := rpcMessage.Body.(message.BranchCommitRequest)
+	// the response goes back on the session the request comes from
+	err := f.getExecutor().submit(ctx, func(taskCtx context.Context) {
+		f.reply(ctx, rpcMessage.ID, f.process(taskCtx, request))
+	})
+	if err != nil {
+		log.Errorf("branch commit request is rejected: xid %s, branchID %d, error: %v", request.Xid, request.BranchId, err)
+		return f.reply(ctx, rpcMessage.ID, newBranchCommitResponse(request, branch.BranchStatusPhasetwoCommitFailedRetryable, err))
+	}
+	return nil
+}
+
+// process commits the branch, the response is always built even if the commit fails.
+func (f *rmBranchCommitProcessor) process(ctx context.Context, request message.BranchCommitRequest) message.BranchCommitResponse {
 	xid := request.Xid
 	branchID := request.BranchId
 	resourceID := request.ResourceId
 	applicationData := request.ApplicationData
 	log.Infof("Branch committing: xid %s, branchID %d, resourceID %s, applicationData %s", xid, branchID, resourceID, applicationData)
+
+	status, err := f.branchCommit(ctx, request)
+	if err != nil {
+		log.Errorf("branch commit error: xid %s, branchID %d, resourceID %s, error: %v", xid, branchID, resourceID, err)
+		if status != branch.BranchStatusPhasetwoCommitFailedUnretryable {
+			status = branch.BranchStatusPhasetwoCommitFailedRetryable
+		}
+	} else {
+		log.Infof("branch commit success: xid %s, branchID %d, resourceID %s, applicationData %s", xid, branchID, resourceID, applicationData)
+	}
+	return newBranchCommitResponse(request, status, err)
+}
+
+func (f *rmBranchCommitProcessor) branchCommit(ctx context.Context, request message.BranchCommitRequest) (status branch.BranchStatus, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			status, err = branch.BranchStatusPhasetwoCommitFailedRetryable, fmt.Errorf("branch commit panic: %v", r)
+		}
+	}()
 	branchResource := rm.BranchResource{
-		ResourceId:      resourceID,
-		BranchId:        branchID,
-		ApplicationData: applicationData,
-		Xid:             xid,
+		BranchType:      request.BranchType,
+		ResourceId:      request.ResourceId,
+		BranchId:        request.BranchId,
+		ApplicationData: request.ApplicationData,
+		Xid:             request.Xid,
 	}
+	return f.getRmCache().GetResourceManager(request.BranchType).BranchCommit(ctx, branchResource)
+}
 
-	status, err := rm.GetRmCacheInstance().GetResourceManager(request.BranchType).BranchCommit(ctx, branchResource)
-	if err != nil {
-		log.Errorf("branch commit error: %s", err.Error())
+// reply commit response to tc server
+func (f *rmBranchCommitProcessor) reply(ctx context.Context, msgID int32, response message.BranchCommitResponse) error {
+	if err := f.getRemotingClient().SendAsyncResponseWithContext(ctx, msgID, response); err != nil {
+		log.Errorf("send branch commit response error: {%#v}", err.Error())
 		return err
 	}
-	log.Infof("branch commit success: xid %s, branchID %d, resourceID %s, applicationData %s", xid, branchID, resourceID, applicationData)
+	log.Infof("send branch commit response success: xid %v, branchID %v, resultCode %v", response.Xid, response.BranchId, response.ResultCode)
+	return nil
+}
 
+func newBranchCommitResponse(request message.BranchCommitRequest, status branch.BranchStatus, err error) message.BranchCommitResponse {
 	var (
 		resultCode message.ResultCode
 		errMsg     string
@@ -67,9 +111,8 @@ func (f *rmBranchCommitProcessor) Process(ctx context.Context, rpcMessage messag
 		resultCode = message.ResultCodeSuccess
 	}
 
-	// reply commit response to tc server
 	// todo add TransactionErrorCode
-	response := message.BranchCommitResponse{
+	return message.BranchCommitResponse{
 		AbstractBranchEndResponse: message.AbstractBranchEndResponse{
 			AbstractTransactionResponse: message.AbstractTransactionResponse{
 				AbstractResultMessage: message.AbstractResultMessage{
@@ -77,16 +120,9 @@ func (f *rmBranchCommitProcessor) Process(ctx context.Context, rpcMessage messag
 					Msg:        errMsg,
 				},
 			},
-			Xid:          xid,
-			BranchId:     branchID,
+			Xid:          request.Xid,
+			BranchId:     request.BranchId,
 			BranchStatus: status,
 		},
 	}
-	err = getty.GetGettyRemotingClient().SendAsyncResponse(rpcMessage.ID, response)
-	if err != nil {
-		log.Errorf("send branch commit response error: {%#v}", err.Error())
-		return err
-	}
-	log.Infof("send branch commit success: xid %v, branchID %v, resourceID %v, applicationData %v", xid, branchID, resourceID, applicationData)
-	return nil
 }
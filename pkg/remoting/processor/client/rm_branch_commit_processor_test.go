@@ -19,11 +19,14 @@ package client
 
 import (
 	"context"
+	"errors"
 	"testing"
 
+	"github.com/stretchr/testify/assert"
+
 	"seata.apache.org/seata-go/pkg/rm/tcc"
 
-	model2 "seata.apache.org/seata-go/pkg/protocol/branch"
+	"seata.apache.org/seata-go/pkg/protocol/branch"
 	"seata.apache.org/seata-go/pkg/protocol/codec"
 	"seata.apache.org/seata-go/pkg/protocol/message"
 	"seata.apache.org/seata-go/pkg/rm"
@@ -34,7 +37,7 @@ func TestRmBranchCommitProcessor(t *testing.T) {
 	tests := []struct {
 		name    string             // testcase name
 		rpcMsg  message.RpcMessage // rpcMessage case
-		wantErr bool               // want testcase err or not
+		wantErr bool               // want testcase failed response or not
 	}{
 		{
 			name: "rbc-testcase1-failure",
@@ -52,14 +55,14 @@ func TestRmBranchCommitProcessor(t *testing.T) {
 					AbstractBranchEndRequest: message.AbstractBranchEndRequest{
 						Xid:             "123344",
 						BranchId:        56678,
-						BranchType:      model2.BranchTypeTCC,
+						BranchType:      branch.BranchTypeTCC,
 						ResourceId:      "1232323",
 						ApplicationData: []byte("TestExtraData"),
 					},
 				},
 			},
 
-			wantErr: true, // need dail to server, so err accured
+			wantErr: true, // the tcc resource is not registered, so err accured
 		},
 	}
 
@@ -71,11 +74,88 @@ func TestRmBranchCommitProcessor(t *testing.T) {
 	// run tests
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
-			err := rbcProcessor.Process(ctx, tc.rpcMsg)
-			if (err != nil) != tc.wantErr {
-				t.Errorf("rmBranchCommitProcessor wantErr: %v, got: %v", tc.wantErr, err)
+			response := rbcProcessor.process(ctx, tc.rpcMsg.Body.(message.BranchCommitRequest))
+			if (response.ResultCode == message.ResultCodeFailed) != tc.wantErr {
+				t.Errorf("rmBranchCommitProcessor wantErr: %v, got: %v", tc.wantErr, response.Msg)
 				return
 			}
 		})
 	}
 }
+
+// fakeResourceManager returns the given status and error in phase two
+type fakeResourceManager struct {
+	rm.ResourceManager
+	branchType branch.BranchType
+	status     branch.BranchStatus
+	err        error
+}
+
+func (f *fakeResourceManager) GetBranchType() branch.BranchType {
+	return f.branchType
+}
+
+func (f *fakeResourceManager) BranchCommit(ctx context.Context, resource rm.BranchResource) (branch.BranchStatus, error) {
+	return f.status, f.err
+}
+
+func (f *fakeResourceManager) BranchRollback(ctx context.Context, resource rm.BranchResource) (branch.BranchStatus, error) {
+	return f.status, f.err
+}
+
+func TestRmBranchCommitProcessor_FailedResponse(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     branch.BranchStatus
+		err        error
+		wantCode   message.ResultCode
+		wantStatus branch.BranchStatus
+	}{
+		{
+			name:       "committed",
+			status:     branch.BranchStatusPhasetwoCommitted,
+			wantCode:   message.ResultCodeSuccess,
+			wantStatus: branch.BranchStatusPhasetwoCommitted,
+		},
+		{
+			name:       "failed-retryable",
+			status:     branch.BranchStatusUnknown,
+			err:        errors.New("commit failed"),
+			wantCode:   message.ResultCodeFailed,
+			wantStatus: branch.BranchStatusPhasetwoCommitFailedRetryable,
+		},
+		{
+			name:       "failed-unretryable",
+			status:     branch.BranchStatusPhasetwoCommitFailedUnretryable,
+			err:        errors.New("commit failed"),
+			wantCode:   message.ResultCodeFailed,
+			wantStatus: branch.BranchStatusPhasetwoCommitFailedUnretryable,
+		},
+	}
+
+	var processor rmBranchCommitProcessor
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			rm.GetRmCacheInstance().RegisterResourceManager(&fakeResourceManager{
+				branchType: branch.BranchTypeSAGA,
+				status:     tc.status,
+				err:        tc.err,
+			})
+
+			response := processor.process(context.Background(), message.BranchCommitRequest{
+				AbstractBranchEndRequest: message.AbstractBranchEndRequest{
+					Xid:        "123344",
+					BranchId:   56678,
+					BranchType: branch.BranchTypeSAGA,
+				},
+			})
+			assert.Equal(t, tc.wantCode, response.ResultCode)
+			assert.Equal(t, tc.wantStatus, response.BranchStatus)
+			assert.Equal(t, "123344", response.Xid)
+			assert.Equal(t, int64(56678), response.BranchId)
+			if tc.err != nil {
+				assert.Equal(t, tc.err.Error(), response.Msg)
+			}
+		})
+	}
+}
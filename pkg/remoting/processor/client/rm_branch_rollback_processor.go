@@ -19,43 +19,87 @@ package client
 
 import (
 	"context"
+	"fmt"
 
+	"seata.apache.org/seata-go/pkg/protocol/branch"
 	"seata.apache.org/seata-go/pkg/protocol/message"
 	"seata.apache.org/seata-go/pkg/util/log"
 
-	"seata.apache.org/seata-go/pkg/remoting/getty"
 	"seata.apache.org/seata-go/pkg/rm"
 )
 
-func initBranchRollback() {
-	rmBranchRollbackProcessor := &rmBranchRollbackProcessor{}
-	getty.GetGettyClientHandlerInstance().RegisterProcessor(message.MessageTypeBranchRollback, rmBranchRollbackProcessor)
+func initBranchRollback(base processorBase) {
+	rmBranchRollbackProcessor := &rmBranchRollbackProcessor{processorBase: base}
+	base.getRemotingClient().RegisterProcessor(message.MessageTypeBranchRollback, rmBranchRollbackProcessor)
 }
 
-type rmBranchRollbackProcessor struct{}
+type rmBranchRollbackProcessor struct {
+	processorBase
+}
 
+// Process hands the branch rollback request over to the branch process executor, the request
+// is replied as failed retryable directly if the executor can not accept it.
 func (f *rmBranchRollbackProcessor) Process(ctx context.Context, rpcMessage message.RpcMessage) error {
 	log.Infof("the rm client received  rmBranchRollback msg %#v from tc server.", rpcMessage)
 	request := rpcMessage.Body.(message.BranchRollbackRequest)
+	// the response goes back on the session the request comes from
+	err := f.getExecutor().submit(ctx, func(taskCtx context.Context) {
+		f.reply(ctx, rpcMessage.ID, f.process(taskCtx, request))
+	})
+	if err != nil {
+		log.Errorf("branch rollback request is rejected: xid %s, branchID %d, error: %v", request.Xid, request.BranchId, err)
+		return f.reply(ctx, rpcMessage.ID, newBranchRollbackResponse(request, branch.BranchStatusPhasetwoRollbackFailedRetryable, err))
+	}
+	return nil
+}
+
+// process rollbacks the branch, the response is always built even if the rollback fails.
+func (f *rmBranchRollbackProcessor) process(ctx context.Context, request message.BranchRollbackRequest) message.BranchRollbackResponse {
 	xid := request.Xid
 	branchID := request.BranchId
 	resourceID := request.ResourceId
 	applicationData := request.ApplicationData
-	log.Infof("Branch rollback request: xid %v, branchID %v, resourceID %v, applicationData %v", xid, branchID, resourceID, applicationData)
+	log.Infof("Branch rollbacking: xid %s, branchID %d, resourceID %s, applicationData %s", xid, branchID, resourceID, applicationData)
+
+	status, err := f.branchRollback(ctx, request)
+	if err != nil {
+		log.Errorf("branch rollback error: xid %s, branchID %d, resourceID %s, error: %v", xid, branchID, resourceID, err)
+		if status != branch.BranchStatusPhasetwoRollbackFailedUnretryable {
+			status = branch.BranchStatusPhasetwoRollbackFailedRetryable
+		}
+	} else {
+		log.Infof("branch rollback success: xid %s, branchID %d, resourceID %s, applicationData %s", xid, branchID, resourceID, applicationData)
+	}
+	return newBranchRollbackResponse(request, status, err)
+}
+
+func (f *rmBranchRollbackProcessor) branchRollback(ctx context.Context, request message.BranchRollbackRequest) (status branch.BranchStatus, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			status, err = branch.BranchStatusPhasetwoRollbackFailedRetryable, fmt.Errorf("branch rollback panic: %v", r)
+		}
+	}()
 	branchResource := rm.BranchResource{
 		BranchType:      request.BranchType,
-		Xid:             xid,
-		BranchId:        branchID,
-		ResourceId:      resourceID,
-		ApplicationData: applicationData,
+		ResourceId:      request.ResourceId,
+		BranchId:        request.BranchId,
+		ApplicationData: request.ApplicationData,
+		Xid:             request.Xid,
 	}
-	status, err := rm.GetRmCacheInstance().GetResourceManager(request.BranchType).BranchRollback(ctx, branchResource)
-	if err != nil {
-		log.Errorf("branch rollback error: %s", err.Error())
+	return f.getRmCache().GetResourceManager(request.BranchType).BranchRollback(ctx, branchResource)
+}
+
+// reply rollback response to tc server
+func (f *rmBranchRollbackProcessor) reply(ctx context.Context, msgID int32, response message.BranchRollbackResponse) error {
+	if err := f.getRemotingClient().SendAsyncResponseWithContext(ctx, msgID, response); err != nil {
+		log.Errorf("send branch rollback response error: {%#v}", err.Error())
 		return err
 	}
-	log.Infof("branch rollback success: xid %s, branchID %d, resourceID %s, applicationData %s", xid, branchID, resourceID, applicationData)
+	log.Infof("send branch rollback response success: xid %v, branchID %v, resultCode %v", response.Xid, response.BranchId, response.ResultCode)
+	return nil
+}
 
+func newBranchRollbackResponse(request message.BranchRollbackRequest, status branch.BranchStatus, err error) message.BranchRollbackResponse {
 	var (
 		resultCode message.ResultCode
 		errMsg     string
@@ -66,8 +110,9 @@ func (f *rmBranchRollbackProcessor) Process(ctx context.Context, rpcMessage mess
 	} else {
 		resultCode = message.ResultCodeSuccess
 	}
-	// reply commit response to tc server
-	response := message.BranchRollbackResponse{
+
+	// todo add TransactionErrorCode
+	return message.BranchRollbackResponse{
 		AbstractBranchEndResponse: message.AbstractBranchEndResponse{
 			AbstractTransactionResponse: message.AbstractTransactionResponse{
 				AbstractResultMessage: message.AbstractResultMessage{
@@ -75,16 +120,9 @@ func (f *rmBranchRollbackProcessor) Process(ctx context.Context, rpcMessage mess
 					Msg:        errMsg,
 				},
 			},
-			Xid:          xid,
-			BranchId:     branchID,
+			Xid:          request.Xid,
+			BranchId:     request.BranchId,
 			BranchStatus: status,
 		},
 	}
-	err = getty.GetGettyRemotingClient().SendAsyncResponse(rpcMessage.ID, response)
-	if err != nil {
-		log.Errorf("send branch rollback response error: {%#v}", err.Error())
-		return err
-	}
-	log.Infof("send branch rollback response success: xid %s, branchID %d, resourceID %s, applicationData %s", xid, branchID, resourceID, applicationData)
-	return nil
 }
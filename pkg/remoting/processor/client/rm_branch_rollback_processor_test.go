@@ -19,11 +19,14 @@ package client
 
 import (
 	"context"
+	"errors"
 	"testing"
 
+	"github.com/stretchr/testify/assert"
+
 	"seata.apache.org/seata-go/pkg/rm/tcc"
 
-	model2 "seata.apache.org/seata-go/pkg/protocol/branch"
+	"seata.apache.org/seata-go/pkg/protocol/branch"
 	"seata.apache.org/seata-go/pkg/protocol/codec"
 	"seata.apache.org/seata-go/pkg/protocol/message"
 	"seata.apache.org/seata-go/pkg/rm"
@@ -34,7 +37,7 @@ func TestRmBranchRollbackProcessor(t *testing.T) {
 	tests := []struct {
 		name    string             // testcase name
 		rpcMsg  message.RpcMessage // rpcMessage case
-		wantErr bool               // want testcase err or not
+		wantErr bool               // want testcase failed response or not
 	}{
 		{
 			name: "rbr-testcase1-failure",
@@ -52,14 +55,14 @@ func TestRmBranchRollbackProcessor(t *testing.T) {
 					AbstractBranchEndRequest: message.AbstractBranchEndRequest{
 						Xid:             "123345",
 						BranchId:        56679,
-						BranchType:      model2.BranchTypeTCC,
+						BranchType:      branch.BranchTypeTCC,
 						ResourceId:      "1232324",
 						ApplicationData: []byte("TestExtraData"),
 					},
 				},
 			},
 
-			wantErr: true, // need dail to server, so err accured
+			wantErr: true, // the tcc resource is not registered, so err accured
 		},
 	}
 
@@ -71,11 +74,42 @@ func TestRmBranchRollbackProcessor(t *testing.T) {
 	// run tests
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
-			err := rbrProcessor.Process(ctx, tc.rpcMsg)
-			if (err != nil) != tc.wantErr {
-				t.Errorf("rmBranchRollbackProcessor wantErr: %v, got: %v", tc.wantErr, err)
+			response := rbrProcessor.process(ctx, tc.rpcMsg.Body.(message.BranchRollbackRequest))
+			if (response.ResultCode == message.ResultCodeFailed) != tc.wantErr {
+				t.Errorf("rmBranchRollbackProcessor wantErr: %v, got: %v", tc.wantErr, response.Msg)
 				return
 			}
 		})
 	}
 }
+
+func TestRmBranchRollbackProcessor_FailedResponse(t *testing.T) {
+	var processor rmBranchRollbackProcessor
+
+	// no resource manager is registered for xa, the panic is replied as failed retryable
+	response := processor.process(context.Background(), message.BranchRollbackRequest{
+		AbstractBranchEndRequest: message.AbstractBranchEndRequest{
+			Xid:        "123345",
+			BranchId:   56679,
+			BranchType: branch.BranchTypeXA,
+		},
+	})
+	assert.Equal(t, message.ResultCodeFailed, response.ResultCode)
+	assert.Equal(t, branch.BranchStatus(branch.BranchStatusPhasetwoRollbackFailedRetryable), response.BranchStatus)
+
+	rm.GetRmCacheInstance().RegisterResourceManager(&fakeResourceManager{
+		branchType: branch.BranchTypeSAGA,
+		status:     branch.BranchStatusPhasetwoRollbackFailedUnretryable,
+		err:        errors.New("rollback failed"),
+	})
+	response = processor.process(context.Background(), message.BranchRollbackRequest{
+		AbstractBranchEndRequest: message.AbstractBranchEndRequest{
+			Xid:        "123345",
+			BranchId:   56680,
+			BranchType: branch.BranchTypeSAGA,
+		},
+	})
+	assert.Equal(t, message.ResultCodeFailed, response.ResultCode)
+	assert.Equal(t, "rollback failed", response.Msg)
+	assert.Equal(t, branch.BranchStatus(branch.BranchStatusPhasetwoRollbackFailedUnretryable), response.BranchStatus)
+}
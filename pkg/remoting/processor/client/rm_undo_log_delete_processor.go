@@ -0,0 +1,77 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"seata.apache.org/seata-go/pkg/protocol/message"
+	"seata.apache.org/seata-go/pkg/rm"
+	"seata.apache.org/seata-go/pkg/util/log"
+)
+
+// defaultUndoLogSaveDays is used if the tc server does not specify the save days
+const defaultUndoLogSaveDays = 7
+
+func initUndoLogDelete(base processorBase) {
+	rmUndoLogDeleteProcessor := &rmUndoLogDeleteProcessor{processorBase: base}
+	base.getRemotingClient().RegisterProcessor(message.MessageTypeRmDeleteUndolog, rmUndoLogDeleteProcessor)
+}
+
+// rmUndoLogDeleteProcessor deletes the expired undo logs when tc server asks, tc server
+// does not wait for any response of this request.
+type rmUndoLogDeleteProcessor struct {
+	processorBase
+}
+
+func (f *rmUndoLogDeleteProcessor) Process(ctx context.Context, rpcMessage message.RpcMessage) error {
+	log.Infof("the rm client received  rmUndoLogDelete msg %#v from tc server.", rpcMessage)
+	request := rpcMessage.Body.(message.UndoLogDeleteRequest)
+	err := f.getExecutor().submit(ctx, func(ctx context.Context) {
+		if err := f.process(ctx, request); err != nil {
+			log.Errorf("delete undo log error: resourceID %s, error: %v", request.ResourceId, err)
+		}
+	})
+	if err != nil {
+		log.Warnf("undo log delete request is rejected, it will be retried by tc server: resourceID %s, error: %v", request.ResourceId, err)
+		return err
+	}
+	return nil
+}
+
+func (f *rmUndoLogDeleteProcessor) process(ctx context.Context, request message.UndoLogDeleteRequest) error {
+	saveDays := int(request.SaveDays)
+	if saveDays <= 0 {
+		saveDays = defaultUndoLogSaveDays
+	}
+	logCreated := time.Now().AddDate(0, 0, -saveDays)
+
+	for _, resourceManager := range f.getRmCache().GetResourceManagers() {
+		if resourceManager.GetBranchType() != request.BranchType {
+			continue
+		}
+		cleaner, ok := resourceManager.(rm.UndoLogCleaner)
+		if !ok {
+			return fmt.Errorf("resource manager of branch type %v does not keep undo logs", request.BranchType)
+		}
+		return cleaner.DeleteUndoLogByLogCreated(ctx, request.ResourceId, logCreated)
+	}
+	return fmt.Errorf("no resource manager for branch type %v", request.BranchType)
+}
@@ -0,0 +1,78 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"seata.apache.org/seata-go/pkg/protocol/branch"
+	"seata.apache.org/seata-go/pkg/protocol/message"
+	"seata.apache.org/seata-go/pkg/rm"
+)
+
+// fakeUndoLogCleaner records the undo log delete calls
+type fakeUndoLogCleaner struct {
+	fakeResourceManager
+	resourceId string
+	logCreated time.Time
+}
+
+func (f *fakeUndoLogCleaner) DeleteUndoLogByLogCreated(ctx context.Context, resourceId string, logCreated time.Time) error {
+	f.resourceId = resourceId
+	f.logCreated = logCreated
+	return nil
+}
+
+func TestRmUndoLogDeleteProcessor(t *testing.T) {
+	cleaner := &fakeUndoLogCleaner{fakeResourceManager: fakeResourceManager{branchType: branch.BranchTypeAT}}
+	rm.GetRmCacheInstance().RegisterResourceManager(cleaner)
+
+	var processor rmUndoLogDeleteProcessor
+	tests := []struct {
+		name     string
+		saveDays message.MessageType
+		wantDays int
+	}{
+		{name: "save-3-days", saveDays: 3, wantDays: 3},
+		{name: "default-save-days", saveDays: 0, wantDays: defaultUndoLogSaveDays},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := processor.process(context.Background(), message.UndoLogDeleteRequest{
+				ResourceId: "test_resource",
+				SaveDays:   tc.saveDays,
+				BranchType: branch.BranchTypeAT,
+			})
+			assert.Nil(t, err)
+			assert.Equal(t, "test_resource", cleaner.resourceId)
+			assert.WithinDuration(t, time.Now().AddDate(0, 0, -tc.wantDays), cleaner.logCreated, time.Minute)
+		})
+	}
+
+	// tcc keeps no undo logs
+	rm.GetRmCacheInstance().RegisterResourceManager(&fakeResourceManager{branchType: branch.BranchTypeTCC})
+	err := processor.process(context.Background(), message.UndoLogDeleteRequest{
+		ResourceId: "test_resource",
+		BranchType: branch.BranchTypeTCC,
+	})
+	assert.NotNil(t, err)
+}
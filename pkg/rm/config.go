@@ -33,6 +33,8 @@ type Config struct {
 	SagaCompensatePersistModeUpdate bool       `yaml:"saga-compensate-persist-mode-update" json:"saga-compensate-persist-mode-update,omitempty" koanf:"saga-compensate-persist-mode-update"`
 	TccActionInterceptorOrder       int        `yaml:"tcc-action-interceptor-order" json:"tcc-action-interceptor-order,omitempty" koanf:"tcc-action-interceptor-order"`
 	SqlParserType                   string     `yaml:"sql-parser-type" json:"sql-parser-type,omitempty" koanf:"sql-parser-type"`
+	PhaseTwoWorkerCount             int        `yaml:"phase-two-worker-count" json:"phase-two-worker-count,omitempty" koanf:"phase-two-worker-count"`
+	PhaseTwoQueueSize               int        `yaml:"phase-two-queue-size" json:"phase-two-queue-size,omitempty" koanf:"phase-two-queue-size"`
 	LockConfig                      LockConfig `yaml:"lock" json:"lock,omitempty" koanf:"lock"`
 }
 
@@ -53,6 +55,8 @@ func (cfg *Config) RegisterFlagsWithPrefix(prefix string, f *flag.FlagSet) {
 	f.BoolVar(&cfg.SagaCompensatePersistModeUpdate, prefix+".saga-compensate-persist-mode-update", false, "")
 	f.IntVar(&cfg.TccActionInterceptorOrder, prefix+".tcc-action-interceptor-order", -2147482648, "The order of tccActionInterceptor.")
 	f.StringVar(&cfg.SqlParserType, prefix+".sql-parser-type", "druid", "The type of sql parser.")
+	f.IntVar(&cfg.PhaseTwoWorkerCount, prefix+".phase-two-worker-count", 16, "The worker count for processing branch commit and rollback requests from tc.")
+	f.IntVar(&cfg.PhaseTwoQueueSize, prefix+".phase-two-queue-size", 1024, "The maximum queued branch commit and rollback requests, requests exceeding it are replied as failed retryable.")
 	cfg.LockConfig.RegisterFlagsWithPrefix(prefix, f)
 }
 
@@ -17,7 +17,16 @@
 
 package rm
 
-var rmConfig RmConfig
+import (
+	"sync"
+
+	"seata.apache.org/seata-go/pkg/remoting/getty"
+)
+
+var (
+	rmConfig                RmConfig
+	onceRegisterOpenSession sync.Once
+)
 
 type RmConfig struct {
 	Config
@@ -29,4 +38,7 @@ type RmConfig struct {
 // InitRmClient init seata rm client
 func InitRm(cfg RmConfig) {
 	rmConfig = cfg
+	onceRegisterOpenSession.Do(func() {
+		getty.GetGettyClientHandlerInstance().RegisterSessionOpenListener(GetRMRemotingInstance().RegisterResourcesOnSession)
+	})
 }
@@ -20,6 +20,7 @@ package rm
 import (
 	"context"
 	"sync"
+	"time"
 
 	"seata.apache.org/seata-go/pkg/protocol/branch"
 )
@@ -31,6 +32,13 @@ type Resource interface {
 	GetBranchType() branch.BranchType
 }
 
+// TxServiceGroupResource is implemented by the resource registered to the tc cluster of its own
+// tx service group, the branches of it are registered to the cluster as well. The resource which
+// does not implement it or returns empty uses the tx service group in the config.
+type TxServiceGroupResource interface {
+	GetTxServiceGroup() string
+}
+
 // BranchResource contains branch to commit or rollback
 type BranchResource struct {
 	BranchType      branch.BranchType
@@ -56,15 +64,21 @@ type BranchRegisterParam struct {
 	Xid             string
 	ApplicationData string
 	LockKeys        string
+	// TxServiceGroup the tx service group the global transaction is begun on, the branch is
+	// registered to the tc cluster of the resource if it is empty.
+	TxServiceGroup string
 }
 
 // BranchReportParam Branch report function param for ResourceManager
 type BranchReportParam struct {
 	BranchType      branch.BranchType
+	ResourceId      string
 	Xid             string
 	BranchId        int64
 	Status          branch.BranchStatus
 	ApplicationData string
+	// TxServiceGroup the tx service group the global transaction is begun on
+	TxServiceGroup string
 }
 
 // LockQueryParam Lock query function param for ResourceManager
@@ -73,6 +87,8 @@ type LockQueryParam struct {
 	ResourceId string
 	Xid        string
 	LockKeys   string
+	// TxServiceGroup the tx service group the global transaction is begun on
+	TxServiceGroup string
 }
 
 // ResourceManagerOutbound Resource Manager: send outbound request to TC
@@ -100,6 +116,18 @@ type ResourceManager interface {
 	GetBranchType() branch.BranchType
 }
 
+// ResourceManagerCloser is implemented by the resource managers which own background goroutines
+type ResourceManagerCloser interface {
+	// Close stops the background goroutines and waits for them until ctx is done
+	Close(ctx context.Context) error
+}
+
+// UndoLogCleaner is implemented by the resource managers which keep undo logs, e.g. AT
+type UndoLogCleaner interface {
+	// DeleteUndoLogByLogCreated deletes the undo logs of the resource which are created before logCreated
+	DeleteUndoLogByLogCreated(ctx context.Context, resourceId string, logCreated time.Time) error
+}
+
 type ResourceManagerGetter interface {
 	GetResourceManager(branchType branch.BranchType) ResourceManager
 }
@@ -18,6 +18,8 @@
 package rm
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"sync"
 
@@ -30,6 +32,7 @@ var (
 	onceRMFacade    = &sync.Once{}
 )
 
+// GetRmCacheInstance returns the resource managers of the default client
 func GetRmCacheInstance() *ResourceManagerCache {
 	if rmCacheInstance == nil {
 		onceRMFacade.Do(func() {
@@ -39,6 +42,11 @@ func GetRmCacheInstance() *ResourceManagerCache {
 	return rmCacheInstance
 }
 
+// NewResourceManagerCache creates an empty cache of resource managers
+func NewResourceManagerCache() *ResourceManagerCache {
+	return &ResourceManagerCache{}
+}
+
 type ResourceManagerCache struct {
 	// BranchType -> ResourceManagerCache
 	resourceManagerMap sync.Map
@@ -55,3 +63,27 @@ func (d *ResourceManagerCache) GetResourceManager(branchType branch.BranchType)
 	}
 	return rm.(ResourceManager)
 }
+
+// GetResourceManagers get all registered resource managers
+func (d *ResourceManagerCache) GetResourceManagers() []ResourceManager {
+	managers := make([]ResourceManager, 0)
+	d.resourceManagerMap.Range(func(key, value interface{}) bool {
+		managers = append(managers, value.(ResourceManager))
+		return true
+	})
+	return managers
+}
+
+// Close closes all registered resource managers which implement ResourceManagerCloser
+func (d *ResourceManagerCache) Close(ctx context.Context) error {
+	var errs []error
+	d.resourceManagerMap.Range(func(key, value interface{}) bool {
+		if closer, ok := value.(ResourceManagerCloser); ok {
+			if err := closer.Close(ctx); err != nil {
+				errs = append(errs, fmt.Errorf("close resource manager %v: %w", key, err))
+			}
+		}
+		return true
+	})
+	return errors.Join(errs...)
+}
@@ -18,16 +18,33 @@
 package rm
 
 import (
+	"context"
 	"fmt"
+	"sort"
+	"strings"
 	"sync"
+	"time"
 
+	getty "github.com/apache/dubbo-getty"
 	"github.com/pkg/errors"
 
+	"seata.apache.org/seata-go/pkg/constant"
+	"seata.apache.org/seata-go/pkg/protocol/branch"
 	"seata.apache.org/seata-go/pkg/protocol/message"
-	"seata.apache.org/seata-go/pkg/remoting/getty"
+	remoting "seata.apache.org/seata-go/pkg/remoting/getty"
+	"seata.apache.org/seata-go/pkg/util/backoff"
 	"seata.apache.org/seata-go/pkg/util/log"
 )
 
+const (
+	// resourceIdSplitChar the separator of the resource ids in RegisterRMRequest
+	resourceIdSplitChar = ","
+
+	registerResourceRetryCount = 3
+	registerResourceMinBackoff = 500 * time.Millisecond
+	registerResourceMaxBackoff = 2 * time.Second
+)
+
 var (
 	rmRemoting        *RMRemoting
 	onceGettyRemoting = &sync.Once{}
@@ -35,6 +52,7 @@ var (
 
 var ErrBranchReportResponseFault = errors.New("branch report response fault")
 
+// GetRMRemotingInstance returns the rm remoting of the default client
 func GetRMRemotingInstance() *RMRemoting {
 	if rmRemoting == nil {
 		onceGettyRemoting.Do(func() {
@@ -44,7 +62,65 @@ func GetRMRemotingInstance() *RMRemoting {
 	return rmRemoting
 }
 
-type RMRemoting struct{}
+type RMRemoting struct {
+	// remotingClient, config and rmCache are the ones of the default client if they are nil
+	remotingClient *remoting.GettyRemotingClient
+	config         *RmConfig
+	rmCache        *ResourceManagerCache
+	// resourceGroups resource id -> the tx service group of the resource which is not the one in config
+	resourceGroups sync.Map
+}
+
+// NewRMRemoting creates a rm remoting which sends the requests by the remoting client, the
+// resources cached by rmCache are registered again on every new session of the client.
+func NewRMRemoting(cfg RmConfig, remotingClient *remoting.GettyRemotingClient, rmCache *ResourceManagerCache) *RMRemoting {
+	r := &RMRemoting{
+		remotingClient: remotingClient,
+		config:         &cfg,
+		rmCache:        rmCache,
+	}
+	remotingClient.RegisterSessionOpenListener(r.RegisterResourcesOnSession)
+	return r
+}
+
+func (r *RMRemoting) getRemotingClient() *remoting.GettyRemotingClient {
+	if r.remotingClient == nil {
+		return remoting.GetGettyRemotingClient()
+	}
+	return r.remotingClient
+}
+
+func (r *RMRemoting) getConfig() *RmConfig {
+	if r.config == nil {
+		return &rmConfig
+	}
+	return r.config
+}
+
+func (r *RMRemoting) getRmCache() *ResourceManagerCache {
+	if r.rmCache == nil {
+		return GetRmCacheInstance()
+	}
+	return r.rmCache
+}
+
+// getTxServiceGroup returns the tx service group the resource is registered to, the requests of
+// the resource and its branches are sent to the tc cluster of the group.
+func (r *RMRemoting) getTxServiceGroup(resourceId string) string {
+	if group, ok := r.resourceGroups.Load(resourceId); ok {
+		return group.(string)
+	}
+	return r.getConfig().TxServiceGroup
+}
+
+// getBranchTxServiceGroup returns the tx service group the requests of the branch are sent to, the
+// branch belongs to the tc cluster its global transaction is begun on, not the one of its resource.
+func (r *RMRemoting) getBranchTxServiceGroup(txServiceGroup, resourceId string) string {
+	if txServiceGroup != "" {
+		return txServiceGroup
+	}
+	return r.getTxServiceGroup(resourceId)
+}
 
 // BranchRegister  Register branch of global transaction
 func (r *RMRemoting) BranchRegister(param BranchRegisterParam) (int64, error) {
@@ -55,7 +131,7 @@ func (r *RMRemoting) BranchRegister(param BranchRegisterParam) (int64, error) {
 		BranchType:      param.BranchType,
 		ApplicationData: []byte(param.ApplicationData),
 	}
-	resp, err := getty.GetGettyRemotingClient().SendSyncRequest(request)
+	resp, err := r.getRemotingClient().SendSyncRequestToGroup(r.getBranchTxServiceGroup(param.TxServiceGroup, param.ResourceId), request)
 	if err != nil || resp == nil {
 		log.Errorf("BranchRegister error: %v, res %v", err.Error(), resp)
 		return 0, err
@@ -72,12 +148,13 @@ func (r *RMRemoting) BranchReport(param BranchReportParam) error {
 	request := message.BranchReportRequest{
 		Xid:             param.Xid,
 		BranchId:        param.BranchId,
+		ResourceId:      param.ResourceId,
 		Status:          param.Status,
 		ApplicationData: []byte(param.ApplicationData),
 		BranchType:      param.BranchType,
 	}
 
-	resp, err := getty.GetGettyRemotingClient().SendSyncRequest(request)
+	resp, err := r.getRemotingClient().SendSyncRequestToGroup(r.getBranchTxServiceGroup(param.TxServiceGroup, param.ResourceId), request)
 	if err != nil {
 		log.Errorf("branch report request error: %+v", err)
 		return err
@@ -101,7 +178,7 @@ func (r *RMRemoting) LockQuery(param LockQueryParam) (bool, error) {
 			BranchType: param.BranchType,
 		},
 	}
-	res, err := getty.GetGettyRemotingClient().SendSyncRequest(req)
+	res, err := r.getRemotingClient().SendSyncRequestToGroup(r.getBranchTxServiceGroup(param.TxServiceGroup, param.ResourceId), req)
 	if err != nil {
 		log.Errorf("send lock query request error: {%#v}", err.Error())
 		return false, err
@@ -115,16 +192,21 @@ func (r *RMRemoting) LockQuery(param LockQueryParam) (bool, error) {
 	return false, nil
 }
 
+// RegisterResource registers the resource to the tc cluster of its tx service group, see TxServiceGroupResource.
 func (r *RMRemoting) RegisterResource(resource Resource) error {
+	if res, ok := resource.(TxServiceGroupResource); ok && res.GetTxServiceGroup() != "" {
+		r.resourceGroups.Store(resource.GetResourceId(), res.GetTxServiceGroup())
+	}
+	txServiceGroup := r.getTxServiceGroup(resource.GetResourceId())
 	req := message.RegisterRMRequest{
 		AbstractIdentifyRequest: message.AbstractIdentifyRequest{
-			Version:                 "1.5.2",
-			ApplicationId:           rmConfig.ApplicationID,
-			TransactionServiceGroup: rmConfig.TxServiceGroup,
+			Version:                 constant.SeataVersion,
+			ApplicationId:           r.getConfig().ApplicationID,
+			TransactionServiceGroup: txServiceGroup,
 		},
 		ResourceIds: resource.GetResourceId(),
 	}
-	res, err := getty.GetGettyRemotingClient().SendSyncRequest(req)
+	res, err := r.getRemotingClient().SendSyncRequestToGroup(txServiceGroup, req)
 	if err != nil {
 		log.Errorf("RegisterResourceManager error: {%#v}", err.Error())
 		return err
@@ -139,6 +221,79 @@ func (r *RMRemoting) RegisterResource(resource Resource) error {
 	return nil
 }
 
+// RegisterResourcesOnSession registers the cached resources of the tx service group of the session
+// to the tc behind it, one request per branch type. It is called on every new session, so the tc
+// restarted or newly added can route the phase two requests back to this rm.
+func (r *RMRemoting) RegisterResourcesOnSession(session getty.Session) {
+	txServiceGroup := remoting.GetTxServiceGroup(session)
+	if txServiceGroup == "" {
+		txServiceGroup = r.getConfig().TxServiceGroup
+	}
+	inGroup := func(resourceId string) bool {
+		return r.getTxServiceGroup(resourceId) == txServiceGroup
+	}
+	for _, resourceManager := range r.getRmCache().GetResourceManagers() {
+		resourceIds := getMergedResourceIds(resourceManager.GetCachedResources(), inGroup)
+		if resourceIds == "" {
+			continue
+		}
+		if err := r.registerResourceIdsOnSession(session, txServiceGroup, resourceManager.GetBranchType(), resourceIds); err != nil {
+			log.Errorf("re-register resources [%s] of branch type %v to %s failed: %v",
+				resourceIds, resourceManager.GetBranchType(), session.RemoteAddr(), err)
+		}
+	}
+}
+
+func (r *RMRemoting) registerResourceIdsOnSession(session getty.Session, txServiceGroup string, branchType branch.BranchType, resourceIds string) error {
+	req := message.RegisterRMRequest{
+		AbstractIdentifyRequest: message.AbstractIdentifyRequest{
+			Version:                 constant.SeataVersion,
+			ApplicationId:           r.getConfig().ApplicationID,
+			TransactionServiceGroup: txServiceGroup,
+		},
+		ResourceIds: resourceIds,
+	}
+
+	bf := backoff.New(context.Background(), backoff.Config{
+		MaxRetries: registerResourceRetryCount,
+		MinBackoff: registerResourceMinBackoff,
+		MaxBackoff: registerResourceMaxBackoff,
+	})
+	var err error
+	for bf.Ongoing() {
+		if session.IsClosed() {
+			return fmt.Errorf("session is closed")
+		}
+		var res interface{}
+		if res, err = r.getRemotingClient().SendSyncRequestWithSession(session, req); err == nil {
+			if isRegisterSuccess(res) {
+				log.Infof("re-register resources [%s] of branch type %v to %s success", resourceIds, branchType, session.RemoteAddr())
+				return nil
+			}
+			err = fmt.Errorf("register rm response is not identified: %#v", res)
+		}
+		log.Warnf("re-register resources of branch type %v to %s failed, will retry: %v", branchType, session.RemoteAddr(), err)
+		bf.Wait()
+	}
+	return err
+}
+
+// getMergedResourceIds joins the ids of the resources accepted by filter with comma in a stable order
+func getMergedResourceIds(resources *sync.Map, filter func(resourceId string) bool) string {
+	if resources == nil {
+		return ""
+	}
+	ids := make([]string, 0)
+	resources.Range(func(key, value interface{}) bool {
+		if id, ok := key.(string); ok && id != "" && filter(id) {
+			ids = append(ids, id)
+		}
+		return true
+	})
+	sort.Strings(ids)
+	return strings.Join(ids, resourceIdSplitChar)
+}
+
 func isQueryLockSuccess(response interface{}) bool {
 	if res, ok := response.(message.GlobalLockQueryResponse); ok {
 		return res.Lockable
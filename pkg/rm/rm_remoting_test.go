@@ -18,9 +18,20 @@
 package rm
 
 import (
+	"fmt"
+	"reflect"
+	"sync"
 	"testing"
 
+	"github.com/agiledragon/gomonkey/v2"
+	getty "github.com/apache/dubbo-getty"
+	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
+
+	"seata.apache.org/seata-go/pkg/protocol/branch"
+	"seata.apache.org/seata-go/pkg/protocol/message"
+	remoting "seata.apache.org/seata-go/pkg/remoting/getty"
+	"seata.apache.org/seata-go/pkg/remoting/mock"
 )
 
 func TestGetRMRemotingInstance(t *testing.T) {
@@ -33,3 +44,121 @@ func TestGetRMRemotingInstance(t *testing.T) {
 		assert.Equalf(t, tests.want, GetRMRemotingInstance(), "GetRMRemotingInstance()")
 	})
 }
+
+func TestRMRemoting_RegisterResourcesOnSession(t *testing.T) {
+	ctl := gomock.NewController(t)
+
+	resources := &sync.Map{}
+	resources.Store("jdbc:mysql://127.0.0.1:3306/order", struct{}{})
+	resources.Store("jdbc:mysql://127.0.0.1:3306/account", struct{}{})
+	mockResourceManager := NewMockResourceManager(ctl)
+	mockResourceManager.EXPECT().GetBranchType().Return(branch.BranchTypeTCC).AnyTimes()
+	mockResourceManager.EXPECT().GetCachedResources().Return(resources).AnyTimes()
+	GetRmCacheInstance().RegisterResourceManager(mockResourceManager)
+
+	session := mock.NewMockTestSession(ctl)
+	session.EXPECT().IsClosed().Return(false).AnyTimes()
+	session.EXPECT().RemoteAddr().Return("127.0.0.1:8091").AnyTimes()
+	session.EXPECT().GetAttribute(gomock.Any()).Return(nil).AnyTimes()
+
+	var requests []message.RegisterRMRequest
+	stub := gomonkey.ApplyMethod(reflect.TypeOf(remoting.GetGettyRemotingClient()), "SendSyncRequestWithSession",
+		func(_ *remoting.GettyRemotingClient, s getty.Session, msg interface{}) (interface{}, error) {
+			assert.Equal(t, session, s)
+			requests = append(requests, msg.(message.RegisterRMRequest))
+			// the first request failed, and the second one should success
+			if len(requests) == 1 {
+				return nil, fmt.Errorf("wait response timeout")
+			}
+			return message.RegisterRMResponse{AbstractIdentifyResponse: message.AbstractIdentifyResponse{Identified: true}}, nil
+		})
+	defer stub.Reset()
+
+	GetRMRemotingInstance().RegisterResourcesOnSession(session)
+
+	assert.Equal(t, 2, len(requests))
+	assert.Equal(t, "jdbc:mysql://127.0.0.1:3306/account,jdbc:mysql://127.0.0.1:3306/order", requests[1].ResourceIds)
+}
+
+func TestGetMergedResourceIds(t *testing.T) {
+	all := func(string) bool { return true }
+	assert.Equal(t, "", getMergedResourceIds(nil, all))
+	assert.Equal(t, "", getMergedResourceIds(&sync.Map{}, all))
+
+	resources := &sync.Map{}
+	resources.Store("b", struct{}{})
+	resources.Store("a", struct{}{})
+	resources.Store("c", struct{}{})
+	assert.Equal(t, "a,b,c", getMergedResourceIds(resources, all))
+	assert.Equal(t, "a,c", getMergedResourceIds(resources, func(id string) bool { return id != "b" }))
+}
+
+type groupResource struct {
+	id    string
+	group string
+}
+
+func (r *groupResource) GetResourceGroupId() string       { return "" }
+func (r *groupResource) GetResourceId() string            { return r.id }
+func (r *groupResource) GetBranchType() branch.BranchType { return branch.BranchTypeTCC }
+func (r *groupResource) GetTxServiceGroup() string        { return r.group }
+
+func TestRMRemoting_RegisterResourceToGroup(t *testing.T) {
+	remotingClient := remoting.NewGettyRemotingClient()
+	rmRemoting := NewRMRemoting(RmConfig{TxServiceGroup: "default_tx_group"}, remotingClient, NewResourceManagerCache())
+
+	groups := make(map[string]string)
+	stub := gomonkey.ApplyMethod(reflect.TypeOf(remotingClient), "SendSyncRequestToGroup",
+		func(_ *remoting.GettyRemotingClient, txServiceGroup string, msg interface{}) (interface{}, error) {
+			switch req := msg.(type) {
+			case message.RegisterRMRequest:
+				assert.Equal(t, txServiceGroup, req.TransactionServiceGroup)
+				groups[req.ResourceIds] = txServiceGroup
+				return message.RegisterRMResponse{AbstractIdentifyResponse: message.AbstractIdentifyResponse{Identified: true}}, nil
+			case message.BranchRegisterRequest:
+				groups[req.Xid] = txServiceGroup
+				return message.BranchRegisterResponse{
+					AbstractTransactionResponse: message.AbstractTransactionResponse{
+						AbstractResultMessage: message.AbstractResultMessage{ResultCode: message.ResultCodeSuccess},
+					},
+					BranchId: 1,
+				}, nil
+			case message.BranchReportRequest:
+				groups["report "+req.Xid] = txServiceGroup
+				return message.BranchReportResponse{
+					AbstractTransactionResponse: message.AbstractTransactionResponse{
+						AbstractResultMessage: message.AbstractResultMessage{ResultCode: message.ResultCodeSuccess},
+					},
+				}, nil
+			case message.GlobalLockQueryRequest:
+				groups["lock "+req.Xid] = txServiceGroup
+				return message.GlobalLockQueryResponse{Lockable: true}, nil
+			}
+			return nil, fmt.Errorf("unexpected request %#v", msg)
+		})
+	defer stub.Reset()
+
+	assert.NoError(t, rmRemoting.RegisterResource(&groupResource{id: "order"}))
+	assert.NoError(t, rmRemoting.RegisterResource(&groupResource{id: "stock", group: "stock_tx_group"}))
+	_, err := rmRemoting.BranchRegister(BranchRegisterParam{Xid: "xid-1", ResourceId: "order"})
+	assert.NoError(t, err)
+	_, err = rmRemoting.BranchRegister(BranchRegisterParam{Xid: "xid-2", ResourceId: "stock"})
+	assert.NoError(t, err)
+	// the resource of the config group joins the global transaction begun on the stock group
+	_, err = rmRemoting.BranchRegister(BranchRegisterParam{Xid: "xid-3", ResourceId: "order", TxServiceGroup: "stock_tx_group"})
+	assert.NoError(t, err)
+	assert.NoError(t, rmRemoting.BranchReport(BranchReportParam{Xid: "xid-3", ResourceId: "order", TxServiceGroup: "stock_tx_group"}))
+	lockable, err := rmRemoting.LockQuery(LockQueryParam{Xid: "xid-3", ResourceId: "order", TxServiceGroup: "stock_tx_group"})
+	assert.NoError(t, err)
+	assert.True(t, lockable)
+
+	assert.Equal(t, map[string]string{
+		"order":        "default_tx_group",
+		"stock":        "stock_tx_group",
+		"xid-1":        "default_tx_group",
+		"xid-2":        "stock_tx_group",
+		"xid-3":        "stock_tx_group",
+		"report xid-3": "stock_tx_group",
+		"lock xid-3":   "stock_tx_group",
+	}, groups)
+}
@@ -48,7 +48,7 @@ func WithFence(ctx context.Context, tx *sql.Tx, callback func() error) (err erro
 // case 4: if fencePhase is FencePhaseRollback, will do rollback fence operation.
 // case 5: if fencePhase not in above case, will return a fence phase illegal error.
 func DoFence(ctx context.Context, tx *sql.Tx) error {
-	hd := handler.GetFenceHandler()
+	hd := handler.GetFenceHandlerFromContext(ctx)
 	phase := tm.GetFencePhase(ctx)
 
 	switch phase {
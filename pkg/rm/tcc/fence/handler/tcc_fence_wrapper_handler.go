@@ -35,12 +35,13 @@ import (
 	"seata.apache.org/seata-go/pkg/util/log"
 )
 
-type tccFenceWrapperHandler struct {
+type TCCFenceWrapperHandler struct {
 	tccFenceDao       dao.TCCFenceStore
 	logQueue          chan *FenceLogIdentity
 	logCache          list.List
 	logQueueOnce      sync.Once
 	logQueueCloseOnce sync.Once
+	logQueueDone      chan struct{}
 }
 
 type FenceLogIdentity struct {
@@ -53,22 +54,44 @@ const (
 )
 
 var (
-	fenceHandler *tccFenceWrapperHandler
+	fenceHandler *TCCFenceWrapperHandler
 	fenceOnce    sync.Once
 )
 
-func GetFenceHandler() *tccFenceWrapperHandler {
+// GetFenceHandler returns the fence handler of the default client
+func GetFenceHandler() *TCCFenceWrapperHandler {
 	if fenceHandler == nil {
 		fenceOnce.Do(func() {
-			fenceHandler = &tccFenceWrapperHandler{
-				tccFenceDao: dao.GetTccFenceStoreDatabaseMapper(),
-			}
+			fenceHandler = NewFenceHandler()
 		})
 	}
 	return fenceHandler
 }
 
-func (handler *tccFenceWrapperHandler) PrepareFence(ctx context.Context, tx *sql.Tx) error {
+// NewFenceHandler creates a fence handler with a log clean channel of its own
+func NewFenceHandler() *TCCFenceWrapperHandler {
+	return &TCCFenceWrapperHandler{
+		tccFenceDao: dao.GetTccFenceStoreDatabaseMapper(),
+	}
+}
+
+type fenceHandlerKey struct{}
+
+// WithFenceHandler binds the fence handler to ctx, the fence of the tcc actions called with ctx
+// is done by it instead of the default one.
+func WithFenceHandler(ctx context.Context, handler *TCCFenceWrapperHandler) context.Context {
+	return context.WithValue(ctx, fenceHandlerKey{}, handler)
+}
+
+// GetFenceHandlerFromContext returns the fence handler bound to ctx, the default one if ctx has none
+func GetFenceHandlerFromContext(ctx context.Context) *TCCFenceWrapperHandler {
+	if handler, ok := ctx.Value(fenceHandlerKey{}).(*TCCFenceWrapperHandler); ok && handler != nil {
+		return handler
+	}
+	return GetFenceHandler()
+}
+
+func (handler *TCCFenceWrapperHandler) PrepareFence(ctx context.Context, tx *sql.Tx) error {
 	xid := tm.GetBusinessActionContext(ctx).Xid
 	branchId := tm.GetBusinessActionContext(ctx).BranchId
 	actionName := tm.GetBusinessActionContext(ctx).ActionName
@@ -85,7 +108,7 @@ func (handler *tccFenceWrapperHandler) PrepareFence(ctx context.Context, tx *sql
 	return nil
 }
 
-func (handler *tccFenceWrapperHandler) CommitFence(ctx context.Context, tx *sql.Tx) error {
+func (handler *TCCFenceWrapperHandler) CommitFence(ctx context.Context, tx *sql.Tx) error {
 	xid := tm.GetBusinessActionContext(ctx).Xid
 	branchId := tm.GetBusinessActionContext(ctx).BranchId
 
@@ -110,7 +133,7 @@ func (handler *tccFenceWrapperHandler) CommitFence(ctx context.Context, tx *sql.
 	return handler.updateFenceStatus(tx, xid, branchId, enum.StatusCommitted)
 }
 
-func (handler *tccFenceWrapperHandler) RollbackFence(ctx context.Context, tx *sql.Tx) error {
+func (handler *TCCFenceWrapperHandler) RollbackFence(ctx context.Context, tx *sql.Tx) error {
 	xid := tm.GetBusinessActionContext(ctx).Xid
 	branchId := tm.GetBusinessActionContext(ctx).BranchId
 	actionName := tm.GetBusinessActionContext(ctx).ActionName
@@ -143,7 +166,7 @@ func (handler *tccFenceWrapperHandler) RollbackFence(ctx context.Context, tx *sq
 	return handler.updateFenceStatus(tx, xid, branchId, enum.StatusRollbacked)
 }
 
-func (handler *tccFenceWrapperHandler) insertTCCFenceLog(tx *sql.Tx, xid string, branchId int64, actionName string, status enum.FenceStatus) error {
+func (handler *TCCFenceWrapperHandler) insertTCCFenceLog(tx *sql.Tx, xid string, branchId int64, actionName string, status enum.FenceStatus) error {
 	tccFenceDo := model.TCCFenceDO{
 		Xid:        xid,
 		BranchId:   branchId,
@@ -153,33 +176,40 @@ func (handler *tccFenceWrapperHandler) insertTCCFenceLog(tx *sql.Tx, xid string,
 	return handler.tccFenceDao.InsertTCCFenceDO(tx, &tccFenceDo)
 }
 
-func (handler *tccFenceWrapperHandler) updateFenceStatus(tx *sql.Tx, xid string, branchId int64, status enum.FenceStatus) error {
+func (handler *TCCFenceWrapperHandler) updateFenceStatus(tx *sql.Tx, xid string, branchId int64, status enum.FenceStatus) error {
 	return handler.tccFenceDao.UpdateTCCFenceDO(tx, xid, branchId, enum.StatusTried, status)
 }
 
-func (handler *tccFenceWrapperHandler) InitLogCleanChannel() {
+func (handler *TCCFenceWrapperHandler) InitLogCleanChannel() {
 	handler.logQueueOnce.Do(func() {
+		handler.logQueue = make(chan *FenceLogIdentity, maxQueueSize)
+		handler.logQueueDone = make(chan struct{})
 		go handler.traversalCleanChannel()
 	})
 }
 
-func (handler *tccFenceWrapperHandler) DestroyLogCleanChannel() {
+// DestroyLogCleanChannel closes the clean channel and waits for the clean goroutine to exit
+func (handler *TCCFenceWrapperHandler) DestroyLogCleanChannel() {
 	handler.logQueueCloseOnce.Do(func() {
+		if handler.logQueue == nil {
+			return
+		}
 		close(handler.logQueue)
+		<-handler.logQueueDone
 	})
 }
 
-func (handler *tccFenceWrapperHandler) deleteFence(xid string, id int64) error {
+func (handler *TCCFenceWrapperHandler) deleteFence(xid string, id int64) error {
 	// todo implement
 	return nil
 }
 
-func (handler *tccFenceWrapperHandler) deleteFenceByDate(datetime time.Time) int32 {
+func (handler *TCCFenceWrapperHandler) deleteFenceByDate(datetime time.Time) int32 {
 	// todo implement
 	return 0
 }
 
-func (handler *tccFenceWrapperHandler) pushCleanChannel(xid string, branchId int64) {
+func (handler *TCCFenceWrapperHandler) pushCleanChannel(xid string, branchId int64) {
 	// todo implement
 	fli := &FenceLogIdentity{
 		xid:      xid,
@@ -194,8 +224,8 @@ func (handler *tccFenceWrapperHandler) pushCleanChannel(xid string, branchId int
 	log.Infof("add one log to clean queue: %v ", fli)
 }
 
-func (handler *tccFenceWrapperHandler) traversalCleanChannel() {
-	handler.logQueue = make(chan *FenceLogIdentity, maxQueueSize)
+func (handler *TCCFenceWrapperHandler) traversalCleanChannel() {
+	defer close(handler.logQueueDone)
 	for li := range handler.logQueue {
 		if err := handler.deleteFence(li.xid, li.branchId); err != nil {
 			log.Errorf("delete fence log failed, xid: %s, branchId: &s", li.xid, li.branchId)
@@ -27,6 +27,7 @@ import (
 	"seata.apache.org/seata-go/pkg/protocol/branch"
 	"seata.apache.org/seata-go/pkg/rm"
 	"seata.apache.org/seata-go/pkg/rm/tcc/fence/enum"
+	"seata.apache.org/seata-go/pkg/rm/tcc/fence/handler"
 	"seata.apache.org/seata-go/pkg/tm"
 	"seata.apache.org/seata-go/pkg/util/log"
 )
@@ -39,6 +40,9 @@ var (
 type TCCResource struct {
 	ResourceGroupId string `default:"DEFAULT"`
 	AppName         string
+	// TxServiceGroup the resource is registered to the tc cluster of the group, it is the one in
+	// the config if it is empty. It is read from the service implementing rm.TxServiceGroupResource.
+	TxServiceGroup string
 	*rm.TwoPhaseAction
 }
 
@@ -48,12 +52,16 @@ func ParseTCCResource(v interface{}) (*TCCResource, error) {
 		log.Errorf("%#v is not tcc two phase service, %s", v, err.Error())
 		return nil, err
 	}
-	return &TCCResource{
+	resource := &TCCResource{
 		// todo read from config
 		ResourceGroupId: `default:"DEFAULT"`,
 		AppName:         "seata-go-mock-app-name",
 		TwoPhaseAction:  t,
-	}, nil
+	}
+	if groupResource, ok := v.(rm.TxServiceGroupResource); ok {
+		resource.TxServiceGroup = groupResource.GetTxServiceGroup()
+	}
+	return resource, nil
 }
 
 func (t *TCCResource) GetResourceGroupId() string {
@@ -68,28 +76,50 @@ func (t *TCCResource) GetBranchType() branch.BranchType {
 	return branch.BranchTypeTCC
 }
 
+func (t *TCCResource) GetTxServiceGroup() string {
+	return t.TxServiceGroup
+}
+
 func InitTCC() {
 	rm.GetRmCacheInstance().RegisterResourceManager(GetTCCResourceManagerInstance())
 }
 
+// GetTCCResourceManagerInstance returns the tcc resource manager of the default client
 func GetTCCResourceManagerInstance() *TCCResourceManager {
 	if tCCResourceManager == nil {
 		onceTCCResourceManager.Do(func() {
-			tCCResourceManager = &TCCResourceManager{
-				resourceManagerMap: sync.Map{},
-				rmRemoting:         rm.GetRMRemotingInstance(),
-			}
+			tCCResourceManager = NewTCCResourceManager(rm.GetRMRemotingInstance(), nil)
 		})
 	}
 	return tCCResourceManager
 }
 
+// NewTCCResourceManager creates a tcc resource manager which registers the resources and the
+// branches by rmRemoting, the fence of its branches is done by fenceHandler, or the default
+// fence handler if it is nil.
+func NewTCCResourceManager(rmRemoting *rm.RMRemoting, fenceHandler *handler.TCCFenceWrapperHandler) *TCCResourceManager {
+	return &TCCResourceManager{
+		rmRemoting:         rmRemoting,
+		fenceHandler:       fenceHandler,
+		resourceManagerMap: sync.Map{},
+	}
+}
+
 type TCCResourceManager struct {
-	rmRemoting *rm.RMRemoting
+	rmRemoting   *rm.RMRemoting
+	fenceHandler *handler.TCCFenceWrapperHandler
 	// resourceID -> resource
 	resourceManagerMap sync.Map
 }
 
+// withFenceHandler binds the fence handler of the resource manager to ctx
+func (t *TCCResourceManager) withFenceHandler(ctx context.Context) context.Context {
+	if t == nil || t.fenceHandler == nil {
+		return ctx
+	}
+	return handler.WithFenceHandler(ctx, t.fenceHandler)
+}
+
 // BranchRegister register transaction branch
 func (t *TCCResourceManager) BranchRegister(ctx context.Context, param rm.BranchRegisterParam) (int64, error) {
 	return t.rmRemoting.BranchRegister(param)
@@ -136,7 +166,7 @@ func (t *TCCResourceManager) BranchCommit(ctx context.Context, branchResource rm
 	businessActionContext := t.getBusinessActionContext(branchResource.Xid, branchResource.BranchId, branchResource.ResourceId, branchResource.ApplicationData)
 
 	// to set up the fence phase
-	ctx = tm.InitSeataContext(ctx)
+	ctx = t.withFenceHandler(tm.InitSeataContext(ctx))
 	tm.SetXID(ctx, branchResource.Xid)
 	tm.SetFencePhase(ctx, enum.FencePhaseCommit)
 	tm.SetBusinessActionContext(ctx, businessActionContext)
@@ -181,7 +211,7 @@ func (t *TCCResourceManager) BranchRollback(ctx context.Context, branchResource
 	businessActionContext := t.getBusinessActionContext(branchResource.Xid, branchResource.BranchId, branchResource.ResourceId, branchResource.ApplicationData)
 
 	// to set up the fence phase
-	ctx = tm.InitSeataContext(ctx)
+	ctx = t.withFenceHandler(tm.InitSeataContext(ctx))
 	tm.SetXID(ctx, branchResource.Xid)
 	tm.SetFencePhase(ctx, enum.FencePhaseRollback)
 	tm.SetBusinessActionContext(ctx, businessActionContext)
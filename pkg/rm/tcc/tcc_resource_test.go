@@ -29,10 +29,29 @@ import (
 	"seata.apache.org/seata-go/pkg/protocol/message"
 	"seata.apache.org/seata-go/pkg/remoting/getty"
 	"seata.apache.org/seata-go/pkg/rm"
+	"seata.apache.org/seata-go/testdata"
 
 	"github.com/stretchr/testify/assert"
 )
 
+type groupTwoPhaseService struct {
+	testdata.TestTwoPhaseService
+}
+
+func (*groupTwoPhaseService) GetTxServiceGroup() string {
+	return "order_tx_group"
+}
+
+func TestParseTCCResourceTxServiceGroup(t *testing.T) {
+	resource, err := ParseTCCResource(&testdata.TestTwoPhaseService{})
+	assert.NoError(t, err)
+	assert.Empty(t, resource.GetTxServiceGroup())
+
+	resource, err = ParseTCCResource(&groupTwoPhaseService{})
+	assert.NoError(t, err)
+	assert.Equal(t, "order_tx_group", resource.GetTxServiceGroup())
+}
+
 func TestActionContext(t *testing.T) {
 	applicationData := `{"actionContext":{"zhangsan":"lisi"}}`
 	businessActionContext := GetTCCResourceManagerInstance().
@@ -46,7 +65,7 @@ func TestActionContext(t *testing.T) {
 
 // TestBranchReport
 func TestBranchReport(t *testing.T) {
-	patches := gomonkey.ApplyMethod(reflect.TypeOf(getty.GetGettyRemotingClient()), "SendSyncRequest", func(_ *getty.GettyRemotingClient, msg interface{}) (interface{}, error) {
+	patches := gomonkey.ApplyMethod(reflect.TypeOf(getty.GetGettyRemotingClient()), "SendSyncRequestToGroup", func(_ *getty.GettyRemotingClient, _ string, msg interface{}) (interface{}, error) {
 		return message.BranchReportResponse{
 			AbstractTransactionResponse: message.AbstractTransactionResponse{
 				AbstractResultMessage: message.AbstractResultMessage{
@@ -39,25 +39,48 @@ import (
 type TCCServiceProxy struct {
 	referenceName        string
 	registerResourceOnce sync.Once
+	// resourceManager is the tcc resource manager of the default client if it is nil
+	resourceManager *TCCResourceManager
 	*TCCResource
 }
 
 func NewTCCServiceProxy(service interface{}) (*TCCServiceProxy, error) {
+	return NewTCCServiceProxyWithResourceManager(service, nil)
+}
+
+// NewTCCServiceProxyWithResourceManager creates a tcc service proxy whose resource and branches
+// are registered by the resource manager, it is the one of the default client if it is nil.
+func NewTCCServiceProxyWithResourceManager(service interface{}, resourceManager *TCCResourceManager) (*TCCServiceProxy, error) {
 	tccResource, err := ParseTCCResource(service)
 	if err != nil {
 		log.Errorf("invalid tcc service, err %v", err)
 		return nil, err
 	}
 	proxy := &TCCServiceProxy{
-		TCCResource: tccResource,
+		resourceManager: resourceManager,
+		TCCResource:     tccResource,
 	}
 	return proxy, proxy.RegisterResource()
 }
 
+func (t *TCCServiceProxy) getResourceManager() rm.ResourceManager {
+	if t.resourceManager == nil {
+		return rm.GetRmCacheInstance().GetResourceManager(branch.BranchTypeTCC)
+	}
+	return t.resourceManager
+}
+
+func (t *TCCServiceProxy) getRMRemoting() *rm.RMRemoting {
+	if t.resourceManager == nil {
+		return rm.GetRMRemotingInstance()
+	}
+	return t.resourceManager.rmRemoting
+}
+
 func (t *TCCServiceProxy) RegisterResource() error {
 	var err error
 	t.registerResourceOnce.Do(func() {
-		err = rm.GetRmCacheInstance().GetResourceManager(branch.BranchTypeTCC).RegisterResource(t.TCCResource)
+		err = t.getResourceManager().RegisterResource(t.TCCResource)
 		if err != nil {
 			log.Errorf("NewTCCServiceProxy RegisterResource error: %#v", err.Error())
 		}
@@ -86,7 +109,7 @@ func (t *TCCServiceProxy) Prepare(ctx context.Context, params interface{}) (inte
 
 	// to set up the fence phase
 	tm.SetFencePhase(ctx, enum.FencePhasePrepare)
-	return t.TCCResource.Prepare(ctx, params)
+	return t.TCCResource.Prepare(t.resourceManager.withFenceHandler(ctx), params)
 }
 
 // registeBranch send register branch transaction request
@@ -106,13 +129,14 @@ func (t *TCCServiceProxy) registeBranch(ctx context.Context, params interface{})
 	applicationData, _ := json.Marshal(map[string]interface{}{
 		constant.ActionContext: actionContext,
 	})
-	branchId, err := rm.GetRMRemotingInstance().BranchRegister(rm.BranchRegisterParam{
+	branchId, err := t.getRMRemoting().BranchRegister(rm.BranchRegisterParam{
 		BranchType:      branch.BranchTypeTCC,
 		ResourceId:      t.GetActionName(),
 		ClientId:        "",
 		Xid:             tm.GetXID(ctx),
 		ApplicationData: string(applicationData),
 		LockKeys:        "",
+		TxServiceGroup:  tm.GetTxServiceGroup(ctx),
 	})
 	if err != nil {
 		log.Errorf("register branch transaction error %s ", err.Error())
@@ -0,0 +1,58 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tc
+
+import (
+	"flag"
+	"time"
+)
+
+type Config struct {
+	Address            string        `yaml:"address" json:"address" koanf:"address"`
+	RequestTimeout     time.Duration `yaml:"request-timeout" json:"request-timeout" koanf:"request-timeout"`
+	TimeoutCheckPeriod time.Duration `yaml:"timeout-check-period" json:"timeout-check-period" koanf:"timeout-check-period"`
+	RetryPeriod        time.Duration `yaml:"retry-period" json:"retry-period" koanf:"retry-period"`
+	StoreConfig        StoreConfig   `yaml:"store" json:"store" koanf:"store"`
+}
+
+// RegisterFlags registers the flags of the standalone tc
+func (cfg *Config) RegisterFlags(f *flag.FlagSet) {
+	f.StringVar(&cfg.Address, "address", ":8091", "The address the tc listens on.")
+	f.DurationVar(&cfg.RequestTimeout, "request-timeout", 30*time.Second, "The timeout to wait for the branch commit/rollback response of the rm.")
+	f.DurationVar(&cfg.TimeoutCheckPeriod, "timeout-check-period", time.Second, "The period to rollback the timeout global transactions, disabled if not positive.")
+	f.DurationVar(&cfg.RetryPeriod, "retry-period", time.Second, "The period to retry the commit/rollback of the global transactions, disabled if not positive.")
+	cfg.StoreConfig.RegisterFlagsWithPrefix("store", f)
+}
+
+type StoreConfig struct {
+	Mode string          `yaml:"mode" json:"mode" koanf:"mode"`
+	File FileStoreConfig `yaml:"file" json:"file" koanf:"file"`
+}
+
+func (cfg *StoreConfig) RegisterFlagsWithPrefix(prefix string, f *flag.FlagSet) {
+	f.StringVar(&cfg.Mode, prefix+".mode", StoreModeMemory, "The session store mode, memory or file.")
+	cfg.File.RegisterFlagsWithPrefix(prefix+".file", f)
+}
+
+type FileStoreConfig struct {
+	Dir string `yaml:"dir" json:"dir" koanf:"dir"`
+}
+
+func (cfg *FileStoreConfig) RegisterFlagsWithPrefix(prefix string, f *flag.FlagSet) {
+	f.StringVar(&cfg.Dir, prefix+".dir", "sessionStore", "The dir of the session files.")
+}
@@ -0,0 +1,560 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tc
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	getty "github.com/apache/dubbo-getty"
+	"go.uber.org/atomic"
+
+	"seata.apache.org/seata-go/pkg/protocol/branch"
+	"seata.apache.org/seata-go/pkg/protocol/message"
+	serror "seata.apache.org/seata-go/pkg/util/errors"
+	"seata.apache.org/seata-go/pkg/util/log"
+)
+
+const resourceIdSplitChar = ","
+
+// phaseTwo describes the status transitions of a global commit or rollback
+type phaseTwo struct {
+	commit     bool
+	doing      message.GlobalStatus
+	retrying   message.GlobalStatus
+	done       message.GlobalStatus
+	failed     message.GlobalStatus
+	branchDone branch.BranchStatus
+	// branchFailed the branch status which can not be retried
+	branchFailed branch.BranchStatus
+}
+
+var (
+	commitPhase = phaseTwo{
+		commit:       true,
+		doing:        message.GlobalStatusCommitting,
+		retrying:     message.GlobalStatusCommitRetrying,
+		done:         message.GlobalStatusCommitted,
+		failed:       message.GlobalStatusCommitFailed,
+		branchDone:   branch.BranchStatusPhasetwoCommitted,
+		branchFailed: branch.BranchStatusPhasetwoCommitFailedUnretryable,
+	}
+	rollbackPhase = phaseTwo{
+		doing:        message.GlobalStatusRollbacking,
+		retrying:     message.GlobalStatusRollbackRetrying,
+		done:         message.GlobalStatusRollbacked,
+		failed:       message.GlobalStatusRollbackFailed,
+		branchDone:   branch.BranchStatusPhasetwoRollbacked,
+		branchFailed: branch.BranchStatusPhasetwoRollbackFailedUnretryable,
+	}
+	timeoutRollbackPhase = phaseTwo{
+		doing:        message.GlobalStatusTimeoutRollbacking,
+		retrying:     message.GlobalStatusTimeoutRollbackRetrying,
+		done:         message.GlobalStatusTimeoutRollbacked,
+		failed:       message.GlobalStatusTimeoutRollbackFailed,
+		branchDone:   branch.BranchStatusPhasetwoRollbacked,
+		branchFailed: branch.BranchStatusPhasetwoRollbackFailedUnretryable,
+	}
+
+	// retryPhases the phase two to retry by the retrying global status
+	retryPhases = map[message.GlobalStatus]phaseTwo{
+		message.GlobalStatusCommitRetrying:          commitPhase,
+		message.GlobalStatusRollbackRetrying:        rollbackPhase,
+		message.GlobalStatusTimeoutRollbackRetrying: timeoutRollbackPhase,
+	}
+	// interruptedPhases the phase two which was interrupted by a restart is retried
+	interruptedPhases = map[message.GlobalStatus]message.GlobalStatus{
+		message.GlobalStatusCommitting:         message.GlobalStatusCommitRetrying,
+		message.GlobalStatusRollbacking:        message.GlobalStatusRollbackRetrying,
+		message.GlobalStatusTimeoutRollbacking: message.GlobalStatusTimeoutRollbackRetrying,
+	}
+)
+
+func isFinished(status message.GlobalStatus) bool {
+	switch status {
+	case message.GlobalStatusCommitted, message.GlobalStatusCommitFailed,
+		message.GlobalStatusRollbacked, message.GlobalStatusRollbackFailed,
+		message.GlobalStatusTimeoutRollbacked, message.GlobalStatusTimeoutRollbackFailed,
+		message.GlobalStatusFinished:
+		return true
+	}
+	return false
+}
+
+// branchTask the branch to drive in phase two with the rm session which registered it
+type branchTask struct {
+	BranchSession
+	session getty.Session
+}
+
+type coordinator struct {
+	store SessionStore
+	// keepFinished keeps the finished global sessions rather than removing them
+	keepFinished bool
+	// send sends the request to the rm session and waits for the response
+	send      func(ctx context.Context, session getty.Session, msg interface{}) (interface{}, error)
+	now       func() time.Time
+	xidPrefix string
+
+	idGenerator *atomic.Int64
+	locks       *lockTable
+
+	mu sync.Mutex
+	// xid -> global session
+	globals map[string]*GlobalSession
+	// branch id -> the rm session which registered the branch
+	branchSessions map[int64]getty.Session
+	// resource id -> rm sessions
+	resources map[string]map[getty.Session]struct{}
+}
+
+func newCoordinator(store SessionStore, keepFinished bool) *coordinator {
+	return &coordinator{
+		store:          store,
+		keepFinished:   keepFinished,
+		now:            time.Now,
+		idGenerator:    atomic.NewInt64(time.Now().UnixNano() / int64(time.Microsecond)),
+		locks:          newLockTable(),
+		globals:        make(map[string]*GlobalSession),
+		branchSessions: make(map[int64]getty.Session),
+		resources:      make(map[string]map[getty.Session]struct{}),
+	}
+}
+
+// restore loads the global sessions from the store, the locks of the unfinished ones are held
+// again and their interrupted phase two will be retried
+func (c *coordinator) restore() error {
+	sessions, err := c.store.LoadAll()
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	maxId := int64(0)
+	for _, g := range sessions {
+		if status, ok := interruptedPhases[g.Status]; ok {
+			g.Status = status
+		}
+		if isFinished(g.Status) && !c.keepFinished {
+			if err = c.store.Remove(g.Xid); err != nil {
+				return err
+			}
+			continue
+		}
+		c.globals[g.Xid] = g
+		if g.TransactionId > maxId {
+			maxId = g.TransactionId
+		}
+		for _, b := range g.Branches {
+			if b.BranchId > maxId {
+				maxId = b.BranchId
+			}
+			if b.LockKey != "" && !isFinished(g.Status) {
+				c.locks.acquire(g.Xid, parseLockKey(b.ResourceId, b.LockKey))
+			}
+		}
+	}
+	if maxId >= c.idGenerator.Load() {
+		c.idGenerator.Store(maxId)
+	}
+	log.Infof("tc restored %d global sessions", len(c.globals))
+	return nil
+}
+
+// handle processes the request and returns the response, nil if the request is not supported
+func (c *coordinator) handle(session getty.Session, msg interface{}) interface{} {
+	switch req := msg.(type) {
+	case message.RegisterTMRequest:
+		return message.RegisterTMResponse{AbstractIdentifyResponse: identified(req.Version)}
+	case message.RegisterRMRequest:
+		c.registerResources(session, req.ResourceIds)
+		return message.RegisterRMResponse{AbstractIdentifyResponse: identified(req.Version)}
+	case message.GlobalBeginRequest:
+		return c.begin(req)
+	case message.BranchRegisterRequest:
+		return c.branchRegister(session, req)
+	case message.BranchReportRequest:
+		return c.branchReport(req)
+	case message.GlobalCommitRequest:
+		return message.GlobalCommitResponse{AbstractGlobalEndResponse: globalEndResponse(c.commit(req.Xid))}
+	case message.GlobalRollbackRequest:
+		return message.GlobalRollbackResponse{AbstractGlobalEndResponse: globalEndResponse(c.rollback(req.Xid))}
+	case message.GlobalStatusRequest:
+		return message.GlobalStatusResponse{AbstractGlobalEndResponse: globalEndResponse(c.globalStatus(req.Xid))}
+	case message.GlobalLockQueryRequest:
+		return message.GlobalLockQueryResponse{
+			AbstractTransactionResponse: success(),
+			Lockable:                    c.locks.lockable(req.Xid, parseLockKey(req.ResourceId, req.LockKey)),
+		}
+	default:
+		log.Errorf("tc does not support the message %#v", msg)
+		return nil
+	}
+}
+
+func (c *coordinator) registerResources(session getty.Session, resourceIds string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, resourceId := range strings.Split(resourceIds, resourceIdSplitChar) {
+		if resourceId == "" {
+			continue
+		}
+		sessions, ok := c.resources[resourceId]
+		if !ok {
+			sessions = make(map[getty.Session]struct{})
+			c.resources[resourceId] = sessions
+		}
+		sessions[session] = struct{}{}
+	}
+}
+
+func (c *coordinator) removeSession(session getty.Session) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, sessions := range c.resources {
+		delete(sessions, session)
+	}
+	for branchId, s := range c.branchSessions {
+		if s == session {
+			delete(c.branchSessions, branchId)
+		}
+	}
+}
+
+func (c *coordinator) begin(req message.GlobalBeginRequest) message.GlobalBeginResponse {
+	transactionId := c.idGenerator.Inc()
+	g := &GlobalSession{
+		Xid:           fmt.Sprintf("%s:%d", c.xidPrefix, transactionId),
+		TransactionId: transactionId,
+		Name:          req.TransactionName,
+		Timeout:       req.Timeout,
+		BeginTime:     c.now(),
+		Status:        message.GlobalStatusBegin,
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.store.Save(g); err != nil {
+		return message.GlobalBeginResponse{AbstractTransactionResponse: failed(
+			serror.TransactionErrorCodeBeginFailed, "save global session failed: %v", err)}
+	}
+	c.globals[g.Xid] = g
+	return message.GlobalBeginResponse{AbstractTransactionResponse: success(), Xid: g.Xid}
+}
+
+func (c *coordinator) branchRegister(session getty.Session, req message.BranchRegisterRequest) message.BranchRegisterResponse {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	g, ok := c.globals[req.Xid]
+	if !ok {
+		return message.BranchRegisterResponse{AbstractTransactionResponse: failed(
+			serror.TransactionErrorCodeGlobalTransactionNotExist, "global transaction %s does not exist", req.Xid)}
+	}
+	if g.Status != message.GlobalStatusBegin || g.expired(c.now()) {
+		return message.BranchRegisterResponse{AbstractTransactionResponse: failed(
+			serror.TransactionErrorCodeGlobalTransactionNotActive, "global transaction %s is not active, status %d",
+			req.Xid, g.Status)}
+	}
+
+	branchId := c.idGenerator.Inc()
+	acquired, row, ok := c.locks.acquire(req.Xid, parseLockKey(req.ResourceId, req.LockKey))
+	if !ok {
+		return message.BranchRegisterResponse{AbstractTransactionResponse: failed(
+			serror.TransactionErrorCodeLockKeyConflict, "global lock acquire failed xid = %s branchId = %d, conflict row %s",
+			req.Xid, branchId, row)}
+	}
+	g.Branches = append(g.Branches, &BranchSession{
+		BranchId:        branchId,
+		BranchType:      req.BranchType,
+		ResourceId:      req.ResourceId,
+		LockKey:         req.LockKey,
+		ApplicationData: req.ApplicationData,
+		Status:          branch.BranchStatusRegistered,
+	})
+	if err := c.store.Save(g); err != nil {
+		g.Branches = g.Branches[:len(g.Branches)-1]
+		// the rows held by the earlier branches of the xid are kept
+		c.locks.releaseRows(req.Xid, acquired)
+		return message.BranchRegisterResponse{AbstractTransactionResponse: failed(
+			serror.TransactionErrorCodeFailedWriteSession, "save branch session failed: %v", err)}
+	}
+	c.branchSessions[branchId] = session
+	return message.BranchRegisterResponse{AbstractTransactionResponse: success(), BranchId: branchId}
+}
+
+func (c *coordinator) branchReport(req message.BranchReportRequest) message.BranchReportResponse {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	g, ok := c.globals[req.Xid]
+	if !ok || g.branch(req.BranchId) == nil {
+		return message.BranchReportResponse{AbstractTransactionResponse: failed(
+			serror.TransactionErrorCodeBranchTransactionNotExist, "branch %d of %s does not exist", req.BranchId, req.Xid)}
+	}
+	b := g.branch(req.BranchId)
+	b.Status = req.Status
+	if len(req.ApplicationData) > 0 {
+		b.ApplicationData = req.ApplicationData
+	}
+	if err := c.store.Save(g); err != nil {
+		return message.BranchReportResponse{AbstractTransactionResponse: failed(
+			serror.TransactionErrorCodeFailedWriteSession, "save branch session failed: %v", err)}
+	}
+	return message.BranchReportResponse{AbstractTransactionResponse: success()}
+}
+
+// commit commits the global transaction, it is rolled back if it is timeout already
+func (c *coordinator) commit(xid string) message.GlobalStatus {
+	c.mu.Lock()
+	g, ok := c.globals[xid]
+	expired := ok && g.expired(c.now())
+	c.mu.Unlock()
+	if expired {
+		return c.doPhaseTwo(xid, message.GlobalStatusBegin, timeoutRollbackPhase)
+	}
+	return c.doPhaseTwo(xid, message.GlobalStatusBegin, commitPhase)
+}
+
+func (c *coordinator) rollback(xid string) message.GlobalStatus {
+	return c.doPhaseTwo(xid, message.GlobalStatusBegin, rollbackPhase)
+}
+
+// doPhaseTwo drives the branches of the global transaction in the status from, the branches
+// are committed in the register order and rolled back in the reverse order. The branches done
+// already are skipped, so the retry continues the interrupted phase two. It returns the status
+// after the phase two, or the current status if the transaction is not in the status from.
+func (c *coordinator) doPhaseTwo(xid string, from message.GlobalStatus, phase phaseTwo) message.GlobalStatus {
+	c.mu.Lock()
+	g, ok := c.globals[xid]
+	if !ok {
+		c.mu.Unlock()
+		return message.GlobalStatusFinished
+	}
+	if g.Status != from {
+		status := g.Status
+		c.mu.Unlock()
+		return status
+	}
+	g.Status = phase.doing
+	c.save(g)
+	tasks := make([]branchTask, 0, len(g.Branches))
+	for _, b := range g.Branches {
+		if b.Status != branch.BranchStatusPhaseoneFailed && b.Status != phase.branchDone {
+			tasks = append(tasks, branchTask{BranchSession: *b, session: c.branchSessions[b.BranchId]})
+		}
+	}
+	c.mu.Unlock()
+
+	if !phase.commit {
+		for i, j := 0, len(tasks)-1; i < j; i, j = i+1, j-1 {
+			tasks[i], tasks[j] = tasks[j], tasks[i]
+		}
+	}
+	status := phase.done
+	for _, task := range tasks {
+		branchStatus := c.branchPhaseTwo(xid, task, phase.commit)
+		c.setBranchStatus(xid, task.BranchId, branchStatus)
+		if branchStatus == phase.branchDone {
+			continue
+		}
+		if branchStatus == phase.branchFailed {
+			status = phase.failed
+			break
+		}
+		status = phase.retrying
+	}
+	c.endPhaseTwo(xid, status)
+	return status
+}
+
+// endPhaseTwo saves the status, the finished global transaction releases its locks and is removed
+func (c *coordinator) endPhaseTwo(xid string, status message.GlobalStatus) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	g, ok := c.globals[xid]
+	if !ok {
+		return
+	}
+	g.Status = status
+	if !isFinished(status) {
+		c.save(g)
+		return
+	}
+	c.locks.release(xid)
+	for _, b := range g.Branches {
+		delete(c.branchSessions, b.BranchId)
+	}
+	if c.keepFinished {
+		c.save(g)
+		return
+	}
+	delete(c.globals, xid)
+	if err := c.store.Remove(xid); err != nil {
+		log.Errorf("remove global session %s failed: %v", xid, err)
+	}
+}
+
+func (c *coordinator) setBranchStatus(xid string, branchId int64, status branch.BranchStatus) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if g, ok := c.globals[xid]; ok {
+		if b := g.branch(branchId); b != nil {
+			b.Status = status
+			c.save(g)
+		}
+	}
+}
+
+// save saves the global session, the error is logged only since the phase two is retried anyway
+func (c *coordinator) save(g *GlobalSession) {
+	if err := c.store.Save(g); err != nil {
+		log.Errorf("save global session %s failed: %v", g.Xid, err)
+	}
+}
+
+func (c *coordinator) branchPhaseTwo(xid string, task branchTask, commit bool) branch.BranchStatus {
+	request := message.AbstractBranchEndRequest{
+		Xid:             xid,
+		BranchId:        task.BranchId,
+		BranchType:      task.BranchType,
+		ResourceId:      task.ResourceId,
+		ApplicationData: task.ApplicationData,
+	}
+	var (
+		resp interface{}
+		err  error
+	)
+	if commit {
+		resp, err = c.sendToRM(task, message.BranchCommitRequest{AbstractBranchEndRequest: request})
+	} else {
+		resp, err = c.sendToRM(task, message.BranchRollbackRequest{AbstractBranchEndRequest: request})
+	}
+	if err != nil {
+		log.Warnf("send branch phase two of %s %d failed: %v", xid, task.BranchId, err)
+	}
+	switch response := resp.(type) {
+	case message.BranchCommitResponse:
+		return response.BranchStatus
+	case message.BranchRollbackResponse:
+		return response.BranchStatus
+	}
+	if commit {
+		return branch.BranchStatusPhasetwoCommitFailedRetryable
+	}
+	return branch.BranchStatusPhasetwoRollbackFailedRetryable
+}
+
+// sendToRM sends the request to the session which registered the branch, or any session
+// which registered the resource of the branch if the former is closed
+func (c *coordinator) sendToRM(task branchTask, request interface{}) (interface{}, error) {
+	session := task.session
+	if session == nil || session.IsClosed() {
+		session = c.resourceSession(task.ResourceId)
+	}
+	if session == nil {
+		return nil, fmt.Errorf("no rm session of resource %s", task.ResourceId)
+	}
+	return c.send(context.Background(), session, request)
+}
+
+func (c *coordinator) resourceSession(resourceId string) getty.Session {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for session := range c.resources[resourceId] {
+		if !session.IsClosed() {
+			return session
+		}
+	}
+	return nil
+}
+
+// rollbackTimeout rollbacks the active global transactions which exceed their timeout
+func (c *coordinator) rollbackTimeout() {
+	c.mu.Lock()
+	now := c.now()
+	xids := make([]string, 0)
+	for xid, g := range c.globals {
+		if g.expired(now) {
+			xids = append(xids, xid)
+		}
+	}
+	c.mu.Unlock()
+	for _, xid := range xids {
+		log.Infof("global transaction %s is timeout, rollback it", xid)
+		c.doPhaseTwo(xid, message.GlobalStatusBegin, timeoutRollbackPhase)
+	}
+}
+
+// retry retries the phase two of the global transactions in the retrying status
+func (c *coordinator) retry() {
+	c.mu.Lock()
+	retrying := make(map[string]message.GlobalStatus)
+	for xid, g := range c.globals {
+		if _, ok := retryPhases[g.Status]; ok {
+			retrying[xid] = g.Status
+		}
+	}
+	c.mu.Unlock()
+	for xid, status := range retrying {
+		c.doPhaseTwo(xid, status, retryPhases[status])
+	}
+}
+
+func (c *coordinator) globalStatus(xid string) message.GlobalStatus {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if g, ok := c.globals[xid]; ok {
+		return g.Status
+	}
+	return message.GlobalStatusFinished
+}
+
+func (c *coordinator) globalSession(xid string) (*GlobalSession, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if g, ok := c.globals[xid]; ok {
+		return g.clone(), true
+	}
+	return nil, false
+}
+
+func identified(version string) message.AbstractIdentifyResponse {
+	return message.AbstractIdentifyResponse{
+		AbstractResultMessage: message.AbstractResultMessage{ResultCode: message.ResultCodeSuccess},
+		Version:               version,
+		Identified:            true,
+	}
+}
+
+func success() message.AbstractTransactionResponse {
+	return message.AbstractTransactionResponse{
+		AbstractResultMessage: message.AbstractResultMessage{ResultCode: message.ResultCodeSuccess},
+	}
+}
+
+func failed(code serror.TransactionErrorCode, format string, args ...interface{}) message.AbstractTransactionResponse {
+	return message.AbstractTransactionResponse{
+		AbstractResultMessage: message.AbstractResultMessage{ResultCode: message.ResultCodeFailed, Msg: fmt.Sprintf(format, args...)},
+		TransactionErrorCode:  code,
+	}
+}
+
+func globalEndResponse(status message.GlobalStatus) message.AbstractGlobalEndResponse {
+	return message.AbstractGlobalEndResponse{AbstractTransactionResponse: success(), GlobalStatus: status}
+}
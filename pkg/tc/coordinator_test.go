@@ -0,0 +1,275 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tc
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	getty "github.com/apache/dubbo-getty"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"seata.apache.org/seata-go/pkg/protocol/branch"
+	"seata.apache.org/seata-go/pkg/protocol/message"
+	serror "seata.apache.org/seata-go/pkg/util/errors"
+)
+
+type fakeSession struct {
+	getty.Session
+}
+
+func (s *fakeSession) IsClosed() bool {
+	return false
+}
+
+// fakeRM answers the branch commit/rollback with the status of the branch, the phase two
+// succeeds by default
+type fakeRM struct {
+	mu        sync.Mutex
+	statuses  map[int64]branch.BranchStatus
+	committed []int64
+	rollbacks []int64
+}
+
+func newFakeRM() *fakeRM {
+	return &fakeRM{statuses: make(map[int64]branch.BranchStatus)}
+}
+
+func (f *fakeRM) setStatus(branchId int64, status branch.BranchStatus) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.statuses[branchId] = status
+}
+
+func (f *fakeRM) send(ctx context.Context, session getty.Session, msg interface{}) (interface{}, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch req := msg.(type) {
+	case message.BranchCommitRequest:
+		f.committed = append(f.committed, req.BranchId)
+		status, ok := f.statuses[req.BranchId]
+		if !ok {
+			status = branch.BranchStatusPhasetwoCommitted
+		}
+		return message.BranchCommitResponse{AbstractBranchEndResponse: message.AbstractBranchEndResponse{BranchStatus: status}}, nil
+	case message.BranchRollbackRequest:
+		f.rollbacks = append(f.rollbacks, req.BranchId)
+		status, ok := f.statuses[req.BranchId]
+		if !ok {
+			status = branch.BranchStatusPhasetwoRollbacked
+		}
+		return message.BranchRollbackResponse{AbstractBranchEndResponse: message.AbstractBranchEndResponse{BranchStatus: status}}, nil
+	}
+	return nil, nil
+}
+
+func newTestCoordinator(t *testing.T, store SessionStore, rm *fakeRM) (*coordinator, getty.Session) {
+	c := newCoordinator(store, false)
+	c.send = rm.send
+	c.xidPrefix = "127.0.0.1:8091"
+	require.NoError(t, c.restore())
+	session := &fakeSession{}
+	c.handle(session, message.RegisterRMRequest{ResourceIds: "db"})
+	return c, session
+}
+
+func beginGlobal(t *testing.T, c *coordinator, timeout time.Duration) string {
+	resp := c.handle(nil, message.GlobalBeginRequest{TransactionName: t.Name(), Timeout: timeout}).(message.GlobalBeginResponse)
+	require.Equal(t, message.ResultCodeSuccess, resp.ResultCode)
+	return resp.Xid
+}
+
+func registerBranch(c *coordinator, session getty.Session, xid, lockKey string) message.BranchRegisterResponse {
+	return c.handle(session, message.BranchRegisterRequest{
+		Xid:        xid,
+		BranchType: branch.BranchTypeAT,
+		ResourceId: "db",
+		LockKey:    lockKey,
+	}).(message.BranchRegisterResponse)
+}
+
+func TestCoordinator_Commit(t *testing.T) {
+	rm := newFakeRM()
+	store := NewMemorySessionStore()
+	c, session := newTestCoordinator(t, store, rm)
+
+	xid := beginGlobal(t, c, time.Minute)
+	first := registerBranch(c, session, xid, "t:1").BranchId
+	second := registerBranch(c, session, xid, "t:2").BranchId
+	resp := registerBranch(c, session, beginGlobal(t, c, time.Minute), "t:2")
+	assert.Equal(t, message.ResultCodeFailed, resp.ResultCode)
+	assert.Equal(t, serror.TransactionErrorCodeLockKeyConflict, resp.TransactionErrorCode)
+
+	assert.Equal(t, message.GlobalStatusCommitted, c.commit(xid))
+	assert.Equal(t, []int64{first, second}, rm.committed)
+	// the finished session is removed and its locks are released
+	assert.Equal(t, message.GlobalStatusFinished, c.globalStatus(xid))
+	sessions, err := store.LoadAll()
+	require.NoError(t, err)
+	assert.Len(t, sessions, 1)
+	assert.True(t, c.locks.lockable("other", parseLockKey("db", "t:1,2")))
+}
+
+// failingStore fails to save the sessions once failed is set
+type failingStore struct {
+	SessionStore
+	failed bool
+}
+
+func (s *failingStore) Save(session *GlobalSession) error {
+	if s.failed {
+		return errors.New("disk full")
+	}
+	return s.SessionStore.Save(session)
+}
+
+func TestCoordinator_RegisterSaveFailed(t *testing.T) {
+	store := &failingStore{SessionStore: NewMemorySessionStore()}
+	c, session := newTestCoordinator(t, store, newFakeRM())
+
+	xid := beginGlobal(t, c, time.Minute)
+	require.Equal(t, message.ResultCodeSuccess, registerBranch(c, session, xid, "t:1").ResultCode)
+	store.failed = true
+	resp := registerBranch(c, session, xid, "t:1,2")
+	assert.Equal(t, message.ResultCodeFailed, resp.ResultCode)
+	assert.Equal(t, serror.TransactionErrorCodeFailedWriteSession, resp.TransactionErrorCode)
+	// only the rows locked by the failed branch are released, the first branch still holds its row
+	assert.False(t, c.locks.lockable("other", parseLockKey("db", "t:1")))
+	assert.True(t, c.locks.lockable("other", parseLockKey("db", "t:2")))
+}
+
+func TestCoordinator_RollbackInReverseOrder(t *testing.T) {
+	rm := newFakeRM()
+	c, session := newTestCoordinator(t, NewMemorySessionStore(), rm)
+
+	xid := beginGlobal(t, c, time.Minute)
+	first := registerBranch(c, session, xid, "").BranchId
+	failed := registerBranch(c, session, xid, "").BranchId
+	last := registerBranch(c, session, xid, "").BranchId
+	report := c.handle(session, message.BranchReportRequest{Xid: xid, BranchId: failed, Status: branch.BranchStatusPhaseoneFailed})
+	assert.Equal(t, message.ResultCodeSuccess, report.(message.BranchReportResponse).ResultCode)
+
+	assert.Equal(t, message.GlobalStatusRollbacked, c.rollback(xid))
+	// the branch failed in phase one is skipped
+	assert.Equal(t, []int64{last, first}, rm.rollbacks)
+}
+
+func TestCoordinator_TimeoutRollback(t *testing.T) {
+	rm := newFakeRM()
+	c, session := newTestCoordinator(t, NewMemorySessionStore(), rm)
+	now := time.Now()
+	c.now = func() time.Time {
+		return now
+	}
+
+	xid := beginGlobal(t, c, time.Second)
+	branchId := registerBranch(c, session, xid, "t:1").BranchId
+	timeoutXid := beginGlobal(t, c, time.Second)
+	timeoutBranchId := registerBranch(c, session, timeoutXid, "t:2").BranchId
+	activeXid := beginGlobal(t, c, time.Minute)
+
+	now = now.Add(2 * time.Second)
+	// the branch of the timeout transaction can not be registered
+	resp := registerBranch(c, session, xid, "t:3")
+	assert.Equal(t, serror.TransactionErrorCodeGlobalTransactionNotActive, resp.TransactionErrorCode)
+	// the commit of the timeout transaction rolls back it
+	assert.Equal(t, message.GlobalStatusTimeoutRollbacked, c.commit(xid))
+	assert.Equal(t, []int64{branchId}, rm.rollbacks)
+
+	c.rollbackTimeout()
+	assert.Equal(t, []int64{branchId, timeoutBranchId}, rm.rollbacks)
+	assert.Equal(t, message.GlobalStatusFinished, c.globalStatus(timeoutXid))
+	assert.Equal(t, message.GlobalStatusBegin, c.globalStatus(activeXid))
+	assert.Empty(t, rm.committed)
+}
+
+func TestCoordinator_Retry(t *testing.T) {
+	rm := newFakeRM()
+	c, session := newTestCoordinator(t, NewMemorySessionStore(), rm)
+
+	xid := beginGlobal(t, c, time.Minute)
+	first := registerBranch(c, session, xid, "t:1").BranchId
+	second := registerBranch(c, session, xid, "t:2").BranchId
+	rm.setStatus(second, branch.BranchStatusPhasetwoCommitFailedRetryable)
+
+	assert.Equal(t, message.GlobalStatusCommitRetrying, c.commit(xid))
+	// the locks are held until the commit is done
+	assert.False(t, c.locks.lockable("other", parseLockKey("db", "t:1")))
+	g, ok := c.globalSession(xid)
+	require.True(t, ok)
+	assert.Equal(t, branch.BranchStatus(branch.BranchStatusPhasetwoCommitted), g.branch(first).Status)
+	assert.Equal(t, branch.BranchStatus(branch.BranchStatusPhasetwoCommitFailedRetryable), g.branch(second).Status)
+
+	c.retry()
+	assert.Equal(t, message.GlobalStatusCommitRetrying, c.globalStatus(xid))
+	rm.setStatus(second, branch.BranchStatusPhasetwoCommitted)
+	c.retry()
+	assert.Equal(t, message.GlobalStatusFinished, c.globalStatus(xid))
+	// the committed branch is not committed again
+	assert.Equal(t, []int64{first, second, second, second}, rm.committed)
+	assert.True(t, c.locks.lockable("other", parseLockKey("db", "t:1")))
+
+	xid = beginGlobal(t, c, time.Minute)
+	branchId := registerBranch(c, session, xid, "").BranchId
+	rm.setStatus(branchId, branch.BranchStatusPhasetwoRollbackFailedUnretryable)
+	assert.Equal(t, message.GlobalStatusRollbackFailed, c.rollback(xid))
+}
+
+func TestCoordinator_Restore(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewFileSessionStore(dir)
+	require.NoError(t, err)
+	rm := newFakeRM()
+	c, session := newTestCoordinator(t, store, rm)
+
+	committing := beginGlobal(t, c, time.Minute)
+	committingBranch := registerBranch(c, session, committing, "t:1").BranchId
+	active := beginGlobal(t, c, time.Minute)
+	registerBranch(c, session, active, "t:2")
+	// the tc crashes in the middle of the commit
+	c.mu.Lock()
+	c.globals[committing].Status = message.GlobalStatusCommitting
+	c.save(c.globals[committing])
+	c.mu.Unlock()
+
+	store, err = NewFileSessionStore(dir)
+	require.NoError(t, err)
+	rm = newFakeRM()
+	restarted, _ := newTestCoordinator(t, store, rm)
+	assert.Equal(t, message.GlobalStatusCommitRetrying, restarted.globalStatus(committing))
+	assert.Equal(t, message.GlobalStatusBegin, restarted.globalStatus(active))
+	// the locks are held again and the ids keep increasing
+	assert.False(t, restarted.locks.lockable("other", parseLockKey("db", "t:1")))
+	assert.False(t, restarted.locks.lockable("other", parseLockKey("db", "t:2")))
+	assert.Greater(t, restarted.idGenerator.Load(), committingBranch)
+
+	// the branch is committed through the session which registered the resource
+	restarted.retry()
+	assert.Equal(t, []int64{committingBranch}, rm.committed)
+	assert.Equal(t, message.GlobalStatusFinished, restarted.globalStatus(committing))
+	assert.True(t, restarted.locks.lockable("other", parseLockKey("db", "t:1")))
+
+	assert.Equal(t, message.GlobalStatusCommitted, restarted.commit(active))
+	sessions, err := store.LoadAll()
+	require.NoError(t, err)
+	assert.Empty(t, sessions)
+}
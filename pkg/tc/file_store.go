@@ -0,0 +1,115 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tc
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const sessionFileSuffix = ".json"
+
+// FileSessionStore keeps every global session in a json file named by its transaction id under the dir
+type FileSessionStore struct {
+	dir string
+	mu  sync.Mutex
+	// xid -> file path
+	files map[string]string
+}
+
+func NewFileSessionStore(dir string) (*FileSessionStore, error) {
+	if dir == "" {
+		return nil, fmt.Errorf("the dir of the file session store is empty")
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &FileSessionStore{dir: dir, files: make(map[string]string)}, nil
+}
+
+func (s *FileSessionStore) Save(session *GlobalSession) error {
+	data, err := json.Marshal(session)
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(s.dir, strconv.FormatInt(session.TransactionId, 10)+sessionFileSuffix)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// write a temp file then rename it, so that a crash never leaves a broken session file
+	tmp := path + ".tmp"
+	if err = os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	if err = os.Rename(tmp, path); err != nil {
+		return err
+	}
+	s.files[session.Xid] = path
+	return nil
+}
+
+func (s *FileSessionStore) Remove(xid string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	path, ok := s.files[xid]
+	if !ok {
+		return nil
+	}
+	delete(s.files, xid)
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func (s *FileSessionStore) LoadAll() ([]*GlobalSession, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, err
+	}
+	sessions := make([]*GlobalSession, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), sessionFileSuffix) {
+			continue
+		}
+		path := filepath.Join(s.dir, entry.Name())
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		session := &GlobalSession{}
+		if err = json.Unmarshal(data, session); err != nil {
+			return nil, fmt.Errorf("decode session file %s: %w", path, err)
+		}
+		s.files[session.Xid] = path
+		sessions = append(sessions, session)
+	}
+	return sessions, nil
+}
+
+func (s *FileSessionStore) Close() error {
+	return nil
+}
@@ -0,0 +1,113 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tc
+
+import (
+	"strings"
+	"sync"
+)
+
+const (
+	lockKeyTableSplitChar = ";"
+	lockKeyPkSplitChar    = ","
+	lockKeyRowSplitChar   = ":"
+	lockRowKeyJoinChar    = "^^^"
+)
+
+// lockTable the in-memory global lock table, the row key is resourceId^^^table^^^pk
+type lockTable struct {
+	mu sync.Mutex
+	// row key -> xid
+	locks map[string]string
+}
+
+func newLockTable() *lockTable {
+	return &lockTable{locks: make(map[string]string)}
+}
+
+// parseLockKey parses the lock key like table1:pk1,pk2;table2:pk3 into the row keys
+func parseLockKey(resourceId, lockKey string) []string {
+	rows := make([]string, 0)
+	for _, tableLock := range strings.Split(lockKey, lockKeyTableSplitChar) {
+		idx := strings.Index(tableLock, lockKeyRowSplitChar)
+		if idx < 0 {
+			continue
+		}
+		table := tableLock[:idx]
+		for _, pk := range strings.Split(tableLock[idx+1:], lockKeyPkSplitChar) {
+			if pk != "" {
+				rows = append(rows, resourceId+lockRowKeyJoinChar+table+lockRowKeyJoinChar+pk)
+			}
+		}
+	}
+	return rows
+}
+
+// acquire locks all the rows for the xid, nothing is locked if any row is held by another transaction.
+// It returns the rows newly locked, the ones held by the xid already are not among them.
+func (l *lockTable) acquire(xid string, rows []string) (acquired []string, conflict string, ok bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, row := range rows {
+		if owner, ok := l.locks[row]; ok && owner != xid {
+			return nil, row, false
+		}
+	}
+	acquired = make([]string, 0, len(rows))
+	for _, row := range rows {
+		if _, ok := l.locks[row]; !ok {
+			l.locks[row] = xid
+			acquired = append(acquired, row)
+		}
+	}
+	return acquired, "", true
+}
+
+// lockable reports whether all the rows are free or held by the xid
+func (l *lockTable) lockable(xid string, rows []string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, row := range rows {
+		if owner, ok := l.locks[row]; ok && owner != xid {
+			return false
+		}
+	}
+	return true
+}
+
+// release releases all the rows held by the xid
+func (l *lockTable) release(xid string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for row, owner := range l.locks {
+		if owner == xid {
+			delete(l.locks, row)
+		}
+	}
+}
+
+// releaseRows releases the rows held by the xid
+func (l *lockTable) releaseRows(xid string, rows []string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, row := range rows {
+		if l.locks[row] == xid {
+			delete(l.locks, row)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseLockKey(t *testing.T) {
+	assert.Equal(t, []string{"db^^^t1^^^1", "db^^^t1^^^2_a", "db^^^t2^^^3"}, parseLockKey("db", "t1:1,2_a;t2:3"))
+	assert.Empty(t, parseLockKey("db", ""))
+}
+
+func TestLockTable(t *testing.T) {
+	locks := newLockTable()
+	acquired, _, ok := locks.acquire("xid-1", []string{"r1", "r2"})
+	assert.True(t, ok)
+	assert.Equal(t, []string{"r1", "r2"}, acquired)
+	_, row, ok := locks.acquire("xid-2", []string{"r3", "r2"})
+	assert.False(t, ok)
+	assert.Equal(t, "r2", row)
+	// the rows held by the xid already are not acquired again
+	acquired, _, ok = locks.acquire("xid-1", []string{"r2", "r4"})
+	assert.True(t, ok)
+	assert.Equal(t, []string{"r4"}, acquired)
+	locks.releaseRows("xid-1", acquired)
+	// nothing is locked on conflict
+	assert.True(t, locks.lockable("xid-3", []string{"r3"}))
+	assert.True(t, locks.lockable("xid-1", []string{"r1"}))
+
+	locks.releaseRows("xid-2", []string{"r1"})
+	assert.False(t, locks.lockable("xid-2", []string{"r1"}))
+	locks.releaseRows("xid-1", []string{"r1"})
+	assert.True(t, locks.lockable("xid-2", []string{"r1"}))
+	assert.False(t, locks.lockable("xid-2", []string{"r2"}))
+
+	locks.release("xid-1")
+	assert.True(t, locks.lockable("xid-2", []string{"r1", "r2"}))
+}
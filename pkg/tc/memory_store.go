@@ -0,0 +1,58 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tc
+
+import "sync"
+
+// MemorySessionStore keeps the global sessions in memory, they are lost when the tc exits
+type MemorySessionStore struct {
+	mu       sync.RWMutex
+	sessions map[string]*GlobalSession
+}
+
+func NewMemorySessionStore() *MemorySessionStore {
+	return &MemorySessionStore{sessions: make(map[string]*GlobalSession)}
+}
+
+func (s *MemorySessionStore) Save(session *GlobalSession) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sessions[session.Xid] = session.clone()
+	return nil
+}
+
+func (s *MemorySessionStore) Remove(xid string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.sessions, xid)
+	return nil
+}
+
+func (s *MemorySessionStore) LoadAll() ([]*GlobalSession, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	sessions := make([]*GlobalSession, 0, len(s.sessions))
+	for _, session := range s.sessions {
+		sessions = append(sessions, session.clone())
+	}
+	return sessions, nil
+}
+
+func (s *MemorySessionStore) Close() error {
+	return nil
+}
@@ -0,0 +1,310 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package tc provides a standalone TC which speaks the getty v1 protocol. It keeps the global
+// sessions in a pluggable SessionStore, rolls back the timeout global transactions and retries
+// the interrupted commit/rollback in background, so that the unfinished transactions are
+// recovered after a restart when a persistent store is used.
+package tc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	getty "github.com/apache/dubbo-getty"
+	gxnet "github.com/dubbogo/gost/net"
+	"go.uber.org/atomic"
+
+	"seata.apache.org/seata-go/pkg/protocol/codec"
+	"seata.apache.org/seata-go/pkg/protocol/message"
+	remoting "seata.apache.org/seata-go/pkg/remoting/getty"
+	"seata.apache.org/seata-go/pkg/util/log"
+)
+
+const maxMsgLen = 4 << 20
+
+var (
+	ErrServerClosed   = errors.New("tc server is closed")
+	ErrRequestTimeout = errors.New("wait rm response timeout")
+)
+
+type options struct {
+	store        SessionStore
+	keepFinished bool
+}
+
+// Option is tc server option.
+type Option func(*options)
+
+// WithSessionStore uses the store rather than the one created by the store config.
+func WithSessionStore(store SessionStore) Option {
+	return func(o *options) {
+		o.store = store
+	}
+}
+
+// WithKeepFinished keeps the finished global sessions, so that their status and branches can
+// still be queried. It is meant for tests, the sessions are never removed.
+func WithKeepFinished() Option {
+	return func(o *options) {
+		o.keepFinished = true
+	}
+}
+
+// Server a standalone TC
+type Server struct {
+	cfg         Config
+	server      getty.StreamServer
+	addr        string
+	coordinator *coordinator
+
+	idGenerator *atomic.Uint32
+	// request id -> chan message.RpcMessage
+	futures  sync.Map
+	sessions sync.Map
+
+	closeOnce sync.Once
+	done      chan struct{}
+	handlers  sync.WaitGroup
+	loops     sync.WaitGroup
+}
+
+// NewServer creates a tc server, it does not listen until Start is called.
+func NewServer(cfg Config, opts ...Option) (*Server, error) {
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	store := o.store
+	if store == nil {
+		var err error
+		if store, err = NewSessionStore(cfg.StoreConfig); err != nil {
+			return nil, err
+		}
+	}
+	s := &Server{
+		cfg:         cfg,
+		idGenerator: &atomic.Uint32{},
+		done:        make(chan struct{}),
+	}
+	s.coordinator = newCoordinator(store, o.keepFinished)
+	s.coordinator.send = s.sendSync
+	return s, nil
+}
+
+// Start restores the global sessions from the store, listens on the address and serves the
+// clients in background.
+func (s *Server) Start() (err error) {
+	if err = s.coordinator.restore(); err != nil {
+		return fmt.Errorf("restore global sessions: %w", err)
+	}
+	codec.Init()
+	// no task pool, the messages are read and dispatched in the session goroutine
+	s.server = getty.NewTCPServer(getty.WithLocalAddress(s.cfg.Address)).(getty.StreamServer)
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("start tc server: %v", r)
+		}
+	}()
+	// RunEventLoop panics if it fails to listen
+	s.server.RunEventLoop(s.newSession)
+	s.addr = s.server.Listener().Addr().String()
+	s.coordinator.xidPrefix = xidPrefix(s.addr)
+	log.Infof("tc server is listening on %s", s.addr)
+
+	s.startLoop(s.cfg.TimeoutCheckPeriod, s.coordinator.rollbackTimeout)
+	s.startLoop(s.cfg.RetryPeriod, s.coordinator.retry)
+	return nil
+}
+
+// xidPrefix the xid starts with the address of the tc, the client routes the requests of the
+// xid to it, so the unspecified host is replaced by the local ip.
+func xidPrefix(addr string) string {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+	if ip := net.ParseIP(host); host == "" || (ip != nil && ip.IsUnspecified()) {
+		if host, err = gxnet.GetLocalIP(); err != nil {
+			host = "127.0.0.1"
+		}
+	}
+	return net.JoinHostPort(host, port)
+}
+
+func (s *Server) startLoop(period time.Duration, fn func()) {
+	if period <= 0 {
+		return
+	}
+	s.loops.Add(1)
+	go func() {
+		defer s.loops.Done()
+		ticker := time.NewTicker(period)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				fn()
+			case <-s.done:
+				return
+			}
+		}
+	}()
+}
+
+// Addr returns the address the server listens on, e.g. 127.0.0.1:8091
+func (s *Server) Addr() string {
+	return s.addr
+}
+
+// GlobalSession returns a copy of the global session of the xid
+func (s *Server) GlobalSession(xid string) (*GlobalSession, bool) {
+	return s.coordinator.globalSession(xid)
+}
+
+// Close stops listening, closes all client sessions and the session store.
+func (s *Server) Close() {
+	s.closeOnce.Do(func() {
+		close(s.done)
+		s.loops.Wait()
+		if s.server != nil {
+			s.server.Close()
+		}
+		// close the connections rather than the sessions, so that every session stops in its own
+		// goroutine, dubbo-getty races on closing a session which is reading packages
+		s.sessions.Range(func(key, value interface{}) bool {
+			if conn := key.(getty.Session).Conn(); conn != nil {
+				_ = conn.Close()
+			}
+			return true
+		})
+		s.handlers.Wait()
+		if err := s.coordinator.store.Close(); err != nil {
+			log.Errorf("close session store failed: %v", err)
+		}
+	})
+}
+
+func (s *Server) isClosed() bool {
+	select {
+	case <-s.done:
+		return true
+	default:
+		return false
+	}
+}
+
+func (s *Server) newSession(session getty.Session) error {
+	session.SetName("seata-tc")
+	session.SetMaxMsgLen(maxMsgLen)
+	session.SetPkgHandler(&remoting.RpcPackageHandler{})
+	session.SetEventListener(s)
+	session.SetReadTimeout(time.Second)
+	session.SetWriteTimeout(5 * time.Second)
+	session.SetCronPeriod(int(time.Second.Milliseconds()))
+	session.SetWaitTime(time.Second)
+	return nil
+}
+
+func (s *Server) OnOpen(session getty.Session) error {
+	if s.isClosed() {
+		return ErrServerClosed
+	}
+	s.sessions.Store(session, true)
+	return nil
+}
+
+func (s *Server) OnClose(session getty.Session) {
+	s.sessions.Delete(session)
+	s.coordinator.removeSession(session)
+}
+
+func (s *Server) OnError(session getty.Session, err error) {
+	log.Infof("tc session{%s} got error{%v}, will be closed.", session.Stat(), err)
+	s.OnClose(session)
+}
+
+func (s *Server) OnCron(session getty.Session) {
+}
+
+func (s *Server) OnMessage(session getty.Session, pkg interface{}) {
+	rpcMessage, ok := pkg.(message.RpcMessage)
+	if !ok {
+		log.Errorf("received message is not protocol.RpcMessage. pkg: %#v", pkg)
+		return
+	}
+	switch rpcMessage.Type {
+	case message.GettyRequestTypeHeartbeatRequest:
+		s.write(session, rpcMessage.ID, message.GettyRequestTypeHeartbeatResponse, message.HeartBeatMessagePong)
+	case message.GettyRequestTypeResponse:
+		if future, ok := s.futures.LoadAndDelete(rpcMessage.ID); ok {
+			future.(chan message.RpcMessage) <- rpcMessage
+		}
+	case message.GettyRequestTypeRequestSync, message.GettyRequestTypeRequestOneway:
+		// handle the request out of the read loop, the global commit waits for the branch
+		// responses which may come from the same session
+		s.handlers.Add(1)
+		go func() {
+			defer s.handlers.Done()
+			if resp := s.coordinator.handle(session, rpcMessage.Body); resp != nil {
+				s.write(session, rpcMessage.ID, message.GettyRequestTypeResponse, resp)
+			}
+		}()
+	}
+}
+
+// sendSync sends the request to the rm session and waits for its response
+func (s *Server) sendSync(ctx context.Context, session getty.Session, msg interface{}) (interface{}, error) {
+	if s.isClosed() {
+		return nil, ErrServerClosed
+	}
+	id := int32(s.idGenerator.Inc())
+	future := make(chan message.RpcMessage, 1)
+	s.futures.Store(id, future)
+	defer s.futures.Delete(id)
+
+	if err := s.write(session, id, message.GettyRequestTypeRequestSync, msg); err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, s.cfg.RequestTimeout)
+	defer cancel()
+	select {
+	case resp := <-future:
+		return resp.Body, nil
+	case <-ctx.Done():
+		return nil, ErrRequestTimeout
+	case <-s.done:
+		return nil, ErrServerClosed
+	}
+}
+
+func (s *Server) write(session getty.Session, id int32, msgType message.GettyRequestType, msg interface{}) error {
+	_, _, err := session.WritePkg(message.RpcMessage{
+		ID:    id,
+		Type:  msgType,
+		Codec: byte(codec.CodecTypeSeata),
+		Body:  msg,
+	}, time.Duration(0))
+	if err != nil {
+		log.Errorf("tc write message %#v to session %s failed: %v", msg, session.Stat(), err)
+	}
+	return err
+}
@@ -0,0 +1,92 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tc
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"seata.apache.org/seata-go/pkg/protocol/message"
+)
+
+func newTestConfig() Config {
+	return Config{
+		Address:        "127.0.0.1:0",
+		RequestTimeout: time.Second,
+		StoreConfig:    StoreConfig{Mode: StoreModeMemory},
+	}
+}
+
+func TestServer_Close(t *testing.T) {
+	server, err := NewServer(newTestConfig())
+	require.NoError(t, err)
+	require.NoError(t, server.Start())
+	conn, err := net.Dial("tcp", server.Addr())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	require.Eventually(t, func() bool {
+		count := 0
+		server.sessions.Range(func(key, value interface{}) bool {
+			count++
+			return true
+		})
+		return count == 1
+	}, 5*time.Second, 10*time.Millisecond)
+
+	server.Close()
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+	_, err = conn.Read(make([]byte, 1))
+	assert.Equal(t, io.EOF, err)
+	_, err = net.Dial("tcp", server.Addr())
+	assert.Error(t, err)
+
+	_, err = server.sendSync(context.Background(), nil, message.BranchCommitRequest{})
+	assert.ErrorIs(t, err, ErrServerClosed)
+}
+
+func TestServer_StartError(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.Address = "127.0.0.1:-1"
+	server, err := NewServer(cfg)
+	require.NoError(t, err)
+	assert.Error(t, server.Start())
+}
+
+func TestNewServer_UnknownStoreMode(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.StoreConfig.Mode = "unknown"
+	_, err := NewServer(cfg)
+	assert.Error(t, err)
+}
+
+func TestXidPrefix(t *testing.T) {
+	assert.Equal(t, "127.0.0.1:8091", xidPrefix("127.0.0.1:8091"))
+	for _, addr := range []string{"[::]:8091", "0.0.0.0:8091"} {
+		host, port, err := net.SplitHostPort(xidPrefix(addr))
+		require.NoError(t, err)
+		assert.Equal(t, "8091", port)
+		assert.False(t, net.ParseIP(host).IsUnspecified())
+	}
+}
@@ -0,0 +1,73 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tc
+
+import (
+	"time"
+
+	"seata.apache.org/seata-go/pkg/protocol/branch"
+	"seata.apache.org/seata-go/pkg/protocol/message"
+)
+
+// GlobalSession the global transaction with its branches kept by the tc
+type GlobalSession struct {
+	Xid           string               `json:"xid"`
+	TransactionId int64                `json:"transactionId"`
+	Name          string               `json:"name"`
+	Timeout       time.Duration        `json:"timeout"`
+	BeginTime     time.Time            `json:"beginTime"`
+	Status        message.GlobalStatus `json:"status"`
+	Branches      []*BranchSession     `json:"branches"`
+}
+
+// BranchSession the branch transaction registered to a global transaction
+type BranchSession struct {
+	BranchId        int64               `json:"branchId"`
+	BranchType      branch.BranchType   `json:"branchType"`
+	ResourceId      string              `json:"resourceId"`
+	LockKey         string              `json:"lockKey"`
+	ApplicationData []byte              `json:"applicationData"`
+	Status          branch.BranchStatus `json:"status"`
+}
+
+// expired reports whether the active global transaction exceeds its timeout, it never
+// expires if the timeout is not positive
+func (g *GlobalSession) expired(now time.Time) bool {
+	return g.Status == message.GlobalStatusBegin && g.Timeout > 0 && now.After(g.BeginTime.Add(g.Timeout))
+}
+
+func (g *GlobalSession) branch(branchId int64) *BranchSession {
+	for _, b := range g.Branches {
+		if b.BranchId == branchId {
+			return b
+		}
+	}
+	return nil
+}
+
+// clone returns a deep copy, so that the copy can be used out of the lock of the coordinator
+func (g *GlobalSession) clone() *GlobalSession {
+	c := *g
+	c.Branches = make([]*BranchSession, 0, len(g.Branches))
+	for _, b := range g.Branches {
+		bc := *b
+		bc.ApplicationData = append([]byte(nil), b.ApplicationData...)
+		c.Branches = append(c.Branches, &bc)
+	}
+	return &c
+}
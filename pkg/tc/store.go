@@ -0,0 +1,73 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tc
+
+import (
+	"fmt"
+	"sync"
+)
+
+const (
+	StoreModeMemory = "memory"
+	StoreModeFile   = "file"
+)
+
+// SessionStore persists the global sessions with their branches, so that the unfinished
+// transactions are recovered after the tc restarts
+type SessionStore interface {
+	// Save inserts or updates the global session
+	Save(session *GlobalSession) error
+	// Remove removes the global session, it is not an error if the session does not exist
+	Remove(xid string) error
+	// LoadAll loads all the global sessions, it is called once on start
+	LoadAll() ([]*GlobalSession, error)
+	Close() error
+}
+
+// SessionStoreFactory creates the session store by the config
+type SessionStoreFactory func(cfg StoreConfig) (SessionStore, error)
+
+var (
+	storeFactoriesLock sync.RWMutex
+	storeFactories     = map[string]SessionStoreFactory{
+		StoreModeMemory: func(cfg StoreConfig) (SessionStore, error) {
+			return NewMemorySessionStore(), nil
+		},
+		StoreModeFile: func(cfg StoreConfig) (SessionStore, error) {
+			return NewFileSessionStore(cfg.File.Dir)
+		},
+	}
+)
+
+// RegisterSessionStore registers the session store factory of the mode, the existing one is replaced
+func RegisterSessionStore(mode string, factory SessionStoreFactory) {
+	storeFactoriesLock.Lock()
+	defer storeFactoriesLock.Unlock()
+	storeFactories[mode] = factory
+}
+
+// NewSessionStore creates the session store of the mode in the config
+func NewSessionStore(cfg StoreConfig) (SessionStore, error) {
+	storeFactoriesLock.RLock()
+	factory, ok := storeFactories[cfg.Mode]
+	storeFactoriesLock.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("session store mode %s is not supported", cfg.Mode)
+	}
+	return factory(cfg)
+}
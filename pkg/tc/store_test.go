@@ -0,0 +1,125 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tc
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"seata.apache.org/seata-go/pkg/protocol/branch"
+	"seata.apache.org/seata-go/pkg/protocol/message"
+)
+
+func newTestGlobalSession(transactionId int64) *GlobalSession {
+	return &GlobalSession{
+		Xid:           fmt.Sprintf("127.0.0.1:8091:%d", transactionId),
+		TransactionId: transactionId,
+		Name:          "test",
+		Timeout:       time.Minute,
+		BeginTime:     time.Unix(1700000000, 0),
+		Status:        message.GlobalStatusBegin,
+		Branches: []*BranchSession{{
+			BranchId:        transactionId + 1,
+			BranchType:      branch.BranchTypeAT,
+			ResourceId:      "db",
+			LockKey:         "t:1",
+			ApplicationData: []byte("{}"),
+			Status:          branch.BranchStatusRegistered,
+		}},
+	}
+}
+
+func testSessionStore(t *testing.T, store SessionStore) {
+	first, second := newTestGlobalSession(1), newTestGlobalSession(10)
+	require.NoError(t, store.Save(first))
+	require.NoError(t, store.Save(second))
+	second.Status = message.GlobalStatusCommitting
+	require.NoError(t, store.Save(second))
+	// the store keeps its own copy
+	second.Status = message.GlobalStatusCommitted
+
+	sessions, err := store.LoadAll()
+	require.NoError(t, err)
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].TransactionId < sessions[j].TransactionId
+	})
+	require.Len(t, sessions, 2)
+	assert.True(t, first.BeginTime.Equal(sessions[0].BeginTime))
+	sessions[0].BeginTime = first.BeginTime
+	assert.Equal(t, first, sessions[0])
+	assert.Equal(t, message.GlobalStatusCommitting, sessions[1].Status)
+
+	require.NoError(t, store.Remove(first.Xid))
+	require.NoError(t, store.Remove("unknown"))
+	sessions, err = store.LoadAll()
+	require.NoError(t, err)
+	require.Len(t, sessions, 1)
+	assert.Equal(t, second.Xid, sessions[0].Xid)
+	assert.NoError(t, store.Close())
+}
+
+func TestMemorySessionStore(t *testing.T) {
+	testSessionStore(t, NewMemorySessionStore())
+}
+
+func TestFileSessionStore(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "sessions")
+	store, err := NewFileSessionStore(dir)
+	require.NoError(t, err)
+	testSessionStore(t, store)
+
+	// the restarted store loads and removes the sessions saved before
+	store, err = NewFileSessionStore(dir)
+	require.NoError(t, err)
+	sessions, err := store.LoadAll()
+	require.NoError(t, err)
+	require.Len(t, sessions, 1)
+	require.NoError(t, store.Remove(sessions[0].Xid))
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+
+	_, err = NewFileSessionStore("")
+	assert.Error(t, err)
+}
+
+func TestNewSessionStore(t *testing.T) {
+	store, err := NewSessionStore(StoreConfig{Mode: StoreModeMemory})
+	require.NoError(t, err)
+	assert.IsType(t, &MemorySessionStore{}, store)
+
+	store, err = NewSessionStore(StoreConfig{Mode: StoreModeFile, File: FileStoreConfig{Dir: t.TempDir()}})
+	require.NoError(t, err)
+	assert.IsType(t, &FileSessionStore{}, store)
+
+	RegisterSessionStore("test", func(cfg StoreConfig) (SessionStore, error) {
+		return NewMemorySessionStore(), nil
+	})
+	_, err = NewSessionStore(StoreConfig{Mode: "test"})
+	assert.NoError(t, err)
+
+	_, err = NewSessionStore(StoreConfig{Mode: "unknown"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,146 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package tcserver provides an embedded fake TC which speaks the getty v1 protocol over a local
+// listener, so that the AT/TCC/XA flows can be tested end to end in plain go test:
+//
+//	server := tcserver.New()
+//	if err := server.Start(); err != nil {
+//		t.Fatal(err)
+//	}
+//	defer server.Close()
+//	// point the grouplist of the client to server.Addr()
+//
+// It runs the standalone tc of package tc with the memory session store, and keeps the finished
+// transactions so that their status and branches can be asserted after the global commit/rollback.
+package tcserver
+
+import (
+	"time"
+
+	"seata.apache.org/seata-go/pkg/protocol/message"
+	"seata.apache.org/seata-go/pkg/tc"
+)
+
+const (
+	defaultAddress        = "127.0.0.1:0"
+	defaultRequestTimeout = 5 * time.Second
+)
+
+// Branch the snapshot of a branch transaction kept by the server
+type Branch = tc.BranchSession
+
+type options struct {
+	address        string
+	requestTimeout time.Duration
+}
+
+// Option is tc server option.
+type Option func(*options)
+
+// WithAddress the address to listen on, a random port of 127.0.0.1 by default.
+func WithAddress(address string) Option {
+	return func(o *options) {
+		o.address = address
+	}
+}
+
+// WithRequestTimeout the timeout to wait for the branch commit/rollback response of the rm.
+func WithRequestTimeout(d time.Duration) Option {
+	return func(o *options) {
+		o.requestTimeout = d
+	}
+}
+
+// Server an in-process fake TC
+type Server struct {
+	options *options
+	server  *tc.Server
+}
+
+// New creates a tc server, it does not listen until Start is called.
+func New(opts ...Option) *Server {
+	o := &options{
+		address:        defaultAddress,
+		requestTimeout: defaultRequestTimeout,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return &Server{options: o}
+}
+
+// Start listens on the address and serves the clients in background.
+func (s *Server) Start() error {
+	server, err := tc.NewServer(tc.Config{
+		Address:            s.options.address,
+		RequestTimeout:     s.options.requestTimeout,
+		TimeoutCheckPeriod: time.Second,
+		RetryPeriod:        time.Second,
+	}, tc.WithSessionStore(tc.NewMemorySessionStore()), tc.WithKeepFinished())
+	if err != nil {
+		return err
+	}
+	if err = server.Start(); err != nil {
+		return err
+	}
+	s.server = server
+	return nil
+}
+
+// Addr returns the address the server listens on, e.g. 127.0.0.1:8091
+func (s *Server) Addr() string {
+	if s.server == nil {
+		return ""
+	}
+	return s.server.Addr()
+}
+
+// Close stops listening and closes all client sessions.
+func (s *Server) Close() {
+	if s.server != nil {
+		s.server.Close()
+	}
+}
+
+// GlobalStatus returns the status of the global transaction, GlobalStatusFinished if it is unknown
+func (s *Server) GlobalStatus(xid string) message.GlobalStatus {
+	if g, ok := s.globalSession(xid); ok {
+		return g.Status
+	}
+	return message.GlobalStatusFinished
+}
+
+// Branches returns the branches registered to the global transaction in the register order
+func (s *Server) Branches(xid string) []Branch {
+	g, ok := s.globalSession(xid)
+	if !ok {
+		return nil
+	}
+	branches := make([]Branch, 0, len(g.Branches))
+	for _, b := range g.Branches {
+		branches = append(branches, *b)
+	}
+	return branches
+}
+
+func (s *Server) globalSession(xid string) (*tc.GlobalSession, bool) {
+	if s.server == nil {
+		return nil, false
+	}
+	return s.server.GlobalSession(xid)
+}
@@ -0,0 +1,312 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tcserver
+
+import (
+	"context"
+	"flag"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"seata.apache.org/seata-go/pkg/discovery"
+	"seata.apache.org/seata-go/pkg/protocol/branch"
+	"seata.apache.org/seata-go/pkg/protocol/message"
+	"seata.apache.org/seata-go/pkg/remoting/config"
+	"seata.apache.org/seata-go/pkg/remoting/fault"
+	"seata.apache.org/seata-go/pkg/remoting/getty"
+	"seata.apache.org/seata-go/pkg/remoting/processor/client"
+	"seata.apache.org/seata-go/pkg/rm"
+	"seata.apache.org/seata-go/pkg/tm"
+	serror "seata.apache.org/seata-go/pkg/util/errors"
+)
+
+const (
+	testTxServiceGroup = "default_tx_group"
+	tccResourceId      = "tcc-resource"
+	atResourceId       = "jdbc:mysql://127.0.0.1:3306/seata"
+)
+
+// fakeResourceManager records the phase two calls, and returns the status of the resource
+type fakeResourceManager struct {
+	rm.ResourceManager
+	branchType branch.BranchType
+	resources  sync.Map
+
+	mu        sync.Mutex
+	statuses  map[string]branch.BranchStatus
+	committed []int64
+	rollbacks []int64
+}
+
+func newFakeResourceManager(branchType branch.BranchType, resourceId string) *fakeResourceManager {
+	f := &fakeResourceManager{branchType: branchType, statuses: make(map[string]branch.BranchStatus)}
+	f.resources.Store(resourceId, nil)
+	return f
+}
+
+func (f *fakeResourceManager) GetBranchType() branch.BranchType {
+	return f.branchType
+}
+
+func (f *fakeResourceManager) GetCachedResources() *sync.Map {
+	return &f.resources
+}
+
+func (f *fakeResourceManager) BranchCommit(ctx context.Context, resource rm.BranchResource) (branch.BranchStatus, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.committed = append(f.committed, resource.BranchId)
+	if status, ok := f.statuses[resource.Xid]; ok {
+		return status, nil
+	}
+	return branch.BranchStatusPhasetwoCommitted, nil
+}
+
+func (f *fakeResourceManager) BranchRollback(ctx context.Context, resource rm.BranchResource) (branch.BranchStatus, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.rollbacks = append(f.rollbacks, resource.BranchId)
+	if status, ok := f.statuses[resource.Xid]; ok {
+		return status, nil
+	}
+	return branch.BranchStatusPhasetwoRollbacked, nil
+}
+
+func (f *fakeResourceManager) setStatus(xid string, status branch.BranchStatus) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.statuses[xid] = status
+}
+
+func (f *fakeResourceManager) calls() ([]int64, []int64) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]int64{}, f.committed...), append([]int64{}, f.rollbacks...)
+}
+
+// initClient connects the getty client of the seata client to the server
+func initClient(t *testing.T, server *Server, managers ...rm.ResourceManager) {
+	for _, manager := range managers {
+		rm.GetRmCacheInstance().RegisterResourceManager(manager)
+	}
+
+	gettyConfig := config.Config{}
+	gettyConfig.RegisterFlagsWithPrefix("getty", flag.NewFlagSet("tcserver", flag.ContinueOnError))
+	rmConfig := rm.Config{}
+	rmConfig.RegisterFlagsWithPrefix("rm", flag.NewFlagSet("tcserver", flag.ContinueOnError))
+
+	serviceConfig := &discovery.ServiceConfig{
+		VgroupMapping: map[string]string{testTxServiceGroup: "default"},
+		Grouplist:     map[string]string{"default": server.Addr()},
+	}
+	discovery.InitRegistry(serviceConfig, &discovery.RegistryConfig{Type: discovery.FILE})
+	rm.InitRm(rm.RmConfig{Config: rmConfig, ApplicationID: "tcserver-test", TxServiceGroup: testTxServiceGroup})
+	client.RegisterProcessor(rmConfig)
+	getty.InitGetty(&gettyConfig, &config.SeataConfig{
+		ApplicationID:        "tcserver-test",
+		TxServiceGroup:       testTxServiceGroup,
+		ServiceVgroupMapping: serviceConfig.VgroupMapping,
+		ServiceGrouplist:     serviceConfig.Grouplist,
+		LoadBalanceType:      gettyConfig.LoadBalanceType,
+	})
+}
+
+func begin(t *testing.T) string {
+	resp, err := getty.GetGettyRemotingClient().SendSyncRequest(message.GlobalBeginRequest{TransactionName: t.Name()})
+	require.NoError(t, err)
+	beginResp := resp.(message.GlobalBeginResponse)
+	require.Equal(t, message.ResultCodeSuccess, beginResp.ResultCode)
+	require.NotEmpty(t, beginResp.Xid)
+	return beginResp.Xid
+}
+
+func branchRegister(xid string, branchType branch.BranchType, resourceId, lockKeys string) (int64, error) {
+	return rm.GetRMRemotingInstance().BranchRegister(rm.BranchRegisterParam{
+		Xid:        xid,
+		BranchType: branchType,
+		ResourceId: resourceId,
+		LockKeys:   lockKeys,
+	})
+}
+
+func globalEnd(t *testing.T, request interface{}) message.GlobalStatus {
+	resp, err := getty.GetGettyRemotingClient().SendSyncRequest(request)
+	require.NoError(t, err)
+	switch r := resp.(type) {
+	case message.GlobalCommitResponse:
+		return r.GlobalStatus
+	case message.GlobalRollbackResponse:
+		return r.GlobalStatus
+	case message.GlobalStatusResponse:
+		return r.GlobalStatus
+	}
+	t.Fatalf("unexpected response %#v", resp)
+	return message.GlobalStatusUnKnown
+}
+
+func lockable(t *testing.T, xid, lockKeys string) bool {
+	ok, err := rm.GetRMRemotingInstance().LockQuery(rm.LockQueryParam{
+		Xid:        xid,
+		BranchType: branch.BranchTypeAT,
+		ResourceId: atResourceId,
+		LockKeys:   lockKeys,
+	})
+	require.NoError(t, err)
+	return ok
+}
+
+func TestServer(t *testing.T) {
+	// neither the server nor the client is closed, dubbo-getty races on closing a session which
+	// has read packages in its task pool, see TestServer_Close of package tc for closing the server
+	server := New()
+	require.NoError(t, server.Start())
+
+	tccManager := newFakeResourceManager(branch.BranchTypeTCC, tccResourceId)
+	atManager := newFakeResourceManager(branch.BranchTypeAT, atResourceId)
+	initClient(t, server, tccManager, atManager)
+
+	t.Run("commit", func(t *testing.T) {
+		xid := begin(t)
+		tccBranch, err := branchRegister(xid, branch.BranchTypeTCC, tccResourceId, "")
+		require.NoError(t, err)
+		atBranch, err := branchRegister(xid, branch.BranchTypeAT, atResourceId, "t_order:1,2")
+		require.NoError(t, err)
+		assert.False(t, lockable(t, "", "t_order:2"))
+		assert.True(t, lockable(t, xid, "t_order:2"))
+
+		assert.Equal(t, message.GlobalStatusCommitted, globalEnd(t, message.GlobalCommitRequest{
+			AbstractGlobalEndRequest: message.AbstractGlobalEndRequest{Xid: xid}}))
+		assert.Equal(t, message.GlobalStatusCommitted, server.GlobalStatus(xid))
+		committed, _ := tccManager.calls()
+		assert.Contains(t, committed, tccBranch)
+		committed, _ = atManager.calls()
+		assert.Contains(t, committed, atBranch)
+		for _, b := range server.Branches(xid) {
+			assert.Equal(t, branch.BranchStatus(branch.BranchStatusPhasetwoCommitted), b.Status)
+		}
+		assert.True(t, lockable(t, "", "t_order:1,2"))
+
+		// the global transaction is not active anymore
+		_, err = branchRegister(xid, branch.BranchTypeTCC, tccResourceId, "")
+		assert.Error(t, err)
+	})
+
+	t.Run("lock-conflict-and-rollback", func(t *testing.T) {
+		xid := begin(t)
+		atBranch, err := branchRegister(xid, branch.BranchTypeAT, atResourceId, "t_order:3;t_stock:1")
+		require.NoError(t, err)
+
+		other := begin(t)
+		_, err = branchRegister(other, branch.BranchTypeAT, atResourceId, "t_stock:1")
+		assert.Error(t, err)
+		assert.False(t, lockable(t, other, "t_stock:1"))
+
+		assert.Equal(t, message.GlobalStatusRollbacked, globalEnd(t, message.GlobalRollbackRequest{
+			AbstractGlobalEndRequest: message.AbstractGlobalEndRequest{Xid: xid}}))
+		_, rollbacks := atManager.calls()
+		assert.Contains(t, rollbacks, atBranch)
+		assert.True(t, lockable(t, other, "t_stock:1"))
+		_, err = branchRegister(other, branch.BranchTypeAT, atResourceId, "t_stock:1")
+		assert.NoError(t, err)
+	})
+
+	t.Run("phase-one-failed-branch-is-skipped", func(t *testing.T) {
+		xid := begin(t)
+		branchId, err := branchRegister(xid, branch.BranchTypeTCC, tccResourceId, "")
+		require.NoError(t, err)
+		require.NoError(t, rm.GetRMRemotingInstance().BranchReport(rm.BranchReportParam{
+			Xid:        xid,
+			BranchId:   branchId,
+			BranchType: branch.BranchTypeTCC,
+			Status:     branch.BranchStatusPhaseoneFailed,
+		}))
+		assert.Equal(t, message.GlobalStatusRollbacked, globalEnd(t, message.GlobalRollbackRequest{
+			AbstractGlobalEndRequest: message.AbstractGlobalEndRequest{Xid: xid}}))
+		_, rollbacks := tccManager.calls()
+		assert.NotContains(t, rollbacks, branchId)
+	})
+
+	t.Run("rollback-failed", func(t *testing.T) {
+		xid := begin(t)
+		_, err := branchRegister(xid, branch.BranchTypeTCC, tccResourceId, "")
+		require.NoError(t, err)
+		tccManager.setStatus(xid, branch.BranchStatusPhasetwoRollbackFailedUnretryable)
+		assert.Equal(t, message.GlobalStatusRollbackFailed, globalEnd(t, message.GlobalRollbackRequest{
+			AbstractGlobalEndRequest: message.AbstractGlobalEndRequest{Xid: xid}}))
+		assert.Equal(t, message.GlobalStatusRollbackFailed, globalEnd(t, message.GlobalStatusRequest{
+			AbstractGlobalEndRequest: message.AbstractGlobalEndRequest{Xid: xid}}))
+	})
+
+	t.Run("commit-retried-on-injected-fault", func(t *testing.T) {
+		injector, err := fault.NewInjector(fault.Rule{
+			Name:         "commit-unreachable",
+			MessageTypes: []string{"GlobalCommit"},
+			Action:       fault.ActionError,
+			Times:        2,
+		})
+		require.NoError(t, err)
+		getty.SetFaultInjector(injector)
+		defer getty.SetFaultInjector(nil)
+		tm.InitTm(tm.TmConfig{CommitRetryCount: 5})
+
+		gtr := &tm.GlobalTransaction{Xid: begin(t), TxRole: tm.Launcher}
+		require.NoError(t, tm.GetGlobalTransactionManager().Commit(context.Background(), gtr))
+		assert.Equal(t, message.GlobalStatusCommitted, gtr.TxStatus)
+		assert.Equal(t, 2, injector.Hits("commit-unreachable"))
+	})
+
+	t.Run("branch-report-failed-on-injected-fault", func(t *testing.T) {
+		injector, err := fault.NewInjector(fault.Rule{
+			Direction:    fault.DirectionReceive,
+			MessageTypes: []string{"BranchStatusReportResult"},
+			Action:       fault.ActionFail,
+			ErrorCode:    serror.TransactionErrorCodeBranchReportFailed,
+		})
+		require.NoError(t, err)
+		getty.SetFaultInjector(injector)
+		defer getty.SetFaultInjector(nil)
+
+		xid := begin(t)
+		branchId, err := branchRegister(xid, branch.BranchTypeTCC, tccResourceId, "")
+		require.NoError(t, err)
+		assert.Error(t, rm.GetRMRemotingInstance().BranchReport(rm.BranchReportParam{
+			Xid:        xid,
+			BranchId:   branchId,
+			BranchType: branch.BranchTypeTCC,
+			Status:     branch.BranchStatusPhaseoneDone,
+		}))
+	})
+
+	t.Run("global-status", func(t *testing.T) {
+		xid := begin(t)
+		assert.Equal(t, message.GlobalStatusBegin, globalEnd(t, message.GlobalStatusRequest{
+			AbstractGlobalEndRequest: message.AbstractGlobalEndRequest{Xid: xid}}))
+		assert.Equal(t, message.GlobalStatusFinished, globalEnd(t, message.GlobalStatusRequest{
+			AbstractGlobalEndRequest: message.AbstractGlobalEndRequest{Xid: "unknown"}}))
+	})
+}
+
+func TestServer_StartError(t *testing.T) {
+	server := New(WithAddress("127.0.0.1:-1"))
+	assert.Error(t, server.Start())
+	assert.Empty(t, server.Addr())
+	server.Close()
+}
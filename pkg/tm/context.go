@@ -38,6 +38,9 @@ type GlobalTransaction struct {
 	TxStatus message.GlobalStatus
 	// TxRole Roles in the transaction propagation behavior
 	TxRole GlobalTransactionRole
+	// TxServiceGroup the transaction is begun on the tc cluster of the group, it is the one in
+	// the config if it is empty
+	TxServiceGroup string
 }
 
 type BusinessActionContext struct {
@@ -92,6 +95,21 @@ func SetTxName(ctx context.Context, name string) {
 	}
 }
 
+func GetTxServiceGroup(ctx context.Context) string {
+	variable := ctx.Value(seataContextVariable)
+	if variable == nil {
+		return ""
+	}
+	return variable.(*ContextVariable).TxServiceGroup
+}
+
+func SetTxServiceGroup(ctx context.Context, txServiceGroup string) {
+	variable := ctx.Value(seataContextVariable)
+	if variable != nil {
+		variable.(*ContextVariable).TxServiceGroup = txServiceGroup
+	}
+}
+
 func IsSeataContext(ctx context.Context) bool {
 	return ctx.Value(seataContextVariable) != nil
 }
@@ -24,6 +24,7 @@ import (
 	"time"
 
 	"github.com/pkg/errors"
+	"go.uber.org/atomic"
 
 	"seata.apache.org/seata-go/pkg/protocol/message"
 	"seata.apache.org/seata-go/pkg/remoting/getty"
@@ -37,6 +38,10 @@ var (
 	onceGlobalTransactionManager = &sync.Once{}
 )
 
+// ErrTmShutdown is returned when beginning a global transaction after the tm is shut down
+var ErrTmShutdown = errors.New("transaction manager is shut down, can not begin new global transaction")
+
+// GetGlobalTransactionManager returns the transaction manager of the default client
 func GetGlobalTransactionManager() *GlobalTransactionManager {
 	if globalTransactionManager == nil {
 		onceGlobalTransactionManager.Do(func() {
@@ -46,15 +51,73 @@ func GetGlobalTransactionManager() *GlobalTransactionManager {
 	return globalTransactionManager
 }
 
-type GlobalTransactionManager struct{}
+// NewGlobalTransactionManager creates a transaction manager which begins and ends the global
+// transactions on the tc connected by the remoting client.
+func NewGlobalTransactionManager(cfg TmConfig, remotingClient *getty.GettyRemotingClient) *GlobalTransactionManager {
+	return &GlobalTransactionManager{
+		remotingClient: remotingClient,
+		config:         &cfg,
+	}
+}
+
+type GlobalTransactionManager struct {
+	shutdown atomic.Bool
+	// remotingClient and config are the ones of the default client if they are nil
+	remotingClient *getty.GettyRemotingClient
+	config         *TmConfig
+}
+
+type transactionManagerKey struct{}
+
+// WithGlobalTransactionManager binds the transaction manager to ctx, the global transactions
+// started by WithGlobalTx with ctx are begun and ended by it instead of the default one.
+func WithGlobalTransactionManager(ctx context.Context, manager *GlobalTransactionManager) context.Context {
+	return context.WithValue(ctx, transactionManagerKey{}, manager)
+}
+
+// getGlobalTransactionManager returns the transaction manager bound to ctx, the default one
+// if ctx has none.
+func getGlobalTransactionManager(ctx context.Context) *GlobalTransactionManager {
+	if manager, ok := ctx.Value(transactionManagerKey{}).(*GlobalTransactionManager); ok && manager != nil {
+		return manager
+	}
+	return GetGlobalTransactionManager()
+}
+
+func (g *GlobalTransactionManager) getRemotingClient() *getty.GettyRemotingClient {
+	if g.remotingClient == nil {
+		return getty.GetGettyRemotingClient()
+	}
+	return g.remotingClient
+}
+
+func (g *GlobalTransactionManager) getConfig() *TmConfig {
+	if g.config == nil {
+		return &config
+	}
+	return g.config
+}
+
+// Shutdown stops accepting new global transactions, the started ones can still be committed or rolled back.
+func (g *GlobalTransactionManager) Shutdown() {
+	g.shutdown.Store(true)
+}
+
+// IsShutdown whether the tm has been shut down
+func (g *GlobalTransactionManager) IsShutdown() bool {
+	return g.shutdown.Load()
+}
 
 // Begin a global transaction with given timeout and given name.
 func (g *GlobalTransactionManager) Begin(ctx context.Context, timeout time.Duration) error {
+	if g.IsShutdown() {
+		return ErrTmShutdown
+	}
 	req := message.GlobalBeginRequest{
 		TransactionName: GetTxName(ctx),
 		Timeout:         timeout,
 	}
-	res, err := getty.GetGettyRemotingClient().SendSyncRequest(req)
+	res, err := g.getRemotingClient().SendSyncRequestToGroup(GetTxServiceGroup(ctx), req)
 	if err != nil {
 		log.Errorf("GlobalBeginRequest  error %v", err)
 		return err
@@ -80,7 +143,7 @@ func (g *GlobalTransactionManager) Commit(ctx context.Context, gtr *GlobalTransa
 	}
 
 	bf := backoff.New(ctx, backoff.Config{
-		MaxRetries: config.CommitRetryCount,
+		MaxRetries: g.getConfig().CommitRetryCount,
 		MinBackoff: 100 * time.Millisecond,
 		MaxBackoff: 200 * time.Millisecond,
 	})
@@ -91,7 +154,7 @@ func (g *GlobalTransactionManager) Commit(ctx context.Context, gtr *GlobalTransa
 	var res interface{}
 	var err error
 	for bf.Ongoing() {
-		if res, err = getty.GetGettyRemotingClient().SendSyncRequest(req); err == nil {
+		if res, err = g.getRemotingClient().SendSyncRequestToGroup(gtr.TxServiceGroup, req); err == nil {
 			break
 		}
 		log.Warnf("send global commit request failed, xid %s, error %v", gtr.Xid, err)
@@ -121,7 +184,7 @@ func (g *GlobalTransactionManager) Rollback(ctx context.Context, gtr *GlobalTran
 	}
 
 	bf := backoff.New(ctx, backoff.Config{
-		MaxRetries: config.RollbackRetryCount,
+		MaxRetries: g.getConfig().RollbackRetryCount,
 		MinBackoff: 100 * time.Millisecond,
 		MaxBackoff: 200 * time.Millisecond,
 	})
@@ -133,7 +196,7 @@ func (g *GlobalTransactionManager) Rollback(ctx context.Context, gtr *GlobalTran
 
 	var err error
 	for bf.Ongoing() {
-		if res, err = getty.GetGettyRemotingClient().SendSyncRequest(req); err == nil {
+		if res, err = g.getRemotingClient().SendSyncRequestToGroup(gtr.TxServiceGroup, req); err == nil {
 			break
 		}
 		log.Errorf("GlobalRollbackRequest rollback failed, xid %s, error %v", gtr.Xid, err)
@@ -60,8 +60,8 @@ func TestBegin(t *testing.T) {
 			wantHasError:       true,
 			wantErrString:      "mock Begin return",
 			wantHasMock:        true,
-			wantMockTargetName: "SendSyncRequest",
-			wantMockFunction: func(_ *getty.GettyRemotingClient, msg interface{}) (interface{}, error) {
+			wantMockTargetName: "SendSyncRequestToGroup",
+			wantMockFunction: func(_ *getty.GettyRemotingClient, _ string, msg interface{}) (interface{}, error) {
 				return nil, errors.New("mock Begin return")
 			},
 		},
@@ -72,8 +72,8 @@ func TestBegin(t *testing.T) {
 			wantHasError:       true,
 			wantErrString:      "GlobalBeginRequest result is empty or result code is failed.",
 			wantHasMock:        true,
-			wantMockTargetName: "SendSyncRequest",
-			wantMockFunction: func(_ *getty.GettyRemotingClient, msg interface{}) (interface{}, error) {
+			wantMockTargetName: "SendSyncRequestToGroup",
+			wantMockFunction: func(_ *getty.GettyRemotingClient, _ string, msg interface{}) (interface{}, error) {
 				return nil, nil
 			},
 		},
@@ -84,8 +84,8 @@ func TestBegin(t *testing.T) {
 			wantHasError:       true,
 			wantErrString:      "GlobalBeginRequest result is empty or result code is failed.",
 			wantHasMock:        true,
-			wantMockTargetName: "SendSyncRequest",
-			wantMockFunction: func(_ *getty.GettyRemotingClient, msg interface{}) (interface{}, error) {
+			wantMockTargetName: "SendSyncRequestToGroup",
+			wantMockFunction: func(_ *getty.GettyRemotingClient, _ string, msg interface{}) (interface{}, error) {
 				return message.GlobalBeginResponse{
 					AbstractTransactionResponse: message.AbstractTransactionResponse{
 						AbstractResultMessage: message.AbstractResultMessage{
@@ -101,8 +101,8 @@ func TestBegin(t *testing.T) {
 			},
 			wantHasError:       false,
 			wantHasMock:        true,
-			wantMockTargetName: "SendSyncRequest",
-			wantMockFunction: func(_ *getty.GettyRemotingClient, msg interface{}) (interface{}, error) {
+			wantMockTargetName: "SendSyncRequestToGroup",
+			wantMockFunction: func(_ *getty.GettyRemotingClient, _ string, msg interface{}) (interface{}, error) {
 				return message.GlobalBeginResponse{
 					AbstractTransactionResponse: message.AbstractTransactionResponse{
 						AbstractResultMessage: message.AbstractResultMessage{
@@ -179,8 +179,8 @@ func TestCommit(t *testing.T) {
 			wantHasError:       true,
 			wantErrString:      "mock error retry",
 			wantHasMock:        true,
-			wantMockTargetName: "SendSyncRequest",
-			wantMockFunction: func(_ *getty.GettyRemotingClient, msg interface{}) (interface{}, error) {
+			wantMockTargetName: "SendSyncRequestToGroup",
+			wantMockFunction: func(_ *getty.GettyRemotingClient, _ string, msg interface{}) (interface{}, error) {
 				return nil, errors.New("mock error retry")
 			},
 		},
@@ -192,8 +192,8 @@ func TestCommit(t *testing.T) {
 			},
 			wantHasError:       false,
 			wantHasMock:        true,
-			wantMockTargetName: "SendSyncRequest",
-			wantMockFunction: func(_ *getty.GettyRemotingClient, msg interface{}) (interface{}, error) {
+			wantMockTargetName: "SendSyncRequestToGroup",
+			wantMockFunction: func(_ *getty.GettyRemotingClient, _ string, msg interface{}) (interface{}, error) {
 				return message.GlobalCommitResponse{
 					AbstractGlobalEndResponse: message.AbstractGlobalEndResponse{
 						GlobalStatus: message.GlobalStatusCommitted,
@@ -269,8 +269,8 @@ func TestRollback(t *testing.T) {
 			wantHasError:       true,
 			wantErrString:      "mock error retry",
 			wantHasMock:        true,
-			wantMockTargetName: "SendSyncRequest",
-			wantMockFunction: func(_ *getty.GettyRemotingClient, msg interface{}) (interface{}, error) {
+			wantMockTargetName: "SendSyncRequestToGroup",
+			wantMockFunction: func(_ *getty.GettyRemotingClient, _ string, msg interface{}) (interface{}, error) {
 				return nil, errors.New("mock error retry")
 			},
 		},
@@ -282,8 +282,8 @@ func TestRollback(t *testing.T) {
 			},
 			wantHasError:       false,
 			wantHasMock:        true,
-			wantMockTargetName: "SendSyncRequest",
-			wantMockFunction: func(_ *getty.GettyRemotingClient, msg interface{}) (interface{}, error) {
+			wantMockTargetName: "SendSyncRequestToGroup",
+			wantMockFunction: func(_ *getty.GettyRemotingClient, _ string, msg interface{}) (interface{}, error) {
 				return message.GlobalRollbackResponse{
 					AbstractGlobalEndResponse: message.AbstractGlobalEndResponse{
 						GlobalStatus: message.GlobalStatusRollbacked,
@@ -313,3 +313,82 @@ func TestRollback(t *testing.T) {
 		}
 	}
 }
+
+func TestNewGlobalTransactionManager(t *testing.T) {
+	remotingClient := getty.NewGettyRemotingClient()
+	manager := NewGlobalTransactionManager(TmConfig{
+		CommitRetryCount:                1,
+		RollbackRetryCount:              1,
+		DefaultGlobalTransactionTimeout: 7 * time.Second,
+	}, remotingClient)
+
+	var requests []interface{}
+	stub := gomonkey.ApplyMethod(reflect.TypeOf(remotingClient), "SendSyncRequestToGroup",
+		func(client *getty.GettyRemotingClient, _ string, msg interface{}) (interface{}, error) {
+			// the requests are sent by the remoting client of the manager only
+			assert.Same(t, remotingClient, client)
+			requests = append(requests, msg)
+			switch msg.(type) {
+			case message.GlobalBeginRequest:
+				return message.GlobalBeginResponse{
+					AbstractTransactionResponse: message.AbstractTransactionResponse{
+						AbstractResultMessage: message.AbstractResultMessage{ResultCode: message.ResultCodeSuccess},
+					},
+					Xid: "xid",
+				}, nil
+			default:
+				return message.GlobalCommitResponse{}, nil
+			}
+		})
+	defer stub.Reset()
+
+	ctx := WithGlobalTransactionManager(context.Background(), manager)
+	err := WithGlobalTx(ctx, &GtxConfig{Name: "newManager"}, func(ctx context.Context) error {
+		assert.Equal(t, "xid", GetXID(ctx))
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Len(t, requests, 2)
+	assert.Equal(t, 7*time.Second, requests[0].(message.GlobalBeginRequest).Timeout)
+	assert.Equal(t, "xid", requests[1].(message.GlobalCommitRequest).Xid)
+
+	// the manager is shut down without the default one
+	manager.Shutdown()
+	assert.ErrorIs(t, manager.Begin(InitSeataContext(ctx), time.Second), ErrTmShutdown)
+	assert.False(t, GetGlobalTransactionManager().IsShutdown())
+	assert.Same(t, GetGlobalTransactionManager(), getGlobalTransactionManager(context.Background()))
+}
+
+func TestWithGlobalTxServiceGroup(t *testing.T) {
+	remotingClient := getty.NewGettyRemotingClient()
+	manager := NewGlobalTransactionManager(TmConfig{CommitRetryCount: 1, RollbackRetryCount: 1}, remotingClient)
+
+	groups := make(map[message.MessageType]string)
+	stub := gomonkey.ApplyMethod(reflect.TypeOf(remotingClient), "SendSyncRequestToGroup",
+		func(_ *getty.GettyRemotingClient, txServiceGroup string, msg interface{}) (interface{}, error) {
+			groups[msg.(message.MessageTypeAware).GetTypeCode()] = txServiceGroup
+			switch msg.(type) {
+			case message.GlobalBeginRequest:
+				return message.GlobalBeginResponse{
+					AbstractTransactionResponse: message.AbstractTransactionResponse{
+						AbstractResultMessage: message.AbstractResultMessage{ResultCode: message.ResultCodeSuccess},
+					},
+					Xid: "xid",
+				}, nil
+			default:
+				return message.GlobalRollbackResponse{}, nil
+			}
+		})
+	defer stub.Reset()
+
+	ctx := WithGlobalTransactionManager(context.Background(), manager)
+	err := WithGlobalTx(ctx, &GtxConfig{Name: "order", TxServiceGroup: "order_tx_group"}, func(ctx context.Context) error {
+		assert.Equal(t, "order_tx_group", GetTxServiceGroup(ctx))
+		return errors.New("business failed")
+	})
+	assert.Error(t, err)
+	assert.Equal(t, map[message.MessageType]string{
+		message.MessageTypeGlobalBegin:    "order_tx_group",
+		message.MessageTypeGlobalRollback: "order_tx_group",
+	}, groups)
+}
@@ -17,8 +17,18 @@
 
 package tm
 
+import "go.uber.org/atomic"
+
 var config TmConfig
 
+// disableGlobalTransaction runs the business of WithGlobalTx without the global transaction, it
+// follows service.disable-global-transaction and may be switched at runtime
+var disableGlobalTransaction atomic.Bool
+
 func InitTm(tmConfig TmConfig) {
 	config = tmConfig
 }
+
+func SetDisableGlobalTransaction(disable bool) {
+	disableGlobalTransaction.Store(disable)
+}
@@ -34,6 +34,9 @@ type GtxConfig struct {
 	Propagation       Propagation
 	LockRetryInternal time.Duration
 	LockRetryTimes    int16
+	// TxServiceGroup the new global transaction is begun, committed and rolled back on the tc
+	// cluster of the group, it is the one in the config if it is empty
+	TxServiceGroup string
 }
 
 // CallbackWithCtx business callback definition
@@ -49,6 +52,11 @@ func WithGlobalTx(ctx context.Context, gc *GtxConfig, business CallbackWithCtx)
 		return fmt.Errorf("global transaction name is required.")
 	}
 
+	if disableGlobalTransaction.Load() {
+		log.Debugf("global transaction is disabled, run %s without it", gc.Name)
+		return business(ctx)
+	}
+
 	// open global transaction for the first time
 	if !IsSeataContext(ctx) {
 		ctx = InitSeataContext(ctx)
@@ -155,11 +163,11 @@ func commitOrRollback(ctx context.Context, isSuccess bool) (re error) {
 	switch *GetTxRole(ctx) {
 	case Launcher:
 		if tx := GetTx(ctx); isSuccess {
-			if re = GetGlobalTransactionManager().Commit(ctx, tx); re != nil {
+			if re = getGlobalTransactionManager(ctx).Commit(ctx, tx); re != nil {
 				log.Errorf("transactionTemplate: commit transaction failed, error %v", re)
 			}
 		} else {
-			if re = GetGlobalTransactionManager().Rollback(ctx, tx); re != nil {
+			if re = getGlobalTransactionManager(ctx).Rollback(ctx, tx); re != nil {
 				log.Errorf("transactionTemplate: Rollback transaction failed, error %v", re)
 			}
 		}
@@ -175,16 +183,18 @@ func commitOrRollback(ctx context.Context, isSuccess bool) (re error) {
 
 // beginNewGtx to construct a default global transaction
 func beginNewGtx(ctx context.Context, gc *GtxConfig) error {
+	manager := getGlobalTransactionManager(ctx)
 	timeout := gc.Timeout
 	if timeout == 0 {
-		timeout = config.DefaultGlobalTransactionTimeout
+		timeout = manager.getConfig().DefaultGlobalTransactionTimeout
 	}
 
 	SetTxRole(ctx, Launcher)
 	SetTxName(ctx, gc.Name)
+	SetTxServiceGroup(ctx, gc.TxServiceGroup)
 	SetTxStatus(ctx, message.GlobalStatusBegin)
 
-	if err := GetGlobalTransactionManager().Begin(ctx, timeout); err != nil {
+	if err := manager.Begin(ctx, timeout); err != nil {
 		return fmt.Errorf("transactionTemplate: Begin transaction failed, error %v", err)
 	}
 	return nil
@@ -204,5 +214,5 @@ func useExistGtx(ctx context.Context, gc *GtxConfig) {
 
 // clearTxConf When using global transactions in local mode, you need to clear tx config to use the propagation of global transactions.
 func clearTxConf(ctx context.Context) {
-	SetTx(ctx, &GlobalTransaction{Xid: GetXID(ctx)})
+	SetTx(ctx, &GlobalTransaction{Xid: GetXID(ctx), TxServiceGroup: GetTxServiceGroup(ctx)})
 }
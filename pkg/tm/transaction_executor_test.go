@@ -501,3 +501,23 @@ func TestWithGlobalTx(t *testing.T) {
 		}
 	}
 }
+
+func TestWithGlobalTx_Disabled(t *testing.T) {
+	SetDisableGlobalTransaction(true)
+	defer SetDisableGlobalTransaction(false)
+
+	beginStub := gomonkey.ApplyFunc(begin, func(ctx context.Context, gc *GtxConfig) error {
+		t.Fatal("global transaction should not begin once it is disabled")
+		return nil
+	})
+	defer beginStub.Reset()
+
+	var called bool
+	err := WithGlobalTx(context.Background(), &GtxConfig{Name: "MockGtxConfig"}, func(ctx context.Context) error {
+		called = true
+		assert.False(t, IsGlobalTx(ctx))
+		return errors.New("mock callback error")
+	})
+	assert.True(t, called)
+	assert.EqualError(t, err, "mock callback error")
+}
@@ -101,3 +101,8 @@ const (
 	// FencePhaseError have fence phase but is not illegal value
 	FencePhaseError
 )
+
+// TransactionErrorCodeNotRaftLeader the tc node which receives the request is not the raft leader.
+// seata server 2.x inserts NotRaftLeader before LockKeyConflictFailFast, so the code shares the wire
+// value with LockKeyConflictFailFast of the old servers, it only makes sense when the tc runs in raft mode.
+const TransactionErrorCodeNotRaftLeader TransactionErrorCode = 19
@@ -19,11 +19,16 @@ package fanout
 
 import (
 	"context"
+	"errors"
 	"log"
 	"runtime"
 	"sync"
+	"time"
 )
 
+// ErrFull is returned by TryDo when the buffer of fanout is full.
+var ErrFull = errors.New("fanout: buffer is full")
+
 type options struct {
 	worker int
 	buffer int
@@ -125,6 +130,38 @@ func (c *Fanout) Do(ctx context.Context, f func(ctx context.Context)) error {
 	return nil
 }
 
+// TryDo save a callback func without blocking, ErrFull is returned if the buffer is full.
+func (c *Fanout) TryDo(ctx context.Context, f func(ctx context.Context)) error {
+	if f == nil || c.ctx.Err() != nil {
+		return c.ctx.Err()
+	}
+	select {
+	case c.ch <- item{f: f, ctx: ctx}:
+	default:
+		return ErrFull
+	}
+	return nil
+}
+
+// Len returns the number of callbacks waiting in the buffer.
+func (c *Fanout) Len() int {
+	return len(c.ch)
+}
+
+// Drain blocks until all buffered callbacks are picked up by the workers or ctx is done.
+func (c *Fanout) Drain(ctx context.Context) error {
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+	for len(c.ch) > 0 {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+	return nil
+}
+
 // Close close fanout.
 func (c *Fanout) Close() error {
 	if err := c.ctx.Err(); err != nil {
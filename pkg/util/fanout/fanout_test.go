@@ -53,3 +53,55 @@ func TestFanout_Close(t *testing.T) {
 		t.Fatal("expect get err")
 	}
 }
+
+func TestFanout_Drain(t *testing.T) {
+	ca := New("cache", WithWorker(1), WithBuffer(1024))
+	var (
+		count int
+		mtx   sync.Mutex
+	)
+	for i := 0; i < 10; i++ {
+		ca.Do(context.Background(), func(c context.Context) {
+			mtx.Lock()
+			count++
+			mtx.Unlock()
+		})
+	}
+
+	if err := ca.Drain(context.Background()); err != nil {
+		t.Fatalf("expect no err, got %v", err)
+	}
+	ca.Close()
+	mtx.Lock()
+	defer mtx.Unlock()
+	if count != 10 {
+		t.Fatalf("expect 10 callbacks be run, got %d", count)
+	}
+}
+
+func TestFanout_TryDo(t *testing.T) {
+	ca := New("cache", WithWorker(1), WithBuffer(1))
+	defer ca.Close()
+
+	block := make(chan struct{})
+	started := make(chan struct{})
+	// occupy the only worker
+	if err := ca.TryDo(context.Background(), func(c context.Context) {
+		close(started)
+		<-block
+	}); err != nil {
+		t.Fatalf("expect no err, got %v", err)
+	}
+	<-started
+	// fill the buffer
+	if err := ca.TryDo(context.Background(), func(c context.Context) {}); err != nil {
+		t.Fatalf("expect no err, got %v", err)
+	}
+	if ca.Len() != 1 {
+		t.Fatalf("expect 1 callback in buffer, got %d", ca.Len())
+	}
+	if err := ca.TryDo(context.Background(), func(c context.Context) {}); err != ErrFull {
+		t.Fatalf("expect ErrFull, got %v", err)
+	}
+	close(block)
+}
@@ -104,7 +104,7 @@ var (
 	zapLogger *zap.Logger
 
 	zapLoggerConfig = zap.Config{
-		// todo read level from config
+		// the level is shared by the loggers, so it can be changed at runtime by SetLevel
 		Level:            zap.NewAtomicLevelAt(zap.InfoLevel),
 		Development:      true,
 		Encoding:         "console",
@@ -162,16 +162,30 @@ func InitWithOption(logPath string, level LogLevel) {
 	encoderConfig.EncodeCaller = encodeCaller
 
 	encoder := zapcore.NewConsoleEncoder(encoderConfig)
-	core := zapcore.NewCore(encoder, syncer, zap.NewAtomicLevelAt(zapcore.Level(level)))
+	zapLoggerConfig.Level.SetLevel(zapcore.Level(level))
+	core := zapcore.NewCore(encoder, syncer, zapLoggerConfig.Level)
 	zapLogger = zap.New(core, zap.AddCaller())
 
 	log = zapLogger.Sugar()
 	getty.SetLogger(log)
 }
 
-// SetLogger: customize yourself logger.
+// SetLevel changes the level of the logger created by Init or InitWithOption
+func SetLevel(level LogLevel) {
+	zapLoggerConfig.Level.SetLevel(zapcore.Level(level))
+}
+
+// ParseLevel parses the level name, such as debug or INFO
+func ParseLevel(text string) (LogLevel, error) {
+	var level LogLevel
+	err := level.UnmarshalText([]byte(text))
+	return level, err
+}
+
+// SetLogger: customize yourself logger, it is used by getty as well.
 func SetLogger(logger Logger) {
 	log = logger
+	getty.SetLogger(logger)
 }
 
 // GetLogger get logger